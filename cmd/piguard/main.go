@@ -4,13 +4,16 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/Fullex26/piguard/internal/config"
 	"github.com/Fullex26/piguard/internal/daemon"
 	"github.com/Fullex26/piguard/internal/doctor"
+	"github.com/Fullex26/piguard/internal/firewall"
 	"github.com/Fullex26/piguard/internal/logging"
 	"github.com/Fullex26/piguard/internal/notifiers"
 	"github.com/Fullex26/piguard/internal/setup"
@@ -35,6 +38,8 @@ func main() {
 		setupCmd(),
 		versionCmd(),
 		doctorCmd(),
+		firewallCmd(),
+		outagesCmd(),
 	)
 
 	if err := root.Execute(); err != nil {
@@ -213,6 +218,160 @@ func doctorCmd() *cobra.Command {
 	}
 }
 
+func firewallCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "firewall",
+		Short: "Snapshot and restore firewall rules",
+	}
+	cmd.AddCommand(firewallSnapshotCmd(), firewallListCmd(), firewallRestoreCmd())
+	return cmd
+}
+
+func firewallSnapshotCmd() *cobra.Command {
+	var label string
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Save the current ruleset (iptables-save or nft) to the store",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			backend, rules, err := firewall.New().Capture()
+			if err != nil {
+				return fmt.Errorf("capturing firewall rules: %w", err)
+			}
+
+			db, err := store.Open(store.DefaultDBPath)
+			if err != nil {
+				return fmt.Errorf("opening store: %w", err)
+			}
+			defer db.Close()
+
+			id, err := db.SaveFirewallSnapshot(store.SnapshotManual, label, backend, string(rules))
+			if err != nil {
+				return fmt.Errorf("saving snapshot: %w", err)
+			}
+			fmt.Printf("✅ Snapshot #%d saved (%s, %d bytes)\n", id, backend, len(rules))
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&label, "label", "l", "manual", "label to store with the snapshot")
+	return cmd
+}
+
+func firewallListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List saved firewall snapshots",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := store.Open(store.DefaultDBPath)
+			if err != nil {
+				return fmt.Errorf("opening store: %w", err)
+			}
+			defer db.Close()
+
+			snaps, err := db.ListFirewallSnapshots()
+			if err != nil {
+				return err
+			}
+			if len(snaps) == 0 {
+				fmt.Println("No firewall snapshots. Create one with: piguard firewall snapshot")
+				return nil
+			}
+
+			fmt.Println("🔥 Firewall Snapshots")
+			fmt.Println("─────────────────────────")
+			for _, snap := range snaps {
+				fmt.Printf("  #%-4d %s  %-8s %-11s %s\n",
+					snap.ID, snap.CreatedAt.Format("2006-01-02 15:04"), snap.Backend, snap.Kind, snap.Label)
+			}
+			return nil
+		},
+	}
+}
+
+func firewallRestoreCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore <id>",
+		Short: "Restore a saved firewall snapshot",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid snapshot id: %q", args[0])
+			}
+
+			db, err := store.Open(store.DefaultDBPath)
+			if err != nil {
+				return fmt.Errorf("opening store: %w", err)
+			}
+			defer db.Close()
+
+			snap, err := db.GetFirewallSnapshot(id)
+			if err != nil {
+				return fmt.Errorf("snapshot #%d not found: %w", id, err)
+			}
+
+			fw := firewall.New()
+			// Save the ruleset being replaced so a bad restore can be undone.
+			if backend, rules, err := fw.Capture(); err == nil {
+				if preID, err := db.SaveFirewallSnapshot(store.SnapshotPreRestore, fmt.Sprintf("pre-restore of #%d", id), backend, string(rules)); err == nil {
+					fmt.Printf("  Current rules saved as snapshot #%d\n", preID)
+				}
+			}
+
+			if err := fw.Restore(snap.Backend, []byte(snap.Rules)); err != nil {
+				return fmt.Errorf("restoring snapshot #%d: %w", id, err)
+			}
+			fmt.Printf("✅ Restored snapshot #%d (%s)\n", snap.ID, snap.Label)
+			return nil
+		},
+	}
+}
+
+func outagesCmd() *cobra.Command {
+	var limit int
+	cmd := &cobra.Command{
+		Use:   "outages",
+		Short: "Show connectivity outage history and availability",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := store.Open(store.DefaultDBPath)
+			if err != nil {
+				return fmt.Errorf("opening store: %w", err)
+			}
+			defer db.Close()
+
+			outages, err := db.ListOutages(limit)
+			if err != nil {
+				return err
+			}
+
+			now := time.Now()
+			fmt.Println("📶 Connectivity Outages")
+			fmt.Println("─────────────────────────")
+			for _, days := range []int{7, 30} {
+				if a, err := db.Availability(now.AddDate(0, 0, -days), now, now); err == nil {
+					fmt.Printf("  Last %d days:  %s\n", days, notifiers.FormatAvailability(a))
+				}
+			}
+			fmt.Println()
+
+			if len(outages) == 0 {
+				fmt.Println("  ✅ No outages recorded")
+				return nil
+			}
+			for _, o := range outages {
+				end := "ongoing"
+				if !o.End.IsZero() {
+					end = o.End.Format("2006-01-02 15:04")
+				}
+				fmt.Printf("  %s → %-16s  %s\n",
+					o.Start.Format("2006-01-02 15:04"), end, notifiers.FormatDowntime(o.Duration(now)))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&limit, "limit", 20, "number of outages to show")
+	return cmd
+}
+
 func versionCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "version",
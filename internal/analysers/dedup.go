@@ -60,11 +60,16 @@ func (d *Deduplicator) dedupKey(event models.Event) string {
 		if event.Port != nil {
 			// Wildcard-exposed ports (0.0.0.0 and ::) are often bound on both
 			// IPv4 and IPv6 by the same process simultaneously. Normalize to
-			// portNum+process so dual-stack binds produce a single alert.
+			// proto+portNum+process so dual-stack binds produce a single alert
+			// while TCP and UDP on the same port stay distinct.
 			if event.Port.IsExposed {
-				return string(event.Type) + ":exposed:" + portFromAddr(event.Port.Address) + ":" + event.Port.ProcessName
+				proto := event.Port.Protocol
+				if proto == "" {
+					proto = "tcp"
+				}
+				return string(event.Type) + ":exposed:" + proto + ":" + portFromAddr(event.Port.Address) + ":" + event.Port.ProcessName
 			}
-			return string(event.Type) + ":" + event.Port.Address
+			return string(event.Type) + ":" + event.Port.Key()
 		}
 	case models.EventFirewallChanged:
 		if event.Firewall != nil {
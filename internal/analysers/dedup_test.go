@@ -203,6 +203,26 @@ func TestShouldAlert_DualStackDedup_DifferentPorts(t *testing.T) {
 	}
 }
 
+func TestShouldAlert_PortProtocolsDistinct(t *testing.T) {
+	d := NewDeduplicator(time.Hour)
+
+	tcp := models.Event{
+		Type: models.EventPortOpened,
+		Port: &models.PortInfo{Address: "0.0.0.0:53", Protocol: "tcp", ProcessName: "dnsmasq", IsExposed: true},
+	}
+	udp := models.Event{
+		Type: models.EventPortOpened,
+		Port: &models.PortInfo{Address: "0.0.0.0:53", Protocol: "udp", ProcessName: "dnsmasq", IsExposed: true},
+	}
+
+	if !d.ShouldAlert(tcp) {
+		t.Error("tcp/53 should alert")
+	}
+	if !d.ShouldAlert(udp) {
+		t.Error("udp/53 should alert independently from tcp/53")
+	}
+}
+
 func TestShouldAlert_GenericDedup(t *testing.T) {
 	d := NewDeduplicator(time.Hour)
 	e := models.Event{Type: models.EventDiskHigh, Message: "disk at 95%"}
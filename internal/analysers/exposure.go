@@ -0,0 +1,372 @@
+package analysers
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Fullex26/piguard/internal/firewall"
+	"github.com/Fullex26/piguard/pkg/models"
+)
+
+// ExposureAnalyser decides whether a listener is actually reachable by
+// evaluating a new inbound connection against the live filter rules. Host
+// listeners are checked against INPUT. Docker-published ports never touch
+// INPUT: DNAT sends them through FORWARD, where Docker's own chains accept
+// them and only DOCKER-USER can stop them, so they are checked there and
+// flagged when they bypass an INPUT that would have dropped them.
+//
+// Evaluation is pessimistic: a rule with matches the parser can't model (an
+// interface other than lo, -d, -m recent, ipsets, ...) is assumed to match if
+// it is an ACCEPT and assumed not to if it is a DROP.
+type ExposureAnalyser struct {
+	mu     sync.Mutex
+	load   func() (v4, v6 *firewall.Ruleset, err error)
+	ttl    time.Duration
+	loaded time.Time
+	v4, v6 *firewall.Ruleset
+}
+
+// NewExposureAnalyser reads rules through iptables-save/ip6tables-save, or
+// nft on nftables-only hosts. Rules are cached for 30 seconds.
+func NewExposureAnalyser() *ExposureAnalyser {
+	return &ExposureAnalyser{load: firewall.New().LoadRulesets, ttl: 30 * time.Second}
+}
+
+// LoadRulesFn overrides the ruleset loader (for testing).
+func (a *ExposureAnalyser) LoadRulesFn(fn func() (v4, v6 *firewall.Ruleset, err error)) {
+	a.load = fn
+	a.loaded = time.Time{}
+}
+
+// Analyse sets port.Reachable. It is left empty when no rules can be read.
+func (a *ExposureAnalyser) Analyse(port models.PortInfo) models.PortInfo {
+	host, portStr, ok := splitHostPortLoose(port.Address)
+	if !ok {
+		return port
+	}
+	portNum, err := strconv.Atoi(portStr)
+	if err != nil {
+		return port
+	}
+	ip := net.ParseIP(host)
+	if host == "*" {
+		ip = net.IPv6unspecified
+	}
+	if ip == nil {
+		return port
+	}
+	if ip.IsLoopback() {
+		port.Reachable = models.ReachLocal
+		return port
+	}
+
+	v4, v6 := a.rules()
+	var rulesets []*firewall.Ruleset
+	switch {
+	case ip.To4() != nil:
+		rulesets = []*firewall.Ruleset{v4}
+	case ip.IsUnspecified():
+		rulesets = []*firewall.Ruleset{v4, v6} // dual-stack wildcard
+	default:
+		rulesets = []*firewall.Ruleset{v6}
+	}
+
+	proto := port.Protocol
+	if proto == "" {
+		proto = "tcp"
+	}
+	verdict := models.Reachability("")
+	for _, rs := range rulesets {
+		if rs == nil {
+			continue
+		}
+		var v models.Reachability
+		if port.Published {
+			v = dockerReachability(rs, proto, portNum)
+		} else {
+			v = hookReachability(rs, "INPUT", packet{proto: proto, dport: portNum, origDport: portNum})
+		}
+		if reachRank(v) > reachRank(verdict) {
+			verdict = v
+		}
+	}
+	port.Reachable = verdict
+	return port
+}
+
+func (a *ExposureAnalyser) rules() (v4, v6 *firewall.Ruleset) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.loaded.IsZero() || time.Since(a.loaded) > a.ttl {
+		v4, v6, err := a.load()
+		if err != nil {
+			v4, v6 = nil, nil
+		}
+		a.v4, a.v6, a.loaded = v4, v6, time.Now()
+	}
+	return a.v4, a.v6
+}
+
+// reachRank orders verdicts from least to most reachable.
+func reachRank(r models.Reachability) int {
+	switch r {
+	case models.ReachLocal:
+		return 1
+	case models.ReachFiltered:
+		return 2
+	case models.ReachLAN:
+		return 3
+	case models.ReachWAN:
+		return 4
+	case models.ReachBypass:
+		return 5
+	}
+	return 0
+}
+
+// hookReachability classifies a new connection from a LAN and from a WAN
+// source through every base chain on the hook.
+func hookReachability(rs *firewall.Ruleset, hook string, p packet) models.Reachability {
+	if len(rs.Hooks[hook]) == 0 {
+		return ""
+	}
+	p.src = srcWAN
+	if hookAccepts(rs, hook, p) {
+		return models.ReachWAN
+	}
+	p.src = srcLAN
+	if hookAccepts(rs, hook, p) {
+		return models.ReachLAN
+	}
+	return models.ReachFiltered
+}
+
+// dockerReachability evaluates a published port on the FORWARD path, where
+// the destination port has already been rewritten to the container's.
+func dockerReachability(rs *firewall.Ruleset, proto string, hostPort int) models.Reachability {
+	p := packet{proto: proto, origDport: hostPort}
+	verdict := hookReachability(rs, "FORWARD", p)
+	if verdict == "" {
+		// No FORWARD chain parsed: nothing stands between DNAT and the container.
+		verdict = models.ReachWAN
+	}
+	if verdict == models.ReachWAN {
+		input := hookReachability(rs, "INPUT", packet{proto: proto, dport: hostPort, origDport: hostPort})
+		if input == models.ReachFiltered || input == models.ReachLAN {
+			return models.ReachBypass
+		}
+	}
+	return verdict
+}
+
+type srcClass int
+
+const (
+	srcWAN srcClass = iota // a public address
+	srcLAN                 // a private/link-local address
+)
+
+// packet is the first packet of a new inbound connection from outside.
+type packet struct {
+	proto     string
+	dport     int // 0 = unknown (after Docker's DNAT)
+	origDport int
+	src       srcClass
+}
+
+type verdict int
+
+const (
+	vContinue verdict = iota // fell off a user chain or RETURNed
+	vAccept
+	vDrop
+)
+
+func hookAccepts(rs *firewall.Ruleset, hook string, p packet) bool {
+	for _, name := range rs.Hooks[hook] {
+		if evalChain(rs, name, p, 0) == vDrop {
+			return false
+		}
+	}
+	return true
+}
+
+func evalChain(rs *firewall.Ruleset, name string, p packet, depth int) verdict {
+	c, ok := rs.Chains[name]
+	if !ok || depth > 16 {
+		return vContinue
+	}
+	for _, r := range c.Rules {
+		m := matchRule(r, p)
+		if m == matchNo {
+			continue
+		}
+		switch r.Target {
+		case "":
+		case "ACCEPT":
+			return vAccept
+		case "DROP", "REJECT":
+			if m == matchYes {
+				return vDrop
+			}
+		case "RETURN":
+			return vContinue
+		default:
+			switch evalChain(rs, r.Target, p, depth+1) {
+			case vAccept:
+				return vAccept
+			case vDrop:
+				if m == matchYes {
+					return vDrop
+				}
+			case vContinue:
+				if r.Goto && m == matchYes {
+					return vContinue
+				}
+			}
+		}
+	}
+	switch c.Policy {
+	case "DROP", "REJECT":
+		return vDrop
+	case "ACCEPT":
+		return vAccept
+	}
+	return vContinue
+}
+
+type match int
+
+const (
+	matchNo match = iota
+	matchMaybe
+	matchYes
+)
+
+func matchRule(r firewall.Rule, p packet) match {
+	m := matchYes
+	and := func(x match) {
+		if x < m {
+			m = x
+		}
+	}
+	if r.Unknown {
+		and(matchMaybe)
+	}
+	if r.Protocol != "" && r.Protocol != "all" && r.Protocol != p.proto {
+		return matchNo
+	}
+	and(matchPorts(r.DstPorts, r.DstPortsNeg, p.dport))
+	and(matchPorts(r.OrigDstPorts, false, p.origDport))
+	and(matchSources(r.Sources, r.SourcesNeg, p.src))
+	and(matchIface(r.InIface, r.InIfaceNeg))
+	and(matchStates(r.States))
+	return m
+}
+
+func matchPorts(ranges []firewall.PortRange, neg bool, port int) match {
+	if ranges == nil {
+		return matchYes
+	}
+	if port == 0 {
+		return matchMaybe
+	}
+	in := false
+	for _, r := range ranges {
+		if r.Contains(port) {
+			in = true
+			break
+		}
+	}
+	if in != neg {
+		return matchYes
+	}
+	return matchNo
+}
+
+// privateNets are the address ranges treated as "LAN".
+var privateNets = func() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, s := range []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "169.254.0.0/16", "fc00::/7", "fe80::/10"} {
+		_, n, _ := net.ParseCIDR(s)
+		nets = append(nets, n)
+	}
+	return nets
+}()
+
+func matchSources(sources []*net.IPNet, neg bool, src srcClass) match {
+	if sources == nil {
+		return matchYes
+	}
+	m := matchNo
+	for _, n := range sources {
+		if x := matchSource(n, src); x > m {
+			m = x
+		}
+	}
+	if neg {
+		return matchYes - m
+	}
+	return m
+}
+
+// matchSource reports whether some address of the class falls inside n.
+// Only a /0 is certain to contain the packet's source.
+func matchSource(n *net.IPNet, src srcClass) match {
+	if ones, _ := n.Mask.Size(); ones == 0 {
+		return matchYes
+	}
+	private, overlapsPrivate := false, false
+	for _, p := range privateNets {
+		pOnes, _ := p.Mask.Size()
+		nOnes, _ := n.Mask.Size()
+		if p.Contains(n.IP) && nOnes >= pOnes {
+			private = true
+		}
+		if n.Contains(p.IP) {
+			overlapsPrivate = true
+		}
+	}
+	switch {
+	case private && src == srcLAN:
+		return matchMaybe
+	case private:
+		return matchNo
+	case overlapsPrivate:
+		return matchMaybe
+	case src == srcWAN:
+		return matchMaybe
+	}
+	return matchNo
+}
+
+// matchIface: the packet arrives on some non-loopback interface.
+func matchIface(iface string, neg bool) match {
+	switch {
+	case iface == "":
+		return matchYes
+	case iface == "lo":
+		if neg {
+			return matchYes
+		}
+		return matchNo
+	}
+	return matchMaybe
+}
+
+func matchStates(states []string) match {
+	if states == nil {
+		return matchYes
+	}
+	for _, s := range states {
+		switch s {
+		case "NEW":
+			return matchYes
+		case "DNAT", "UNTRACKED":
+			return matchMaybe
+		}
+	}
+	return matchNo // ESTABLISHED, RELATED, INVALID only
+}
@@ -0,0 +1,182 @@
+package analysers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Fullex26/piguard/internal/firewall"
+	"github.com/Fullex26/piguard/pkg/models"
+)
+
+// ufwDocker is the classic UFW + Docker host: INPUT defaults to drop and only
+// allows SSH (and 8000 from the LAN), while Docker publishes 8080 via FORWARD.
+const ufwDocker = `*filter
+:INPUT DROP [0:0]
+:FORWARD DROP [0:0]
+:OUTPUT ACCEPT [0:0]
+:DOCKER - [0:0]
+:DOCKER-ISOLATION-STAGE-1 - [0:0]
+:DOCKER-ISOLATION-STAGE-2 - [0:0]
+:DOCKER-USER - [0:0]
+:ufw-before-input - [0:0]
+:ufw-user-input - [0:0]
+:ufw-not-local - [0:0]
+-A INPUT -j ufw-before-input
+-A INPUT -j ufw-user-input
+-A FORWARD -j DOCKER-USER
+-A FORWARD -j DOCKER-ISOLATION-STAGE-1
+-A FORWARD -o docker0 -m conntrack --ctstate RELATED,ESTABLISHED -j ACCEPT
+-A FORWARD -o docker0 -j DOCKER
+-A DOCKER -d 172.17.0.2/32 ! -i docker0 -o docker0 -p tcp -m tcp --dport 80 -j ACCEPT
+-A DOCKER -d 172.17.0.3/32 ! -i docker0 -o docker0 -p udp -m udp --dport 53 -j ACCEPT
+-A DOCKER-ISOLATION-STAGE-1 -i docker0 ! -o docker0 -j DOCKER-ISOLATION-STAGE-2
+-A DOCKER-ISOLATION-STAGE-1 -j RETURN
+-A DOCKER-ISOLATION-STAGE-2 -o docker0 -j DROP
+-A DOCKER-ISOLATION-STAGE-2 -j RETURN
+-A DOCKER-USER -j RETURN
+-A ufw-before-input -i lo -j ACCEPT
+-A ufw-before-input -m conntrack --ctstate RELATED,ESTABLISHED -j ACCEPT
+-A ufw-before-input -m conntrack --ctstate INVALID -j DROP
+-A ufw-before-input -p udp -m udp --sport 67 --dport 68 -j ACCEPT
+-A ufw-before-input -j ufw-not-local
+-A ufw-not-local -m addrtype --dst-type LOCAL -j RETURN
+-A ufw-not-local -j DROP
+-A ufw-user-input -p tcp -m tcp --dport 22 -j ACCEPT
+-A ufw-user-input -s 192.168.1.0/24 -p tcp -m tcp --dport 8000 -j ACCEPT
+COMMIT
+`
+
+func newTestExposure(t *testing.T, v4, v6 string) *ExposureAnalyser {
+	t.Helper()
+	parse := func(dump string) *firewall.Ruleset {
+		if dump == "" {
+			return nil
+		}
+		rs, err := firewall.ParseIPTablesSave([]byte(dump))
+		if err != nil {
+			t.Fatalf("ParseIPTablesSave: %v", err)
+		}
+		return rs
+	}
+	a := NewExposureAnalyser()
+	rs4, rs6 := parse(v4), parse(v6)
+	a.LoadRulesFn(func() (*firewall.Ruleset, *firewall.Ruleset, error) { return rs4, rs6, nil })
+	return a
+}
+
+func TestExposureAnalyser_UFWDocker(t *testing.T) {
+	a := newTestExposure(t, ufwDocker, "")
+	tests := []struct {
+		name string
+		port models.PortInfo
+		want models.Reachability
+	}{
+		{"ssh allowed", models.PortInfo{Address: "0.0.0.0:22", ProcessName: "sshd"}, models.ReachWAN},
+		{"lan-only rule", models.PortInfo{Address: "0.0.0.0:8000", ProcessName: "python3"}, models.ReachLAN},
+		{"dropped by policy", models.PortInfo{Address: "0.0.0.0:3000", ProcessName: "node"}, models.ReachFiltered},
+		{"udp dropped", models.PortInfo{Address: "0.0.0.0:5353", Protocol: "udp", ProcessName: "avahi"}, models.ReachFiltered},
+		{"loopback", models.PortInfo{Address: "127.0.0.1:5432", ProcessName: "postgres"}, models.ReachLocal},
+		{"docker bypass", models.PortInfo{Address: "0.0.0.0:8080", ProcessName: "docker-proxy", Published: true}, models.ReachBypass},
+		{"docker on allowed port", models.PortInfo{Address: "0.0.0.0:22", ProcessName: "docker-proxy", Published: true}, models.ReachWAN},
+		{"wildcard without v6 rules", models.PortInfo{Address: "[::]:3000", ProcessName: "node"}, models.ReachFiltered},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := a.Analyse(tt.port).Reachable; got != tt.want {
+				t.Errorf("Reachable = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExposureAnalyser_DockerUserFilter(t *testing.T) {
+	rules := `*filter
+:INPUT DROP [0:0]
+:FORWARD DROP [0:0]
+:DOCKER - [0:0]
+:DOCKER-USER - [0:0]
+-A FORWARD -j DOCKER-USER
+-A FORWARD -o docker0 -j DOCKER
+-A DOCKER -d 172.17.0.2/32 ! -i docker0 -o docker0 -p tcp -m tcp --dport 80 -j ACCEPT
+-A DOCKER-USER -s 192.168.0.0/16 -j RETURN
+-A DOCKER-USER -p tcp -m conntrack --ctorigdstport 8080 -j DROP
+-A DOCKER-USER -j RETURN
+COMMIT
+`
+	a := newTestExposure(t, rules, "")
+
+	got := a.Analyse(models.PortInfo{Address: "0.0.0.0:8080", ProcessName: "docker-proxy", Published: true}).Reachable
+	if got != models.ReachLAN {
+		t.Errorf("filtered published port = %q, want %q", got, models.ReachLAN)
+	}
+	got = a.Analyse(models.PortInfo{Address: "0.0.0.0:9000", ProcessName: "docker-proxy", Published: true}).Reachable
+	if got != models.ReachBypass {
+		t.Errorf("unfiltered published port = %q, want %q", got, models.ReachBypass)
+	}
+}
+
+func TestExposureAnalyser_DNATOnlyPort(t *testing.T) {
+	a := newTestExposure(t, ufwDocker, "")
+
+	// userland-proxy: false leaves no listener; the port comes from the
+	// runtime and is judged on FORWARD all the same.
+	got := a.Analyse(models.PortInfo{Address: "0.0.0.0:8080", ProcessName: "docker", Published: true}).Reachable
+	if got != models.ReachBypass {
+		t.Errorf("Reachable = %q, want %q", got, models.ReachBypass)
+	}
+	// A rootless forwarder is an ordinary INPUT listener.
+	got = a.Analyse(models.PortInfo{Address: "0.0.0.0:8080", ProcessName: "rootlessport"}).Reachable
+	if got != models.ReachFiltered {
+		t.Errorf("rootless Reachable = %q, want %q", got, models.ReachFiltered)
+	}
+}
+
+func TestExposureAnalyser_OpenFirewall(t *testing.T) {
+	a := newTestExposure(t, "*filter\n:INPUT ACCEPT [0:0]\n:FORWARD ACCEPT [0:0]\nCOMMIT\n", "")
+
+	for _, p := range []models.PortInfo{
+		{Address: "0.0.0.0:3000", ProcessName: "node"},
+		{Address: "0.0.0.0:8080", ProcessName: "docker-proxy", Published: true},
+	} {
+		if got := a.Analyse(p).Reachable; got != models.ReachWAN {
+			t.Errorf("%s %s = %q, want wan (nothing to bypass)", p.ProcessName, p.Address, got)
+		}
+	}
+}
+
+func TestExposureAnalyser_UnknownMatchesArePessimistic(t *testing.T) {
+	rules := `*filter
+:INPUT DROP [0:0]
+-A INPUT -p tcp -m set --match-set blocklist src -j DROP
+-A INPUT -p tcp -m recent --rcheck --seconds 60 -j ACCEPT
+COMMIT
+`
+	a := newTestExposure(t, rules, "")
+	if got := a.Analyse(models.PortInfo{Address: "0.0.0.0:3000"}).Reachable; got != models.ReachWAN {
+		t.Errorf("Reachable = %q, want wan", got)
+	}
+}
+
+func TestExposureAnalyser_DualStack(t *testing.T) {
+	v6 := "*filter\n:INPUT ACCEPT [0:0]\nCOMMIT\n"
+	a := newTestExposure(t, ufwDocker, v6)
+
+	if got := a.Analyse(models.PortInfo{Address: "[::]:3000"}).Reachable; got != models.ReachWAN {
+		t.Errorf("dual-stack wildcard = %q, want wan via open IPv6", got)
+	}
+	if got := a.Analyse(models.PortInfo{Address: "0.0.0.0:3000"}).Reachable; got != models.ReachFiltered {
+		t.Errorf("IPv4 wildcard = %q, want filtered", got)
+	}
+}
+
+func TestExposureAnalyser_NoRules(t *testing.T) {
+	a := NewExposureAnalyser()
+	a.LoadRulesFn(func() (*firewall.Ruleset, *firewall.Ruleset, error) {
+		return nil, nil, errors.New("iptables-save: not found")
+	})
+
+	port := a.Analyse(models.PortInfo{Address: "0.0.0.0:3000"})
+	if port.Reachable != "" {
+		t.Errorf("Reachable = %q, want empty when rules can't be read", port.Reachable)
+	}
+}
@@ -0,0 +1,111 @@
+package analysers
+
+import (
+	"strings"
+
+	"github.com/Fullex26/piguard/pkg/models"
+)
+
+// KnownPort labels a service by address and optionally sets how severe it is
+// for that service to be reachable from the network.
+//
+// Addr is "[proto:]host:port" where host and port may be "*", e.g.
+// "0.0.0.0:8123", "*:6379" or "udp:*:161". Risk is a severity name (info,
+// warning, critical); empty keeps the default.
+type KnownPort struct {
+	Addr  string
+	Label string
+	Risk  string
+}
+
+// builtinKnownPorts is the catalogue of well-known services. Entries marked
+// critical are ones that should essentially never face the network: no auth
+// by default, plaintext credentials, or remote code execution by design.
+var builtinKnownPorts = []KnownPort{
+	{Addr: "tcp:*:21", Label: "FTP", Risk: "warning"},
+	{Addr: "tcp:*:22", Label: "SSH"},
+	{Addr: "tcp:*:23", Label: "Telnet", Risk: "critical"},
+	{Addr: "*:53", Label: "DNS"},
+	{Addr: "tcp:*:80", Label: "HTTP"},
+	{Addr: "tcp:*:111", Label: "rpcbind", Risk: "critical"},
+	{Addr: "udp:*:111", Label: "rpcbind", Risk: "critical"},
+	{Addr: "tcp:*:139", Label: "NetBIOS/SMB", Risk: "critical"},
+	{Addr: "udp:*:161", Label: "SNMP", Risk: "critical"},
+	{Addr: "tcp:*:443", Label: "HTTPS"},
+	{Addr: "tcp:*:445", Label: "SMB", Risk: "critical"},
+	{Addr: "tcp:*:1883", Label: "MQTT", Risk: "warning"},
+	{Addr: "tcp:*:2049", Label: "NFS", Risk: "warning"},
+	{Addr: "tcp:*:2375", Label: "Docker API (unencrypted)", Risk: "critical"},
+	{Addr: "tcp:*:2376", Label: "Docker API (TLS)", Risk: "warning"},
+	{Addr: "tcp:*:3306", Label: "MySQL", Risk: "critical"},
+	{Addr: "tcp:*:3389", Label: "RDP", Risk: "critical"},
+	{Addr: "tcp:*:5432", Label: "PostgreSQL", Risk: "critical"},
+	{Addr: "udp:*:5353", Label: "mDNS"},
+	{Addr: "tcp:*:5900", Label: "VNC", Risk: "critical"},
+	{Addr: "tcp:*:6379", Label: "Redis", Risk: "critical"},
+	{Addr: "tcp:*:8123", Label: "Home Assistant"},
+	{Addr: "tcp:*:9200", Label: "Elasticsearch", Risk: "critical"},
+	{Addr: "*:11211", Label: "Memcached", Risk: "critical"},
+	{Addr: "tcp:*:27017", Label: "MongoDB", Risk: "critical"},
+}
+
+// PortRegistry resolves ports to known services. User entries are checked
+// before the built-in catalogue, so they can relabel or downgrade a service.
+type PortRegistry struct {
+	entries []KnownPort
+}
+
+// NewPortRegistry builds a registry from user entries plus the catalogue.
+func NewPortRegistry(user []KnownPort) *PortRegistry {
+	entries := make([]KnownPort, 0, len(user)+len(builtinKnownPorts))
+	entries = append(entries, user...)
+	entries = append(entries, builtinKnownPorts...)
+	return &PortRegistry{entries: entries}
+}
+
+// Lookup returns the first entry matching the port's protocol and address.
+func (r *PortRegistry) Lookup(port models.PortInfo) (KnownPort, bool) {
+	for _, k := range r.entries {
+		if matchKnownAddr(port, k.Addr) {
+			return k, true
+		}
+	}
+	return KnownPort{}, false
+}
+
+func matchKnownAddr(port models.PortInfo, pattern string) bool {
+	proto := port.Protocol
+	if proto == "" {
+		proto = "tcp"
+	}
+	for _, p := range []string{"tcp:", "udp:", "raw:"} {
+		if rest, ok := strings.CutPrefix(pattern, p); ok {
+			if p[:len(p)-1] != proto {
+				return false
+			}
+			pattern = rest
+			break
+		}
+	}
+
+	wantHost, wantPort, ok := splitHostPortLoose(pattern)
+	if !ok {
+		return false
+	}
+	host, portNum, ok := splitHostPortLoose(port.Address)
+	if !ok {
+		return false
+	}
+	return (wantHost == "*" || wantHost == host) && (wantPort == "*" || wantPort == portNum)
+}
+
+// splitHostPortLoose splits on the last colon so ss-style unbracketed IPv6
+// (":::443", "::1:5432") works as well as "[::1]:5432".
+func splitHostPortLoose(addr string) (host, port string, ok bool) {
+	idx := strings.LastIndex(addr, ":")
+	if idx < 0 {
+		return "", "", false
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(addr[:idx], "["), "]")
+	return host, addr[idx+1:], true
+}
@@ -0,0 +1,74 @@
+package analysers
+
+import (
+	"testing"
+
+	"github.com/Fullex26/piguard/pkg/models"
+)
+
+func TestPortRegistry_Lookup(t *testing.T) {
+	r := NewPortRegistry([]KnownPort{
+		{Addr: "0.0.0.0:8123", Label: "Home Assistant UI", Risk: "info"},
+		{Addr: "*:6379", Label: "Cache (trusted LAN)", Risk: "warning"},
+	})
+
+	tests := []struct {
+		name      string
+		port      models.PortInfo
+		wantLabel string
+		wantRisk  string
+		wantOK    bool
+	}{
+		{"user exact", models.PortInfo{Address: "0.0.0.0:8123", Protocol: "tcp"}, "Home Assistant UI", "info", true},
+		{"user overrides builtin", models.PortInfo{Address: "0.0.0.0:6379", Protocol: "tcp"}, "Cache (trusted LAN)", "warning", true},
+		{"builtin telnet", models.PortInfo{Address: "0.0.0.0:23", Protocol: "tcp"}, "Telnet", "critical", true},
+		{"builtin docker api ipv6", models.PortInfo{Address: ":::2375", Protocol: "tcp"}, "Docker API (unencrypted)", "critical", true},
+		{"builtin udp only", models.PortInfo{Address: "0.0.0.0:161", Protocol: "udp"}, "SNMP", "critical", true},
+		{"protocol mismatch", models.PortInfo{Address: "0.0.0.0:161", Protocol: "tcp"}, "", "", false},
+		{"any protocol", models.PortInfo{Address: "0.0.0.0:53", Protocol: "udp"}, "DNS", "", true},
+		{"empty protocol is tcp", models.PortInfo{Address: "0.0.0.0:3306"}, "MySQL", "critical", true},
+		{"unknown", models.PortInfo{Address: "0.0.0.0:31337", Protocol: "tcp"}, "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, ok := r.Lookup(tt.port)
+			if ok != tt.wantOK || k.Label != tt.wantLabel || k.Risk != tt.wantRisk {
+				t.Errorf("Lookup(%s) = %+v, %v; want label %q risk %q, %v",
+					tt.port.Key(), k, ok, tt.wantLabel, tt.wantRisk, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestMatchKnownAddr_Host(t *testing.T) {
+	port := models.PortInfo{Address: "192.168.1.10:8080", Protocol: "tcp"}
+	if !matchKnownAddr(port, "192.168.1.10:8080") {
+		t.Error("exact host should match")
+	}
+	if matchKnownAddr(port, "127.0.0.1:8080") {
+		t.Error("different host should not match")
+	}
+	if !matchKnownAddr(port, "192.168.1.10:*") {
+		t.Error("wildcard port should match")
+	}
+	if !matchKnownAddr(models.PortInfo{Address: "::1:5432"}, "[::1]:5432") {
+		t.Error("bracketed IPv6 pattern should match ss-style address")
+	}
+}
+
+func TestPortLabeller_Label_KnownPort(t *testing.T) {
+	l := NewPortLabeller()
+	l.SetKnownPorts([]KnownPort{{Addr: "*:8123", Label: "Home Assistant UI", Risk: "info"}})
+
+	// Known ports are labelled even when the owning PID is unknown.
+	got := l.Label(models.PortInfo{Address: "0.0.0.0:8123", Protocol: "tcp", IsExposed: true})
+	if got.Label != "Home Assistant UI" || got.RiskLevel() != models.SeverityInfo {
+		t.Errorf("got label %q severity %v, want Home Assistant UI/info", got.Label, got.RiskLevel())
+	}
+
+	got = l.Label(models.PortInfo{Address: "0.0.0.0:6379", Protocol: "tcp", IsExposed: true})
+	if got.Label != "Redis" || got.RiskLevel() != models.SeverityCritical {
+		t.Errorf("got label %q severity %v, want Redis/critical", got.Label, got.RiskLevel())
+	}
+}
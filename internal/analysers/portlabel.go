@@ -1,20 +1,30 @@
 package analysers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/Fullex26/piguard/internal/dockerapi"
 	"github.com/Fullex26/piguard/pkg/models"
 )
 
-// PortLabeller resolves port ownership: PID → process → container
+// PortLabeller resolves port ownership: PID → process → container, and
+// names the service from the known-ports registry.
 type PortLabeller struct {
+	known            *PortRegistry
 	containerCache   map[int]containerInfo // PID -> container info
 	readProcessName  func(pid int) string
 	resolveContainer func(addr string) containerInfo
+	docker           *dockerapi.Client
+	runtime          dockerapi.Runtime // CLI fallback when the socket is unreachable
+	listContainers   func(ctx context.Context) ([]dockerapi.Container, error)
 }
 
 type containerInfo struct {
@@ -24,20 +34,57 @@ type containerInfo struct {
 
 func NewPortLabeller() *PortLabeller {
 	l := &PortLabeller{
+		known:          NewPortRegistry(nil),
 		containerCache: make(map[int]containerInfo),
+		docker:         dockerapi.New(""),
+		runtime:        dockerapi.RuntimeDocker,
 	}
 	l.readProcessName = l.getProcessName
 	l.resolveContainer = l.resolveDockerContainer
+	l.listContainers = func(ctx context.Context) ([]dockerapi.Container, error) {
+		return l.docker.ListContainers(ctx, false)
+	}
 	return l
 }
 
+// ListContainersFn overrides how running containers are listed (for testing).
+func (l *PortLabeller) ListContainersFn(fn func(ctx context.Context) ([]dockerapi.Container, error)) {
+	l.listContainers = fn
+}
+
 // ReadProcessNameFn overrides the process name resolution function (for testing).
 func (l *PortLabeller) ReadProcessNameFn(fn func(pid int) string) {
 	l.readProcessName = fn
 }
 
-// Label enriches a PortInfo with process and container details
+// SetDockerSocket points container resolution at a non-default Engine API socket.
+func (l *PortLabeller) SetDockerSocket(path string) {
+	l.docker = dockerapi.New(path)
+}
+
+// SetRuntime selects the container runtime whose CLI is used when the socket
+// can't be reached. "" and "auto" keep docker.
+func (l *PortLabeller) SetRuntime(rt dockerapi.Runtime) {
+	if rt == dockerapi.RuntimePodman {
+		l.runtime = rt
+	}
+}
+
+// SetKnownPorts replaces the user-defined known ports (ports.known). The
+// built-in catalogue is always consulted after them.
+func (l *PortLabeller) SetKnownPorts(known []KnownPort) {
+	l.known = NewPortRegistry(known)
+}
+
+// Label enriches a PortInfo with service label, risk, process and container details
 func (l *PortLabeller) Label(port models.PortInfo) models.PortInfo {
+	if l.known != nil {
+		if k, ok := l.known.Lookup(port); ok {
+			port.Label = k.Label
+			port.Risk = k.Risk
+		}
+	}
+
 	if port.PID == 0 {
 		return port
 	}
@@ -47,16 +94,87 @@ func (l *PortLabeller) Label(port models.PortInfo) models.PortInfo {
 		port.ProcessName = l.readProcessName(port.PID)
 	}
 
-	// If this is docker-proxy, resolve the actual container
-	if port.ProcessName == "docker-proxy" {
+	// If a container runtime's port forwarder owns it, resolve the container
+	if IsContainerProxy(port.ProcessName) {
 		ci := l.resolveContainer(port.Address)
 		port.ContainerName = ci.Name
 		port.ContainerID = ci.ID
+		port.Published = port.Published || dnatForwarders[port.ProcessName]
 	}
 
 	return port
 }
 
+// PublishedPorts lists the host ports that running containers publish, as
+// the Engine API reports them. Docker with userland-proxy disabled and
+// rootful Podman with netavark publish ports with DNAT rules alone, so no
+// process listens on them and only the runtime knows they are open.
+func (l *PortLabeller) PublishedPorts() ([]models.PortInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	containers, err := l.listContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var ports []models.PortInfo
+	for _, c := range containers {
+		for _, p := range c.Ports {
+			if p.PublicPort == 0 {
+				continue
+			}
+			ip := net.ParseIP(p.IP)
+			if ip == nil {
+				ip = net.IPv4zero
+			}
+			proto := p.Type
+			if proto == "" {
+				proto = "tcp"
+			}
+			port := models.PortInfo{
+				Address:       ip.String() + ":" + strconv.Itoa(int(p.PublicPort)),
+				Protocol:      proto,
+				ProcessName:   l.runtime.CLI(),
+				ContainerName: c.Name(),
+				ContainerID:   c.ID,
+				IsExposed:     ip.IsUnspecified(),
+				Published:     true,
+			}
+			if !seen[port.Key()] {
+				seen[port.Key()] = true
+				ports = append(ports, l.Label(port))
+			}
+		}
+	}
+	return ports, nil
+}
+
+// containerProxies are the processes that hold published container ports on
+// the host: Docker's userland proxy, rootless Podman's rootlessport (slirp4netns)
+// and pasta forwarders, and conmon, which reserves ports for rootful Podman.
+var containerProxies = map[string]bool{
+	"docker-proxy":       true,
+	"rootlessport":       true,
+	"rootlessport-child": true,
+	"pasta":              true,
+	"pasta.avx2":         true,
+	"conmon":             true,
+}
+
+// dnatForwarders are the container proxies of rootful runtimes, which also
+// DNAT the port to the container: its traffic takes FORWARD, not INPUT.
+// Rootless forwarders are ordinary INPUT listeners.
+var dnatForwarders = map[string]bool{
+	"docker-proxy": true,
+	"conmon":       true,
+}
+
+// IsContainerProxy reports whether a process name is a container port
+// forwarder, so the port belongs to a container rather than the process.
+func IsContainerProxy(name string) bool {
+	return containerProxies[name]
+}
+
 func (l *PortLabeller) getProcessName(pid int) string {
 	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", pid))
 	if err != nil {
@@ -74,11 +192,21 @@ func (l *PortLabeller) resolveDockerContainer(addr string) containerInfo {
 	}
 	port := parts[len(parts)-1]
 
-	// Use docker inspect to find the container with this published port
-	out, err := exec.Command("docker", "ps", "--format", "json").Output()
+	// Ask the Engine API which running container publishes this port
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if containers, err := l.docker.ListContainers(ctx, false); err == nil {
+		return publisherOf(containers, port)
+	}
+
+	// No socket access: fall back to the runtime's CLI
+	out, err := exec.Command(l.runtime.CLI(), "ps", "--format", "json").Output()
 	if err != nil {
 		return containerInfo{}
 	}
+	if containers, ok, _ := dockerapi.ParsePS(out); ok { // podman prints one array
+		return publisherOf(containers, port)
+	}
 
 	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
 		if line == "" {
@@ -103,3 +231,15 @@ func (l *PortLabeller) resolveDockerContainer(addr string) containerInfo {
 
 	return containerInfo{}
 }
+
+// publisherOf returns the container that publishes host port port.
+func publisherOf(containers []dockerapi.Container, port string) containerInfo {
+	for _, c := range containers {
+		for _, p := range c.Ports {
+			if p.PublicPort != 0 && strconv.Itoa(int(p.PublicPort)) == port {
+				return containerInfo{Name: c.Name(), ID: c.ID}
+			}
+		}
+	}
+	return containerInfo{}
+}
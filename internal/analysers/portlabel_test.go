@@ -1,8 +1,10 @@
 package analysers
 
 import (
+	"context"
 	"testing"
 
+	"github.com/Fullex26/piguard/internal/dockerapi"
 	"github.com/Fullex26/piguard/pkg/models"
 )
 
@@ -62,6 +64,67 @@ func TestPortLabeller_Label_DockerProxy(t *testing.T) {
 	}
 }
 
+func TestPortLabeller_Label_PodmanForwarders(t *testing.T) {
+	for _, proc := range []string{"rootlessport", "pasta", "conmon"} {
+		l := NewPortLabeller()
+		l.readProcessName = func(pid int) string { return proc }
+		l.resolveContainer = func(addr string) containerInfo {
+			return containerInfo{Name: "jellyfin", ID: "def456"}
+		}
+
+		result := l.Label(models.PortInfo{PID: 999, Address: "0.0.0.0:8096"})
+		if result.ContainerName != "jellyfin" {
+			t.Errorf("%s: ContainerName = %q, want jellyfin", proc, result.ContainerName)
+		}
+		if want := proc == "conmon"; result.Published != want {
+			t.Errorf("%s: Published = %v, want %v", proc, result.Published, want)
+		}
+	}
+}
+
+func TestPortLabeller_PublishedPorts(t *testing.T) {
+	l := NewPortLabeller()
+	l.SetKnownPorts([]KnownPort{{Addr: "*:8123", Label: "Home Assistant", Risk: "critical"}})
+	l.ListContainersFn(func(ctx context.Context) ([]dockerapi.Container, error) {
+		return []dockerapi.Container{
+			{ID: "a", Names: []string{"/db"}, Ports: []dockerapi.Port{{PrivatePort: 5432, Type: "tcp"}}},
+			{ID: "b", Names: []string{"/hass"}, Ports: []dockerapi.Port{
+				{IP: "0.0.0.0", PrivatePort: 8123, PublicPort: 8123, Type: "tcp"},
+				{IP: "::", PrivatePort: 8123, PublicPort: 8123, Type: "tcp"},
+				{IP: "127.0.0.1", PrivatePort: 53, PublicPort: 5353, Type: "udp"},
+			}},
+		}, nil
+	})
+
+	ports, err := l.PublishedPorts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ports) != 3 {
+		t.Fatalf("got %d ports, want 3 (unpublished 5432 skipped): %+v", len(ports), ports)
+	}
+	p := ports[0]
+	if p.Key() != "tcp:0.0.0.0:8123" || !p.Published || !p.IsExposed || p.ContainerName != "hass" || p.Label != "Home Assistant" {
+		t.Errorf("unexpected port: %+v", p)
+	}
+	if p := ports[2]; p.Key() != "udp:127.0.0.1:5353" || p.IsExposed {
+		t.Errorf("unexpected loopback port: %+v", p)
+	}
+}
+
+func TestPublisherOf(t *testing.T) {
+	containers := []dockerapi.Container{
+		{ID: "a", Names: []string{"/db"}, Ports: []dockerapi.Port{{PrivatePort: 5432}}},
+		{ID: "b", Names: []string{"/web"}, Ports: []dockerapi.Port{{IP: "0.0.0.0", PrivatePort: 80, PublicPort: 8080}}},
+	}
+	if got := publisherOf(containers, "8080"); got.Name != "web" || got.ID != "b" {
+		t.Errorf("publisherOf(8080) = %+v", got)
+	}
+	if got := publisherOf(containers, "5432"); got.Name != "" {
+		t.Errorf("unpublished port resolved to %+v", got)
+	}
+}
+
 func TestPortLabeller_Label_NonDockerProcess(t *testing.T) {
 	l := NewPortLabeller()
 	l.readProcessName = func(pid int) string { return "node" }
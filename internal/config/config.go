@@ -2,7 +2,9 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"path"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -22,6 +24,9 @@ type Config struct {
 	SecurityTools   SecurityToolsConfig  `yaml:"security_tools"`
 	Network         NetworkConfig        `yaml:"network"`
 	Connectivity    ConnectivityConfig   `yaml:"connectivity"`
+	DNS             DNSConfig            `yaml:"dns"`
+	Addresses       AddressConfig        `yaml:"addresses"`
+	Outbound        OutboundConfig       `yaml:"outbound"`
 	AutoUpdate      AutoUpdateConfig     `yaml:"auto_update"`
 	Backup          BackupConfig         `yaml:"backup"`
 	AuthLog         AuthLogConfig        `yaml:"auth_log"`
@@ -61,16 +66,18 @@ type WebhookConfig struct {
 }
 
 type PortConfig struct {
-	Enabled  bool        `yaml:"enabled"`
-	Ignore   []string    `yaml:"ignore"`
-	Known    []KnownPort `yaml:"known"`
-	Cooldown string      `yaml:"cooldown"`
+	Enabled          bool        `yaml:"enabled"`
+	Protocols        []string    `yaml:"protocols"` // tcp, udp, raw; default: tcp + udp
+	Ignore           []string    `yaml:"ignore"`
+	Known            []KnownPort `yaml:"known"`
+	Cooldown         string      `yaml:"cooldown"`
+	ExposureAnalysis bool        `yaml:"exposure_analysis"` // check new listeners against iptables/nftables rules
 }
 
 type KnownPort struct {
-	Addr  string `yaml:"addr"`
-	Label string `yaml:"label"`
-	Risk  string `yaml:"risk"`
+	Addr  string `yaml:"addr"`  // [proto:]host:port, "*" wildcards allowed
+	Label string `yaml:"label"` // e.g. "Home Assistant UI"
+	Risk  string `yaml:"risk"`  // severity when exposed: info, warning, critical
 }
 
 type FirewallConfig struct {
@@ -93,10 +100,11 @@ type SystemConfig struct {
 }
 
 type AlertConfig struct {
-	MinSeverity  string     `yaml:"min_severity"`
-	DailySummary string     `yaml:"daily_summary"`
-	WeeklyReport string     `yaml:"weekly_report"` // e.g. "sunday:20:00"
-	QuietHours   QuietHours `yaml:"quiet_hours"`
+	MinSeverity   string     `yaml:"min_severity"`
+	DailySummary  string     `yaml:"daily_summary"`
+	WeeklyReport  string     `yaml:"weekly_report"`  // e.g. "sunday:20:00"
+	MonthlyReport string     `yaml:"monthly_report"` // e.g. "1:09:00" (day of month 1-28)
+	QuietHours    QuietHours `yaml:"quiet_hours"`
 }
 
 type QuietHours struct {
@@ -111,20 +119,71 @@ type BaselineConfig struct {
 
 type DockerConfig struct {
 	Enabled      bool   `yaml:"enabled"`
-	PollInterval string `yaml:"poll_interval"` // default: "10s"
+	Runtime      string `yaml:"runtime"`       // "auto" (default), "docker" or "podman"
+	Socket       string `yaml:"socket"`        // Engine API socket, default: "/var/run/docker.sock"; Podman's is found automatically
+	PollInterval string `yaml:"poll_interval"` // default: "10s"; used when the event stream is unavailable
 	AlertOnStop  bool   `yaml:"alert_on_stop"` // alert on graceful stop (default: false)
+
+	CrashLoopRestarts int    `yaml:"crash_loop_restarts"` // restarts within crash_loop_window that count as a loop; 0 disables
+	CrashLoopWindow   string `yaml:"crash_loop_window"`   // default: "5m"
+
+	PostureAudit  bool     `yaml:"posture_audit"`  // audit the configuration of running and newly started containers (default: true)
+	PostureRootUser bool   `yaml:"posture_root_user"` // also flag containers running as root (default: false; most images do)
+	PostureIgnore []string `yaml:"posture_ignore"` // "check" or "container:check", e.g. "root_user", "watchtower:docker_socket"
+
+	ActivityAudit bool       `yaml:"activity_audit"` // record docker exec/cp, image pull/tag/delete, volume and network creation (default: true)
+	ExecAllowlist []ExecRule `yaml:"exec_allowlist"` // routine execs not to report; the container's own healthcheck is always skipped
+
+	Resources DockerResourcesConfig `yaml:"resources"`
+}
+
+// ExecRule allowlists execs whose command matches Command ("*" matches any
+// run of characters) in containers whose name matches Container (a glob;
+// empty = any container).
+type ExecRule struct {
+	Container string `yaml:"container"`
+	Command   string `yaml:"command"`
+}
+
+// DockerResourcesConfig sets the per-container usage thresholds read from
+// cgroup v2. A threshold of 0 disables that check.
+type DockerResourcesConfig struct {
+	Enabled       bool                  `yaml:"enabled"`
+	Interval      string                `yaml:"interval"`       // default: "30s"
+	Sustained     string                `yaml:"sustained"`      // how long a threshold must be exceeded before alerting, default: "5m"
+	CPUPercent    int                   `yaml:"cpu_percent"`    // 100 = one full core, as in docker stats
+	MemoryPercent int                   `yaml:"memory_percent"` // of the container's memory limit, or of host RAM if unlimited
+	Pids          int                   `yaml:"pids"`           // number of processes and threads
+	IOMBps        int                   `yaml:"io_mbps"`        // block I/O read+write, MB/s
+	Containers    []ContainerThresholds `yaml:"containers"`     // per-container overrides
+}
+
+// ContainerThresholds overrides the resource thresholds for containers whose
+// name matches Name (globs allowed). 0 keeps the default, -1 disables.
+type ContainerThresholds struct {
+	Name          string `yaml:"name"`
+	CPUPercent    int    `yaml:"cpu_percent"`
+	MemoryPercent int    `yaml:"memory_percent"`
+	Pids          int    `yaml:"pids"`
+	IOMBps        int    `yaml:"io_mbps"`
+	Sustained     string `yaml:"sustained"`
 }
 
 type FileIntegrityConfig struct {
-	Enabled  bool        `yaml:"enabled"`
-	Paths    []WatchPath `yaml:"paths"`
-	Cooldown string      `yaml:"cooldown"`
+	Enabled      bool        `yaml:"enabled"`
+	Paths        []WatchPath `yaml:"paths"`
+	Cooldown     string      `yaml:"cooldown"`
+	MaxWatches   int         `yaml:"max_watches"`   // inotify watches across all paths; 0 = no limit
+	ScanInterval string      `yaml:"scan_interval"` // full scan against the stored hashes; "" = at startup only
+	DiffMaxSize  int         `yaml:"diff_max_size"` // bytes; text files up to this size are kept for diffs; 0 = no diffs
+	DiffExclude  []string    `yaml:"diff_exclude"`  // globs never kept or diffed, besides shadow files; without "/" they match the file name
 }
 
 type WatchPath struct {
 	Path        string `yaml:"path"`
 	Description string `yaml:"description"`
-	Severity    string `yaml:"severity"` // "warning" or "critical"
+	Severity    string `yaml:"severity"`  // "warning" or "critical"
+	Recursive   bool   `yaml:"recursive"` // directories: watch subdirectories too, including new ones
 }
 
 type SecurityToolsConfig struct {
@@ -139,6 +198,30 @@ type NetworkConfig struct {
 	PollInterval string   `yaml:"poll_interval"` // default: "5m"
 	AlertOnLeave bool     `yaml:"alert_on_leave"` // alert when known device leaves
 	IgnoreMACs   []string `yaml:"ignore_macs"`    // MACs to never alert on
+	OUIFile      string   `yaml:"oui_file"`       // vendor registry merged over the embedded one (IEEE oui.txt, nmap or Wireshark format); "" = system copy if installed
+	LeaseFiles   []string `yaml:"lease_files"`    // DHCP lease files for hostnames; empty = dnsmasq, Pi-hole and isc-dhcp defaults
+	SpoofDetection bool   `yaml:"spoof_detection"` // alert on gateway MAC changes, MACs claiming many IPs and IPs flapping between MACs
+	MACIPLimit     int    `yaml:"mac_ip_limit"`    // IPv4 addresses one MAC may hold before it is flagged; 0 disables
+	IPFlapWindow   string `yaml:"ip_flap_window"`  // default: "1h"; an IP changing MAC twice within it is flagged
+	ActiveScan     bool   `yaml:"active_scan"`     // sweep the subnet with ARP requests each poll (needs CAP_NET_RAW)
+	Interface      string `yaml:"interface"`       // interface to sweep; "" = the default route's
+	ScanRate       int    `yaml:"scan_rate"`       // ARP requests per second during a sweep; default 50
+	DeviceRetentionDays int `yaml:"device_retention_days"` // forget unnamed, untrusted devices unseen this long; 0 = keep forever
+
+	DHCP DHCPCheckConfig `yaml:"dhcp"`
+}
+
+// DHCPCheckConfig configures the rogue DHCP server check: a DHCPDISCOVER is
+// broadcast on the interface and every offer that comes back is compared with
+// the expected servers, router and DNS servers.
+type DHCPCheckConfig struct {
+	Enabled   bool     `yaml:"enabled"`
+	Interval  string   `yaml:"interval"`  // default: "15m"
+	Timeout   string   `yaml:"timeout"`   // how long to collect offers, default: "5s"
+	Interface string   `yaml:"interface"` // "" = network.interface, then the default route's
+	Servers   []string `yaml:"servers"`   // allowed DHCP server IPs; empty = alert only when more than one server answers
+	Routers   []string `yaml:"routers"`   // expected router option; empty = the default gateway
+	DNS       []string `yaml:"dns"`       // expected DNS servers; empty = not checked
 }
 
 type AutoUpdateConfig struct {
@@ -153,6 +236,61 @@ type ConnectivityConfig struct {
 	Enabled      bool     `yaml:"enabled"`
 	PollInterval string   `yaml:"poll_interval"` // default: "30s"
 	Hosts        []string `yaml:"hosts"`          // TCP dial targets, e.g. "8.8.8.8:53"
+	Probes       []ProbeConfig `yaml:"probes"`    // additional typed probes
+
+	Window          int    `yaml:"window"`           // results kept per probe for latency and loss, default 20
+	DegradedLatency string `yaml:"degraded_latency"` // average latency above which the link is degraded, default "500ms"
+	DegradedLoss    int    `yaml:"degraded_loss"`    // loss percentage above which the link is degraded, default 20
+}
+
+// ProbeConfig is one connectivity probe. Target is "host:port" for tcp, a
+// host for icmp, the name to resolve for dns and a URL for http.
+type ProbeConfig struct {
+	Name         string `yaml:"name"`          // label in alerts and /status; default: "<type> <target>"
+	Type         string `yaml:"type"`          // "tcp", "icmp", "dns" or "http"
+	Target       string `yaml:"target"`
+	Resolver     string `yaml:"resolver"`      // dns only: resolver "ip[:port]"; "" = system resolver
+	ExpectStatus int    `yaml:"expect_status"` // http only: default 200
+	Timeout      string `yaml:"timeout"`       // default: "3s"
+}
+
+// DNSConfig watches the resolvers in use and checks that canary names
+// resolve to the addresses they should.
+type DNSConfig struct {
+	Enabled         bool        `yaml:"enabled"`
+	PollInterval    string      `yaml:"poll_interval"`    // default: "5m"
+	Timeout         string      `yaml:"timeout"`          // per lookup, default: "5s"
+	ResolvConf      string      `yaml:"resolv_conf"`      // default: "/etc/resolv.conf"
+	TrustedResolver string      `yaml:"trusted_resolver"` // "ip[:port]" canaries without expect are compared with; "" = none
+	Canaries        []DNSCanary `yaml:"canaries"`
+}
+
+// DNSCanary is a name resolved through the system resolver each poll.
+type DNSCanary struct {
+	Name   string   `yaml:"name"`
+	Expect []string `yaml:"expect"` // IPs or CIDRs every IPv4 answer must be in; empty = compare with trusted_resolver
+}
+
+// AddressConfig watches local interface addresses and links over rtnetlink
+// and, optionally, the public IP.
+type AddressConfig struct {
+	Enabled          bool     `yaml:"enabled"`
+	Ignore           []string `yaml:"ignore"`             // interface name globs not watched, e.g. "veth*"
+	PublicIPURL      string   `yaml:"public_ip_url"`      // "what is my IP" endpoint answering with the bare address; "" = off
+	PublicIPInterval string   `yaml:"public_ip_interval"` // default: "10m"
+}
+
+type OutboundConfig struct {
+	Enabled            bool     `yaml:"enabled"`
+	PollInterval       string   `yaml:"poll_interval"`       // default: "10s"
+	LearningPeriod     string   `yaml:"learning_period"`     // default: "1h"; destinations are learned silently
+	BlockedPorts       []int    `yaml:"blocked_ports"`       // remote ports that always alert (mining pools, IRC, Tor)
+	IgnoreProcesses    []string `yaml:"ignore_processes"`    // process or "container:<name>" owners to skip
+	IgnoreDestinations []string `yaml:"ignore_destinations"` // [proto:]host:port, host may be a CIDR, "*" wildcards
+	IgnoreSelf         bool     `yaml:"ignore_self"`         // skip piguard's own connections and the commands it runs (default: true)
+	DestinationKey     string   `yaml:"destination_key"`     // "network" (default): owner + remote /24 (IPv6 /48) + port; "port": remote port only
+	BaselineTTL        string   `yaml:"baseline_ttl"`        // default: "720h"; destinations unseen this long are forgotten
+	BaselineMax        int      `yaml:"baseline_max"`        // default: 5000; least recently seen destinations are dropped past it
 }
 
 type BackupConfig struct {
@@ -206,9 +344,11 @@ func Load(path string) (*Config, error) {
 func DefaultConfig() *Config {
 	return &Config{
 		Ports: PortConfig{
-			Enabled:  true,
-			Cooldown: "15m",
-			Ignore:   []string{"127.0.0.1:*", "::1:*"},
+			Enabled:          true,
+			Protocols:        []string{"tcp", "udp"},
+			Cooldown:         "15m",
+			Ignore:           []string{"127.0.0.1:*", "::1:*"},
+			ExposureAnalysis: true,
 		},
 		Firewall: FirewallConfig{
 			Enabled:       true,
@@ -224,9 +364,10 @@ func DefaultConfig() *Config {
 			TempThreshold:   75,
 		},
 		Alerts: AlertConfig{
-			MinSeverity:  "warning",
-			DailySummary: "08:00",
-			WeeklyReport: "sunday:20:00",
+			MinSeverity:   "warning",
+			DailySummary:  "08:00",
+			WeeklyReport:  "sunday:20:00",
+			MonthlyReport: "1:09:00",
 			QuietHours: QuietHours{
 				Start: "23:00",
 				End:   "07:00",
@@ -238,12 +379,35 @@ func DefaultConfig() *Config {
 		},
 		Docker: DockerConfig{
 			Enabled:      true,
+			Runtime:      "auto",
+			Socket:       "/var/run/docker.sock",
 			PollInterval: "10s",
 			AlertOnStop:  false,
+
+			CrashLoopRestarts: 3,
+			CrashLoopWindow:   "5m",
+
+			PostureAudit: true,
+
+			ActivityAudit: true,
+
+			Resources: DockerResourcesConfig{
+				Enabled:       true,
+				Interval:      "30s",
+				Sustained:     "5m",
+				CPUPercent:    90,
+				MemoryPercent: 90,
+			},
 		},
 		FileIntegrity: FileIntegrityConfig{
-			Enabled:  true,
-			Cooldown: "5m",
+			Enabled:      true,
+			Cooldown:     "5m",
+			MaxWatches:   1024,
+			ScanInterval: "24h",
+			DiffMaxSize:  65536,
+			DiffExclude: []string{
+				"/etc/ssh/ssh_host_*_key", "*.key", "*.pem",
+			},
 			Paths: []WatchPath{
 				{Path: "/etc/passwd", Description: "User accounts", Severity: "critical"},
 				{Path: "/etc/shadow", Description: "Password hashes", Severity: "critical"},
@@ -264,11 +428,55 @@ func DefaultConfig() *Config {
 			Enabled:      false,
 			PollInterval: "5m",
 			AlertOnLeave: false,
+			SpoofDetection: true,
+			MACIPLimit:     2,
+			IPFlapWindow:   "1h",
+			ActiveScan:     false,
+			ScanRate:       50,
+			DeviceRetentionDays: 90,
+
+			DHCP: DHCPCheckConfig{
+				Enabled:  false,
+				Interval: "15m",
+				Timeout:  "5s",
+			},
 		},
 		Connectivity: ConnectivityConfig{
 			Enabled:      true,
 			PollInterval: "30s",
 			Hosts:        []string{"8.8.8.8:53", "1.1.1.1:53"},
+
+			Window:          20,
+			DegradedLatency: "500ms",
+			DegradedLoss:    20,
+		},
+		DNS: DNSConfig{
+			Enabled:         false,
+			PollInterval:    "5m",
+			Timeout:         "5s",
+			ResolvConf:      "/etc/resolv.conf",
+			TrustedResolver: "1.1.1.1",
+			Canaries: []DNSCanary{
+				{Name: "one.one.one.one", Expect: []string{"1.1.1.1", "1.0.0.1"}},
+				{Name: "dns.google", Expect: []string{"8.8.8.8", "8.8.4.4"}},
+			},
+		},
+		Addresses: AddressConfig{
+			Enabled:          false,
+			Ignore:           []string{"lo", "docker*", "br-*", "veth*", "virbr*", "cni*", "flannel*"},
+			PublicIPURL:      "https://api.ipify.org",
+			PublicIPInterval: "10m",
+		},
+		Outbound: OutboundConfig{
+			Enabled:         false,
+			PollInterval:    "10s",
+			LearningPeriod:  "1h",
+			BlockedPorts:    []int{3333, 4444, 5555, 6667, 9050, 14444},
+			IgnoreProcesses: []string{"apt", "apt-get", "http", "https", "systemd-timesyn"},
+			IgnoreSelf:      true,
+			DestinationKey:  "network",
+			BaselineTTL:     "720h",
+			BaselineMax:     5000,
 		},
 		AutoUpdate: AutoUpdateConfig{
 			Enabled:            false,
@@ -324,7 +532,158 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("ntfy topic is required when ntfy is enabled")
 	}
 
+	validProtocols := map[string]bool{"tcp": true, "udp": true, "raw": true}
+	for _, p := range c.Ports.Protocols {
+		if !validProtocols[strings.ToLower(p)] {
+			return fmt.Errorf("invalid ports.protocols entry: %s (must be tcp, udp, or raw)", p)
+		}
+	}
+
 	validSeverities := map[string]bool{"info": true, "warning": true, "critical": true}
+	for _, k := range c.Ports.Known {
+		if k.Addr == "" {
+			return fmt.Errorf("ports.known entry %q has no addr", k.Label)
+		}
+		if k.Risk != "" && !validSeverities[strings.ToLower(k.Risk)] {
+			return fmt.Errorf("invalid ports.known risk for %s: %s (must be info, warning, or critical)", k.Addr, k.Risk)
+		}
+	}
+
+	switch c.Docker.Runtime {
+	case "", "auto", "docker", "podman":
+	default:
+		return fmt.Errorf("invalid docker.runtime: %s (must be auto, docker, or podman)", c.Docker.Runtime)
+	}
+
+	if c.FileIntegrity.MaxWatches < 0 {
+		return fmt.Errorf("invalid file_integrity.max_watches: %d (must be 0 or more)", c.FileIntegrity.MaxWatches)
+	}
+	if c.FileIntegrity.DiffMaxSize < 0 {
+		return fmt.Errorf("invalid file_integrity.diff_max_size: %d (must be 0 or more)", c.FileIntegrity.DiffMaxSize)
+	}
+	for _, pattern := range c.FileIntegrity.DiffExclude {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid file_integrity.diff_exclude pattern: %q", pattern)
+		}
+	}
+
+	if c.Network.ScanRate < 0 {
+		return fmt.Errorf("invalid network.scan_rate: %d (must be 0 or more)", c.Network.ScanRate)
+	}
+
+	for _, list := range []struct {
+		key string
+		ips []string
+	}{
+		{"servers", c.Network.DHCP.Servers},
+		{"routers", c.Network.DHCP.Routers},
+		{"dns", c.Network.DHCP.DNS},
+	} {
+		for _, ip := range list.ips {
+			if net.ParseIP(ip) == nil {
+				return fmt.Errorf("invalid network.dhcp.%s entry: %q (must be an IP address)", list.key, ip)
+			}
+		}
+	}
+
+	if c.Network.MACIPLimit < 0 {
+		return fmt.Errorf("invalid network.mac_ip_limit: %d (must be 0 or more)", c.Network.MACIPLimit)
+	}
+
+	if c.Network.DeviceRetentionDays < 0 {
+		return fmt.Errorf("invalid network.device_retention_days: %d (must be 0 or more)", c.Network.DeviceRetentionDays)
+	}
+
+	for i, p := range c.Connectivity.Probes {
+		switch p.Type {
+		case "tcp", "icmp", "dns", "http":
+		default:
+			return fmt.Errorf("invalid connectivity.probes[%d].type: %q (must be tcp, icmp, dns, or http)", i, p.Type)
+		}
+		if p.Target == "" {
+			return fmt.Errorf("connectivity.probes[%d] has no target", i)
+		}
+	}
+	if c.Connectivity.Window < 0 {
+		return fmt.Errorf("invalid connectivity.window: %d (must be 0 or more)", c.Connectivity.Window)
+	}
+	if c.Connectivity.DegradedLoss < 0 || c.Connectivity.DegradedLoss > 100 {
+		return fmt.Errorf("invalid connectivity.degraded_loss: %d (must be 0-100)", c.Connectivity.DegradedLoss)
+	}
+
+	if r := c.DNS.TrustedResolver; r != "" {
+		host, _, err := net.SplitHostPort(r)
+		if err != nil {
+			host = r
+		}
+		if net.ParseIP(host) == nil {
+			return fmt.Errorf("invalid dns.trusted_resolver: %q (must be ip or ip:port)", r)
+		}
+	}
+	for i, canary := range c.DNS.Canaries {
+		if canary.Name == "" {
+			return fmt.Errorf("dns.canaries[%d] has no name", i)
+		}
+		for _, e := range canary.Expect {
+			if _, _, err := net.ParseCIDR(e); err != nil && net.ParseIP(e) == nil {
+				return fmt.Errorf("invalid dns.canaries[%d].expect entry: %q (must be an IP address or CIDR)", i, e)
+			}
+		}
+	}
+
+	for _, pattern := range c.Addresses.Ignore {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid addresses.ignore pattern: %q", pattern)
+		}
+	}
+	if u := c.Addresses.PublicIPURL; u != "" && !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+		return fmt.Errorf("invalid addresses.public_ip_url: %q (must be an http or https URL)", u)
+	}
+
+	if c.Docker.CrashLoopRestarts < 0 {
+		return fmt.Errorf("invalid docker.crash_loop_restarts: %d (must be 0 or more)", c.Docker.CrashLoopRestarts)
+	}
+
+	postureChecks := map[string]bool{
+		"privileged": true, "host_network": true, "host_pid": true, "docker_socket": true,
+		"cap_sys_admin": true, "writable_host_mount": true, "root_user": true, "public_port": true,
+	}
+	for _, entry := range c.Docker.PostureIgnore {
+		check := entry
+		if i := strings.LastIndex(entry, ":"); i >= 0 {
+			check = entry[i+1:]
+		}
+		if !postureChecks[check] && check != "*" {
+			return fmt.Errorf("invalid docker.posture_ignore entry: %s (unknown check %q)", entry, check)
+		}
+	}
+
+	for _, r := range c.Docker.ExecAllowlist {
+		if r.Command == "" {
+			return fmt.Errorf("docker.exec_allowlist entry has no command")
+		}
+	}
+
+	for _, ct := range c.Docker.Resources.Containers {
+		if ct.Name == "" {
+			return fmt.Errorf("docker.resources.containers entry has no name")
+		}
+	}
+
+	for _, p := range c.Outbound.BlockedPorts {
+		if p < 1 || p > 65535 {
+			return fmt.Errorf("invalid outbound.blocked_ports entry: %d (must be 1-65535)", p)
+		}
+	}
+	switch c.Outbound.DestinationKey {
+	case "", "port", "network":
+	default:
+		return fmt.Errorf("invalid outbound.destination_key: %q (must be port or network)", c.Outbound.DestinationKey)
+	}
+	if c.Outbound.BaselineMax < 0 {
+		return fmt.Errorf("invalid outbound.baseline_max: %d (must be 0 or more)", c.Outbound.BaselineMax)
+	}
+
 	if !validSeverities[strings.ToLower(c.Alerts.MinSeverity)] {
 		return fmt.Errorf("invalid min_severity: %s (must be info, warning, or critical)", c.Alerts.MinSeverity)
 	}
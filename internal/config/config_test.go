@@ -34,6 +34,12 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.Ports.Cooldown != "15m" {
 		t.Errorf("Ports.Cooldown = %q, want %q", cfg.Ports.Cooldown, "15m")
 	}
+	if !cfg.Ports.ExposureAnalysis {
+		t.Error("Ports.ExposureAnalysis should default to true")
+	}
+	if cfg.Docker.Socket != "/var/run/docker.sock" {
+		t.Errorf("Docker.Socket = %q, want default socket", cfg.Docker.Socket)
+	}
 	if cfg.System.DiskThreshold != 80 {
 		t.Errorf("System.DiskThreshold = %d, want 80", cfg.System.DiskThreshold)
 	}
@@ -216,6 +222,316 @@ func TestValidate_ValidSeverities(t *testing.T) {
 	}
 }
 
+func TestValidate_InvalidPortProtocol(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Notifications.Ntfy.Enabled = true
+	cfg.Notifications.Ntfy.Topic = "test"
+	cfg.Ports.Protocols = []string{"tcp", "icmp"}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected error for invalid protocol")
+	}
+	if !strings.Contains(err.Error(), "ports.protocols") {
+		t.Errorf("error = %q", err.Error())
+	}
+}
+
+func TestValidate_KnownPorts(t *testing.T) {
+	tests := []struct {
+		name    string
+		known   KnownPort
+		wantErr string
+	}{
+		{"valid", KnownPort{Addr: "0.0.0.0:8123", Label: "Home Assistant UI", Risk: "info"}, ""},
+		{"no risk", KnownPort{Addr: "*:1883", Label: "MQTT"}, ""},
+		{"missing addr", KnownPort{Label: "oops"}, "no addr"},
+		{"bad risk", KnownPort{Addr: "*:22", Risk: "high"}, "invalid ports.known risk"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Notifications.Ntfy.Enabled = true
+			cfg.Notifications.Ntfy.Topic = "test"
+			cfg.Ports.Known = []KnownPort{tt.known}
+
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_OutboundBlockedPorts(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Notifications.Ntfy.Enabled = true
+	cfg.Notifications.Ntfy.Topic = "test"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("default blocked ports should validate: %v", err)
+	}
+
+	cfg.Outbound.BlockedPorts = []int{3333, 70000}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "outbound.blocked_ports") {
+		t.Errorf("error = %v, want outbound.blocked_ports error", err)
+	}
+}
+
+func TestValidate_FileIntegrityMaxWatches(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Notifications.Ntfy.Enabled = true
+	cfg.Notifications.Ntfy.Topic = "test"
+	cfg.FileIntegrity.MaxWatches = 0 // no limit is valid
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("max_watches 0 should validate: %v", err)
+	}
+
+	cfg.FileIntegrity.MaxWatches = -1
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "file_integrity.max_watches") {
+		t.Errorf("error = %v, want file_integrity.max_watches error", err)
+	}
+}
+
+func TestValidate_FileIntegrityDiffs(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Notifications.Ntfy.Enabled = true
+	cfg.Notifications.Ntfy.Topic = "test"
+	cfg.FileIntegrity.DiffMaxSize = 0 // diffs off is valid
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("diff_max_size 0 should validate: %v", err)
+	}
+
+	cfg.FileIntegrity.DiffMaxSize = -1
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "file_integrity.diff_max_size") {
+		t.Errorf("error = %v, want file_integrity.diff_max_size error", err)
+	}
+
+	cfg.FileIntegrity.DiffMaxSize = 4096
+	cfg.FileIntegrity.DiffExclude = []string{"/etc/[shadow"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "file_integrity.diff_exclude") {
+		t.Errorf("error = %v, want file_integrity.diff_exclude error", err)
+	}
+}
+
+func TestValidate_NetworkMACIPLimit(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Notifications.Ntfy.Enabled = true
+	cfg.Notifications.Ntfy.Topic = "test"
+	cfg.Network.MACIPLimit = 0 // disabled is valid
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("mac_ip_limit 0 should validate: %v", err)
+	}
+
+	cfg.Network.MACIPLimit = -1
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "network.mac_ip_limit") {
+		t.Errorf("error = %v, want network.mac_ip_limit error", err)
+	}
+}
+
+func TestValidate_NetworkScanRate(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Notifications.Ntfy.Enabled = true
+	cfg.Notifications.Ntfy.Topic = "test"
+	cfg.Network.ScanRate = 0 // falls back to the default rate
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("scan_rate 0 should validate: %v", err)
+	}
+
+	cfg.Network.ScanRate = -5
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "network.scan_rate") {
+		t.Errorf("error = %v, want network.scan_rate error", err)
+	}
+}
+
+func TestValidate_NetworkDHCPAddresses(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Notifications.Ntfy.Enabled = true
+	cfg.Notifications.Ntfy.Topic = "test"
+	cfg.Network.DHCP.Servers = []string{"192.168.1.1"}
+	cfg.Network.DHCP.DNS = []string{"192.168.1.2", "fd00::53"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("valid DHCP addresses rejected: %v", err)
+	}
+
+	cfg.Network.DHCP.Routers = []string{"router.lan"}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "network.dhcp.routers") {
+		t.Errorf("error = %v, want network.dhcp.routers error", err)
+	}
+}
+
+func TestValidate_ConnectivityProbes(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Notifications.Ntfy.Enabled = true
+	cfg.Notifications.Ntfy.Topic = "test"
+	cfg.Connectivity.Probes = []ProbeConfig{
+		{Type: "icmp", Target: "192.168.1.1"},
+		{Type: "http", Target: "https://example.com", ExpectStatus: 204},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("valid probes rejected: %v", err)
+	}
+
+	cfg.Connectivity.Probes = append(cfg.Connectivity.Probes, ProbeConfig{Type: "udp", Target: "1.1.1.1:53"})
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "connectivity.probes[2].type") {
+		t.Errorf("error = %v, want connectivity.probes[2].type error", err)
+	}
+
+	cfg.Connectivity.Probes = []ProbeConfig{{Type: "dns"}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "no target") {
+		t.Errorf("error = %v, want missing target error", err)
+	}
+
+	cfg.Connectivity.Probes = nil
+	cfg.Connectivity.DegradedLoss = 120
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "connectivity.degraded_loss") {
+		t.Errorf("error = %v, want connectivity.degraded_loss error", err)
+	}
+}
+
+func TestValidate_DNSCanaries(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Notifications.Ntfy.Enabled = true
+	cfg.Notifications.Ntfy.Topic = "test"
+	cfg.DNS.TrustedResolver = "9.9.9.9:53"
+	cfg.DNS.Canaries = append(cfg.DNS.Canaries, DNSCanary{Name: "nas.lan", Expect: []string{"192.168.1.0/24"}})
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("valid DNS config rejected: %v", err)
+	}
+
+	cfg.DNS.Canaries = []DNSCanary{{Name: "example.com", Expect: []string{"example.net"}}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "dns.canaries[0].expect") {
+		t.Errorf("error = %v, want dns.canaries[0].expect error", err)
+	}
+
+	cfg.DNS.Canaries = []DNSCanary{{Expect: []string{"1.1.1.1"}}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "has no name") {
+		t.Errorf("error = %v, want missing name error", err)
+	}
+
+	cfg.DNS.Canaries = nil
+	cfg.DNS.TrustedResolver = "dns.quad9.net"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "dns.trusted_resolver") {
+		t.Errorf("error = %v, want dns.trusted_resolver error", err)
+	}
+}
+
+func TestValidate_Addresses(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Notifications.Ntfy.Enabled = true
+	cfg.Notifications.Ntfy.Topic = "test"
+	cfg.Addresses.PublicIPURL = ""
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("defaults with public IP polling off rejected: %v", err)
+	}
+
+	cfg.Addresses.PublicIPURL = "api.ipify.org"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "addresses.public_ip_url") {
+		t.Errorf("error = %v, want addresses.public_ip_url error", err)
+	}
+
+	cfg.Addresses.PublicIPURL = "https://ifconfig.me/ip"
+	cfg.Addresses.Ignore = []string{"wg[0-"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "addresses.ignore") {
+		t.Errorf("error = %v, want addresses.ignore error", err)
+	}
+}
+
+func TestValidate_DockerCrashLoopRestarts(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Notifications.Ntfy.Enabled = true
+	cfg.Notifications.Ntfy.Topic = "test"
+	cfg.Docker.CrashLoopRestarts = 0 // disabled is valid
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("crash_loop_restarts 0 should validate: %v", err)
+	}
+
+	cfg.Docker.CrashLoopRestarts = -1
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "docker.crash_loop_restarts") {
+		t.Errorf("error = %v, want docker.crash_loop_restarts error", err)
+	}
+}
+
+func TestValidate_DockerPostureIgnore(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Notifications.Ntfy.Enabled = true
+	cfg.Notifications.Ntfy.Topic = "test"
+	cfg.Docker.PostureIgnore = []string{"root_user", "watchtower:docker_socket", "netdata:*"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("valid posture_ignore rejected: %v", err)
+	}
+
+	cfg.Docker.PostureIgnore = []string{"portainer:docker_sock"}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "docker.posture_ignore") {
+		t.Errorf("error = %v, want docker.posture_ignore error", err)
+	}
+}
+
+func TestValidate_DockerResourceOverrides(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Notifications.Ntfy.Enabled = true
+	cfg.Notifications.Ntfy.Topic = "test"
+	cfg.Docker.Resources.Containers = []ContainerThresholds{{Name: "plex", CPUPercent: 300}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("valid override rejected: %v", err)
+	}
+
+	cfg.Docker.Resources.Containers = []ContainerThresholds{{CPUPercent: 300}}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "docker.resources.containers") {
+		t.Errorf("error = %v, want docker.resources.containers error", err)
+	}
+}
+
+func TestValidate_DockerRuntime(t *testing.T) {
+	for _, rt := range []string{"", "auto", "docker", "podman"} {
+		cfg := DefaultConfig()
+		cfg.Notifications.Ntfy.Enabled = true
+		cfg.Notifications.Ntfy.Topic = "test"
+		cfg.Docker.Runtime = rt
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("runtime %q rejected: %v", rt, err)
+		}
+	}
+
+	cfg := DefaultConfig()
+	cfg.Notifications.Ntfy.Enabled = true
+	cfg.Notifications.Ntfy.Topic = "test"
+	cfg.Docker.Runtime = "containerd"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "docker.runtime") {
+		t.Errorf("error = %v, want docker.runtime error", err)
+	}
+}
+
+func TestValidate_DockerExecAllowlist(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Notifications.Ntfy.Enabled = true
+	cfg.Notifications.Ntfy.Topic = "test"
+	cfg.Docker.ExecAllowlist = []ExecRule{{Container: "db", Command: "pg_isready*"}, {Command: "/usr/bin/netdata-probe *"}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("valid allowlist rejected: %v", err)
+	}
+
+	cfg.Docker.ExecAllowlist = []ExecRule{{Container: "db"}}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "docker.exec_allowlist") {
+		t.Errorf("error = %v, want docker.exec_allowlist error", err)
+	}
+}
+
 func TestValidate_DiscordNoExtraValidation(t *testing.T) {
 	cfg := DefaultConfig()
 	cfg.Notifications.Discord.Enabled = true
@@ -14,6 +14,7 @@ import (
 
 	"github.com/Fullex26/piguard/internal/analysers"
 	"github.com/Fullex26/piguard/internal/config"
+	"github.com/Fullex26/piguard/internal/dockerapi"
 	"github.com/Fullex26/piguard/internal/eventbus"
 	"github.com/Fullex26/piguard/internal/notifiers"
 	"github.com/Fullex26/piguard/internal/store"
@@ -34,6 +35,24 @@ type Daemon struct {
 	dedup     *analysers.Deduplicator
 }
 
+// withContainerRuntime resolves docker.runtime and docker.socket once, so
+// every watcher and the Telegram bot talk to the same engine (Docker, or
+// Podman's compatible socket). The result is a copy: the loaded config is
+// left as the user wrote it. Nothing is probed when Docker monitoring is off.
+func withContainerRuntime(cfg *config.Config, detect func(ctx context.Context, setting, socket string) (dockerapi.Runtime, string)) *config.Config {
+	if !cfg.Docker.Enabled {
+		return cfg
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	runtime, socket := detect(ctx, cfg.Docker.Runtime, cfg.Docker.Socket)
+	slog.Info("container runtime", "runtime", runtime, "socket", socket)
+
+	resolved := *cfg
+	resolved.Docker.Runtime, resolved.Docker.Socket = string(runtime), socket
+	return &resolved
+}
+
 // New creates a new daemon instance
 func New(cfg *config.Config) (*Daemon, error) {
 	bus := eventbus.New()
@@ -48,6 +67,8 @@ func New(cfg *config.Config) (*Daemon, error) {
 		return nil, fmt.Errorf("opening store: %w", err)
 	}
 
+	cfg = withContainerRuntime(cfg, dockerapi.Detect)
+
 	// Parse cooldown
 	cooldown, err := time.ParseDuration(cfg.Ports.Cooldown)
 	if err != nil {
@@ -66,12 +87,12 @@ func New(cfg *config.Config) (*Daemon, error) {
 		d.watchers = append(d.watchers, watchers.NewNetlinkWatcher(cfg, bus))
 	}
 	if cfg.Firewall.Enabled {
-		d.watchers = append(d.watchers, watchers.NewFirewallWatcher(cfg, bus))
+		d.watchers = append(d.watchers, watchers.NewFirewallWatcher(cfg, bus, db))
 	}
 	d.watchers = append(d.watchers, watchers.NewSystemWatcher(cfg, bus))
 
 	if cfg.FileIntegrity.Enabled {
-		d.watchers = append(d.watchers, watchers.NewInotifyWatcher(cfg, bus))
+		d.watchers = append(d.watchers, watchers.NewInotifyWatcher(cfg, bus, db))
 	}
 
 	// Backup watcher (must be created before Telegram bot so it can be wired in)
@@ -85,24 +106,49 @@ func New(cfg *config.Config) (*Daemon, error) {
 	autoUpdateW := watchers.NewAutoUpdateWatcher(cfg, bus)
 	d.watchers = append(d.watchers, autoUpdateW)
 
+	// Connectivity watcher (created before the Telegram bot so /status can show its probes)
+	var connW *watchers.ConnectivityWatcher
+	if cfg.Connectivity.Enabled {
+		connW = watchers.NewConnectivityWatcher(cfg, bus, db)
+		d.watchers = append(d.watchers, connW)
+	}
+
+	// Docker watcher (created before the Telegram bot so a compose down from Telegram isn't alerted)
+	var dockerW *watchers.DockerWatcher
+	if cfg.Docker.Enabled {
+		dockerW = watchers.NewDockerWatcher(cfg, bus, db)
+		d.watchers = append(d.watchers, dockerW)
+		if cfg.Docker.Resources.Enabled {
+			d.watchers = append(d.watchers, watchers.NewDockerResourceWatcher(cfg, bus))
+		}
+	}
+
 	// Telegram interactive bot (two-way commands)
 	if cfg.Notifications.Telegram.Enabled {
 		tbot := watchers.NewTelegramBotWatcher(cfg, bus, db)
 		tbot.BackupWatcher = backupW           // nil-safe; commands check for nil
 		tbot.AutoUpdateWatcher = autoUpdateW
+		tbot.ConnectivityWatcher = connW // nil-safe
+		tbot.DockerWatcher = dockerW     // nil-safe
 		d.watchers = append(d.watchers, tbot)
 	}
 	if cfg.SecurityTools.Enabled {
 		d.watchers = append(d.watchers, watchers.NewSecToolsWatcher(cfg, bus))
 	}
-	if cfg.Docker.Enabled {
-		d.watchers = append(d.watchers, watchers.NewDockerWatcher(cfg, bus))
-	}
 	if cfg.Network.Enabled {
-		d.watchers = append(d.watchers, watchers.NewNetworkScanWatcher(cfg, bus))
+		d.watchers = append(d.watchers, watchers.NewNetworkScanWatcher(cfg, bus, db))
+		if cfg.Network.DHCP.Enabled {
+			d.watchers = append(d.watchers, watchers.NewDHCPWatcher(cfg, bus))
+		}
 	}
-	if cfg.Connectivity.Enabled {
-		d.watchers = append(d.watchers, watchers.NewConnectivityWatcher(cfg, bus))
+	if cfg.DNS.Enabled {
+		d.watchers = append(d.watchers, watchers.NewDNSWatcher(cfg, bus, db))
+	}
+	if cfg.Addresses.Enabled {
+		d.watchers = append(d.watchers, watchers.NewAddressWatcher(cfg, bus, db))
+	}
+	if cfg.Outbound.Enabled {
+		d.watchers = append(d.watchers, watchers.NewOutboundWatcher(cfg, bus, db))
 	}
 	if cfg.AuthLog.Enabled {
 		d.watchers = append(d.watchers, watchers.NewAuthLogWatcher(cfg, bus))
@@ -166,6 +212,15 @@ func (d *Daemon) Run() error {
 		d.runWeeklyReport(ctx)
 	}()
 
+	// Start monthly availability report scheduler
+	if d.cfg.Connectivity.Enabled {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.runMonthlyReport(ctx)
+		}()
+	}
+
 	// Start dedup cleanup
 	wg.Add(1)
 	go func() {
@@ -323,7 +378,13 @@ func (d *Daemon) runWeeklyReport(ctx context.Context) {
 			}
 
 			uptimeStr := getUptimeStr()
-			msg := notifiers.FormatWeeklyReport(hostname, thisWeek, lastWeekOnly, totalThis, totalLast, uptimeStr)
+			var conn *models.Availability
+			if d.cfg.Connectivity.Enabled {
+				if a, err := d.store.Availability(now.AddDate(0, 0, -7), now, now); err == nil {
+					conn = &a
+				}
+			}
+			msg := notifiers.FormatWeeklyReport(hostname, thisWeek, lastWeekOnly, totalThis, totalLast, uptimeStr, conn)
 			for _, n := range d.notifiers {
 				slog.Info("sending notification", "notifier", n.Name(), "type", "weekly_report")
 				if err := n.SendRaw(msg); err != nil {
@@ -337,6 +398,94 @@ func (d *Daemon) runWeeklyReport(ctx context.Context) {
 	}
 }
 
+func (d *Daemon) runMonthlyReport(ctx context.Context) {
+	schedule := d.cfg.Alerts.MonthlyReport
+	if schedule == "" {
+		return
+	}
+
+	// Parse "1:09:00" → day of month + HH:MM
+	parts := strings.SplitN(schedule, ":", 2)
+	day, err := strconv.Atoi(parts[0])
+	if len(parts) != 2 || err != nil || day < 1 || day > 28 {
+		slog.Warn("invalid monthly_report format", "value", schedule)
+		return
+	}
+	timeStr := parts[1]
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(1 * time.Minute):
+			now := time.Now()
+			if now.Day() != day {
+				continue
+			}
+			hhmm := fmt.Sprintf("%02d:%02d", now.Hour(), now.Minute())
+			if hhmm != timeStr {
+				continue
+			}
+
+			msg, err := d.monthlyReport(now)
+			if err != nil {
+				slog.Error("building monthly report failed", "error", err)
+			} else {
+				for _, n := range d.notifiers {
+					slog.Info("sending notification", "notifier", n.Name(), "type", "monthly_report")
+					if err := n.SendRaw(msg); err != nil {
+						slog.Error("notification failed", "notifier", n.Name(), "type", "monthly_report", "error", err)
+					}
+				}
+			}
+
+			// Sleep past this minute to avoid double-send
+			time.Sleep(61 * time.Second)
+		}
+	}
+}
+
+// monthlyReport formats connectivity availability for the calendar month
+// before now, in total and per week.
+func (d *Daemon) monthlyReport(now time.Time) (string, error) {
+	from, to := previousMonth(now)
+	total, err := d.store.Availability(from, to, now)
+	if err != nil {
+		return "", err
+	}
+	var weeks []models.Availability
+	for _, span := range weekSpans(from, to) {
+		a, err := d.store.Availability(span[0], span[1], now)
+		if err != nil {
+			return "", err
+		}
+		weeks = append(weeks, a)
+	}
+	hostname, _ := os.Hostname()
+	return notifiers.FormatMonthlyReport(hostname, total, weeks), nil
+}
+
+// previousMonth returns the bounds of the calendar month before now:
+// midnight on its first day up to midnight on the first of now's month.
+func previousMonth(now time.Time) (from, to time.Time) {
+	to = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	return to.AddDate(0, -1, 0), to
+}
+
+// weekSpans splits [from, to) into seven-day spans starting at from; the
+// last span is cut short at to.
+func weekSpans(from, to time.Time) [][2]time.Time {
+	var spans [][2]time.Time
+	for start := from; start.Before(to); start = start.AddDate(0, 0, 7) {
+		end := start.AddDate(0, 0, 7)
+		if end.After(to) {
+			end = to
+		}
+		spans = append(spans, [2]time.Time{start, end})
+	}
+	return spans
+}
+
 func parseWeekdayName(s string) time.Weekday {
 	switch strings.ToLower(s) {
 	case "sunday", "sun":
@@ -388,6 +537,14 @@ func (d *Daemon) runCleanup(ctx context.Context) {
 			if pruned > 0 {
 				slog.Info("pruned old events", "count", pruned)
 			}
+			if days := d.cfg.Network.DeviceRetentionDays; days > 0 {
+				before := time.Now().AddDate(0, 0, -days)
+				if n, err := d.store.PruneDevices(before); err != nil {
+					slog.Error("pruning devices failed", "error", err)
+				} else if n > 0 {
+					slog.Info("pruned stale devices", "count", n)
+				}
+			}
 		}
 	}
 }
@@ -1,13 +1,16 @@
 package daemon
 
 import (
+	"context"
 	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/Fullex26/piguard/internal/analysers"
 	"github.com/Fullex26/piguard/internal/config"
+	"github.com/Fullex26/piguard/internal/dockerapi"
 	"github.com/Fullex26/piguard/internal/eventbus"
 	"github.com/Fullex26/piguard/internal/notifiers"
 	"github.com/Fullex26/piguard/internal/store"
@@ -355,3 +358,67 @@ func TestParseWeekdayName(t *testing.T) {
 		})
 	}
 }
+
+func TestPreviousMonth(t *testing.T) {
+	from, to := previousMonth(time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC))
+	if !from.Equal(time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)) || !to.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("previousMonth = %s – %s, want December 2025", from, to)
+	}
+}
+
+func TestWeekSpans(t *testing.T) {
+	from := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	spans := weekSpans(from, from.AddDate(0, 1, 0))
+	if len(spans) != 4 {
+		t.Fatalf("February 2026 split into %d spans, want 4", len(spans))
+	}
+
+	from = time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	spans = weekSpans(from, to)
+	if len(spans) != 5 {
+		t.Fatalf("September 2026 split into %d spans, want 5", len(spans))
+	}
+	if !spans[1][0].Equal(from.AddDate(0, 0, 7)) || !spans[4][1].Equal(to) {
+		t.Errorf("spans = %v", spans)
+	}
+}
+
+func TestMonthlyReport(t *testing.T) {
+	d, _ := newTestDaemonWithStore(t, testCfg())
+	start := time.Date(2026, 9, 10, 12, 0, 0, 0, time.Local)
+	id, _ := d.store.StartOutage(start)
+	d.store.EndOutage(id, start.Add(30*time.Minute))
+
+	msg, err := d.monthlyReport(time.Date(2026, 10, 1, 9, 0, 0, 0, time.Local))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"September 2026", "30m down in 1 outage", "8–14 Sep", "1–7 Sep: 100% — no downtime"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("monthly report missing %q in %q", want, msg)
+		}
+	}
+}
+
+func TestWithContainerRuntime(t *testing.T) {
+	detected := 0
+	detect := func(ctx context.Context, setting, socket string) (dockerapi.Runtime, string) {
+		detected++
+		return dockerapi.RuntimePodman, "/run/podman/podman.sock"
+	}
+
+	cfg := &config.Config{Docker: config.DockerConfig{Enabled: false, Runtime: "auto", Socket: "/var/run/docker.sock"}}
+	if got := withContainerRuntime(cfg, detect); got != cfg || detected != 0 {
+		t.Errorf("docker disabled: detect called %d times, config replaced = %v", detected, got != cfg)
+	}
+
+	cfg.Docker.Enabled = true
+	got := withContainerRuntime(cfg, detect)
+	if detected != 1 || got.Docker.Runtime != "podman" || got.Docker.Socket != "/run/podman/podman.sock" {
+		t.Errorf("resolved docker = %+v after %d detections", got.Docker, detected)
+	}
+	if cfg.Docker.Runtime != "auto" || cfg.Docker.Socket != "/var/run/docker.sock" {
+		t.Errorf("loaded config modified: %+v", cfg.Docker)
+	}
+}
@@ -0,0 +1,370 @@
+// Package dockerapi is a minimal Docker Engine API client over the daemon's
+// unix socket. It covers what PiGuard needs — listing and inspecting
+// containers, lifecycle actions and the /events stream — without pulling in
+// the Docker SDK or forking the docker CLI.
+package dockerapi
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSocket is the Docker daemon's default API socket.
+const DefaultSocket = "/var/run/docker.sock"
+
+// Client talks to the Engine API. Paths are unversioned so the daemon
+// answers with its own API version.
+type Client struct {
+	socket string
+	http   *http.Client // request/response calls; a minute covers a slow stop
+	stream *http.Client // long-lived /events connection, no timeout
+}
+
+// New creates a client for the socket at path ("" = DefaultSocket).
+func New(path string) *Client {
+	if path == "" {
+		path = DefaultSocket
+	}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+		MaxIdleConns: 2,
+	}
+	return &Client{
+		socket: path,
+		http:   &http.Client{Transport: transport, Timeout: time.Minute},
+		stream: &http.Client{Transport: transport},
+	}
+}
+
+// Socket returns the socket path the client dials.
+func (c *Client) Socket() string { return c.socket }
+
+// Container is one entry of GET /containers/json.
+type Container struct {
+	ID      string            `json:"Id"`
+	Names   []string          `json:"Names"` // with leading "/"
+	Image   string            `json:"Image"`
+	ImageID string            `json:"ImageID"`
+	State   string            `json:"State"`  // "running", "exited", ...
+	Status  string            `json:"Status"` // "Up 2 hours (healthy)"
+	Ports   []Port            `json:"Ports"`
+	Labels  map[string]string `json:"Labels"`
+	Created int64             `json:"Created"`
+}
+
+// Name returns the container's primary name without the leading slash.
+func (c Container) Name() string {
+	if len(c.Names) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
+
+// PortsString formats the ports the way `docker ps` prints them, e.g.
+// "0.0.0.0:8080->80/tcp, [::]:8080->80/tcp, 443/tcp".
+func (c Container) PortsString() string {
+	parts := make([]string, 0, len(c.Ports))
+	for _, p := range c.Ports {
+		if p.PublicPort == 0 {
+			parts = append(parts, fmt.Sprintf("%d/%s", p.PrivatePort, p.Type))
+			continue
+		}
+		host := net.JoinHostPort(p.IP, strconv.Itoa(int(p.PublicPort)))
+		parts = append(parts, fmt.Sprintf("%s->%d/%s", host, p.PrivatePort, p.Type))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Port is a container port and, when published, its host binding.
+type Port struct {
+	IP          string `json:"IP"`
+	PrivatePort uint16 `json:"PrivatePort"`
+	PublicPort  uint16 `json:"PublicPort"` // 0 = not published
+	Type        string `json:"Type"`       // "tcp", "udp"
+}
+
+// ContainerJSON is the subset of GET /containers/{id}/json that PiGuard uses.
+type ContainerJSON struct {
+	ID           string         `json:"Id"`
+	Name         string         `json:"Name"`  // with leading "/"
+	Image        string         `json:"Image"` // image ID
+	Created      string         `json:"Created"`
+	RestartCount int            `json:"RestartCount"`
+	State        ContainerState `json:"State"`
+	Config       struct {
+		Image       string            `json:"Image"` // image reference, e.g. "nginx:latest"
+		User        string            `json:"User"`
+		Labels      map[string]string `json:"Labels"`
+		Healthcheck *HealthConfig     `json:"Healthcheck"` // merged with the image's; nil = none
+	} `json:"Config"`
+	HostConfig struct {
+		Memory        int64 `json:"Memory"` // bytes, 0 = unlimited
+		RestartPolicy struct {
+			Name              string `json:"Name"`
+			MaximumRetryCount int    `json:"MaximumRetryCount"`
+		} `json:"RestartPolicy"`
+		Privileged   bool                     `json:"Privileged"`
+		NetworkMode  string                   `json:"NetworkMode"`  // "bridge", "host", "container:<id>", ...
+		PidMode      string                   `json:"PidMode"`      // "" or "host"
+		CapAdd       []string                 `json:"CapAdd"`       // e.g. "SYS_ADMIN", "CAP_NET_ADMIN", "ALL"
+		PortBindings map[string][]PortBinding `json:"PortBindings"` // "80/tcp" → host bindings
+	} `json:"HostConfig"`
+	Mounts []Mount `json:"Mounts"`
+}
+
+// HealthConfig is a container's healthcheck definition.
+type HealthConfig struct {
+	Test []string `json:"Test"` // ["CMD", "curl", ...], ["CMD-SHELL", "curl ..."] or ["NONE"]
+}
+
+// ExecJSON is the subset of GET /exec/{id}/json that PiGuard uses.
+type ExecJSON struct {
+	ID            string `json:"ID"`
+	ContainerID   string `json:"ContainerID"`
+	Running       bool   `json:"Running"`
+	ProcessConfig struct {
+		Entrypoint string   `json:"entrypoint"`
+		Arguments  []string `json:"arguments"`
+		User       string   `json:"user"` // "" = the container's user
+		Privileged bool     `json:"privileged"`
+		TTY        bool     `json:"tty"`
+	} `json:"ProcessConfig"`
+}
+
+// PortBinding is where a container port is published on the host.
+type PortBinding struct {
+	HostIP   string `json:"HostIp"` // "" = all interfaces
+	HostPort string `json:"HostPort"`
+}
+
+// Mount is a bind mount, volume or tmpfs attached to a container.
+type Mount struct {
+	Type        string `json:"Type"` // "bind", "volume", "tmpfs"
+	Source      string `json:"Source"`
+	Destination string `json:"Destination"`
+	RW          bool   `json:"RW"`
+}
+
+// ContainerState is the runtime state reported by inspect.
+type ContainerState struct {
+	Status     string  `json:"Status"` // "running", "exited", "restarting", ...
+	Running    bool    `json:"Running"`
+	Restarting bool    `json:"Restarting"`
+	OOMKilled  bool    `json:"OOMKilled"`
+	Dead       bool    `json:"Dead"`
+	Pid        int     `json:"Pid"`
+	ExitCode   int     `json:"ExitCode"`
+	Error      string  `json:"Error"`
+	StartedAt  string  `json:"StartedAt"`
+	FinishedAt string  `json:"FinishedAt"`
+	Health     *Health `json:"Health"` // nil without a HEALTHCHECK
+}
+
+// Health is a container's healthcheck state.
+type Health struct {
+	Status        string `json:"Status"` // "starting", "healthy", "unhealthy"
+	FailingStreak int    `json:"FailingStreak"`
+}
+
+// Event is one message from GET /events.
+type Event struct {
+	Type   string `json:"Type"`   // "container", "image", "network", "volume", ...
+	Action string `json:"Action"` // "start", "die", "health_status: unhealthy", ...
+	Actor  struct {
+		ID         string            `json:"ID"`
+		Attributes map[string]string `json:"Attributes"` // "name", "image", "exitCode", labels
+	} `json:"Actor"`
+	Scope    string `json:"scope"`
+	Time     int64  `json:"time"`
+	TimeNano int64  `json:"timeNano"`
+}
+
+// ErrNotFound is returned when the daemon answers 404.
+var ErrNotFound = errors.New("not found")
+
+// APIError is a non-2xx response from the daemon.
+type APIError struct {
+	Status  int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("docker API: %s (HTTP %d)", e.Message, e.Status)
+}
+
+// Ping checks that the daemon answers on the socket.
+func (c *Client) Ping(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/_ping", nil, nil)
+}
+
+// ListContainers returns running containers, or all containers when all is set.
+func (c *Client) ListContainers(ctx context.Context, all bool) ([]Container, error) {
+	q := url.Values{}
+	if all {
+		q.Set("all", "1")
+	}
+	var out []Container
+	if err := c.do(ctx, http.MethodGet, "/containers/json", q, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// InspectContainer returns the full configuration and state of one container.
+func (c *Client) InspectContainer(ctx context.Context, id string) (*ContainerJSON, error) {
+	var out ContainerJSON
+	if err := c.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(id)+"/json", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// InspectExec returns the process configuration of an exec instance. The
+// daemon forgets an exec shortly after it exits, so inspect it on creation.
+func (c *Client) InspectExec(ctx context.Context, id string) (*ExecJSON, error) {
+	var out ExecJSON
+	if err := c.do(ctx, http.MethodGet, "/exec/"+url.PathEscape(id)+"/json", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// StopContainer stops a container, letting Docker apply its default timeout.
+func (c *Client) StopContainer(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(id)+"/stop", nil, nil)
+}
+
+// RestartContainer restarts a container.
+func (c *Client) RestartContainer(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(id)+"/restart", nil, nil)
+}
+
+// RemoveContainer removes a container; force kills it first if it is running.
+func (c *Client) RemoveContainer(ctx context.Context, id string, force bool) error {
+	q := url.Values{}
+	if force {
+		q.Set("force", "1")
+	}
+	return c.do(ctx, http.MethodDelete, "/containers/"+url.PathEscape(id), q, nil)
+}
+
+// Events subscribes to the daemon's event stream. filters uses the API's
+// filter syntax, e.g. {"type": {"container"}}. Events are delivered until ctx
+// is cancelled or the stream breaks; either way the error channel receives
+// exactly one value (nil after cancellation) and both channels are closed.
+func (c *Client) Events(ctx context.Context, filters map[string][]string) (<-chan Event, <-chan error) {
+	events := make(chan Event)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(events)
+		defer close(errs)
+
+		q := url.Values{}
+		if len(filters) > 0 {
+			data, _ := json.Marshal(filters)
+			q.Set("filters", string(data))
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://docker/events?"+q.Encode(), nil)
+		if err != nil {
+			errs <- err
+			return
+		}
+		resp, err := c.stream.Do(req)
+		if err != nil {
+			errs <- streamErr(ctx, err)
+			return
+		}
+		defer resp.Body.Close()
+		if err := checkResponse(resp); err != nil {
+			errs <- err
+			return
+		}
+
+		dec := json.NewDecoder(bufio.NewReader(resp.Body))
+		for {
+			var ev Event
+			if err := dec.Decode(&ev); err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF // the daemon went away
+				}
+				errs <- streamErr(ctx, err)
+				return
+			}
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+				errs <- nil
+				return
+			}
+		}
+	}()
+	return events, errs
+}
+
+// streamErr hides the error caused by our own cancellation.
+func streamErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	return fmt.Errorf("docker events: %w", err)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, q url.Values, out any) error {
+	u := "http://docker" + path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("docker API %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding %s: %w", path, err)
+	}
+	return nil
+}
+
+// checkResponse turns a non-2xx response into an error carrying the
+// daemon's {"message": ...} text. 304 (already started/stopped) is success.
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode/100 == 2 || resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+	var body struct {
+		Message string `json:"message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if json.Unmarshal(data, &body) != nil || body.Message == "" {
+		body.Message = strings.TrimSpace(string(data))
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrNotFound, body.Message)
+	}
+	return &APIError{Status: resp.StatusCode, Message: body.Message}
+}
@@ -0,0 +1,215 @@
+package dockerapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Fullex26/piguard/internal/dockerapi/dockertest"
+)
+
+// fakeDaemon serves handler on a unix socket and returns a client for it.
+func fakeDaemon(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+	return New(dockertest.Socket(t, handler))
+}
+
+func TestPing(t *testing.T) {
+	c := fakeDaemon(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_ping" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		fmt.Fprint(w, "OK")
+	}))
+	if err := c.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+}
+
+func TestPing_NoSocket(t *testing.T) {
+	c := New(filepath.Join(t.TempDir(), "missing.sock"))
+	if err := c.Ping(context.Background()); err == nil {
+		t.Fatal("expected error dialing a missing socket")
+	}
+}
+
+func TestListContainers(t *testing.T) {
+	c := fakeDaemon(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/containers/json" || r.URL.Query().Get("all") != "1" {
+			t.Errorf("request = %s", r.URL)
+		}
+		fmt.Fprint(w, `[{"Id":"abc123","Names":["/nginx"],"Image":"nginx:latest","ImageID":"sha256:aaa",
+			"State":"running","Status":"Up 2 hours (healthy)",
+			"Ports":[{"IP":"0.0.0.0","PrivatePort":80,"PublicPort":8080,"Type":"tcp"},
+			         {"IP":"::","PrivatePort":80,"PublicPort":8080,"Type":"tcp"},
+			         {"PrivatePort":443,"Type":"tcp"}],
+			"Labels":{"com.docker.compose.project":"web"}}]`)
+	}))
+
+	got, err := c.ListContainers(context.Background(), true)
+	if err != nil {
+		t.Fatalf("ListContainers: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("containers = %d, want 1", len(got))
+	}
+	ct := got[0]
+	if ct.ID != "abc123" || ct.Name() != "nginx" || ct.Labels["com.docker.compose.project"] != "web" {
+		t.Errorf("container = %+v", ct)
+	}
+	if want := "0.0.0.0:8080->80/tcp, [::]:8080->80/tcp, 443/tcp"; ct.PortsString() != want {
+		t.Errorf("PortsString = %q, want %q", ct.PortsString(), want)
+	}
+}
+
+func TestInspectContainer(t *testing.T) {
+	c := fakeDaemon(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/containers/gone/json" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"No such container: gone"}`)
+			return
+		}
+		fmt.Fprint(w, `{"Id":"abc123","Name":"/worker","Image":"sha256:bbb","RestartCount":4,
+			"State":{"Status":"exited","OOMKilled":true,"ExitCode":137,"Health":{"Status":"unhealthy","FailingStreak":3}},
+			"Config":{"Image":"worker:1.2","Labels":{},"Healthcheck":{"Test":["CMD-SHELL","curl -f localhost"]}},
+			"HostConfig":{"Memory":268435456,"RestartPolicy":{"Name":"always"},"Privileged":true,
+				"PortBindings":{"80/tcp":[{"HostIp":"","HostPort":"8080"}]}},
+			"Mounts":[{"Type":"bind","Source":"/var/run/docker.sock","Destination":"/var/run/docker.sock","RW":true}]}`)
+	}))
+
+	info, err := c.InspectContainer(context.Background(), "abc123")
+	if err != nil {
+		t.Fatalf("InspectContainer: %v", err)
+	}
+	if info.Name != "/worker" || info.RestartCount != 4 || info.Config.Image != "worker:1.2" ||
+		info.Config.Healthcheck == nil || info.Config.Healthcheck.Test[0] != "CMD-SHELL" {
+		t.Errorf("info = %+v", info)
+	}
+	if !info.State.OOMKilled || info.State.ExitCode != 137 || info.State.Health.Status != "unhealthy" {
+		t.Errorf("state = %+v", info.State)
+	}
+	if info.HostConfig.Memory != 256<<20 || info.HostConfig.RestartPolicy.Name != "always" {
+		t.Errorf("host config = %+v", info.HostConfig)
+	}
+	if !info.HostConfig.Privileged || info.HostConfig.PortBindings["80/tcp"][0].HostPort != "8080" {
+		t.Errorf("host config = %+v", info.HostConfig)
+	}
+	if len(info.Mounts) != 1 || info.Mounts[0].Source != "/var/run/docker.sock" || !info.Mounts[0].RW {
+		t.Errorf("mounts = %+v", info.Mounts)
+	}
+
+	_, err = c.InspectContainer(context.Background(), "gone")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestInspectExec(t *testing.T) {
+	c := fakeDaemon(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/exec/e1/json" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"ID":"e1","ContainerID":"abc123","Running":true,
+			"ProcessConfig":{"entrypoint":"/bin/sh","arguments":["-c","id"],"user":"root","privileged":false,"tty":true}}`)
+	}))
+
+	info, err := c.InspectExec(context.Background(), "e1")
+	if err != nil {
+		t.Fatalf("InspectExec: %v", err)
+	}
+	pc := info.ProcessConfig
+	if info.ContainerID != "abc123" || pc.Entrypoint != "/bin/sh" || len(pc.Arguments) != 2 || pc.User != "root" || !pc.TTY {
+		t.Errorf("exec = %+v", info)
+	}
+}
+
+func TestContainerActions(t *testing.T) {
+	var calls []string
+	c := fakeDaemon(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.RequestURI())
+		if r.URL.Path == "/containers/broken/restart" {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"message":"cannot restart container"}`)
+			return
+		}
+		if r.URL.Path == "/containers/web/stop" {
+			w.WriteHeader(http.StatusNotModified) // already stopped
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	ctx := context.Background()
+
+	if err := c.StopContainer(ctx, "web"); err != nil {
+		t.Errorf("StopContainer: %v", err)
+	}
+	if err := c.RemoveContainer(ctx, "web", true); err != nil {
+		t.Errorf("RemoveContainer: %v", err)
+	}
+	err := c.RestartContainer(ctx, "broken")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != 500 || apiErr.Message != "cannot restart container" {
+		t.Errorf("RestartContainer err = %v", err)
+	}
+
+	want := []string{"POST /containers/web/stop", "DELETE /containers/web?force=1", "POST /containers/broken/restart"}
+	if fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	c := fakeDaemon(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var filters map[string][]string
+		if err := json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters); err != nil || filters["type"][0] != "container" {
+			t.Errorf("filters = %q", r.URL.Query().Get("filters"))
+		}
+		flusher := w.(http.Flusher)
+		fmt.Fprintln(w, `{"Type":"container","Action":"start","Actor":{"ID":"abc","Attributes":{"name":"web","image":"nginx"}},"time":1700000000}`)
+		flusher.Flush()
+		fmt.Fprintln(w, `{"Type":"container","Action":"die","Actor":{"ID":"abc","Attributes":{"name":"web","exitCode":"1"}},"time":1700000005}`)
+		flusher.Flush()
+		// Returning closes the stream, as when the daemon restarts.
+	}))
+
+	events, errs := c.Events(context.Background(), map[string][]string{"type": {"container"}})
+	var got []Event
+	for ev := range events {
+		got = append(got, ev)
+	}
+	if len(got) != 2 || got[0].Action != "start" || got[1].Actor.Attributes["exitCode"] != "1" {
+		t.Fatalf("events = %+v", got)
+	}
+	if err := <-errs; err == nil {
+		t.Error("expected an error when the stream ends")
+	}
+}
+
+func TestEvents_Cancel(t *testing.T) {
+	c := fakeDaemon(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, errs := c.Events(ctx, nil)
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-errs:
+		if err != nil {
+			t.Errorf("err = %v, want nil after cancel", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("stream did not stop after cancel")
+	}
+	if _, ok := <-events; ok {
+		t.Error("events channel should be closed")
+	}
+}
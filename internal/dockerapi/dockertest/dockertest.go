@@ -0,0 +1,28 @@
+// Package dockertest provides a fake container engine for tests: an HTTP
+// handler served on a unix socket, as the Docker and Podman APIs are.
+package dockertest
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+// Socket serves handler on a unix socket in a temporary directory and
+// returns the socket's path. The server stops when the test ends.
+func Socket(t testing.TB, handler http.Handler) string {
+	t.Helper()
+	sock := filepath.Join(t.TempDir(), "docker.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Listener.Close()
+	srv.Listener = l
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return sock
+}
@@ -0,0 +1,197 @@
+package dockerapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Runtime is the container engine behind the socket. Podman serves a
+// Docker-compatible Engine API, so the client works with either; the
+// runtime only decides which CLI to fall back to and how to parse it.
+type Runtime string
+
+const (
+	RuntimeDocker Runtime = "docker"
+	RuntimePodman Runtime = "podman"
+)
+
+// CLI returns the command-line tool used when the socket can't be reached.
+func (r Runtime) CLI() string {
+	if r == RuntimePodman {
+		return "podman"
+	}
+	return "docker"
+}
+
+// PSArgs returns the CLI arguments that list all containers as JSON: one
+// object per line for docker, a single array for podman (see ParsePS).
+func (r Runtime) PSArgs() []string {
+	if r == RuntimePodman {
+		return []string{"ps", "--all", "--format", "json"}
+	}
+	return []string{"ps", "--all", "--no-trunc", "--format", "{{json .}}"}
+}
+
+// podmanSockets are Podman's API sockets: rootful first, then the rootless
+// socket of each user running `systemctl --user enable podman.socket`.
+var podmanSockets = []string{"/run/podman/podman.sock", "/run/user/*/podman/podman.sock"}
+
+// lookPath is exec.LookPath; replaced in tests.
+var lookPath = exec.LookPath
+
+// Detect resolves the docker.runtime setting ("auto", "docker" or "podman")
+// and the configured socket to a runtime and the socket to use. With "auto"
+// it asks each candidate socket which engine answers (Podman's docker.sock
+// shim included), and falls back to whichever CLI is installed.
+func Detect(ctx context.Context, setting, socket string) (Runtime, string) {
+	if socket == "" {
+		socket = DefaultSocket
+	}
+	switch Runtime(setting) {
+	case RuntimeDocker:
+		return RuntimeDocker, socket
+	case RuntimePodman:
+		if socket == DefaultSocket {
+			if found := existingPodmanSockets(); len(found) > 0 {
+				return RuntimePodman, found[0]
+			}
+		}
+		return RuntimePodman, socket
+	}
+
+	for _, candidate := range append([]string{socket}, existingPodmanSockets()...) {
+		pctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		v, err := New(candidate).Version(pctx)
+		cancel()
+		if err == nil {
+			return v.Runtime(), candidate
+		}
+	}
+	if _, err := lookPath("docker"); err != nil {
+		if _, err := lookPath("podman"); err == nil {
+			if found := existingPodmanSockets(); len(found) > 0 {
+				return RuntimePodman, found[0]
+			}
+			return RuntimePodman, socket
+		}
+	}
+	return RuntimeDocker, socket
+}
+
+func existingPodmanSockets() []string {
+	var found []string
+	for _, pattern := range podmanSockets {
+		matches, _ := filepath.Glob(pattern)
+		for _, m := range matches {
+			if fi, err := os.Stat(m); err == nil && fi.Mode()&os.ModeSocket != 0 {
+				found = append(found, m)
+			}
+		}
+	}
+	return found
+}
+
+// Version is the subset of GET /version that identifies the engine.
+type Version struct {
+	Version    string `json:"Version"`
+	Components []struct {
+		Name    string `json:"Name"` // "Engine", or "Podman Engine" on Podman
+		Version string `json:"Version"`
+	} `json:"Components"`
+}
+
+// Runtime reports which engine answered.
+func (v Version) Runtime() Runtime {
+	for _, c := range v.Components {
+		if strings.Contains(strings.ToLower(c.Name), "podman") {
+			return RuntimePodman
+		}
+	}
+	return RuntimeDocker
+}
+
+// Version returns the engine's version information.
+func (c *Client) Version(ctx context.Context) (*Version, error) {
+	var out Version
+	if err := c.do(ctx, http.MethodGet, "/version", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// podmanPS is one entry of `podman ps --format json`.
+type podmanPS struct {
+	ID       string            `json:"Id"`
+	Names    []string          `json:"Names"` // no leading "/"
+	Image    string            `json:"Image"`
+	ImageID  string            `json:"ImageID"`
+	State    string            `json:"State"`
+	Status   string            `json:"Status"` // health only on some versions
+	ExitCode int               `json:"ExitCode"`
+	Labels   map[string]string `json:"Labels"`
+	Created  int64             `json:"Created"`
+	Ports    []struct {
+		HostIP        string `json:"host_ip"`
+		ContainerPort uint16 `json:"container_port"`
+		HostPort      uint16 `json:"host_port"`
+		Protocol      string `json:"protocol"`
+	} `json:"Ports"`
+}
+
+// ParsePS converts `podman ps --format json` output, a single JSON array,
+// to Engine API containers. ok is false for anything else, such as the
+// object-per-line output of `docker ps`, which callers parse themselves.
+func ParsePS(out []byte) (containers []Container, ok bool, err error) {
+	trimmed := strings.TrimSpace(string(out))
+	if !strings.HasPrefix(trimmed, "[") {
+		return nil, false, nil
+	}
+	var list []podmanPS
+	if err := json.Unmarshal([]byte(trimmed), &list); err != nil {
+		return nil, true, fmt.Errorf("parsing podman ps: %w", err)
+	}
+	containers = make([]Container, 0, len(list))
+	for _, p := range list {
+		if p.State == "stopped" {
+			p.State = "exited" // podman's name for a container stopped after running
+		}
+		c := Container{
+			ID: p.ID, Image: p.Image, ImageID: p.ImageID, State: p.State, Status: p.Status,
+			Labels: p.Labels, Created: p.Created,
+		}
+		for _, n := range p.Names {
+			c.Names = append(c.Names, "/"+n)
+		}
+		if c.Status == "" {
+			c.Status = podmanStatus(p.State, p.ExitCode)
+		}
+		for _, port := range p.Ports {
+			ip := port.HostIP
+			if ip == "" {
+				ip = "0.0.0.0"
+			}
+			c.Ports = append(c.Ports, Port{IP: ip, PrivatePort: port.ContainerPort, PublicPort: port.HostPort, Type: port.Protocol})
+		}
+		containers = append(containers, c)
+	}
+	return containers, true, nil
+}
+
+// podmanStatus renders a docker-style status for podman versions whose JSON
+// leaves Status empty.
+func podmanStatus(state string, exitCode int) string {
+	switch state {
+	case "running":
+		return "Up"
+	case "exited":
+		return fmt.Sprintf("Exited (%d)", exitCode)
+	}
+	return state
+}
@@ -0,0 +1,110 @@
+package dockerapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestRuntimeCLI(t *testing.T) {
+	if got := RuntimePodman.CLI(); got != "podman" {
+		t.Errorf("podman CLI = %q", got)
+	}
+	for _, rt := range []Runtime{RuntimeDocker, "", "auto"} {
+		if got := rt.CLI(); got != "docker" {
+			t.Errorf("%q CLI = %q, want docker", rt, got)
+		}
+	}
+}
+
+func TestDetect_Explicit(t *testing.T) {
+	defer func(s []string) { podmanSockets = s }(podmanSockets)
+	podmanSockets = nil
+
+	if rt, sock := Detect(context.Background(), "docker", ""); rt != RuntimeDocker || sock != DefaultSocket {
+		t.Errorf("docker = %s %s", rt, sock)
+	}
+	if rt, sock := Detect(context.Background(), "podman", "/run/user/1000/podman/podman.sock"); rt != RuntimePodman || sock != "/run/user/1000/podman/podman.sock" {
+		t.Errorf("podman = %s %s", rt, sock)
+	}
+}
+
+func TestDetect_AutoAsksTheSocket(t *testing.T) {
+	defer func(s []string) { podmanSockets = s }(podmanSockets)
+	podmanSockets = nil
+
+	c := fakeDaemon(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/version" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"Version":"5.0.2","Components":[{"Name":"Podman Engine","Version":"5.0.2"}]}`)
+	}))
+	if rt, sock := Detect(context.Background(), "auto", c.Socket()); rt != RuntimePodman || sock != c.Socket() {
+		t.Errorf("auto = %s %s, want podman on the docker.sock shim", rt, sock)
+	}
+
+	docker := fakeDaemon(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"Version":"27.1.1","Components":[{"Name":"Engine","Version":"27.1.1"}]}`)
+	}))
+	if rt, _ := Detect(context.Background(), "auto", docker.Socket()); rt != RuntimeDocker {
+		t.Errorf("auto = %s, want docker", rt)
+	}
+}
+
+func TestDetect_AutoFindsPodmanSocket(t *testing.T) {
+	defer func(s []string) { podmanSockets = s }(podmanSockets)
+	podman := fakeDaemon(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"Components":[{"Name":"Podman Engine"}]}`)
+	}))
+	podmanSockets = []string{podman.Socket()}
+
+	missing := filepath.Join(t.TempDir(), "docker.sock")
+	if rt, sock := Detect(context.Background(), "", missing); rt != RuntimePodman || sock != podman.Socket() {
+		t.Errorf("auto = %s %s, want the podman socket", rt, sock)
+	}
+}
+
+func TestDetect_AutoFallsBackToCLI(t *testing.T) {
+	defer func(s []string, lp func(string) (string, error)) { podmanSockets, lookPath = s, lp }(podmanSockets, lookPath)
+	podmanSockets = nil
+	lookPath = func(name string) (string, error) {
+		if name == "podman" {
+			return "/usr/bin/podman", nil
+		}
+		return "", errors.New("not found")
+	}
+
+	missing := filepath.Join(t.TempDir(), "docker.sock")
+	if rt, _ := Detect(context.Background(), "auto", missing); rt != RuntimePodman {
+		t.Errorf("auto = %s, want podman (only CLI installed)", rt)
+	}
+}
+
+func TestParsePS(t *testing.T) {
+	out := []byte(`[{"Id":"abc123","Names":["web"],"Image":"docker.io/library/nginx:latest","State":"running","Status":"",
+		"Labels":{"com.docker.compose.project":"site"},
+		"Ports":[{"host_ip":"","container_port":80,"host_port":8080,"range":1,"protocol":"tcp"}]},
+		{"Id":"def456","Names":["job"],"Image":"alpine","State":"stopped","ExitCode":2}]`)
+
+	containers, ok, err := ParsePS(out)
+	if !ok || err != nil || len(containers) != 2 {
+		t.Fatalf("ParsePS = %v %v %v", containers, ok, err)
+	}
+	web := containers[0]
+	if web.Name() != "web" || web.Status != "Up" || web.Labels["com.docker.compose.project"] != "site" {
+		t.Errorf("web = %+v", web)
+	}
+	if got := web.PortsString(); got != "0.0.0.0:8080->80/tcp" {
+		t.Errorf("ports = %q", got)
+	}
+	if job := containers[1]; job.State != "exited" || job.Status != "Exited (2)" {
+		t.Errorf("job = %+v", job)
+	}
+
+	if _, ok, _ := ParsePS([]byte(`{"ID":"abc","Names":"web"}`)); ok {
+		t.Error("docker's object-per-line output should not be claimed")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/Fullex26/piguard/internal/config"
+	"github.com/Fullex26/piguard/internal/netdevice"
 	"github.com/Fullex26/piguard/internal/store"
 )
 
@@ -125,8 +126,10 @@ func (r *Runner) Run() []CheckResult {
 
 	if r.cfg != nil && r.cfg.Network.Enabled {
 		results = append(results, r.checkIP())
+		results = append(results, r.checkOUI())
 	} else {
 		results = append(results, skip("Dependencies", "ip", "Network watcher disabled"))
+		results = append(results, skip("Dependencies", "OUI registry", "Network watcher disabled"))
 	}
 
 	if r.cfg != nil && r.cfg.AutoUpdate.Enabled {
@@ -253,7 +256,29 @@ func (r *Runner) checkIPTables() CheckResult {
 	return CheckResult{Category: "Dependencies", Name: "iptables", Status: StatusOK, Message: "Readable"}
 }
 
+// checkDocker checks the configured container runtime. With runtime "auto"
+// a missing docker CLI falls through to podman.
 func (r *Runner) checkDocker() CheckResult {
+	runtime := ""
+	if r.cfg != nil {
+		runtime = r.cfg.Docker.Runtime
+	}
+	switch runtime {
+	case "podman":
+		return r.checkPodman()
+	case "docker":
+		return r.checkDockerCLI()
+	}
+	res := r.checkDockerCLI()
+	if res.Status == StatusFail {
+		if p := r.checkPodman(); p.Status != StatusFail {
+			return p
+		}
+	}
+	return res
+}
+
+func (r *Runner) checkDockerCLI() CheckResult {
 	out, code := r.execFn("docker", "info", "--format", "{{.Containers}}")
 	if code == -1 {
 		return CheckResult{
@@ -283,6 +308,28 @@ func (r *Runner) checkDocker() CheckResult {
 	}
 }
 
+func (r *Runner) checkPodman() CheckResult {
+	out, code := r.execFn("podman", "info", "--format", "{{.Store.ContainerStore.Number}}")
+	if code == -1 {
+		return CheckResult{
+			Category: "Dependencies", Name: "podman",
+			Status: StatusFail, Message: "Not found",
+			Fix: "sudo apt install podman",
+		}
+	}
+	if code != 0 {
+		return CheckResult{
+			Category: "Dependencies", Name: "podman",
+			Status: StatusWarn, Message: "Podman not responding — container watcher will fail",
+			Fix: "sudo systemctl enable --now podman.socket (rootless: systemctl --user enable --now podman.socket, then set docker.socket)",
+		}
+	}
+	return CheckResult{
+		Category: "Dependencies", Name: "podman",
+		Status: StatusOK, Message: fmt.Sprintf("Running (%s containers)", strings.TrimSpace(out)),
+	}
+}
+
 func (r *Runner) checkRKHunter() CheckResult {
 	_, code := r.execFn("rkhunter", "--version")
 	if code == -1 {
@@ -328,6 +375,28 @@ func (r *Runner) checkIP() CheckResult {
 	return CheckResult{Category: "Dependencies", Name: "ip", Status: StatusOK, Message: "Available"}
 }
 
+// checkOUI reports which MAC vendor registry is in use: the one embedded in
+// the binary, or a file merged over it. Only a configured network.oui_file
+// that is missing is a problem.
+func (r *Runner) checkOUI() CheckResult {
+	if path := r.cfg.Network.OUIFile; path != "" {
+		if r.existsFn(path) {
+			return CheckResult{Category: "Dependencies", Name: "OUI registry", Status: StatusOK, Message: path + " (over embedded registry)"}
+		}
+		return CheckResult{
+			Category: "Dependencies", Name: "OUI registry",
+			Status: StatusWarn, Message: fmt.Sprintf("%s not found — using the embedded registry", path),
+			Fix: "Fix or clear network.oui_file",
+		}
+	}
+	for _, path := range netdevice.SystemOUIFiles {
+		if r.existsFn(path) {
+			return CheckResult{Category: "Dependencies", Name: "OUI registry", Status: StatusOK, Message: path + " (over embedded registry)"}
+		}
+	}
+	return CheckResult{Category: "Dependencies", Name: "OUI registry", Status: StatusOK, Message: "Embedded IEEE registry"}
+}
+
 func (r *Runner) checkAptGet() CheckResult {
 	_, code := r.execFn("apt-get", "--version")
 	if code == -1 {
@@ -247,6 +247,29 @@ func TestCheckDocker_NotFound(t *testing.T) {
 	}
 }
 
+func TestCheckDocker_AutoFallsBackToPodman(t *testing.T) {
+	r := stubRunner(minimalCfg(), map[string]struct{ out string; code int }{
+		"podman": {"3", 0},
+	}, &mockDB{})
+	res := r.checkDocker()
+	if res.Status != StatusOK || res.Name != "podman" || !strings.Contains(res.Message, "3 containers") {
+		t.Errorf("want podman OK, got %+v", res)
+	}
+}
+
+func TestCheckDocker_PodmanConfigured(t *testing.T) {
+	cfg := minimalCfg()
+	cfg.Docker.Runtime = "podman"
+	r := stubRunner(cfg, map[string]struct{ out string; code int }{
+		"docker": {"5", 0},
+		"podman": {"Error: unable to connect to Podman socket", 125},
+	}, &mockDB{})
+	res := r.checkDocker()
+	if res.Status != StatusWarn || res.Name != "podman" {
+		t.Errorf("want podman Warn, got %+v", res)
+	}
+}
+
 // ── checkRKHunter ─────────────────────────────────────────────────────────────
 
 func TestCheckRKHunter_NotInstalled(t *testing.T) {
@@ -301,6 +324,26 @@ func TestCheckClamAV_Installed(t *testing.T) {
 	}
 }
 
+// ── checkOUI ──────────────────────────────────────────────────────────────────
+
+func TestCheckOUI(t *testing.T) {
+	r := stubRunner(minimalCfg(), map[string]struct{ out string; code int }{}, &mockDB{})
+	r.existsFn = func(string) bool { return false }
+	if res := r.checkOUI(); res.Status != StatusOK || !strings.Contains(res.Message, "Embedded") {
+		t.Errorf("no system copy: got %v %q, want OK with the embedded registry", res.Status, res.Message)
+	}
+
+	r.existsFn = func(path string) bool { return path == "/usr/share/nmap/nmap-mac-prefixes" }
+	if res := r.checkOUI(); res.Status != StatusOK || !strings.HasPrefix(res.Message, "/usr/share/nmap/nmap-mac-prefixes") {
+		t.Errorf("nmap installed: got %v %q, want OK naming the file", res.Status, res.Message)
+	}
+
+	r.cfg.Network.OUIFile = "/opt/oui.txt"
+	if res := r.checkOUI(); res.Status != StatusWarn || !strings.Contains(res.Message, "embedded") {
+		t.Errorf("missing oui_file: got %v %q, want Warn falling back to the embedded registry", res.Status, res.Message)
+	}
+}
+
 // ── disabled-watcher skips ────────────────────────────────────────────────────
 
 func TestRun_DisabledWatchersAreSkipped(t *testing.T) {
@@ -330,7 +373,7 @@ func TestRun_DisabledWatchersAreSkipped(t *testing.T) {
 		return CheckResult{}
 	}
 
-	for _, name := range []string{"iptables", "docker", "rkhunter", "ClamAV", "ip"} {
+	for _, name := range []string{"iptables", "docker", "rkhunter", "ClamAV", "ip", "OUI registry"} {
 		if res := checkName(name); res.Status != StatusSkip {
 			t.Errorf("disabled watcher %q: want Skip, got %v", name, res.Status)
 		}
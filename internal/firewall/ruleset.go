@@ -0,0 +1,418 @@
+package firewall
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Ruleset is a filter-table ruleset reduced to what reachability analysis
+// needs: which chains a packet traverses and which rules accept or drop it.
+type Ruleset struct {
+	Chains map[string]*Chain   // by name; nft chains are "<table>/<chain>"
+	Hooks  map[string][]string // "INPUT"/"FORWARD" -> base chains, in evaluation order
+}
+
+// Chain is one filter chain. Policy is set for base chains only; packets that
+// fall off the end of a user chain return to the caller.
+type Chain struct {
+	Name   string
+	Policy string // "ACCEPT", "DROP" or "" (user chain)
+	Rules  []Rule
+}
+
+// Rule is one rule's matches and verdict. Nil slices match anything.
+type Rule struct {
+	Protocol     string      // "tcp", "udp", ... ("" = any)
+	DstPorts     []PortRange // --dport/--dports, nft "th dport"
+	DstPortsNeg  bool
+	OrigDstPorts []PortRange // conntrack --ctorigdstport (port before DNAT)
+	Sources      []*net.IPNet
+	SourcesNeg   bool
+	InIface      string
+	InIfaceNeg   bool
+	States       []string // conntrack states, upper case ("NEW", "ESTABLISHED")
+	Target       string   // "ACCEPT", "DROP", "REJECT", "RETURN", a chain name, or "" (no verdict)
+	Goto         bool     // -g / nft goto: the jump does not come back
+	Unknown      bool     // matches the parser does not model; may or may not match
+}
+
+// PortRange is an inclusive port range; single ports have Lo == Hi.
+type PortRange struct {
+	Lo, Hi int
+}
+
+// Contains reports whether port falls inside the range.
+func (r PortRange) Contains(port int) bool {
+	return port >= r.Lo && port <= r.Hi
+}
+
+// LoadRulesets reads the live IPv4 and IPv6 filter rules. iptables-save and
+// ip6tables-save are preferred, as in Capture; on nftables-only hosts both
+// families come from one `nft list ruleset`. A nil Ruleset means no rules
+// could be read for that family.
+func (r *Runner) LoadRulesets() (v4, v6 *Ruleset, err error) {
+	if out, err := r.runFn(nil, "iptables-save"); err == nil && hasIPTablesRules(out) {
+		v4, err = ParseIPTablesSave(out)
+		if err != nil {
+			return nil, nil, err
+		}
+		if out6, err := r.runFn(nil, "ip6tables-save"); err == nil && hasIPTablesRules(out6) {
+			v6, _ = ParseIPTablesSave(out6)
+		}
+		return v4, v6, nil
+	}
+
+	out, err := r.runFn(nil, "nft", "list", "ruleset")
+	if err != nil {
+		return nil, nil, fmt.Errorf("nft list ruleset: %w (%s)", err, strings.TrimSpace(string(out)))
+	}
+	return ParseNFT(out, "ip"), ParseNFT(out, "ip6"), nil
+}
+
+// ParseIPTablesSave parses the *filter table of iptables-save output.
+func ParseIPTablesSave(data []byte) (*Ruleset, error) {
+	rs := &Ruleset{Chains: make(map[string]*Chain), Hooks: make(map[string][]string)}
+	inFilter := false
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "*"):
+			inFilter = line == "*filter"
+		case !inFilter || line == "" || strings.HasPrefix(line, "#") || line == "COMMIT":
+		case strings.HasPrefix(line, ":"):
+			// :INPUT DROP [0:0] — user chains have policy "-"
+			fields := strings.Fields(line[1:])
+			if len(fields) < 2 {
+				continue
+			}
+			c := &Chain{Name: fields[0]}
+			if fields[1] != "-" {
+				c.Policy = fields[1]
+				rs.Hooks[fields[0]] = []string{fields[0]}
+			}
+			rs.Chains[c.Name] = c
+		case strings.HasPrefix(line, "-A "):
+			args := splitArgs(line)
+			if len(args) < 2 {
+				continue
+			}
+			c, ok := rs.Chains[args[1]]
+			if !ok {
+				return nil, fmt.Errorf("rule for undeclared chain %s", args[1])
+			}
+			c.Rules = append(c.Rules, parseIPTablesRule(args[2:]))
+		}
+	}
+	if len(rs.Chains) == 0 {
+		return nil, fmt.Errorf("no filter table in iptables-save output")
+	}
+	return rs, scanner.Err()
+}
+
+func parseIPTablesRule(args []string) Rule {
+	var r Rule
+	neg := false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		next := func() string {
+			if i+1 < len(args) {
+				i++
+				return args[i]
+			}
+			return ""
+		}
+		if arg == "!" {
+			neg = true
+			continue
+		}
+		switch arg {
+		case "-p", "--protocol":
+			if proto := strings.ToLower(next()); neg {
+				r.Unknown = true
+			} else {
+				r.Protocol = proto
+			}
+		case "-s", "--source":
+			r.Sources = parseNets(strings.Split(next(), ","))
+			r.SourcesNeg = neg
+			r.Unknown = r.Unknown || r.Sources == nil
+		case "-i", "--in-interface":
+			r.InIface = next()
+			r.InIfaceNeg = neg
+		case "--dport", "--destination-port", "--dports", "--destination-ports":
+			r.DstPorts = parsePortList(next(), ",", ":")
+			r.DstPortsNeg = neg
+			r.Unknown = r.Unknown || r.DstPorts == nil // service names
+		case "--ctorigdstport":
+			r.OrigDstPorts = parsePortList(next(), ",", ":")
+			if neg || r.OrigDstPorts == nil {
+				r.Unknown = true
+			}
+		case "--ctstate", "--state":
+			r.States = strings.Split(strings.ToUpper(next()), ",")
+			if neg {
+				r.Unknown = true
+			}
+		case "-j", "--jump", "-g", "--goto":
+			r.Target = next()
+			r.Goto = arg == "-g" || arg == "--goto"
+			// Target options (--reject-with, --log-prefix) carry no matches.
+			return r
+		case "-m", "--match", "--comment":
+			next() // module names and comments don't change what matches
+		default:
+			if strings.HasPrefix(arg, "-") {
+				// -d, -o, --sport, -m recent/limit/addrtype/set options, ...
+				r.Unknown = true
+				for i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") && args[i+1] != "!" {
+					i++
+				}
+			}
+		}
+		neg = false
+	}
+	return r
+}
+
+// ParseNFT parses `nft list ruleset` for one address family ("ip" or "ip6").
+// Tables of that family and "inet" tables apply; base chains of type filter
+// on the input and forward hooks become the "INPUT" and "FORWARD" hooks.
+func ParseNFT(data []byte, family string) *Ruleset {
+	rs := &Ruleset{Chains: make(map[string]*Chain), Hooks: make(map[string][]string)}
+	var table string // "" = skipping this table
+	var chain *Chain
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case fields[0] == "table" && len(fields) >= 3:
+			table = ""
+			if fields[1] == family || fields[1] == "inet" {
+				table = fields[1] + " " + fields[2]
+			}
+		case table == "":
+		case fields[0] == "chain" && len(fields) >= 2:
+			chain = &Chain{Name: table + "/" + fields[1]}
+			rs.Chains[chain.Name] = chain
+		case line == "}":
+			chain = nil
+		case chain == nil:
+		case fields[0] == "type":
+			// type filter hook input priority filter; policy drop;
+			hook := nftField(fields, "hook")
+			if fields[1] != "filter" || (hook != "input" && hook != "forward") {
+				continue
+			}
+			chain.Policy = "ACCEPT"
+			if strings.TrimSuffix(nftField(fields, "policy"), ";") == "drop" {
+				chain.Policy = "DROP"
+			}
+			name := strings.ToUpper(hook)
+			rs.Hooks[name] = append(rs.Hooks[name], chain.Name)
+		default:
+			chain.Rules = append(chain.Rules, parseNFTRule(splitArgs(line), table))
+		}
+	}
+	if len(rs.Hooks) == 0 {
+		return nil
+	}
+	return rs
+}
+
+func nftField(fields []string, key string) string {
+	for i := 0; i+1 < len(fields); i++ {
+		if fields[i] == key {
+			return strings.TrimSuffix(fields[i+1], ";")
+		}
+	}
+	return ""
+}
+
+func parseNFTRule(tokens []string, table string) Rule {
+	var r Rule
+	for i := 0; i < len(tokens); i++ {
+		// value reads one value or a { a, b } set, and notes a "!=" before it.
+		value := func() ([]string, bool) {
+			neg := false
+			if i+1 < len(tokens) && tokens[i+1] == "!=" {
+				neg = true
+				i++
+			}
+			if i+1 >= len(tokens) {
+				return nil, neg
+			}
+			i++
+			if tokens[i] != "{" {
+				return []string{strings.Trim(tokens[i], `"`)}, neg
+			}
+			var set []string
+			for i+1 < len(tokens) && tokens[i+1] != "}" {
+				i++
+				if v := strings.Trim(tokens[i], `",`); v != "" {
+					set = append(set, v)
+				}
+			}
+			i++ // closing brace
+			return set, neg
+		}
+
+		switch tok := tokens[i]; tok {
+		case "tcp", "udp", "th":
+			if i+1 < len(tokens) && tokens[i+1] == "dport" {
+				if tok != "th" {
+					r.Protocol = tok
+				}
+				i++
+				ports, neg := value()
+				r.DstPorts = parsePortList(strings.Join(ports, ","), ",", "-")
+				r.DstPortsNeg = neg
+				r.Unknown = r.Unknown || r.DstPorts == nil // named sets, service names
+			} else {
+				r.Unknown = true
+			}
+		case "meta":
+			if i+1 < len(tokens) && tokens[i+1] == "l4proto" {
+				i++
+				protos, neg := value()
+				if len(protos) == 1 && !neg {
+					r.Protocol = protos[0]
+				} else {
+					r.Unknown = true
+				}
+			} else {
+				r.Unknown = true
+			}
+		case "iif", "iifname":
+			ifaces, neg := value()
+			if len(ifaces) == 1 {
+				r.InIface, r.InIfaceNeg = ifaces[0], neg
+			} else {
+				r.Unknown = true
+			}
+		case "ip", "ip6":
+			if i+1 < len(tokens) && tokens[i+1] == "saddr" {
+				i++
+				nets, neg := value()
+				r.Sources, r.SourcesNeg = parseNets(nets), neg
+				r.Unknown = r.Unknown || r.Sources == nil // named sets
+			} else {
+				r.Unknown = true
+			}
+		case "ct":
+			if i+1 < len(tokens) && tokens[i+1] == "state" {
+				i++
+				states, neg := value()
+				for _, s := range states {
+					r.States = append(r.States, strings.Split(strings.ToUpper(s), ",")...)
+				}
+				if neg {
+					r.Unknown = true
+				}
+			} else {
+				r.Unknown = true
+			}
+		case "counter":
+			// counter packets N bytes N
+			for i+1 < len(tokens) && (tokens[i+1] == "packets" || tokens[i+1] == "bytes") {
+				i += 2
+			}
+		case "comment", "log":
+			return r // comments and log options run to the end of the rule
+		case "accept", "drop", "reject", "return":
+			r.Target = strings.ToUpper(tok)
+			return r
+		case "jump", "goto":
+			if i+1 < len(tokens) {
+				r.Target = table + "/" + tokens[i+1]
+				r.Goto = tok == "goto"
+			}
+			return r
+		default:
+			r.Unknown = true
+		}
+	}
+	return r
+}
+
+// splitArgs splits a rule line on spaces, keeping quoted strings whole and
+// treating nft set braces as separate tokens.
+func splitArgs(line string) []string {
+	var args []string
+	var cur strings.Builder
+	quoted := false
+	flush := func() {
+		if cur.Len() > 0 {
+			args = append(args, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, ch := range line {
+		switch {
+		case ch == '"':
+			quoted = !quoted
+		case quoted:
+			cur.WriteRune(ch)
+		case ch == ' ' || ch == '\t':
+			flush()
+		case ch == '{' || ch == '}':
+			flush()
+			args = append(args, string(ch))
+		default:
+			cur.WriteRune(ch)
+		}
+	}
+	flush()
+	return args
+}
+
+// parsePortList parses "22", "80,443" or ranges such as "8000:8100"
+// (iptables) and "8000-8100" (nft).
+func parsePortList(s, sep, rangeSep string) []PortRange {
+	var ranges []PortRange
+	for _, part := range strings.Split(s, sep) {
+		part = strings.TrimSpace(part)
+		lo, hi, isRange := strings.Cut(part, rangeSep)
+		l, err := strconv.Atoi(lo)
+		if err != nil {
+			continue
+		}
+		h := l
+		if isRange {
+			if h, err = strconv.Atoi(hi); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, PortRange{Lo: l, Hi: h})
+	}
+	return ranges
+}
+
+// parseNets parses addresses and CIDRs; bare addresses become host routes.
+func parseNets(specs []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, s := range specs {
+		s = strings.TrimSpace(s)
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				continue
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		if _, n, err := net.ParseCIDR(s); err == nil {
+			nets = append(nets, n)
+		}
+	}
+	return nets
+}
@@ -0,0 +1,202 @@
+package firewall
+
+import (
+	"testing"
+)
+
+// ufwDockerDump is a trimmed iptables-save from a host running UFW and Docker.
+const ufwDockerDump = `# Generated by iptables-save v1.8.9
+*nat
+:PREROUTING ACCEPT [0:0]
+:DOCKER - [0:0]
+-A PREROUTING -m addrtype --dst-type LOCAL -j DOCKER
+-A DOCKER ! -i docker0 -p tcp -m tcp --dport 8080 -j DNAT --to-destination 172.17.0.2:80
+COMMIT
+*filter
+:INPUT DROP [0:0]
+:FORWARD DROP [0:0]
+:OUTPUT ACCEPT [0:0]
+:DOCKER - [0:0]
+:DOCKER-USER - [0:0]
+:ufw-user-input - [0:0]
+-A INPUT -i lo -j ACCEPT
+-A INPUT -m conntrack --ctstate RELATED,ESTABLISHED -j ACCEPT
+-A INPUT -j ufw-user-input
+-A FORWARD -j DOCKER-USER
+-A FORWARD -o docker0 -j DOCKER
+-A DOCKER -d 172.17.0.2/32 ! -i docker0 -o docker0 -p tcp -m tcp --dport 80 -j ACCEPT
+-A DOCKER-USER -j RETURN
+-A ufw-user-input -p tcp -m tcp --dport 22 -m comment --comment "'dapp_OpenSSH'" -j ACCEPT
+-A ufw-user-input -s 192.168.1.0/24 -p tcp -m multiport --dports 8000:8100,9090 -j ACCEPT
+-A ufw-user-input -p udp -m udp --dport ssh -j ACCEPT
+COMMIT
+`
+
+func TestParseIPTablesSave(t *testing.T) {
+	rs, err := ParseIPTablesSave([]byte(ufwDockerDump))
+	if err != nil {
+		t.Fatalf("ParseIPTablesSave: %v", err)
+	}
+	if _, ok := rs.Chains["PREROUTING"]; ok {
+		t.Error("nat table chains should be skipped")
+	}
+	if rs.Chains["INPUT"].Policy != "DROP" {
+		t.Errorf("INPUT policy = %q, want DROP", rs.Chains["INPUT"].Policy)
+	}
+	if got := rs.Hooks["FORWARD"]; len(got) != 1 || got[0] != "FORWARD" {
+		t.Errorf("FORWARD hook = %v", got)
+	}
+	if _, ok := rs.Hooks["DOCKER"]; ok {
+		t.Error("user chains should not be hooks")
+	}
+
+	input := rs.Chains["INPUT"].Rules
+	if len(input) != 3 {
+		t.Fatalf("INPUT rules = %d, want 3", len(input))
+	}
+	if input[0].InIface != "lo" || input[0].Target != "ACCEPT" {
+		t.Errorf("rule 0 = %+v", input[0])
+	}
+	if len(input[1].States) != 2 || input[1].States[0] != "RELATED" {
+		t.Errorf("rule 1 states = %v", input[1].States)
+	}
+	if input[2].Target != "ufw-user-input" {
+		t.Errorf("rule 2 target = %q", input[2].Target)
+	}
+
+	docker := rs.Chains["DOCKER"].Rules[0]
+	if !docker.InIfaceNeg || docker.InIface != "docker0" || !docker.Unknown {
+		t.Errorf("DOCKER rule = %+v, want ! -i docker0 and unknown -d/-o", docker)
+	}
+
+	ufw := rs.Chains["ufw-user-input"].Rules
+	if ufw[0].Unknown || ufw[0].Protocol != "tcp" || ufw[0].DstPorts[0] != (PortRange{22, 22}) {
+		t.Errorf("ssh rule = %+v", ufw[0])
+	}
+	if len(ufw[1].Sources) != 1 || ufw[1].Sources[0].String() != "192.168.1.0/24" {
+		t.Errorf("sources = %v", ufw[1].Sources)
+	}
+	if len(ufw[1].DstPorts) != 2 || ufw[1].DstPorts[0] != (PortRange{8000, 8100}) {
+		t.Errorf("multiport = %v", ufw[1].DstPorts)
+	}
+	if !ufw[2].Unknown {
+		t.Error("service-name port should be unknown")
+	}
+}
+
+func TestParseIPTablesSave_NoFilter(t *testing.T) {
+	if _, err := ParseIPTablesSave([]byte("*nat\n:PREROUTING ACCEPT [0:0]\nCOMMIT\n")); err == nil {
+		t.Error("expected error without a filter table")
+	}
+}
+
+const nftDump = `table inet filter {
+	set trusted {
+		type ipv4_addr
+		elements = { 192.168.1.5 }
+	}
+
+	chain input {
+		type filter hook input priority filter; policy drop;
+		ct state established,related accept
+		iifname "lo" accept
+		tcp dport { 22, 80-81 } counter packets 12 bytes 720 accept
+		ip saddr @trusted accept
+		jump lan_only
+	}
+
+	chain lan_only {
+		ip saddr != 10.0.0.0/8 return
+		udp dport 53 accept comment "dns"
+	}
+}
+table ip6 extra {
+	chain input {
+		type filter hook input priority 10; policy accept;
+	}
+}
+table ip nat {
+	chain prerouting {
+		type nat hook prerouting priority dstnat; policy accept;
+	}
+}
+`
+
+func TestParseNFT(t *testing.T) {
+	rs := ParseNFT([]byte(nftDump), "ip")
+	if rs == nil {
+		t.Fatal("expected a ruleset")
+	}
+	if got := rs.Hooks["INPUT"]; len(got) != 1 || got[0] != "inet filter/input" {
+		t.Fatalf("INPUT hook = %v", got)
+	}
+	input := rs.Chains["inet filter/input"]
+	if input.Policy != "DROP" {
+		t.Errorf("policy = %q, want DROP", input.Policy)
+	}
+	if len(input.Rules) != 5 {
+		t.Fatalf("rules = %d, want 5", len(input.Rules))
+	}
+	if s := input.Rules[0].States; len(s) != 2 || s[0] != "ESTABLISHED" {
+		t.Errorf("states = %v", s)
+	}
+	if r := input.Rules[2]; r.Protocol != "tcp" || len(r.DstPorts) != 2 || r.DstPorts[1] != (PortRange{80, 81}) || r.Unknown {
+		t.Errorf("port rule = %+v", r)
+	}
+	if !input.Rules[3].Unknown {
+		t.Error("named set should be unknown")
+	}
+	if r := input.Rules[4]; r.Target != "inet filter/lan_only" {
+		t.Errorf("jump target = %q", r.Target)
+	}
+	lan := rs.Chains["inet filter/lan_only"].Rules
+	if !lan[0].SourcesNeg || lan[0].Target != "RETURN" {
+		t.Errorf("lan rule = %+v", lan[0])
+	}
+	if lan[1].Target != "ACCEPT" || lan[1].Protocol != "udp" {
+		t.Errorf("dns rule = %+v", lan[1])
+	}
+
+	rs6 := ParseNFT([]byte(nftDump), "ip6")
+	if got := rs6.Hooks["INPUT"]; len(got) != 2 {
+		t.Errorf("ip6 INPUT hook = %v, want inet and ip6 base chains", got)
+	}
+	if rs6.Chains["ip6 extra/input"].Policy != "ACCEPT" {
+		t.Error("ip6 extra input should default to accept")
+	}
+}
+
+func TestLoadRulesets_FallsBackToNFT(t *testing.T) {
+	var calls []call
+	r := fakeRunner(map[string]fakeResult{
+		"iptables-save": {out: "# Warning: iptables-legacy tables present\n"},
+		"nft":           {out: nftDump},
+	}, &calls)
+
+	v4, v6, err := r.LoadRulesets()
+	if err != nil {
+		t.Fatalf("LoadRulesets: %v", err)
+	}
+	if v4 == nil || v6 == nil {
+		t.Fatalf("expected both families, got v4=%v v6=%v", v4, v6)
+	}
+}
+
+func TestLoadRulesets_IPTables(t *testing.T) {
+	var calls []call
+	r := fakeRunner(map[string]fakeResult{
+		"iptables-save":  {out: ufwDockerDump},
+		"ip6tables-save": {out: ""},
+	}, &calls)
+
+	v4, v6, err := r.LoadRulesets()
+	if err != nil {
+		t.Fatalf("LoadRulesets: %v", err)
+	}
+	if v4 == nil || v4.Chains["DOCKER-USER"] == nil {
+		t.Error("expected v4 ruleset with DOCKER-USER")
+	}
+	if v6 != nil {
+		t.Error("expected no v6 ruleset from empty ip6tables-save")
+	}
+}
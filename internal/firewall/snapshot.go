@@ -0,0 +1,145 @@
+package firewall
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// Backend identifies which tool produced (and can restore) a ruleset dump.
+const (
+	BackendIPTables = "iptables"
+	BackendNFT      = "nft"
+)
+
+// Runner captures and restores the full host ruleset. iptables-save is tried
+// first because Docker and UFW both manage their rules through iptables
+// (including iptables-nft); native nftables is the fallback. An iptables
+// dump carries the ip6tables-save rules too, after ip6Marker.
+type Runner struct {
+	runFn func(stdin []byte, name string, args ...string) ([]byte, error) // injectable for tests
+}
+
+// New creates a Runner wired to the real iptables/nft binaries.
+func New() *Runner {
+	return &Runner{runFn: defaultRun}
+}
+
+// defaultRun returns stdout only, so warnings printed on stderr never end up
+// inside a saved ruleset. On failure stderr is returned in its place.
+func defaultRun(stdin []byte, name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return stderr.Bytes(), err
+	}
+	return out, nil
+}
+
+// ip6Marker separates the IPv4 and IPv6 halves of an iptables dump. It is
+// a comment, so SameRules still compares both halves line by line.
+const ip6Marker = "# piguard: ip6tables-save"
+
+// Capture dumps the current ruleset and reports which backend produced it.
+// For iptables both families are captured; hosts without ip6tables keep the
+// IPv4 dump alone. nft's ruleset already covers every family.
+func (r *Runner) Capture() (string, []byte, error) {
+	out, err := r.runFn(nil, "iptables-save")
+	if err == nil && hasIPTablesRules(out) {
+		if out6, err6 := r.runFn(nil, "ip6tables-save"); err6 == nil && hasIPTablesRules(out6) {
+			dump := append(bytes.TrimRight(out, "\n"), "\n"+ip6Marker+"\n"...)
+			out = append(dump, out6...)
+		}
+		return BackendIPTables, out, nil
+	}
+
+	nftOut, nftErr := r.runFn(nil, "nft", "list", "ruleset")
+	if nftErr == nil && len(bytes.TrimSpace(nftOut)) > 0 {
+		return BackendNFT, nftOut, nil
+	}
+
+	if err != nil {
+		return "", nil, fmt.Errorf("iptables-save: %w (%s)", err, strings.TrimSpace(string(out)))
+	}
+	if nftErr != nil {
+		return "", nil, fmt.Errorf("nft list ruleset: %w (%s)", nftErr, strings.TrimSpace(string(nftOut)))
+	}
+	return "", nil, fmt.Errorf("no firewall rules found")
+}
+
+// Restore atomically replaces the live ruleset with a previous dump. An
+// iptables dump is restored family by family, IPv4 first.
+func (r *Runner) Restore(backend string, rules []byte) error {
+	switch backend {
+	case BackendIPTables:
+		v4, v6 := splitIPTablesDump(rules)
+		if out, err := r.runFn(v4, "iptables-restore"); err != nil {
+			return fmt.Errorf("iptables-restore: %w (%s)", err, strings.TrimSpace(string(out)))
+		}
+		if v6 == nil {
+			return nil // captured on a host without ip6tables
+		}
+		if out, err := r.runFn(v6, "ip6tables-restore"); err != nil {
+			return fmt.Errorf("ip6tables-restore (IPv4 rules already restored): %w (%s)", err, strings.TrimSpace(string(out)))
+		}
+	case BackendNFT:
+		// `nft list ruleset` output has no flush, so prepend one to make the
+		// load a replacement rather than a merge. nft applies -f atomically.
+		script := append([]byte("flush ruleset\n"), rules...)
+		if out, err := r.runFn(script, "nft", "-f", "-"); err != nil {
+			return fmt.Errorf("nft -f: %w (%s)", err, strings.TrimSpace(string(out)))
+		}
+	default:
+		return fmt.Errorf("unknown firewall backend: %q", backend)
+	}
+	return nil
+}
+
+// splitIPTablesDump splits a Capture dump into its iptables-save and
+// ip6tables-save halves; v6 is nil when the dump has no IPv6 rules.
+func splitIPTablesDump(rules []byte) (v4, v6 []byte) {
+	before, after, found := bytes.Cut(rules, []byte("\n"+ip6Marker+"\n"))
+	if !found {
+		return rules, nil
+	}
+	return append(before, '\n'), after
+}
+
+// counters matches the packet and byte counts in iptables-save chain lines
+// ("[12:3456]") and nft counter statements ("packets 12 bytes 3456").
+var counters = regexp.MustCompile(`\[\d+:\d+\]|packets \d+ bytes \d+`)
+
+// SameRules reports whether two dumps hold the same rules, ignoring comments
+// (iptables-save prints a timestamp) and packet counters.
+func SameRules(a, b string) bool {
+	return normalizeRules(a) == normalizeRules(b)
+}
+
+func normalizeRules(dump string) string {
+	var lines []string
+	for _, line := range strings.Split(dump, "\n") {
+		line = strings.TrimSpace(counters.ReplaceAllString(line, ""))
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// hasIPTablesRules reports whether iptables-save produced at least one table.
+// On nftables-only hosts iptables-save succeeds but prints nothing useful.
+func hasIPTablesRules(out []byte) bool {
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "*") {
+			return true
+		}
+	}
+	return false
+}
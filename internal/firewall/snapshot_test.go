@@ -0,0 +1,216 @@
+package firewall
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type fakeResult struct {
+	out string
+	err error
+}
+
+type call struct {
+	name  string
+	args  []string
+	stdin string
+}
+
+func fakeRunner(responses map[string]fakeResult, calls *[]call) *Runner {
+	return &Runner{runFn: func(stdin []byte, name string, args ...string) ([]byte, error) {
+		*calls = append(*calls, call{name: name, args: args, stdin: string(stdin)})
+		r := responses[name]
+		return []byte(r.out), r.err
+	}}
+}
+
+const iptablesDump = `# Generated by iptables-save
+*filter
+:INPUT DROP [0:0]
+-A INPUT -i lo -j ACCEPT
+COMMIT
+`
+
+func TestCapture_PrefersIPTables(t *testing.T) {
+	var calls []call
+	r := fakeRunner(map[string]fakeResult{
+		"iptables-save": {out: iptablesDump},
+		"nft":           {out: "table inet filter {}"},
+	}, &calls)
+
+	backend, rules, err := r.Capture()
+	if err != nil {
+		t.Fatalf("Capture: %v", err)
+	}
+	if backend != BackendIPTables {
+		t.Errorf("backend = %q, want %q", backend, BackendIPTables)
+	}
+	if string(rules) != iptablesDump {
+		t.Errorf("rules = %q", rules)
+	}
+	for _, c := range calls {
+		if c.name == "nft" {
+			t.Error("expected nft not to be called")
+		}
+	}
+}
+
+const ip6tablesDump = `# Generated by ip6tables-save
+*filter
+:INPUT DROP [0:0]
+-A INPUT -p ipv6-icmp -j ACCEPT
+COMMIT
+`
+
+func TestCapture_IncludesIP6Tables(t *testing.T) {
+	var calls []call
+	r := fakeRunner(map[string]fakeResult{
+		"iptables-save":  {out: iptablesDump},
+		"ip6tables-save": {out: ip6tablesDump},
+	}, &calls)
+
+	backend, rules, err := r.Capture()
+	if err != nil || backend != BackendIPTables {
+		t.Fatalf("Capture = %q, %v", backend, err)
+	}
+	want := iptablesDump + ip6Marker + "\n" + ip6tablesDump
+	if string(rules) != want {
+		t.Errorf("rules = %q, want %q", rules, want)
+	}
+
+	// A change to the IPv6 rules alone makes the dumps differ.
+	changed := strings.Replace(want, "ipv6-icmp -j ACCEPT", "ipv6-icmp -j ACCEPT\n-A INPUT -j ACCEPT", 1)
+	if SameRules(want, changed) {
+		t.Error("dumps with different IPv6 rules should not match")
+	}
+}
+
+func TestRestore_IPTablesBothFamilies(t *testing.T) {
+	var calls []call
+	r := fakeRunner(nil, &calls)
+
+	// The snapshot's IPv6 rules differ from the (tampered) live ones, so
+	// restore must reapply them, not just the IPv4 half.
+	if err := r.Restore(BackendIPTables, []byte(iptablesDump+ip6Marker+"\n"+ip6tablesDump)); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if len(calls) != 2 || calls[0].name != "iptables-restore" || calls[1].name != "ip6tables-restore" {
+		t.Fatalf("calls = %+v", calls)
+	}
+	if calls[0].stdin != iptablesDump || calls[1].stdin != ip6tablesDump {
+		t.Errorf("stdin = %q / %q, want the IPv4 and IPv6 dumps", calls[0].stdin, calls[1].stdin)
+	}
+}
+
+func TestRestore_IP6TablesError(t *testing.T) {
+	var calls []call
+	r := fakeRunner(map[string]fakeResult{
+		"ip6tables-restore": {out: "line 2 failed", err: errors.New("exit status 1")},
+	}, &calls)
+
+	err := r.Restore(BackendIPTables, []byte(iptablesDump+ip6Marker+"\n"+ip6tablesDump))
+	if err == nil || !strings.Contains(err.Error(), "ip6tables-restore") {
+		t.Errorf("expected ip6tables-restore error, got %v", err)
+	}
+}
+
+func TestCapture_FallsBackToNFT(t *testing.T) {
+	var calls []call
+	r := fakeRunner(map[string]fakeResult{
+		"iptables-save": {out: "# Warning: iptables-legacy tables present\n"},
+		"nft":           {out: "table inet filter {\n}\n"},
+	}, &calls)
+
+	backend, rules, err := r.Capture()
+	if err != nil {
+		t.Fatalf("Capture: %v", err)
+	}
+	if backend != BackendNFT {
+		t.Errorf("backend = %q, want %q", backend, BackendNFT)
+	}
+	if !strings.Contains(string(rules), "table inet filter") {
+		t.Errorf("rules = %q", rules)
+	}
+}
+
+func TestCapture_BothFail(t *testing.T) {
+	var calls []call
+	r := fakeRunner(map[string]fakeResult{
+		"iptables-save": {out: "permission denied", err: errors.New("exit status 1")},
+		"nft":           {err: errors.New("not found")},
+	}, &calls)
+
+	if _, _, err := r.Capture(); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestRestore_IPTables(t *testing.T) {
+	var calls []call
+	r := fakeRunner(nil, &calls)
+
+	if err := r.Restore(BackendIPTables, []byte(iptablesDump)); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if len(calls) != 1 || calls[0].name != "iptables-restore" {
+		t.Fatalf("calls = %+v", calls)
+	}
+	if calls[0].stdin != iptablesDump {
+		t.Errorf("stdin = %q, want dump", calls[0].stdin)
+	}
+}
+
+func TestRestore_NFTFlushesFirst(t *testing.T) {
+	var calls []call
+	r := fakeRunner(nil, &calls)
+
+	if err := r.Restore(BackendNFT, []byte("table inet filter {}\n")); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if len(calls) != 1 || calls[0].name != "nft" {
+		t.Fatalf("calls = %+v", calls)
+	}
+	if !strings.HasPrefix(calls[0].stdin, "flush ruleset\n") {
+		t.Errorf("stdin should start with flush, got %q", calls[0].stdin)
+	}
+}
+
+func TestRestore_Error(t *testing.T) {
+	var calls []call
+	r := fakeRunner(map[string]fakeResult{
+		"iptables-restore": {out: "line 3 failed", err: errors.New("exit status 1")},
+	}, &calls)
+
+	err := r.Restore(BackendIPTables, []byte(iptablesDump))
+	if err == nil || !strings.Contains(err.Error(), "line 3 failed") {
+		t.Errorf("expected wrapped error with output, got %v", err)
+	}
+}
+
+func TestRestore_UnknownBackend(t *testing.T) {
+	var calls []call
+	r := fakeRunner(nil, &calls)
+	if err := r.Restore("pf", nil); err == nil {
+		t.Error("expected error for unknown backend")
+	}
+	if len(calls) != 0 {
+		t.Errorf("expected no commands, got %d", len(calls))
+	}
+}
+
+func TestSameRules(t *testing.T) {
+	a := "# Generated by iptables-save v1.8.9 on Mon Oct 13 02:00:00 2026\n*filter\n:INPUT DROP [120:9600]\n-A INPUT -p tcp --dport 22 -j ACCEPT\nCOMMIT\n"
+	b := "# Generated by iptables-save v1.8.9 on Tue Oct 14 09:30:00 2026\n*filter\n:INPUT DROP [0:0]\n-A INPUT -p tcp --dport 22 -j ACCEPT\nCOMMIT\n"
+	if !SameRules(a, b) {
+		t.Error("dumps differing only in timestamp and counters should match")
+	}
+	if SameRules(a, strings.Replace(b, "--dport 22", "--dport 2222", 1)) {
+		t.Error("different rules should not match")
+	}
+	nft := "table inet filter {\n\tchain input {\n\t\ttcp dport 22 counter packets %d bytes %d accept\n\t}\n}\n"
+	if !SameRules(fmt.Sprintf(nft, 5, 300), fmt.Sprintf(nft, 0, 0)) {
+		t.Error("nft counters should be ignored")
+	}
+}
@@ -0,0 +1,351 @@
+package netdevice
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultLeaseFiles are the DHCP lease databases of dnsmasq, Pi-hole and
+// isc-dhcp-server. Missing files are skipped.
+var DefaultLeaseFiles = []string{
+	"/var/lib/misc/dnsmasq.leases",
+	"/etc/pihole/dhcp.leases",
+	"/var/lib/dhcp/dhcpd.leases",
+}
+
+// Identity is what could be learnt about a device.
+type Identity struct {
+	Vendor     string // "" = unknown
+	Randomised bool   // locally administered MAC
+	Hostname   string // "" = none found
+	Source     string // where Hostname came from: "DHCP lease", "reverse DNS" or "mDNS"
+}
+
+// Details renders the identity for an alert's Details field.
+func (i Identity) Details() string {
+	var parts []string
+	switch {
+	case i.Randomised:
+		parts = append(parts, "Vendor: none (randomised MAC, e.g. a phone's private Wi-Fi address)")
+	case i.Vendor != "":
+		parts = append(parts, "Vendor: "+i.Vendor)
+	default:
+		parts = append(parts, "Vendor: unknown")
+	}
+	if i.Hostname != "" {
+		parts = append(parts, fmt.Sprintf("Hostname: %s (%s)", i.Hostname, i.Source))
+	} else {
+		parts = append(parts, "Hostname: unknown")
+	}
+	return strings.Join(parts, " | ")
+}
+
+// Identifier looks up device vendors and hostnames.
+type Identifier struct {
+	ouis       OUIDB
+	registry   string // OUI file merged over the embedded registry; "" = embedded only
+	leaseFiles []string
+	timeout    time.Duration // per lookup
+
+	lookupAddr func(ctx context.Context, ip string) ([]string, error) // injectable for tests
+	queryMDNS  func(ctx context.Context, ip string) (string, error)   // injectable for tests
+}
+
+// NewIdentifier builds an identifier from the embedded vendor registry
+// merged with ouiFile (or the first of SystemOUIFiles when empty), reading
+// hostnames from leaseFiles (DefaultLeaseFiles when empty).
+func NewIdentifier(ouiFile string, leaseFiles []string) (*Identifier, error) {
+	id := &Identifier{
+		ouis:       BuiltinOUI(),
+		leaseFiles: leaseFiles,
+		timeout:    2 * time.Second,
+		lookupAddr: net.DefaultResolver.LookupAddr,
+		queryMDNS:  QueryMDNS,
+	}
+	if len(id.leaseFiles) == 0 {
+		id.leaseFiles = DefaultLeaseFiles
+	}
+
+	var err error
+	if ouiFile != "" {
+		if err = id.ouis.Merge(ouiFile); err == nil {
+			id.registry = ouiFile
+		}
+	} else {
+		for _, path := range SystemOUIFiles {
+			if id.ouis.Merge(path) == nil {
+				id.registry = path
+				break
+			}
+		}
+	}
+	return id, err
+}
+
+// Registry returns the OUI file merged over the embedded registry, or ""
+// when the embedded registry is used alone.
+func (id *Identifier) Registry() string { return id.registry }
+
+// Identify returns the vendor of the MAC and the best hostname found for the
+// device: DHCP leases first, then reverse DNS, then a direct mDNS query.
+func (id *Identifier) Identify(ctx context.Context, mac, ip string) Identity {
+	var i Identity
+	i.Randomised = Randomised(mac)
+	if !i.Randomised {
+		i.Vendor, _ = id.ouis.Vendor(mac)
+	}
+
+	if name := LeaseHostname(id.leaseFiles, mac, ip); name != "" {
+		i.Hostname, i.Source = name, "DHCP lease"
+		return i
+	}
+
+	rctx, cancel := context.WithTimeout(ctx, id.timeout)
+	names, err := id.lookupAddr(rctx, ip)
+	cancel()
+	if err == nil && len(names) > 0 {
+		i.Hostname, i.Source = strings.TrimSuffix(names[0], "."), "reverse DNS"
+		return i
+	}
+
+	mctx, cancel := context.WithTimeout(ctx, id.timeout)
+	name, err := id.queryMDNS(mctx, ip)
+	cancel()
+	if err == nil && name != "" {
+		i.Hostname, i.Source = name, "mDNS"
+	}
+	return i
+}
+
+// LeaseHostname returns the hostname the device gave the DHCP server, from
+// the first lease file with a lease for its MAC (or, failing that, its IP).
+func LeaseHostname(files []string, mac, ip string) string {
+	mac = strings.ToLower(mac)
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var leases []lease
+		if strings.Contains(string(data), "lease ") && strings.Contains(string(data), "{") {
+			leases = parseDHCPDLeases(string(data))
+		} else {
+			leases = parseDnsmasqLeases(string(data))
+		}
+		byIP := ""
+		for _, l := range leases {
+			if l.Hostname == "" {
+				continue
+			}
+			if l.MAC == mac {
+				return l.Hostname
+			}
+			if l.IP == ip && byIP == "" {
+				byIP = l.Hostname
+			}
+		}
+		if byIP != "" {
+			return byIP
+		}
+	}
+	return ""
+}
+
+type lease struct {
+	MAC      string
+	IP       string
+	Hostname string
+}
+
+// parseDnsmasqLeases parses dnsmasq (and Pi-hole) lease files:
+// "<expiry> <mac> <ip> <hostname|*> <client-id|*>" per line.
+func parseDnsmasqLeases(data string) []lease {
+	var leases []lease
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		l := lease{MAC: strings.ToLower(fields[1]), IP: fields[2]}
+		if fields[3] != "*" {
+			l.Hostname = fields[3]
+		}
+		leases = append(leases, l)
+	}
+	return leases
+}
+
+// parseDHCPDLeases parses isc-dhcp-server's dhcpd.leases. The file is
+// append-only, so later blocks for the same address are more recent.
+func parseDHCPDLeases(data string) []lease {
+	var leases []lease
+	var cur *lease
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSuffix(strings.TrimSpace(scanner.Text()), ";")
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) >= 2 && fields[0] == "lease":
+			cur = &lease{IP: fields[1]}
+		case cur == nil:
+		case line == "}":
+			leases = append(leases, *cur)
+			cur = nil
+		case len(fields) == 3 && fields[0] == "hardware" && fields[1] == "ethernet":
+			cur.MAC = strings.ToLower(fields[2])
+		case len(fields) >= 2 && fields[0] == "client-hostname":
+			cur.Hostname = strings.Trim(strings.Join(fields[1:], " "), `"`)
+		}
+	}
+	// Most recent first, so the first match wins in LeaseHostname.
+	for i, j := 0, len(leases)-1; i < j; i, j = i+1, j-1 {
+		leases[i], leases[j] = leases[j], leases[i]
+	}
+	return leases
+}
+
+// mdnsPort is where QueryMDNS sends its query; replaced in tests.
+var mdnsPort = 5353
+
+// QueryMDNS asks the device's own mDNS responder for the name of its address
+// with a one-shot unicast query (RFC 6762 §5.1), which Avahi, Apple devices
+// and most printers and smart speakers answer directly.
+func QueryMDNS(ctx context.Context, ip string) (string, error) {
+	rev, err := reverseName(ip)
+	if err != nil {
+		return "", err
+	}
+	conn, err := net.ListenPacket("udp", ":0")
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	const id = 0x5047
+	query := binary.BigEndian.AppendUint16(nil, id)
+	query = append(query, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0) // flags, 1 question
+	query = appendName(query, rev)
+	query = append(query, 0, 12, 0x80, 1) // PTR, IN with the unicast-response bit
+
+	addr := &net.UDPAddr{IP: net.ParseIP(ip), Port: mdnsPort}
+	if _, err := conn.WriteTo(query, addr); err != nil {
+		return "", err
+	}
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return "", err
+		}
+		if name, ok := parsePTRAnswer(buf[:n]); ok {
+			return name, nil
+		}
+	}
+}
+
+// reverseName returns the in-addr.arpa or ip6.arpa name of an address.
+func reverseName(ip string) (string, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return "", fmt.Errorf("invalid IP %q", ip)
+	}
+	if v4 := addr.To4(); v4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa", v4[3], v4[2], v4[1], v4[0]), nil
+	}
+	var b strings.Builder
+	for i := len(addr) - 1; i >= 0; i-- {
+		fmt.Fprintf(&b, "%x.%x.", addr[i]&0x0f, addr[i]>>4)
+	}
+	b.WriteString("ip6.arpa")
+	return b.String(), nil
+}
+
+func appendName(b []byte, name string) []byte {
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0)
+}
+
+// parsePTRAnswer returns the target of the first PTR record in the answer
+// section of a DNS response.
+func parsePTRAnswer(msg []byte) (string, bool) {
+	if len(msg) < 12 || msg[2]&0x80 == 0 {
+		return "", false // not a response
+	}
+	qd := int(binary.BigEndian.Uint16(msg[4:]))
+	an := int(binary.BigEndian.Uint16(msg[6:]))
+	off := 12
+	for i := 0; i < qd; i++ {
+		_, next, err := readName(msg, off)
+		if err != nil || next+4 > len(msg) {
+			return "", false
+		}
+		off = next + 4
+	}
+	for i := 0; i < an; i++ {
+		_, next, err := readName(msg, off)
+		if err != nil || next+10 > len(msg) {
+			return "", false
+		}
+		typ := binary.BigEndian.Uint16(msg[next:])
+		rdlen := int(binary.BigEndian.Uint16(msg[next+8:]))
+		rdata := next + 10
+		if rdata+rdlen > len(msg) {
+			return "", false
+		}
+		if typ == 12 {
+			name, _, err := readName(msg, rdata)
+			return name, err == nil && name != ""
+		}
+		off = rdata + rdlen
+	}
+	return "", false
+}
+
+// readName decodes a possibly compressed domain name at off and returns it
+// with the offset just past it.
+func readName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	next := -1
+	for hops := 0; hops < 32; hops++ {
+		if off >= len(msg) {
+			return "", 0, errors.New("name out of bounds")
+		}
+		n := int(msg[off])
+		switch {
+		case n == 0:
+			if next < 0 {
+				next = off + 1
+			}
+			return strings.Join(labels, "."), next, nil
+		case n&0xc0 == 0xc0:
+			if off+1 >= len(msg) {
+				return "", 0, errors.New("pointer out of bounds")
+			}
+			if next < 0 {
+				next = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3fff)
+		default:
+			if off+1+n > len(msg) {
+				return "", 0, errors.New("label out of bounds")
+			}
+			labels = append(labels, string(msg[off+1:off+1+n]))
+			off += 1 + n
+		}
+	}
+	return "", 0, errors.New("too many compression pointers")
+}
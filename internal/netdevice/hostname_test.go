@@ -0,0 +1,183 @@
+package netdevice
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLeaseHostname_Dnsmasq(t *testing.T) {
+	path := writeFile(t, "dnsmasq.leases",
+		"1760000000 b8:27:eb:12:34:56 192.168.1.20 pihole 01:b8:27:eb:12:34:56\n"+
+			"1760000000 da:a1:19:00:00:01 192.168.1.21 * *\n"+
+			"1760000000 aa:bb:cc:00:00:09 192.168.1.22 printer *\n")
+
+	if got := LeaseHostname([]string{path}, "B8:27:EB:12:34:56", "192.168.1.99"); got != "pihole" {
+		t.Errorf("by MAC = %q, want pihole", got)
+	}
+	if got := LeaseHostname([]string{path}, "11:11:11:11:11:11", "192.168.1.22"); got != "printer" {
+		t.Errorf("by IP = %q, want printer", got)
+	}
+	if got := LeaseHostname([]string{path}, "da:a1:19:00:00:01", "192.168.1.21"); got != "" {
+		t.Errorf("no hostname = %q, want empty", got)
+	}
+}
+
+func TestLeaseHostname_DHCPD(t *testing.T) {
+	path := writeFile(t, "dhcpd.leases", `# The format of this file is documented in the dhcpd.leases(5) manual page.
+lease 192.168.1.30 {
+  starts 4 2026/10/15 10:00:00;
+  hardware ethernet aa:bb:cc:dd:ee:01;
+  client-hostname "old-name";
+}
+lease 192.168.1.30 {
+  starts 5 2026/10/16 10:00:00;
+  hardware ethernet aa:bb:cc:dd:ee:01;
+  uid "\001\252\273\314\335\356\001";
+  client-hostname "living-room-tv";
+}
+`)
+	if got := LeaseHostname([]string{"/nonexistent", path}, "aa:bb:cc:dd:ee:01", "192.168.1.30"); got != "living-room-tv" {
+		t.Errorf("LeaseHostname = %q, want the most recent lease's name", got)
+	}
+}
+
+func TestIdentify_Order(t *testing.T) {
+	leases := writeFile(t, "dnsmasq.leases", "1760000000 b8:27:eb:12:34:56 192.168.1.20 pihole *\n")
+	id, err := NewIdentifier("", []string{leases})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id.ouis = OUIDB{"B827EB": "Raspberry Pi Foundation", "001132": "Synology"}
+	id.lookupAddr = func(ctx context.Context, ip string) ([]string, error) {
+		if ip == "192.168.1.40" {
+			return []string{"nas.lan."}, nil
+		}
+		return nil, errors.New("no PTR")
+	}
+	id.queryMDNS = func(ctx context.Context, ip string) (string, error) {
+		if ip == "192.168.1.50" {
+			return "MacBook.local", nil
+		}
+		return "", errors.New("timeout")
+	}
+
+	tests := []struct {
+		mac, ip string
+		want    Identity
+	}{
+		{"b8:27:eb:12:34:56", "192.168.1.20", Identity{Vendor: "Raspberry Pi Foundation", Hostname: "pihole", Source: "DHCP lease"}},
+		{"00:11:32:00:00:01", "192.168.1.40", Identity{Vendor: "Synology", Hostname: "nas.lan", Source: "reverse DNS"}},
+		{"da:a1:19:00:00:01", "192.168.1.50", Identity{Randomised: true, Hostname: "MacBook.local", Source: "mDNS"}},
+		{"00:12:34:00:00:01", "192.168.1.60", Identity{}},
+	}
+	for _, tt := range tests {
+		if got := id.Identify(context.Background(), tt.mac, tt.ip); got != tt.want {
+			t.Errorf("Identify(%s, %s) = %+v, want %+v", tt.mac, tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestIdentity_Details(t *testing.T) {
+	tests := []struct {
+		id   Identity
+		want string
+	}{
+		{Identity{Vendor: "Sonos", Hostname: "kitchen", Source: "mDNS"}, "Vendor: Sonos | Hostname: kitchen (mDNS)"},
+		{Identity{}, "Vendor: unknown | Hostname: unknown"},
+	}
+	for _, tt := range tests {
+		if got := tt.id.Details(); got != tt.want {
+			t.Errorf("Details() = %q, want %q", got, tt.want)
+		}
+	}
+	if got := (Identity{Randomised: true}).Details(); !strings.Contains(got, "randomised MAC") {
+		t.Errorf("randomised Details() = %q", got)
+	}
+}
+
+func TestQueryMDNS(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	old := mdnsPort
+	mdnsPort = conn.LocalAddr().(*net.UDPAddr).Port
+	defer func() { mdnsPort = old }()
+
+	go func() {
+		buf := make([]byte, 1500)
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		q := buf[:n]
+		// Echo the question and answer it with a compressed PTR record.
+		resp := append([]byte{}, q[:2]...)
+		resp = append(resp, 0x84, 0, 0, 1, 0, 1, 0, 0, 0, 0)
+		resp = append(resp, q[12:]...)
+		resp = append(resp, 0xc0, 12, 0, 12, 0, 1, 0, 0, 0, 120)
+		rdata := appendName(nil, "printer.local")
+		resp = binary.BigEndian.AppendUint16(resp, uint16(len(rdata)))
+		resp = append(resp, rdata...)
+		conn.WriteTo(resp, from)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	name, err := QueryMDNS(ctx, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("QueryMDNS: %v", err)
+	}
+	if name != "printer.local" {
+		t.Errorf("name = %q, want printer.local", name)
+	}
+}
+
+func TestReverseName(t *testing.T) {
+	if got, _ := reverseName("192.168.1.20"); got != "20.1.168.192.in-addr.arpa" {
+		t.Errorf("IPv4 = %q", got)
+	}
+	got, _ := reverseName("2001:db8::1")
+	if !strings.HasPrefix(got, "1.0.0.0.") || !strings.HasSuffix(got, ".8.b.d.0.1.0.0.2.ip6.arpa") {
+		t.Errorf("IPv6 = %q", got)
+	}
+	if _, err := reverseName("nope"); err == nil {
+		t.Error("expected error for invalid IP")
+	}
+}
+
+func TestNewIdentifier_Registry(t *testing.T) {
+	path := writeFile(t, "oui.txt", "28-CD-C1   (hex)\t\tRaspberry Pi Trading Ltd\n")
+	id, err := NewIdentifier(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Registry() != path {
+		t.Errorf("Registry() = %q, want %q", id.Registry(), path)
+	}
+
+	id, err = NewIdentifier("/nonexistent/oui.txt", nil)
+	if err == nil {
+		t.Error("expected an error for a missing oui_file")
+	}
+	if id.Registry() != "" {
+		t.Errorf("Registry() = %q, want built-in only", id.Registry())
+	}
+}
@@ -0,0 +1,107 @@
+// Package netdevice identifies devices on the LAN from their MAC and IP
+// address: the vendor registered for the MAC's OUI, whether the MAC is
+// randomised, and a hostname from DHCP leases, reverse DNS or mDNS.
+package netdevice
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+// builtinOUI is the IEEE MA-L registry, gzipped to keep the binary small.
+// scripts/update-oui.sh (make oui) regenerates it.
+//
+//go:embed oui.txt.gz
+var builtinOUI []byte
+
+// SystemOUIFiles are copies of the registry installed by common packages
+// (ieee-data, nmap, wireshark). None is needed, but the first one present is
+// merged over the embedded registry when no file is configured, so a newer
+// system copy names recently registered prefixes.
+var SystemOUIFiles = []string{
+	"/usr/share/ieee-data/oui.txt",
+	"/usr/share/nmap/nmap-mac-prefixes",
+	"/usr/share/wireshark/manuf",
+}
+
+// OUIDB maps a 24-bit OUI ("B827EB") to the vendor that registered it.
+type OUIDB map[string]string
+
+// BuiltinOUI returns the embedded vendor registry.
+func BuiltinOUI() OUIDB {
+	db := make(OUIDB)
+	// Only a broken regeneration can make this fail, which the tests catch.
+	if zr, err := gzip.NewReader(bytes.NewReader(builtinOUI)); err == nil {
+		db.read(zr)
+	}
+	return db
+}
+
+// Merge adds the entries of an OUI file, overriding embedded names. The IEEE
+// oui.txt, nmap-mac-prefixes and Wireshark manuf formats are understood.
+func (db OUIDB) Merge(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if n := db.read(f); n == 0 {
+		return fmt.Errorf("no OUI entries in %s", path)
+	}
+	return nil
+}
+
+func (db OUIDB) read(r io.Reader) int {
+	n := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var prefix, vendor string
+		if before, after, ok := strings.Cut(line, "(hex)"); ok {
+			// IEEE: "28-CD-C1   (hex)		Raspberry Pi Trading Ltd"
+			prefix, vendor = before, after
+		} else if fields := strings.Split(line, "\t"); len(fields) >= 2 {
+			// Wireshark: "B8:27:EB	Raspberr	Raspberry Pi Foundation"
+			prefix, vendor = fields[0], fields[len(fields)-1]
+		} else {
+			// nmap and the embedded registry: "B827EB Raspberry Pi Foundation"
+			prefix, vendor, _ = strings.Cut(line, " ")
+		}
+		prefix = strings.ToUpper(strings.NewReplacer(":", "", "-", "", ".", "").Replace(strings.TrimSpace(prefix)))
+		vendor = strings.TrimSpace(vendor)
+		if len(prefix) != 6 || vendor == "" {
+			continue // MA-M/MA-S ranges ("/28") and malformed lines
+		}
+		db[prefix] = vendor
+		n++
+	}
+	return n
+}
+
+// Vendor returns the vendor registered for the MAC's OUI.
+func (db OUIDB) Vendor(mac string) (string, bool) {
+	hw, err := net.ParseMAC(mac)
+	if err != nil || len(hw) < 3 {
+		return "", false
+	}
+	v, ok := db[fmt.Sprintf("%02X%02X%02X", hw[0], hw[1], hw[2])]
+	return v, ok
+}
+
+// Randomised reports whether the MAC is locally administered, as the
+// private addresses of phones and laptops (and virtual interfaces) are.
+// Such MACs have no registered vendor.
+func Randomised(mac string) bool {
+	hw, err := net.ParseMAC(mac)
+	return err == nil && len(hw) > 0 && hw[0]&0x02 != 0
+}
@@ -0,0 +1,92 @@
+package netdevice
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuiltinOUI_Vendor(t *testing.T) {
+	db := BuiltinOUI()
+	tests := []struct {
+		mac  string
+		want string
+		ok   bool
+	}{
+		// Names are matched by prefix so regenerating the registry doesn't
+		// break the test when IEEE appends "Ltd" or "BV".
+		{"b8:27:eb:12:34:56", "Raspberry Pi", true},
+		{"DC-A6-32-00-00-01", "Raspberry Pi", true},
+		{"00:17:88:aa:bb:cc", "Philips", true},
+		{"12:34:56:78:9a:bc", "", false}, // locally administered, never registered
+		{"not-a-mac", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := db.Vendor(tt.mac)
+		if !strings.HasPrefix(got, tt.want) || ok != tt.ok {
+			t.Errorf("Vendor(%q) = %q, %v; want %q..., %v", tt.mac, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestOUIDB_Merge_Formats(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"oui.txt": "OUI/MA-L\t\t\tOrganization\n" +
+			"28-CD-C1   (hex)\t\tRaspberry Pi Trading Ltd\n" +
+			"28CDC1     (base 16)\t\tRaspberry Pi Trading Ltd\n",
+		"nmap-mac-prefixes": "# comment\nAABBCC Example Corp\n",
+		"manuf": "00:00:0C\tCisco\tCisco Systems, Inc\n" +
+			"00:1B:C5:00:00:00/36\tConverg\tConverging Systems Inc.\n",
+	}
+	want := map[string]string{
+		"oui.txt":           "Raspberry Pi Trading Ltd",
+		"nmap-mac-prefixes": "Example Corp",
+		"manuf":             "Cisco Systems, Inc",
+	}
+	macs := map[string]string{
+		"oui.txt":           "28:cd:c1:00:00:01",
+		"nmap-mac-prefixes": "aa:bb:cc:00:00:01",
+		"manuf":             "00:00:0c:00:00:01",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		db := BuiltinOUI()
+		if err := db.Merge(path); err != nil {
+			t.Fatalf("Merge(%s): %v", name, err)
+		}
+		if got, _ := db.Vendor(macs[name]); got != want[name] {
+			t.Errorf("%s: Vendor = %q, want %q", name, got, want[name])
+		}
+	}
+}
+
+func TestOUIDB_Merge_Errors(t *testing.T) {
+	db := BuiltinOUI()
+	if err := db.Merge(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected error for missing file")
+	}
+	empty := filepath.Join(t.TempDir(), "empty")
+	os.WriteFile(empty, []byte("# nothing\n"), 0644)
+	if err := db.Merge(empty); err == nil {
+		t.Error("expected error for file without entries")
+	}
+}
+
+func TestRandomised(t *testing.T) {
+	tests := map[string]bool{
+		"b8:27:eb:12:34:56": false,
+		"da:a1:19:00:00:01": true, // iOS/Android private address
+		"02:42:ac:11:00:02": true, // docker bridge
+		"bogus":             false,
+	}
+	for mac, want := range tests {
+		if got := Randomised(mac); got != want {
+			t.Errorf("Randomised(%q) = %v, want %v", mac, got, want)
+		}
+	}
+}
@@ -1,11 +1,15 @@
 package notifiers
 
 import (
+	"encoding/json"
 	"fmt"
+	"html"
+	"math"
 	"net/http"
 	"net/url"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/Fullex26/piguard/internal/config"
 	"github.com/Fullex26/piguard/pkg/models"
@@ -13,6 +17,12 @@ import (
 
 const telegramAPI = "https://api.telegram.org/bot%s/sendMessage"
 
+// Diffs in alerts are cut to these; the rest is behind "Show full diff".
+const (
+	alertDiffMaxLines = 40
+	alertDiffMaxBytes = 2500
+)
+
 // Telegram sends notifications via Telegram Bot API
 type Telegram struct {
 	token  string
@@ -30,26 +40,55 @@ func NewTelegram(cfg config.TelegramConfig) *Telegram {
 
 func (t *Telegram) Name() string { return "telegram" }
 
+// inlineButton mirrors the bot's InlineButton; callbacks are handled by
+// TelegramBotWatcher, which owns the callback prefixes.
+type inlineButton struct {
+	Text string `json:"text"`
+	Data string `json:"callback_data"`
+}
+
 func (t *Telegram) Send(event models.Event) error {
 	msg := t.formatEvent(event)
-	return t.send(msg)
+	return t.send(msg, eventButtons(event))
 }
 
 func (t *Telegram) SendRaw(message string) error {
-	return t.send(message)
+	return t.send(message, nil)
 }
 
 func (t *Telegram) Test() error {
-	return t.send("🛡️ <b>PiGuard</b> — Test notification\n\nIf you see this, PiGuard is connected!")
+	return t.send("🛡️ <b>PiGuard</b> — Test notification\n\nIf you see this, PiGuard is connected!", nil)
 }
 
-func (t *Telegram) send(text string) error {
+// eventButtons returns the inline actions offered under an alert, if any.
+func eventButtons(event models.Event) [][]inlineButton {
+	if event.Type == models.EventFirewallChanged {
+		return [][]inlineButton{{
+			{Text: "♻️ Restore last known good", Data: "f:restore"},
+			{Text: "✅ Accept current rules", Data: "f:accept"},
+		}}
+	}
+	if event.File != nil && event.ID != "" {
+		if _, more := truncateDiff(event.File.Diff); more > 0 {
+			return [][]inlineButton{{{Text: "📄 Show full diff", Data: "i:diff:" + event.ID}}}
+		}
+	}
+	return nil
+}
+
+func (t *Telegram) send(text string, buttons [][]inlineButton) error {
 	apiURL := fmt.Sprintf(telegramAPI, t.token)
 
 	data := url.Values{}
 	data.Set("chat_id", t.chatID)
 	data.Set("parse_mode", "HTML")
 	data.Set("text", text)
+	if len(buttons) > 0 {
+		markup, _ := json.Marshal(struct {
+			InlineKeyboard [][]inlineButton `json:"inline_keyboard"`
+		}{InlineKeyboard: buttons})
+		data.Set("reply_markup", string(markup))
+	}
 
 	resp, err := t.client.PostForm(apiURL, data)
 	if err != nil {
@@ -77,6 +116,15 @@ func (t *Telegram) formatEvent(event models.Event) string {
 		b.WriteString(fmt.Sprintf("%s\n", event.Details))
 	}
 
+	// Changed file content
+	if event.File != nil && event.File.Diff != "" {
+		diff, more := truncateDiff(event.File.Diff)
+		b.WriteString(fmt.Sprintf("<pre>%s</pre>\n", html.EscapeString(diff)))
+		if more > 0 {
+			b.WriteString(fmt.Sprintf("<i>… %s not shown</i>\n", plural(more, "line")))
+		}
+	}
+
 	// Suggested fix
 	if event.Suggested != "" {
 		b.WriteString(fmt.Sprintf("\n💡 <i>%s</i>", event.Suggested))
@@ -85,6 +133,23 @@ func (t *Telegram) formatEvent(event models.Event) string {
 	return b.String()
 }
 
+// truncateDiff cuts a diff to fit an alert, at a line boundary, and returns
+// how many lines were cut.
+func truncateDiff(diff string) (string, int) {
+	lines := strings.SplitAfter(strings.TrimSuffix(diff, "\n"), "\n")
+	size := 0
+	for i, l := range lines {
+		size += len(l)
+		if i == alertDiffMaxLines || size > alertDiffMaxBytes {
+			if i == 0 { // one very long line
+				return strings.ToValidUTF8(l[:alertDiffMaxBytes], ""), len(lines)
+			}
+			return strings.TrimSuffix(strings.Join(lines[:i], ""), "\n"), len(lines) - i
+		}
+	}
+	return strings.TrimSuffix(diff, "\n"), 0
+}
+
 // FormatDailySummary creates a daily summary message
 func FormatDailySummary(hostname string, health models.SystemHealth, lastAlert string) string {
 	var b strings.Builder
@@ -113,7 +178,8 @@ func FormatDailySummary(hostname string, health models.SystemHealth, lastAlert s
 }
 
 // FormatWeeklyReport creates a weekly trend report comparing this week vs last week.
-func FormatWeeklyReport(hostname string, thisWeek, lastWeek map[string]int, totalThis, totalLast int, uptimeStr string) string {
+// conn is this week's connectivity availability; nil omits it.
+func FormatWeeklyReport(hostname string, thisWeek, lastWeek map[string]int, totalThis, totalLast int, uptimeStr string, conn *models.Availability) string {
 	var b strings.Builder
 
 	b.WriteString(fmt.Sprintf("📊 <b>PiGuard — %s — Weekly Report</b>\n\n", hostname))
@@ -121,7 +187,11 @@ func FormatWeeklyReport(hostname string, thisWeek, lastWeek map[string]int, tota
 	// Total trend
 	trend := trendArrow(totalThis, totalLast)
 	b.WriteString(fmt.Sprintf("<b>Total events:</b> %d %s (last week: %d)\n", totalThis, trend, totalLast))
-	b.WriteString(fmt.Sprintf("<b>Uptime:</b> %s\n\n", uptimeStr))
+	b.WriteString(fmt.Sprintf("<b>Uptime:</b> %s\n", uptimeStr))
+	if conn != nil {
+		b.WriteString(fmt.Sprintf("<b>Connectivity:</b> %s\n", FormatAvailability(*conn)))
+	}
+	b.WriteString("\n")
 
 	// Top event types (sorted by this week's count)
 	type kv struct {
@@ -160,6 +230,69 @@ func FormatWeeklyReport(hostname string, thisWeek, lastWeek map[string]int, tota
 	return b.String()
 }
 
+// FormatMonthlyReport creates the monthly connectivity report: availability
+// over the whole month and the downtime of each week in it.
+func FormatMonthlyReport(hostname string, month models.Availability, weeks []models.Availability) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("📅 <b>PiGuard — %s — Monthly Report</b>\n", hostname))
+	b.WriteString(fmt.Sprintf("<b>%s</b>\n\n", month.From.Format("January 2006")))
+	b.WriteString(fmt.Sprintf("<b>Connectivity:</b> %s\n", FormatAvailability(month)))
+
+	if len(weeks) > 0 {
+		b.WriteString("\n<b>Per week:</b>\n")
+		for _, w := range weeks {
+			last := w.To.Add(-time.Nanosecond)
+			down := "no downtime"
+			if w.Downtime > 0 {
+				down = fmt.Sprintf("%s down (%s)", FormatDowntime(w.Downtime), plural(w.Outages, "outage"))
+			}
+			b.WriteString(fmt.Sprintf("  • %d–%d %s: %s — %s\n",
+				w.From.Day(), last.Day(), w.From.Format("Jan"), formatPercent(w.Percent()), down))
+		}
+	}
+
+	return b.String()
+}
+
+// FormatAvailability renders availability as "99.95% available, 12m down in 3 outages".
+func FormatAvailability(a models.Availability) string {
+	if a.Downtime <= 0 {
+		return formatPercent(a.Percent()) + " available, no outages"
+	}
+	return fmt.Sprintf("%s available, %s down in %s",
+		formatPercent(a.Percent()), FormatDowntime(a.Downtime), plural(a.Outages, "outage"))
+}
+
+// FormatDowntime renders a duration to the second below a minute and to the
+// minute above, without trailing zero units ("45s", "12m", "1h5m", "3h").
+func FormatDowntime(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(time.Second).String()
+	}
+	s := d.Round(time.Minute).String()
+	s = strings.TrimSuffix(s, "0s")
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// formatPercent truncates rather than rounds, so 99.999% never shows as 100%.
+func formatPercent(p float64) string {
+	if p >= 100 {
+		return "100%"
+	}
+	return fmt.Sprintf("%.2f%%", math.Floor(p*100)/100)
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
 func trendArrow(current, previous int) string {
 	if previous == 0 && current == 0 {
 		return "→"
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/Fullex26/piguard/pkg/models"
 )
@@ -112,6 +113,37 @@ func TestTelegram_Send_FormData(t *testing.T) {
 	}
 }
 
+func TestTelegram_Send_FirewallEventHasRestoreButton(t *testing.T) {
+	var capturedBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		capturedBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	tg := &Telegram{
+		token:  "test-token",
+		chatID: "12345",
+		client: &http.Client{Transport: redirectTransport(srv.URL)},
+	}
+
+	tg.Send(models.Event{
+		Type:     models.EventFirewallChanged,
+		Hostname: "pi",
+		Severity: models.SeverityWarning,
+		Message:  "Firewall rules changed in INPUT chain",
+	})
+	if !strings.Contains(capturedBody, "reply_markup") || !strings.Contains(capturedBody, "f%3Arestore") || !strings.Contains(capturedBody, "f%3Aaccept") {
+		t.Errorf("firewall alert should carry restore and accept buttons: %q", capturedBody)
+	}
+
+	tg.Send(models.Event{Type: models.EventPortOpened, Hostname: "pi", Message: "port"})
+	if strings.Contains(capturedBody, "reply_markup") {
+		t.Errorf("non-firewall alert should not carry buttons: %q", capturedBody)
+	}
+}
+
 func TestTelegram_SendRaw(t *testing.T) {
 	var capturedBody string
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -176,6 +208,45 @@ func TestTelegram_formatEvent(t *testing.T) {
 	}
 }
 
+func TestTelegram_formatEvent_Diff(t *testing.T) {
+	tg := &Telegram{}
+	event := models.Event{
+		Hostname: "pi",
+		Severity: models.SeverityWarning,
+		Message:  "File modified: /etc/hosts",
+		File:     &models.FileChange{Path: "/etc/hosts", Diff: "--- /etc/hosts (last known)\n+++ /etc/hosts\n@@ -1 +1 @@\n-127.0.0.1 <old>\n+127.0.0.1 evil & co\n"},
+	}
+	result := tg.formatEvent(event)
+	if !strings.Contains(result, "<pre>--- /etc/hosts (last known)\n") || !strings.Contains(result, "+127.0.0.1 evil &amp; co</pre>") {
+		t.Errorf("diff not escaped in <pre>: %q", result)
+	}
+	if strings.Contains(result, "not shown") || eventButtons(event) != nil {
+		t.Errorf("short diff should be shown whole: %q", result)
+	}
+
+	var long strings.Builder
+	for i := 0; i < 100; i++ {
+		long.WriteString("+line\n")
+	}
+	event.ID = "fim-1-modified"
+	event.File.Diff = long.String()
+	result = tg.formatEvent(event)
+	if strings.Count(result, "+line") != alertDiffMaxLines || !strings.Contains(result, "<i>… 60 lines not shown</i>") {
+		t.Errorf("long diff not truncated: %q", result)
+	}
+	if b := eventButtons(event); len(b) != 1 || b[0][0].Data != "i:diff:fim-1-modified" {
+		t.Errorf("buttons = %+v, want show full diff", b)
+	}
+}
+
+func TestTruncateDiff_Bytes(t *testing.T) {
+	diff := "+" + strings.Repeat("é", alertDiffMaxBytes) + "\n+short\n"
+	got, more := truncateDiff(diff)
+	if len(got) > alertDiffMaxBytes || !utf8.ValidString(got) || more != 2 {
+		t.Errorf("truncateDiff = %d bytes (valid %v), %d more", len(got), utf8.ValidString(got), more)
+	}
+}
+
 func TestFormatDailySummary(t *testing.T) {
 	health := models.SystemHealth{
 		DiskUsagePercent:  50,
@@ -234,6 +305,71 @@ func TestFormatDailySummary_EmptyLastAlert(t *testing.T) {
 	}
 }
 
+func TestFormatWeeklyReport_Connectivity(t *testing.T) {
+	result := FormatWeeklyReport("pi", nil, nil, 0, 0, "3d 4h", nil)
+	if strings.Contains(result, "Connectivity:") {
+		t.Error("should omit connectivity when nil")
+	}
+
+	week := time.Date(2026, 9, 7, 0, 0, 0, 0, time.UTC)
+	conn := models.Availability{From: week, To: week.AddDate(0, 0, 7), Downtime: 12 * time.Minute, Outages: 3}
+	result = FormatWeeklyReport("pi", nil, nil, 0, 0, "3d 4h", &conn)
+	if !strings.Contains(result, "<b>Connectivity:</b> 99.88% available, 12m down in 3 outages") {
+		t.Errorf("connectivity line missing from %q", result)
+	}
+}
+
+func TestFormatMonthlyReport(t *testing.T) {
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	month := models.Availability{From: from, To: to, Downtime: 90 * time.Minute, Outages: 1}
+	weeks := []models.Availability{
+		{From: from, To: from.AddDate(0, 0, 7)},
+		{From: from.AddDate(0, 0, 7), To: from.AddDate(0, 0, 14), Downtime: 90 * time.Minute, Outages: 1},
+		{From: from.AddDate(0, 0, 28), To: to},
+	}
+
+	result := FormatMonthlyReport("pi", month, weeks)
+	for _, want := range []string{
+		"September 2026",
+		"99.79% available, 1h30m down in 1 outage",
+		"1–7 Sep: 100% — no downtime",
+		"8–14 Sep: 99.10% — 1h30m down (1 outage)",
+		"29–30 Sep: 100%",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("FormatMonthlyReport() missing %q in %q", want, result)
+		}
+	}
+}
+
+func TestFormatDowntime(t *testing.T) {
+	tests := []struct {
+		in   time.Duration
+		want string
+	}{
+		{0, "0s"},
+		{45 * time.Second, "45s"},
+		{12*time.Minute + 20*time.Second, "12m"},
+		{65 * time.Minute, "1h5m"},
+		{3 * time.Hour, "3h"},
+	}
+	for _, tt := range tests {
+		if got := FormatDowntime(tt.in); got != tt.want {
+			t.Errorf("FormatDowntime(%s) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormatPercent_NeverRoundsUpTo100(t *testing.T) {
+	if got := formatPercent(99.9999); got != "99.99%" {
+		t.Errorf("formatPercent(99.9999) = %q", got)
+	}
+	if got := formatPercent(100); got != "100%" {
+		t.Errorf("formatPercent(100) = %q", got)
+	}
+}
+
 // -- helpers --
 
 type roundTripFunc func(req *http.Request) *http.Response
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Fullex26/piguard/pkg/models"
@@ -71,6 +72,73 @@ func (s *Store) migrate() error {
 			key TEXT PRIMARY KEY,
 			value TEXT NOT NULL
 		);
+
+		CREATE TABLE IF NOT EXISTS firewall_snapshots (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			label TEXT NOT NULL,
+			kind TEXT NOT NULL DEFAULT 'manual',
+			backend TEXT NOT NULL,
+			rules TEXT NOT NULL,
+			created_at DATETIME NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS devices (
+			mac TEXT PRIMARY KEY,
+			ip TEXT NOT NULL,
+			name TEXT NOT NULL DEFAULT '',
+			trusted BOOLEAN NOT NULL DEFAULT FALSE,
+			first_seen DATETIME NOT NULL,
+			last_seen DATETIME NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS device_ips (
+			mac TEXT NOT NULL,
+			ip TEXT NOT NULL,
+			first_seen DATETIME NOT NULL,
+			last_seen DATETIME NOT NULL,
+			PRIMARY KEY (mac, ip)
+		);
+
+		CREATE TABLE IF NOT EXISTS outages (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			start_time DATETIME NOT NULL,
+			end_time DATETIME,
+			duration_seconds INTEGER,
+			last_seen DATETIME
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_outages_start ON outages(start_time);
+
+		CREATE TABLE IF NOT EXISTS address_changes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			changed_at DATETIME NOT NULL,
+			scope TEXT NOT NULL,
+			old TEXT NOT NULL,
+			new TEXT NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS file_records (
+			path TEXT PRIMARY KEY,
+			hash TEXT NOT NULL,
+			mode INTEGER NOT NULL,
+			uid INTEGER NOT NULL,
+			gid INTEGER NOT NULL,
+			size INTEGER NOT NULL,
+			mod_time DATETIME NOT NULL,
+			checked_at DATETIME NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS file_contents (
+			path TEXT PRIMARY KEY,
+			content TEXT NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS outbound_baseline (
+			owner TEXT NOT NULL,
+			destination TEXT NOT NULL,
+			last_seen DATETIME NOT NULL,
+			PRIMARY KEY (owner, destination)
+		);
 	`)
 	return err
 }
@@ -87,6 +155,17 @@ func (s *Store) SaveEvent(event models.Event) error {
 	return err
 }
 
+// GetEvent returns the event with the given ID, or sql.ErrNoRows.
+func (s *Store) GetEvent(id string) (models.Event, error) {
+	var payload string
+	var event models.Event
+	if err := s.db.QueryRow(`SELECT payload FROM events WHERE id = ?`, id).Scan(&payload); err != nil {
+		return event, err
+	}
+	err := json.Unmarshal([]byte(payload), &event)
+	return event, err
+}
+
 // GetRecentEvents returns events from the last N hours
 func (s *Store) GetRecentEvents(hours int) ([]models.Event, error) {
 	since := time.Now().Add(-time.Duration(hours) * time.Hour)
@@ -195,3 +274,566 @@ func (s *Store) Prune(days int) (int64, error) {
 	}
 	return result.RowsAffected()
 }
+
+// Firewall snapshot kinds. Every kind but SnapshotPreRestore is a known good
+// ruleset: a pre-restore snapshot holds the rules a restore replaced, which
+// are usually the bad ones.
+const (
+	SnapshotManual     = "manual"      // piguard firewall snapshot
+	SnapshotBaseline   = "baseline"    // taken when the firewall watcher starts
+	SnapshotAccepted   = "accepted"    // a change accepted from an alert
+	SnapshotPreRestore = "pre-restore" // the rules a restore replaced
+)
+
+// FirewallSnapshot is a saved copy of the full host ruleset.
+type FirewallSnapshot struct {
+	ID        int64
+	Label     string
+	Kind      string // one of the Snapshot* kinds
+	Backend   string // "iptables" or "nft"
+	Rules     string
+	CreatedAt time.Time
+}
+
+const firewallSnapshotColumns = `id, label, kind, backend, rules, created_at`
+
+func scanFirewallSnapshot(row interface{ Scan(...any) error }) (FirewallSnapshot, error) {
+	var snap FirewallSnapshot
+	err := row.Scan(&snap.ID, &snap.Label, &snap.Kind, &snap.Backend, &snap.Rules, &snap.CreatedAt)
+	return snap, err
+}
+
+// SaveFirewallSnapshot stores a ruleset dump of the given kind and returns
+// its ID.
+func (s *Store) SaveFirewallSnapshot(kind, label, backend, rules string) (int64, error) {
+	result, err := s.db.Exec(`
+		INSERT INTO firewall_snapshots (label, kind, backend, rules, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		label, kind, backend, rules, time.Now(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// ListFirewallSnapshots returns snapshots newest first.
+func (s *Store) ListFirewallSnapshots() ([]FirewallSnapshot, error) {
+	rows, err := s.db.Query(`
+		SELECT ` + firewallSnapshotColumns + ` FROM firewall_snapshots
+		ORDER BY id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snaps []FirewallSnapshot
+	for rows.Next() {
+		snap, err := scanFirewallSnapshot(rows)
+		if err != nil {
+			return nil, fmt.Errorf("reading firewall snapshot: %w", err)
+		}
+		snaps = append(snaps, snap)
+	}
+	return snaps, rows.Err()
+}
+
+// GetFirewallSnapshot returns a single snapshot by ID.
+func (s *Store) GetFirewallSnapshot(id int64) (FirewallSnapshot, error) {
+	return scanFirewallSnapshot(s.db.QueryRow(`
+		SELECT `+firewallSnapshotColumns+` FROM firewall_snapshots
+		WHERE id = ?`, id))
+}
+
+// GetLastGoodFirewallSnapshot returns the most recent known good snapshot,
+// skipping pre-restore ones — the ruleset offered for one-tap restore.
+func (s *Store) GetLastGoodFirewallSnapshot() (FirewallSnapshot, error) {
+	return scanFirewallSnapshot(s.db.QueryRow(`
+		SELECT `+firewallSnapshotColumns+` FROM firewall_snapshots
+		WHERE kind != ?
+		ORDER BY id DESC
+		LIMIT 1`, SnapshotPreRestore))
+}
+
+// ErrNoDevice is returned when a MAC address is not in the device inventory.
+var ErrNoDevice = errors.New("no such device")
+
+// Device is a LAN device seen by the network scan, keyed by MAC address.
+type Device struct {
+	MAC       string
+	IP        string // most recent address
+	Name      string // user-assigned label; "" = unnamed
+	Trusted   bool
+	FirstSeen time.Time
+	LastSeen  time.Time
+	IPs       []DeviceIP // address history, most recent first; GetDevice only
+}
+
+// DeviceIP is an address a device has used and when.
+type DeviceIP struct {
+	IP        string
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// Label names the device for alerts: its name with the MAC, or the MAC alone.
+func (d Device) Label() string {
+	if d.Name != "" {
+		return fmt.Sprintf("%s (%s)", d.Name, d.MAC)
+	}
+	return d.MAC
+}
+
+// RecordDevice notes that a device was seen at ip, adding it to the inventory
+// (untrusted, unnamed) if it is new. It reports whether the device was new.
+func (s *Store) RecordDevice(mac, ip string, seen time.Time) (bool, error) {
+	result, err := s.db.Exec(`
+		INSERT INTO devices (mac, ip, first_seen, last_seen) VALUES (?, ?, ?, ?)
+		ON CONFLICT(mac) DO NOTHING`, mac, ip, seen, seen)
+	if err != nil {
+		return false, err
+	}
+	added, _ := result.RowsAffected()
+	if added == 0 {
+		if _, err := s.db.Exec(`UPDATE devices SET ip = ?, last_seen = ? WHERE mac = ?`, ip, seen, mac); err != nil {
+			return false, err
+		}
+	}
+	_, err = s.db.Exec(`
+		INSERT INTO device_ips (mac, ip, first_seen, last_seen) VALUES (?, ?, ?, ?)
+		ON CONFLICT(mac, ip) DO UPDATE SET last_seen = excluded.last_seen`, mac, ip, seen, seen)
+	return added > 0, err
+}
+
+// ListDevices returns the inventory, most recently seen first.
+func (s *Store) ListDevices() ([]Device, error) {
+	rows, err := s.db.Query(`
+		SELECT mac, ip, name, trusted, first_seen, last_seen FROM devices
+		ORDER BY last_seen DESC, mac`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var devices []Device
+	for rows.Next() {
+		var d Device
+		if err := rows.Scan(&d.MAC, &d.IP, &d.Name, &d.Trusted, &d.FirstSeen, &d.LastSeen); err != nil {
+			return nil, fmt.Errorf("reading device: %w", err)
+		}
+		devices = append(devices, d)
+	}
+	return devices, rows.Err()
+}
+
+// GetDevice returns one device with its address history, or ErrNoDevice.
+func (s *Store) GetDevice(mac string) (Device, error) {
+	var d Device
+	err := s.db.QueryRow(`
+		SELECT mac, ip, name, trusted, first_seen, last_seen FROM devices
+		WHERE mac = ?`, mac).Scan(&d.MAC, &d.IP, &d.Name, &d.Trusted, &d.FirstSeen, &d.LastSeen)
+	if errors.Is(err, sql.ErrNoRows) {
+		return d, ErrNoDevice
+	}
+	if err != nil {
+		return d, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT ip, first_seen, last_seen FROM device_ips
+		WHERE mac = ?
+		ORDER BY last_seen DESC`, mac)
+	if err != nil {
+		return d, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var ip DeviceIP
+		if err := rows.Scan(&ip.IP, &ip.FirstSeen, &ip.LastSeen); err != nil {
+			return d, fmt.Errorf("reading device address: %w", err)
+		}
+		d.IPs = append(d.IPs, ip)
+	}
+	return d, rows.Err()
+}
+
+// PruneDevices forgets devices not seen since before, unless they are named
+// or trusted, and addresses no device has used since then other than its
+// current one. It returns how many devices were removed. Randomised MACs and
+// ARP sweeps otherwise grow the inventory without bound.
+func (s *Store) PruneDevices(before time.Time) (int64, error) {
+	result, err := s.db.Exec(`
+		DELETE FROM devices
+		WHERE last_seen < ? AND name = '' AND NOT trusted`, before)
+	if err != nil {
+		return 0, err
+	}
+	_, err = s.db.Exec(`
+		DELETE FROM device_ips
+		WHERE mac NOT IN (SELECT mac FROM devices)
+		   OR (last_seen < ? AND ip != (SELECT ip FROM devices WHERE devices.mac = device_ips.mac))`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// SetDeviceName labels a device; an empty name clears the label.
+func (s *Store) SetDeviceName(mac, name string) error {
+	return s.updateDevice(`UPDATE devices SET name = ? WHERE mac = ?`, name, mac)
+}
+
+// SetDeviceTrusted marks a device as trusted or untrusted.
+func (s *Store) SetDeviceTrusted(mac string, trusted bool) error {
+	return s.updateDevice(`UPDATE devices SET trusted = ? WHERE mac = ?`, trusted, mac)
+}
+
+func (s *Store) updateDevice(query string, args ...any) error {
+	result, err := s.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNoDevice
+	}
+	return nil
+}
+
+// Outage is a period in which every connectivity probe failed.
+type Outage struct {
+	ID       int64
+	Start    time.Time
+	End      time.Time // zero while the outage is ongoing
+	LastSeen time.Time // last probe that found it ongoing
+}
+
+// Until returns when the outage ended or, while it is ongoing, when a probe
+// last saw it: time the daemon or the Pi was down is not known to be an
+// outage.
+func (o Outage) Until(now time.Time) time.Time {
+	switch {
+	case !o.End.IsZero():
+		return o.End
+	case o.LastSeen.IsZero():
+		return o.Start
+	case o.LastSeen.After(now):
+		return now
+	}
+	return o.LastSeen
+}
+
+// Duration returns how long the outage lasted, or has lasted by its last
+// probe.
+func (o Outage) Duration(now time.Time) time.Duration {
+	return o.Until(now).Sub(o.Start)
+}
+
+// StartOutage records the start of an outage and returns its ID.
+func (s *Store) StartOutage(start time.Time) (int64, error) {
+	result, err := s.db.Exec(`INSERT INTO outages (start_time, last_seen) VALUES (?, ?)`, start, start)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// EndOutage records when an outage ended and its duration.
+func (s *Store) EndOutage(id int64, end time.Time) error {
+	var start time.Time
+	if err := s.db.QueryRow(`SELECT start_time FROM outages WHERE id = ?`, id).Scan(&start); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`UPDATE outages SET end_time = ?, duration_seconds = ? WHERE id = ?`,
+		end, int64(end.Sub(start).Seconds()), id)
+	return err
+}
+
+// TouchOutage records that a probe found an ongoing outage still ongoing at
+// the given time.
+func (s *Store) TouchOutage(id int64, at time.Time) error {
+	_, err := s.db.Exec(`UPDATE outages SET last_seen = ? WHERE id = ? AND end_time IS NULL`, at, id)
+	return err
+}
+
+// OpenOutage returns the ongoing outage, if any, so one that was still open
+// when the daemon stopped is resumed or closed. sql.ErrNoRows means there is
+// none.
+func (s *Store) OpenOutage() (Outage, error) {
+	rows, err := s.db.Query(`
+		SELECT id, start_time, end_time, last_seen FROM outages
+		WHERE end_time IS NULL
+		ORDER BY id DESC
+		LIMIT 1`)
+	if err != nil {
+		return Outage{}, err
+	}
+	outages, err := scanOutages(rows)
+	if err != nil {
+		return Outage{}, err
+	}
+	if len(outages) == 0 {
+		return Outage{}, sql.ErrNoRows
+	}
+	return outages[0], nil
+}
+
+// ListOutages returns up to limit outages, most recent first.
+func (s *Store) ListOutages(limit int) ([]Outage, error) {
+	rows, err := s.db.Query(`
+		SELECT id, start_time, end_time, last_seen FROM outages
+		ORDER BY start_time DESC
+		LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanOutages(rows)
+}
+
+// Availability sums the downtime of the outages overlapping [from, to),
+// clipped to the period. An ongoing outage counts until its last probe.
+func (s *Store) Availability(from, to, now time.Time) (models.Availability, error) {
+	a := models.Availability{From: from, To: to}
+	if now.Before(to) {
+		a.To = now
+	}
+	rows, err := s.db.Query(`
+		SELECT id, start_time, end_time, last_seen FROM outages
+		WHERE start_time < ? AND (end_time IS NULL OR end_time > ?)`, a.To, from)
+	if err != nil {
+		return a, err
+	}
+	outages, err := scanOutages(rows)
+	if err != nil {
+		return a, err
+	}
+	for _, o := range outages {
+		start, end := o.Start, o.Until(now)
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(a.To) {
+			end = a.To
+		}
+		if end.After(start) {
+			a.Downtime += end.Sub(start)
+			a.Outages++
+		}
+	}
+	return a, nil
+}
+
+func scanOutages(rows *sql.Rows) ([]Outage, error) {
+	defer rows.Close()
+	var outages []Outage
+	for rows.Next() {
+		var o Outage
+		var end, lastSeen sql.NullTime
+		if err := rows.Scan(&o.ID, &o.Start, &end, &lastSeen); err != nil {
+			return nil, err
+		}
+		if end.Valid {
+			o.End = end.Time
+		}
+		if lastSeen.Valid {
+			o.LastSeen = lastSeen.Time
+		}
+		outages = append(outages, o)
+	}
+	return outages, rows.Err()
+}
+
+// AddressChange is a change of the public IP or an interface's addresses.
+type AddressChange struct {
+	ChangedAt time.Time
+	Scope     string // "public" or an interface name
+	Old       string // comma-separated; empty when none
+	New       string
+}
+
+// AddAddressChange records an address change.
+func (s *Store) AddAddressChange(c AddressChange) error {
+	_, err := s.db.Exec(`INSERT INTO address_changes (changed_at, scope, old, new) VALUES (?, ?, ?, ?)`,
+		c.ChangedAt, c.Scope, c.Old, c.New)
+	return err
+}
+
+// ListAddressChanges returns up to limit address changes, most recent first.
+func (s *Store) ListAddressChanges(limit int) ([]AddressChange, error) {
+	rows, err := s.db.Query(`
+		SELECT changed_at, scope, old, new FROM address_changes
+		ORDER BY changed_at DESC, id DESC
+		LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []AddressChange
+	for rows.Next() {
+		var c AddressChange
+		if err := rows.Scan(&c.ChangedAt, &c.Scope, &c.Old, &c.New); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// ErrNoFileRecord is returned when a path is not in the file integrity database.
+var ErrNoFileRecord = errors.New("no such file record")
+
+// FileRecord is the last known state of a file watched for integrity.
+type FileRecord struct {
+	Path      string
+	Hash      string // SHA256, hex
+	Mode      uint32 // os.FileMode bits
+	UID       int
+	GID       int
+	Size      int64
+	ModTime   time.Time
+	CheckedAt time.Time // when the file was last hashed
+	Content   string    // last known text, for diffs; "" = not kept
+}
+
+// PutFileRecord adds or replaces the record for r.Path. The content is kept
+// in its own table so listing records stays cheap.
+func (s *Store) PutFileRecord(r FileRecord) error {
+	if r.Content == "" {
+		if _, err := s.db.Exec(`DELETE FROM file_contents WHERE path = ?`, r.Path); err != nil {
+			return err
+		}
+	} else {
+		if _, err := s.db.Exec(`
+			INSERT INTO file_contents (path, content) VALUES (?, ?)
+			ON CONFLICT(path) DO UPDATE SET content = excluded.content`, r.Path, r.Content); err != nil {
+			return err
+		}
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO file_records (path, hash, mode, uid, gid, size, mod_time, checked_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(path) DO UPDATE SET
+			hash = excluded.hash, mode = excluded.mode, uid = excluded.uid, gid = excluded.gid,
+			size = excluded.size, mod_time = excluded.mod_time, checked_at = excluded.checked_at`,
+		r.Path, r.Hash, r.Mode, r.UID, r.GID, r.Size, r.ModTime, r.CheckedAt)
+	return err
+}
+
+// GetFileRecord returns the record for path with its content, or
+// ErrNoFileRecord.
+func (s *Store) GetFileRecord(path string) (FileRecord, error) {
+	var r FileRecord
+	err := s.db.QueryRow(`
+		SELECT r.path, r.hash, r.mode, r.uid, r.gid, r.size, r.mod_time, r.checked_at, COALESCE(c.content, '')
+		FROM file_records r LEFT JOIN file_contents c ON c.path = r.path
+		WHERE r.path = ?`, path).Scan(&r.Path, &r.Hash, &r.Mode, &r.UID, &r.GID, &r.Size, &r.ModTime, &r.CheckedAt, &r.Content)
+	if errors.Is(err, sql.ErrNoRows) {
+		return r, ErrNoFileRecord
+	}
+	return r, err
+}
+
+// ListFileRecords returns every record without its content, ordered by path.
+func (s *Store) ListFileRecords() ([]FileRecord, error) {
+	rows, err := s.db.Query(`
+		SELECT path, hash, mode, uid, gid, size, mod_time, checked_at FROM file_records
+		ORDER BY path`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []FileRecord
+	for rows.Next() {
+		var r FileRecord
+		if err := rows.Scan(&r.Path, &r.Hash, &r.Mode, &r.UID, &r.GID, &r.Size, &r.ModTime, &r.CheckedAt); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// DeleteFileRecords removes the record for path and, when path is a
+// directory, the records of everything below it.
+func (s *Store) DeleteFileRecords(path string) error {
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	for _, table := range []string{"file_records", "file_contents"} {
+		if _, err := s.db.Exec(`DELETE FROM `+table+` WHERE path = ? OR substr(path, 1, ?) = ?`,
+			path, len(prefix), prefix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OutboundDest is a destination an owner (a process name or
+// "container:<name>") is known to connect to, as learned by the outbound
+// watcher.
+type OutboundDest struct {
+	Owner       string
+	Destination string
+	LastSeen    time.Time
+}
+
+// SaveOutboundDests adds the destinations, or refreshes their last-seen time.
+func (s *Store) SaveOutboundDests(dests []OutboundDest) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, d := range dests {
+		if _, err := tx.Exec(`
+			INSERT INTO outbound_baseline (owner, destination, last_seen) VALUES (?, ?, ?)
+			ON CONFLICT(owner, destination) DO UPDATE SET last_seen = excluded.last_seen`,
+			d.Owner, d.Destination, d.LastSeen); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ListOutboundDests returns the learned destinations seen since after.
+func (s *Store) ListOutboundDests(after time.Time) ([]OutboundDest, error) {
+	rows, err := s.db.Query(`
+		SELECT owner, destination, last_seen FROM outbound_baseline
+		WHERE last_seen >= ?
+		ORDER BY owner, destination`, after)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var dests []OutboundDest
+	for rows.Next() {
+		var d OutboundDest
+		if err := rows.Scan(&d.Owner, &d.Destination, &d.LastSeen); err != nil {
+			return nil, err
+		}
+		dests = append(dests, d)
+	}
+	return dests, rows.Err()
+}
+
+// PruneOutboundDests forgets destinations not seen since before and, past
+// keep entries, the least recently seen ones. keep 0 means no cap.
+func (s *Store) PruneOutboundDests(before time.Time, keep int) (int64, error) {
+	result, err := s.db.Exec(`DELETE FROM outbound_baseline WHERE last_seen < ?`, before)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := result.RowsAffected()
+	if keep <= 0 {
+		return n, nil
+	}
+	result, err = s.db.Exec(`
+		DELETE FROM outbound_baseline WHERE rowid NOT IN (
+			SELECT rowid FROM outbound_baseline ORDER BY last_seen DESC LIMIT ?
+		)`, keep)
+	if err != nil {
+		return n, err
+	}
+	capped, _ := result.RowsAffected()
+	return n + capped, nil
+}
@@ -2,8 +2,10 @@ package store
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -196,6 +198,25 @@ func TestSaveEvent_PayloadRoundTrip(t *testing.T) {
 	}
 }
 
+func TestGetEvent(t *testing.T) {
+	s := openTestStore(t)
+	s.SaveEvent(models.Event{
+		ID:        "fim-1-modified",
+		Type:      models.EventFileChanged,
+		Timestamp: time.Now(),
+		Message:   "File modified: /etc/hosts",
+		File:      &models.FileChange{Path: "/etc/hosts", Diff: "-old\n+new\n"},
+	})
+
+	got, err := s.GetEvent("fim-1-modified")
+	if err != nil || got.File == nil || got.File.Diff != "-old\n+new\n" {
+		t.Errorf("GetEvent = %+v, %v", got.File, err)
+	}
+	if _, err := s.GetEvent("missing"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetEvent(missing) error = %v, want sql.ErrNoRows", err)
+	}
+}
+
 func TestGetLastAlertTime_NoEvents(t *testing.T) {
 	s := openTestStore(t)
 	result, err := s.GetLastAlertTime()
@@ -390,3 +411,342 @@ func TestPrune_NothingToDelete(t *testing.T) {
 		t.Errorf("affected = %d, want 0", affected)
 	}
 }
+
+func TestFirewallSnapshots_SaveListGet(t *testing.T) {
+	s := openTestStore(t)
+
+	id1, err := s.SaveFirewallSnapshot(SnapshotManual, "before upgrade", "iptables", "*filter\nCOMMIT\n")
+	if err != nil {
+		t.Fatalf("SaveFirewallSnapshot: %v", err)
+	}
+	id2, err := s.SaveFirewallSnapshot(SnapshotBaseline, "after ufw", "nft", "table inet filter {}\n")
+	if err != nil {
+		t.Fatalf("SaveFirewallSnapshot: %v", err)
+	}
+
+	snaps, err := s.ListFirewallSnapshots()
+	if err != nil {
+		t.Fatalf("ListFirewallSnapshots: %v", err)
+	}
+	if len(snaps) != 2 {
+		t.Fatalf("got %d snapshots, want 2", len(snaps))
+	}
+	if snaps[0].ID != id2 || snaps[1].ID != id1 {
+		t.Errorf("expected newest first, got ids %d, %d", snaps[0].ID, snaps[1].ID)
+	}
+
+	got, err := s.GetFirewallSnapshot(id1)
+	if err != nil {
+		t.Fatalf("GetFirewallSnapshot: %v", err)
+	}
+	if got.Label != "before upgrade" || got.Kind != SnapshotManual || got.Backend != "iptables" || got.Rules != "*filter\nCOMMIT\n" {
+		t.Errorf("unexpected snapshot: %+v", got)
+	}
+
+	latest, err := s.GetLastGoodFirewallSnapshot()
+	if err != nil {
+		t.Fatalf("GetLastGoodFirewallSnapshot: %v", err)
+	}
+	if latest.ID != id2 {
+		t.Errorf("latest ID = %d, want %d", latest.ID, id2)
+	}
+
+	// The rules a restore replaced are never offered as known good.
+	if _, err := s.SaveFirewallSnapshot(SnapshotPreRestore, "pre-restore of #2", "nft", "table inet bad {}\n"); err != nil {
+		t.Fatalf("SaveFirewallSnapshot: %v", err)
+	}
+	if latest, _ := s.GetLastGoodFirewallSnapshot(); latest.ID != id2 {
+		t.Errorf("last good ID after a restore = %d, want %d", latest.ID, id2)
+	}
+}
+
+func TestFirewallSnapshots_NotFound(t *testing.T) {
+	s := openTestStore(t)
+
+	if _, err := s.GetLastGoodFirewallSnapshot(); err != sql.ErrNoRows {
+		t.Errorf("GetLastGoodFirewallSnapshot error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := s.GetFirewallSnapshot(42); err != sql.ErrNoRows {
+		t.Errorf("GetFirewallSnapshot error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestDevices_RecordListGet(t *testing.T) {
+	s := openTestStore(t)
+	t0 := time.Now().Add(-2 * time.Hour)
+
+	isNew, err := s.RecordDevice("aa:bb:cc:dd:ee:01", "192.168.1.20", t0)
+	if err != nil || !isNew {
+		t.Fatalf("first RecordDevice = %v, %v; want new", isNew, err)
+	}
+	isNew, err = s.RecordDevice("aa:bb:cc:dd:ee:01", "192.168.1.21", t0.Add(time.Hour))
+	if err != nil || isNew {
+		t.Fatalf("second RecordDevice = %v, %v; want known", isNew, err)
+	}
+	s.RecordDevice("aa:bb:cc:dd:ee:02", "192.168.1.30", t0.Add(30*time.Minute))
+
+	devices, err := s.ListDevices()
+	if err != nil {
+		t.Fatalf("ListDevices: %v", err)
+	}
+	if len(devices) != 2 || devices[0].MAC != "aa:bb:cc:dd:ee:01" {
+		t.Fatalf("expected most recently seen first, got %+v", devices)
+	}
+	if devices[0].IP != "192.168.1.21" || devices[0].Trusted || devices[0].Name != "" {
+		t.Errorf("unexpected device: %+v", devices[0])
+	}
+
+	d, err := s.GetDevice("aa:bb:cc:dd:ee:01")
+	if err != nil {
+		t.Fatalf("GetDevice: %v", err)
+	}
+	if !d.FirstSeen.Equal(t0) {
+		t.Errorf("FirstSeen = %v, want %v", d.FirstSeen, t0)
+	}
+	if len(d.IPs) != 2 || d.IPs[0].IP != "192.168.1.21" || d.IPs[1].IP != "192.168.1.20" {
+		t.Errorf("IP history = %+v, want .21 then .20", d.IPs)
+	}
+}
+
+func TestDevices_NameAndTrust(t *testing.T) {
+	s := openTestStore(t)
+	s.RecordDevice("aa:bb:cc:dd:ee:01", "192.168.1.20", time.Now())
+
+	if err := s.SetDeviceName("aa:bb:cc:dd:ee:01", "Living room TV"); err != nil {
+		t.Fatalf("SetDeviceName: %v", err)
+	}
+	if err := s.SetDeviceTrusted("aa:bb:cc:dd:ee:01", true); err != nil {
+		t.Fatalf("SetDeviceTrusted: %v", err)
+	}
+	// Seeing the device again keeps its name and trust.
+	s.RecordDevice("aa:bb:cc:dd:ee:01", "192.168.1.20", time.Now())
+
+	d, _ := s.GetDevice("aa:bb:cc:dd:ee:01")
+	if d.Name != "Living room TV" || !d.Trusted {
+		t.Errorf("unexpected device: %+v", d)
+	}
+	if got := d.Label(); got != "Living room TV (aa:bb:cc:dd:ee:01)" {
+		t.Errorf("Label() = %q", got)
+	}
+
+	if err := s.SetDeviceName("ff:ff:ff:ff:ff:ff", "x"); err != ErrNoDevice {
+		t.Errorf("SetDeviceName unknown = %v, want ErrNoDevice", err)
+	}
+	if _, err := s.GetDevice("ff:ff:ff:ff:ff:ff"); err != ErrNoDevice {
+		t.Errorf("GetDevice unknown = %v, want ErrNoDevice", err)
+	}
+}
+
+func TestDevices_Prune(t *testing.T) {
+	s := openTestStore(t)
+	now := time.Now()
+	old := now.Add(-100 * 24 * time.Hour)
+	cutoff := now.Add(-90 * 24 * time.Hour)
+
+	s.RecordDevice("aa:bb:cc:dd:ee:01", "192.168.1.20", old) // stale: pruned
+	s.RecordDevice("aa:bb:cc:dd:ee:02", "192.168.1.21", old) // stale but named: kept
+	s.SetDeviceName("aa:bb:cc:dd:ee:02", "NAS")
+	s.RecordDevice("aa:bb:cc:dd:ee:03", "192.168.1.22", old) // stale but trusted: kept
+	s.SetDeviceTrusted("aa:bb:cc:dd:ee:03", true)
+	s.RecordDevice("aa:bb:cc:dd:ee:04", "192.168.1.40", old) // current, with an old address
+	s.RecordDevice("aa:bb:cc:dd:ee:04", "192.168.1.41", now)
+
+	n, err := s.PruneDevices(cutoff)
+	if err != nil {
+		t.Fatalf("PruneDevices: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("pruned %d devices, want 1", n)
+	}
+	if _, err := s.GetDevice("aa:bb:cc:dd:ee:01"); err != ErrNoDevice {
+		t.Errorf("stale device still present: %v", err)
+	}
+	for _, mac := range []string{"aa:bb:cc:dd:ee:02", "aa:bb:cc:dd:ee:03"} {
+		d, err := s.GetDevice(mac)
+		if err != nil {
+			t.Fatalf("GetDevice(%s): %v", mac, err)
+		}
+		if len(d.IPs) != 1 {
+			t.Errorf("%s lost its current address: %+v", mac, d.IPs)
+		}
+	}
+	d, _ := s.GetDevice("aa:bb:cc:dd:ee:04")
+	if len(d.IPs) != 1 || d.IPs[0].IP != "192.168.1.41" {
+		t.Errorf("address history = %+v, want only 192.168.1.41", d.IPs)
+	}
+}
+
+func TestOutboundDests_SaveListPrune(t *testing.T) {
+	s := openTestStore(t)
+	now := time.Now()
+	if err := s.SaveOutboundDests([]OutboundDest{
+		{Owner: "node", Destination: "tcp:203.0.113.0/24:443", LastSeen: now.Add(-48 * time.Hour)},
+		{Owner: "node", Destination: "tcp:198.51.100.0/24:22", LastSeen: now.Add(-time.Hour)},
+		{Owner: "container:web", Destination: "tcp:203.0.113.0/24:443", LastSeen: now},
+	}); err != nil {
+		t.Fatalf("SaveOutboundDests: %v", err)
+	}
+	// Seeing a destination again refreshes it.
+	s.SaveOutboundDests([]OutboundDest{{Owner: "node", Destination: "tcp:203.0.113.0/24:443", LastSeen: now}})
+
+	dests, err := s.ListOutboundDests(now.Add(-24 * time.Hour))
+	if err != nil || len(dests) != 3 {
+		t.Fatalf("ListOutboundDests = %+v, %v; want 3", dests, err)
+	}
+
+	n, err := s.PruneOutboundDests(now.Add(-30*time.Minute), 1)
+	if err != nil || n != 2 {
+		t.Fatalf("PruneOutboundDests = %d, %v; want 2 (one expired, one over the cap)", n, err)
+	}
+	if dests, _ := s.ListOutboundDests(time.Time{}); len(dests) != 1 {
+		t.Errorf("left %+v, want 1", dests)
+	}
+}
+
+func TestOutages_StartEndList(t *testing.T) {
+	s := openTestStore(t)
+	if _, err := s.OpenOutage(); err != sql.ErrNoRows {
+		t.Fatalf("OpenOutage on empty store = %v, want sql.ErrNoRows", err)
+	}
+
+	t0 := time.Date(2026, 9, 1, 10, 0, 0, 0, time.Local)
+	id1, _ := s.StartOutage(t0)
+	if err := s.EndOutage(id1, t0.Add(5*time.Minute)); err != nil {
+		t.Fatalf("EndOutage: %v", err)
+	}
+	id2, _ := s.StartOutage(t0.Add(time.Hour))
+	s.TouchOutage(id2, t0.Add(80*time.Minute))
+	s.TouchOutage(id1, t0.Add(3*time.Hour)) // ended: left alone
+
+	open, err := s.OpenOutage()
+	if err != nil || open.ID != id2 {
+		t.Fatalf("OpenOutage = %+v, %v; want #%d", open, err, id2)
+	}
+
+	outages, err := s.ListOutages(10)
+	if err != nil || len(outages) != 2 {
+		t.Fatalf("ListOutages = %+v, %v", outages, err)
+	}
+	if outages[0].ID != id2 || !outages[0].End.IsZero() {
+		t.Errorf("newest outage = %+v, want ongoing #%d", outages[0], id2)
+	}
+	if got := outages[1].Duration(time.Now()); got != 5*time.Minute {
+		t.Errorf("ended outage duration = %s, want 5m", got)
+	}
+	// An ongoing outage lasts until its last probe, not until now: the
+	// daemon may have been stopped since.
+	if got := outages[0].Duration(t0.Add(10 * time.Hour)); got != 20*time.Minute {
+		t.Errorf("ongoing outage duration = %s, want 20m", got)
+	}
+
+	var secs int64
+	s.db.QueryRow(`SELECT duration_seconds FROM outages WHERE id = ?`, id1).Scan(&secs)
+	if secs != 300 {
+		t.Errorf("duration_seconds = %d, want 300", secs)
+	}
+}
+
+func TestOutages_Availability(t *testing.T) {
+	s := openTestStore(t)
+	from := time.Date(2026, 9, 7, 0, 0, 0, 0, time.Local)
+	to := from.Add(7 * 24 * time.Hour)
+
+	// Straddles the start of the week: only the 30 minutes inside count.
+	id, _ := s.StartOutage(from.Add(-30 * time.Minute))
+	s.EndOutage(id, from.Add(30*time.Minute))
+	// Entirely inside.
+	id, _ = s.StartOutage(from.Add(48 * time.Hour))
+	s.EndOutage(id, from.Add(48*time.Hour+15*time.Minute))
+	// Before the week: ignored.
+	id, _ = s.StartOutage(from.Add(-48 * time.Hour))
+	s.EndOutage(id, from.Add(-47*time.Hour))
+	// Still ongoing near the end of the week, last probed 15 minutes in.
+	id, _ = s.StartOutage(to.Add(-time.Hour))
+	s.TouchOutage(id, to.Add(-45*time.Minute))
+
+	a, err := s.Availability(from, to, to.Add(-45*time.Minute))
+	if err != nil {
+		t.Fatalf("Availability: %v", err)
+	}
+	if a.Outages != 3 || a.Downtime != 60*time.Minute {
+		t.Errorf("availability = %d outages, %s down; want 3, 1h", a.Outages, a.Downtime)
+	}
+	// Hours later with no probe since (the Pi was off), nothing more counts.
+	if a, _ := s.Availability(from, to, to.Add(-time.Minute)); a.Downtime != 60*time.Minute {
+		t.Errorf("downtime after the last probe = %s, want 1h", a.Downtime)
+	}
+	// The period ends at "now", not at the end of the week.
+	if !a.To.Equal(to.Add(-45 * time.Minute)) {
+		t.Errorf("To = %v, want now", a.To)
+	}
+	if p := a.Percent(); p < 99.3 || p > 99.5 {
+		t.Errorf("Percent = %.3f, want about 99.4", p)
+	}
+}
+
+func TestAddressChanges(t *testing.T) {
+	s := openTestStore(t)
+	t0 := time.Date(2026, 10, 1, 8, 0, 0, 0, time.Local)
+	s.AddAddressChange(AddressChange{ChangedAt: t0, Scope: "public", Old: "203.0.113.7", New: "198.51.100.23"})
+	s.AddAddressChange(AddressChange{ChangedAt: t0.Add(time.Hour), Scope: "eth0", Old: "192.168.1.20/24", New: "192.168.1.57/24"})
+
+	changes, err := s.ListAddressChanges(10)
+	if err != nil || len(changes) != 2 {
+		t.Fatalf("ListAddressChanges = %+v, %v", changes, err)
+	}
+	if changes[0].Scope != "eth0" || changes[0].New != "192.168.1.57/24" || !changes[0].ChangedAt.Equal(t0.Add(time.Hour)) {
+		t.Errorf("newest change = %+v", changes[0])
+	}
+	if changes[1].Old != "203.0.113.7" {
+		t.Errorf("older change = %+v", changes[1])
+	}
+
+	if changes, _ := s.ListAddressChanges(1); len(changes) != 1 {
+		t.Errorf("limit 1 returned %d changes", len(changes))
+	}
+}
+
+func TestFileRecords(t *testing.T) {
+	s := openTestStore(t)
+	if _, err := s.GetFileRecord("/etc/shadow"); !errors.Is(err, ErrNoFileRecord) {
+		t.Fatalf("GetFileRecord on empty store: %v, want ErrNoFileRecord", err)
+	}
+
+	mtime := time.Date(2026, 10, 1, 8, 0, 0, 0, time.Local)
+	shadow := FileRecord{Path: "/etc/shadow", Hash: "aaa", Mode: 0o640, UID: 0, GID: 42, Size: 1024, ModTime: mtime, CheckedAt: mtime}
+	s.PutFileRecord(shadow)
+	s.PutFileRecord(FileRecord{Path: "/etc/cron.d/backup", Hash: "bbb", Mode: 0o644, ModTime: mtime, CheckedAt: mtime})
+	s.PutFileRecord(FileRecord{Path: "/etc/cron.d/sub/job", Hash: "ccc", Mode: 0o644, ModTime: mtime, CheckedAt: mtime})
+	s.PutFileRecord(FileRecord{Path: "/etc/cron.daily/logrotate", Hash: "ddd", Mode: 0o755, ModTime: mtime, CheckedAt: mtime})
+
+	hosts := FileRecord{Path: "/etc/hosts", Hash: "fff", ModTime: mtime, CheckedAt: mtime, Content: "127.0.0.1 localhost\n"}
+	s.PutFileRecord(hosts)
+	if got, _ := s.GetFileRecord("/etc/hosts"); got.Content != hosts.Content {
+		t.Errorf("content = %q, want %q", got.Content, hosts.Content)
+	}
+	hosts.Content = "" // no longer kept, e.g. grown past the size cap
+	s.PutFileRecord(hosts)
+	if got, _ := s.GetFileRecord("/etc/hosts"); got.Content != "" {
+		t.Errorf("content after clearing = %q", got.Content)
+	}
+	s.DeleteFileRecords("/etc/hosts")
+
+	shadow.Hash = "eee"
+	s.PutFileRecord(shadow)
+	got, err := s.GetFileRecord("/etc/shadow")
+	if err != nil || got.Hash != "eee" || got.GID != 42 || got.Mode != 0o640 || !got.ModTime.Equal(mtime) {
+		t.Errorf("GetFileRecord = %+v, %v", got, err)
+	}
+
+	if err := s.DeleteFileRecords("/etc/cron.d"); err != nil {
+		t.Fatal(err)
+	}
+	records, _ := s.ListFileRecords()
+	var paths []string
+	for _, r := range records {
+		paths = append(paths, r.Path)
+	}
+	if strings.Join(paths, ",") != "/etc/cron.daily/logrotate,/etc/shadow" {
+		t.Errorf("records after deleting /etc/cron.d = %v", paths)
+	}
+}
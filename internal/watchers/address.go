@@ -0,0 +1,430 @@
+package watchers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Fullex26/piguard/internal/config"
+	"github.com/Fullex26/piguard/internal/eventbus"
+	"github.com/Fullex26/piguard/internal/store"
+	"github.com/Fullex26/piguard/pkg/models"
+)
+
+// addressSettle is how long notifications must stop before interfaces are
+// compared, so a DHCP renew's delete-then-add reads as one change and a link
+// that bounces for a moment isn't reported.
+const addressSettle = 2 * time.Second
+
+// PublicIPStateKey holds the last public IP seen, for /ip and so a change
+// made while the daemon was down is still caught.
+const PublicIPStateKey = "network.public_ip"
+
+// addressStatePrefix persists each interface's addresses for the same reason.
+const addressStatePrefix = "network.addresses:"
+
+// ifaceState is an interface as last compared.
+type ifaceState struct {
+	Up    bool
+	Addrs []string // sorted "ip/prefix"; while down, the addresses it last had up
+}
+
+// AddressWatcher follows rtnetlink link and address notifications and alerts
+// when an interface gains, loses or changes an address or its link goes down
+// or up. It also polls a "what is my IP" endpoint for public IP changes.
+// Changes are recorded in the store for /ip.
+type AddressWatcher struct {
+	Base
+	store          *store.Store // nil = changes not recorded
+	ignore         []string
+	publicURL      string
+	publicInterval time.Duration
+
+	mu      sync.Mutex                 // guards the live state below, fed by the netlink listener
+	names   map[int]string             // ifindex → name
+	links   map[string]bool            // name → up
+	addrs   map[string]map[string]bool // name → addresses
+	dirty   bool
+	lastMsg time.Time
+
+	reported map[string]ifaceState // nil until the first comparison
+	publicIP string
+
+	listenFn func(ctx context.Context, handle func([]rtnlMsg, bool)) error // injectable for tests
+	fetchFn  func(url string, timeout time.Duration) (string, error)       // injectable for tests
+}
+
+func NewAddressWatcher(cfg *config.Config, bus *eventbus.Bus, db *store.Store) *AddressWatcher {
+	ac := cfg.Addresses
+	interval, err := time.ParseDuration(ac.PublicIPInterval)
+	if err != nil || interval <= 0 {
+		interval = 10 * time.Minute
+	}
+	return &AddressWatcher{
+		Base:           Base{Cfg: cfg, Bus: bus},
+		store:          db,
+		ignore:         ac.Ignore,
+		publicURL:      ac.PublicIPURL,
+		publicInterval: interval,
+		names:          make(map[int]string),
+		links:          make(map[string]bool),
+		addrs:          make(map[string]map[string]bool),
+		listenFn:       rtnlListen,
+		fetchFn:        fetchPublicIP,
+	}
+}
+
+func (w *AddressWatcher) Name() string { return "addresses" }
+func (w *AddressWatcher) Stop() error  { return nil }
+
+func (w *AddressWatcher) Start(ctx context.Context) error {
+	slog.Info("starting address watcher", "public_ip_url", w.publicURL, "public_ip_interval", w.publicInterval)
+	hostname, _ := os.Hostname()
+
+	go func() {
+		err := w.listenFn(ctx, w.handle)
+		switch {
+		case errors.Is(err, errRtnetlinkUnsupported):
+			slog.Info("interface address monitoring needs Linux; watching the public IP only")
+		case err != nil:
+			slog.Warn("interface address monitoring stopped", "error", err)
+		}
+	}()
+
+	var publicTick <-chan time.Time
+	if w.publicURL != "" {
+		w.checkPublicIP(hostname)
+		ticker := time.NewTicker(w.publicInterval)
+		defer ticker.Stop()
+		publicTick = ticker.C
+	}
+	settle := time.NewTicker(addressSettle / 4)
+	defer settle.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-publicTick:
+			w.checkPublicIP(hostname)
+		case <-settle.C:
+			if !w.settled() {
+				continue
+			}
+			// A WAN reconnect often shows up locally first; look at the
+			// public IP now rather than at the next poll.
+			if w.report(hostname) && w.publicURL != "" {
+				w.checkPublicIP(hostname)
+			}
+		}
+	}
+}
+
+// handle applies rtnetlink messages to the live state. full replaces it.
+func (w *AddressWatcher) handle(msgs []rtnlMsg, full bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if full {
+		w.names = make(map[int]string)
+		w.links = make(map[string]bool)
+		w.addrs = make(map[string]map[string]bool)
+	}
+	for _, m := range msgs {
+		switch m.Type {
+		case rtmNewLink:
+			w.names[m.Index] = m.Name
+			if w.ignored(m.Name) {
+				continue
+			}
+			w.links[m.Name] = m.Up
+			if w.addrs[m.Name] == nil {
+				w.addrs[m.Name] = make(map[string]bool)
+			}
+		case rtmDelLink:
+			name := w.names[m.Index]
+			delete(w.names, m.Index)
+			delete(w.links, name)
+			delete(w.addrs, name)
+		case rtmNewAddr, rtmDelAddr:
+			set := w.addrs[w.names[m.Index]]
+			if set == nil || m.LinkLocal || m.Temporary {
+				continue // unknown or ignored interface, or an address expected to come and go
+			}
+			if m.Type == rtmNewAddr {
+				set[m.Addr] = true
+			} else {
+				delete(set, m.Addr)
+			}
+		}
+	}
+	w.dirty = true
+	w.lastMsg = time.Now()
+}
+
+func (w *AddressWatcher) ignored(name string) bool {
+	for _, pattern := range w.ignore {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// settled reports whether there are changes and notifications have stopped.
+func (w *AddressWatcher) settled() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.dirty && time.Since(w.lastMsg) >= addressSettle
+}
+
+func (w *AddressWatcher) snapshot() map[string]ifaceState {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.dirty = false
+	snap := make(map[string]ifaceState, len(w.links))
+	for name, up := range w.links {
+		addrs := make([]string, 0, len(w.addrs[name]))
+		for a := range w.addrs[name] {
+			addrs = append(addrs, a)
+		}
+		sort.Strings(addrs)
+		snap[name] = ifaceState{Up: up, Addrs: addrs}
+	}
+	return snap
+}
+
+// report compares the interfaces with the last comparison and alerts on
+// each difference. The first comparison only checks the addresses of
+// interfaces that are up against the ones saved before a restart. It
+// returns whether anything was reported.
+func (w *AddressWatcher) report(hostname string) bool {
+	cur := w.snapshot()
+	first := w.reported == nil
+	if first {
+		w.reported = make(map[string]ifaceState)
+	}
+
+	names := make(map[string]bool)
+	for name := range w.reported {
+		names[name] = true
+	}
+	for name := range cur {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	if first {
+		changed := false
+		for _, name := range sorted {
+			c := cur[name]
+			last, found := c.Addrs, false
+			if w.store != nil {
+				if saved, err := w.store.GetState(addressStatePrefix + name); err == nil {
+					last, found = splitList(saved), true
+				}
+			}
+			if !c.Up {
+				w.reported[name] = ifaceState{Up: false, Addrs: last}
+				continue
+			}
+			w.reported[name] = c
+			if !found {
+				w.saveAddrs(name, c.Addrs)
+			} else if w.compareAddrs(hostname, name, last, c.Addrs) {
+				changed = true
+			}
+		}
+		return changed
+	}
+
+	changed := false
+	for _, name := range sorted {
+		p, had := w.reported[name]
+		c, has := cur[name]
+		switch {
+		case had && !has:
+			delete(w.reported, name)
+			if p.Up {
+				w.emit(hostname, models.EventLinkDown, models.SeverityWarning,
+					fmt.Sprintf("Interface %s removed", name),
+					fmt.Sprintf("Interface: %s | Last addresses: %s", name, orNone(strings.Join(p.Addrs, ", "))),
+					"A USB adapter unplugged or a VPN or container network torn down removes an interface")
+				changed = true
+			}
+		case !had && has:
+			w.reported[name] = c
+			if c.Up {
+				w.emit(hostname, models.EventLinkUp, models.SeverityInfo,
+					fmt.Sprintf("Interface %s appeared", name),
+					fmt.Sprintf("Interface: %s | Addresses: %s", name, orNone(strings.Join(c.Addrs, ", "))), "")
+				w.saveAddrs(name, c.Addrs)
+				changed = true
+			}
+		case p.Up && !c.Up:
+			w.reported[name] = ifaceState{Up: false, Addrs: p.Addrs}
+			w.emit(hostname, models.EventLinkDown, models.SeverityWarning,
+				fmt.Sprintf("Link down on %s", name),
+				fmt.Sprintf("Interface: %s | Last addresses: %s", name, orNone(strings.Join(p.Addrs, ", "))),
+				fmt.Sprintf("Check the cable or Wi-Fi connection: ip link show %s", name))
+			changed = true
+		case !p.Up && c.Up:
+			w.reported[name] = c
+			w.emit(hostname, models.EventLinkUp, models.SeverityInfo,
+				fmt.Sprintf("Link up on %s", name),
+				fmt.Sprintf("Interface: %s | Addresses: %s", name, orNone(strings.Join(c.Addrs, ", "))), "")
+			w.compareAddrs(hostname, name, p.Addrs, c.Addrs)
+			changed = true
+		case c.Up:
+			w.reported[name] = c
+			if w.compareAddrs(hostname, name, p.Addrs, c.Addrs) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// compareAddrs alerts and records the change when an interface's addresses
+// differ from before.
+func (w *AddressWatcher) compareAddrs(hostname, name string, prev, cur []string) bool {
+	oldList, newList := strings.Join(prev, ", "), strings.Join(cur, ", ")
+	if oldList == newList {
+		return false
+	}
+	w.saveAddrs(name, cur)
+
+	added, removed := listDiff(cur, prev), listDiff(prev, cur)
+	var msg string
+	switch {
+	case len(added) > 0 && len(removed) > 0:
+		msg = fmt.Sprintf("%s address changed: %s → %s", name, strings.Join(removed, ", "), strings.Join(added, ", "))
+	case len(added) > 0:
+		msg = fmt.Sprintf("%s gained address %s", name, strings.Join(added, ", "))
+	default:
+		msg = fmt.Sprintf("%s lost address %s", name, strings.Join(removed, ", "))
+	}
+	w.emit(hostname, models.EventAddressChanged, models.SeverityWarning, msg,
+		fmt.Sprintf("Interface: %s | Previous: %s | Now: %s", name, orNone(oldList), orNone(newList)),
+		"Port forwards, firewall rules and VPN configs naming the old address need updating. "+
+			"To keep the address, reserve it in the router's DHCP settings")
+	w.recordChange(name, oldList, newList)
+	return true
+}
+
+func (w *AddressWatcher) saveAddrs(name string, addrs []string) {
+	if w.store != nil {
+		w.store.SetState(addressStatePrefix+name, strings.Join(addrs, ", "))
+	}
+}
+
+// checkPublicIP fetches the public IP and alerts when it differs from the
+// last one seen. Fetch errors are left to the connectivity monitor.
+func (w *AddressWatcher) checkPublicIP(hostname string) {
+	ip, err := w.fetchFn(w.publicURL, 10*time.Second)
+	if err != nil {
+		slog.Debug("public IP lookup failed", "url", w.publicURL, "error", err)
+		return
+	}
+	if w.publicIP == "" && w.store != nil {
+		w.publicIP, _ = w.store.GetState(PublicIPStateKey)
+	}
+	prev := w.publicIP
+	w.publicIP = ip
+	if prev == ip {
+		return
+	}
+	if w.store != nil {
+		w.store.SetState(PublicIPStateKey, ip)
+	}
+	if prev == "" {
+		return // first sighting
+	}
+
+	w.emit(hostname, models.EventPublicIPChanged, models.SeverityWarning,
+		fmt.Sprintf("Public IP changed: %s → %s", prev, ip),
+		fmt.Sprintf("Previous: %s | Now: %s | Source: %s", prev, ip, w.publicURL),
+		"Update dynamic DNS records, VPN endpoints and allowlists that use the old address")
+	w.recordChange("public", prev, ip)
+}
+
+func (w *AddressWatcher) recordChange(scope, prev, cur string) {
+	if w.store == nil {
+		return
+	}
+	if err := w.store.AddAddressChange(store.AddressChange{ChangedAt: time.Now(), Scope: scope, Old: prev, New: cur}); err != nil {
+		slog.Error("recording address change failed", "error", err)
+	}
+}
+
+func (w *AddressWatcher) emit(hostname string, evType models.EventType, sev models.Severity, msg, details, suggested string) {
+	w.Bus.Publish(models.Event{
+		ID:        fmt.Sprintf("%s-%d", evType, time.Now().UnixNano()),
+		Type:      evType,
+		Severity:  sev,
+		Hostname:  hostname,
+		Timestamp: time.Now(),
+		Message:   msg,
+		Details:   details,
+		Suggested: suggested,
+		Source:    "addresses",
+	})
+}
+
+// fetchPublicIP GETs a "what is my IP" endpoint that answers with the bare
+// address, such as https://api.ipify.org or https://ifconfig.me/ip.
+func fetchPublicIP(url string, timeout time.Duration) (string, error) {
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64))
+	if err != nil {
+		return "", err
+	}
+	ip := net.ParseIP(strings.TrimSpace(string(body)))
+	if ip == nil {
+		return "", fmt.Errorf("response %q is not an IP address", strings.TrimSpace(string(body)))
+	}
+	return ip.String(), nil
+}
+
+// listDiff returns the entries of a not in b.
+func listDiff(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, s := range b {
+		in[s] = true
+	}
+	var out []string
+	for _, s := range a {
+		if !in[s] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ", ")
+}
@@ -0,0 +1,202 @@
+package watchers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fullex26/piguard/internal/config"
+	"github.com/Fullex26/piguard/internal/eventbus"
+	"github.com/Fullex26/piguard/internal/store"
+	"github.com/Fullex26/piguard/pkg/models"
+)
+
+func newTestAddressWatcher(db *store.Store) (*AddressWatcher, chan models.Event) {
+	bus := eventbus.New()
+	received := make(chan models.Event, 10)
+	bus.Subscribe(func(e models.Event) { received <- e })
+
+	cfg := config.DefaultConfig()
+	w := NewAddressWatcher(cfg, bus, db)
+	return w, received
+}
+
+func linkMsg(index int, name string, up bool) rtnlMsg {
+	return rtnlMsg{Type: rtmNewLink, Index: index, Name: name, Up: up}
+}
+
+func addrMsg(msgType uint16, index int, addr string) rtnlMsg {
+	return rtnlMsg{Type: msgType, Index: index, Addr: addr}
+}
+
+// baselineEth0 dumps eth0 up with 192.168.1.20/24 and takes the first
+// comparison.
+func baselineEth0(t *testing.T, w *AddressWatcher, received chan models.Event) {
+	t.Helper()
+	w.handle([]rtnlMsg{
+		linkMsg(1, "lo", true),
+		linkMsg(2, "eth0", true),
+		linkMsg(5, "docker0", true),
+		addrMsg(rtmNewAddr, 1, "127.0.0.1/8"),
+		addrMsg(rtmNewAddr, 2, "192.168.1.20/24"),
+		addrMsg(rtmNewAddr, 5, "172.17.0.1/16"),
+	}, true)
+	if w.report("pi") {
+		t.Fatal("first comparison reported a change")
+	}
+	expectNoEvent(t, received)
+}
+
+func TestAddressWatcher_AddressChanged(t *testing.T) {
+	db := openNetworkTestStore(t)
+	w, received := newTestAddressWatcher(db)
+	baselineEth0(t, w, received)
+
+	// A DHCP lease moving to another address: delete then add.
+	w.handle([]rtnlMsg{addrMsg(rtmDelAddr, 2, "192.168.1.20/24")}, false)
+	w.handle([]rtnlMsg{addrMsg(rtmNewAddr, 2, "192.168.1.57/24")}, false)
+	if !w.report("pi") {
+		t.Fatal("address change not reported")
+	}
+	e := awaitEvent(t, received)
+	if e.Type != models.EventAddressChanged || e.Severity != models.SeverityWarning {
+		t.Errorf("event = %s/%s, want address changed warning", e.Type, e.Severity)
+	}
+	if e.Message != "eth0 address changed: 192.168.1.20/24 → 192.168.1.57/24" {
+		t.Errorf("message = %q", e.Message)
+	}
+	expectNoEvent(t, received)
+
+	changes, _ := db.ListAddressChanges(10)
+	if len(changes) != 1 || changes[0].Scope != "eth0" || changes[0].Old != "192.168.1.20/24" {
+		t.Errorf("history = %+v", changes)
+	}
+
+	// A renew that deletes and re-adds the same address is no change.
+	w.handle([]rtnlMsg{addrMsg(rtmDelAddr, 2, "192.168.1.57/24"), addrMsg(rtmNewAddr, 2, "192.168.1.57/24")}, false)
+	if w.report("pi") {
+		t.Error("renew of the same address reported")
+	}
+	expectNoEvent(t, received)
+}
+
+func TestAddressWatcher_IgnoresVirtualAndRotatingAddresses(t *testing.T) {
+	w, received := newTestAddressWatcher(nil)
+	baselineEth0(t, w, received)
+
+	w.handle([]rtnlMsg{
+		addrMsg(rtmNewAddr, 5, "172.18.0.1/16"), // docker0 is ignored
+		{Type: rtmNewAddr, Index: 2, Addr: "2001:db8::a1b2/64", Temporary: true},
+		{Type: rtmNewAddr, Index: 2, Addr: "fe80::1/64", LinkLocal: true},
+		linkMsg(9, "veth1a2b3c", true),
+		addrMsg(rtmNewAddr, 9, "172.17.0.5/16"),
+	}, false)
+	if w.report("pi") {
+		t.Error("ignored changes reported")
+	}
+	expectNoEvent(t, received)
+}
+
+func TestAddressWatcher_LinkDownAndUp(t *testing.T) {
+	w, received := newTestAddressWatcher(nil)
+	baselineEth0(t, w, received)
+
+	// Carrier lost; dhcpcd drops the address with it.
+	w.handle([]rtnlMsg{linkMsg(2, "eth0", false), addrMsg(rtmDelAddr, 2, "192.168.1.20/24")}, false)
+	w.report("pi")
+	e := awaitEvent(t, received)
+	if e.Type != models.EventLinkDown || e.Message != "Link down on eth0" {
+		t.Errorf("event = %s %q, want link down on eth0", e.Type, e.Message)
+	}
+	expectNoEvent(t, received) // no separate "lost address" alert
+
+	// Back with the same address: link up only.
+	w.handle([]rtnlMsg{linkMsg(2, "eth0", true), addrMsg(rtmNewAddr, 2, "192.168.1.20/24")}, false)
+	w.report("pi")
+	if e := awaitEvent(t, received); e.Type != models.EventLinkUp || e.Severity != models.SeverityInfo {
+		t.Errorf("event = %s/%s, want link up info", e.Type, e.Severity)
+	}
+	expectNoEvent(t, received)
+
+	// Removed entirely.
+	w.handle([]rtnlMsg{{Type: rtmDelLink, Index: 2}}, false)
+	w.report("pi")
+	if e := awaitEvent(t, received); e.Type != models.EventLinkDown || e.Message != "Interface eth0 removed" {
+		t.Errorf("event = %s %q, want eth0 removed", e.Type, e.Message)
+	}
+}
+
+func TestAddressWatcher_ChangedWhileDown(t *testing.T) {
+	db := openNetworkTestStore(t)
+	w, received := newTestAddressWatcher(db)
+	baselineEth0(t, w, received)
+
+	w, received = newTestAddressWatcher(db)
+	w.handle([]rtnlMsg{linkMsg(2, "eth0", true), addrMsg(rtmNewAddr, 2, "192.168.1.99/24")}, true)
+	if !w.report("pi") {
+		t.Fatal("change since the last run not reported")
+	}
+	if e := awaitEvent(t, received); !strings.Contains(e.Message, "192.168.1.20/24 → 192.168.1.99/24") {
+		t.Errorf("message = %q", e.Message)
+	}
+}
+
+func TestAddressWatcher_PublicIP(t *testing.T) {
+	db := openNetworkTestStore(t)
+	w, received := newTestAddressWatcher(db)
+	ip := "203.0.113.7"
+	w.fetchFn = func(string, time.Duration) (string, error) { return ip, nil }
+
+	w.checkPublicIP("pi")
+	expectNoEvent(t, received) // first sighting
+
+	w.fetchFn = func(string, time.Duration) (string, error) { return "", errors.New("timeout") }
+	w.checkPublicIP("pi")
+	expectNoEvent(t, received)
+
+	ip = "198.51.100.23"
+	w.fetchFn = func(string, time.Duration) (string, error) { return ip, nil }
+	w.checkPublicIP("pi")
+	e := awaitEvent(t, received)
+	if e.Type != models.EventPublicIPChanged || e.Message != "Public IP changed: 203.0.113.7 → 198.51.100.23" {
+		t.Errorf("event = %s %q", e.Type, e.Message)
+	}
+	if saved, _ := db.GetState(PublicIPStateKey); saved != ip {
+		t.Errorf("saved public IP = %q", saved)
+	}
+
+	// A fresh watcher picks up where the last one stopped.
+	w, received = newTestAddressWatcher(db)
+	w.fetchFn = func(string, time.Duration) (string, error) { return "198.51.100.99", nil }
+	w.checkPublicIP("pi")
+	if e := awaitEvent(t, received); !strings.Contains(e.Message, "198.51.100.23 → 198.51.100.99") {
+		t.Errorf("message after restart = %q", e.Message)
+	}
+}
+
+func TestFetchPublicIP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ip":
+			w.Write([]byte("203.0.113.7\n"))
+		case "/html":
+			w.Write([]byte("<html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	if ip, err := fetchPublicIP(srv.URL+"/ip", time.Second); err != nil || ip != "203.0.113.7" {
+		t.Errorf("fetchPublicIP = %q, %v", ip, err)
+	}
+	if _, err := fetchPublicIP(srv.URL+"/html", time.Second); err == nil {
+		t.Error("non-IP response accepted")
+	}
+	if _, err := fetchPublicIP(srv.URL+"/missing", time.Second); err == nil {
+		t.Error("404 accepted")
+	}
+}
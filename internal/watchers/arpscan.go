@@ -0,0 +1,112 @@
+package watchers
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ARP over Ethernet wire constants (RFC 826). Declared here rather than taken
+// from x/sys/unix so frame building and parsing build and test on every
+// platform; only the AF_PACKET socket I/O in arpscan_linux.go is Linux-only.
+const (
+	ethPArp       = 0x0806
+	ethHdrLen     = 14
+	arpPacketLen  = 28
+	ethMinFrame   = 60 // minimum Ethernet frame without FCS
+	arpHTEthernet = 1
+	arpPTIPv4     = 0x0800
+	arpOpRequest  = 1
+	arpOpReply    = 2
+
+	// maxSweepHosts caps a sweep at a /20 so a misconfigured /8 doesn't turn
+	// into 16 million requests.
+	maxSweepHosts = 4096
+)
+
+var errARPSweepUnsupported = errors.New("active ARP sweep not supported on this platform")
+
+// buildARPRequest encodes a broadcast Ethernet frame asking who has dstIP.
+func buildARPRequest(srcMAC net.HardwareAddr, srcIP, dstIP net.IP) []byte {
+	frame := make([]byte, ethMinFrame)
+	copy(frame[0:6], []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	copy(frame[6:12], srcMAC)
+	binary.BigEndian.PutUint16(frame[12:14], ethPArp)
+
+	arp := frame[ethHdrLen:]
+	binary.BigEndian.PutUint16(arp[0:2], arpHTEthernet)
+	binary.BigEndian.PutUint16(arp[2:4], arpPTIPv4)
+	arp[4] = 6 // hardware address length
+	arp[5] = 4 // protocol address length
+	binary.BigEndian.PutUint16(arp[6:8], arpOpRequest)
+	copy(arp[8:14], srcMAC)
+	copy(arp[14:18], srcIP.To4())
+	// target hardware address stays zero
+	copy(arp[24:28], dstIP.To4())
+	return frame
+}
+
+// parseARPFrame returns the sender of an Ethernet ARP request or reply.
+// Requests count too: a host asking for an address has revealed its own.
+func parseARPFrame(frame []byte) (networkDevice, bool) {
+	if len(frame) < ethHdrLen+arpPacketLen || binary.BigEndian.Uint16(frame[12:14]) != ethPArp {
+		return networkDevice{}, false
+	}
+	arp := frame[ethHdrLen:]
+	if binary.BigEndian.Uint16(arp[0:2]) != arpHTEthernet || binary.BigEndian.Uint16(arp[2:4]) != arpPTIPv4 ||
+		arp[4] != 6 || arp[5] != 4 {
+		return networkDevice{}, false
+	}
+	if op := binary.BigEndian.Uint16(arp[6:8]); op != arpOpRequest && op != arpOpReply {
+		return networkDevice{}, false
+	}
+	ip := net.IP(append([]byte(nil), arp[14:18]...))
+	if ip.IsUnspecified() {
+		return networkDevice{}, false // ARP probe (RFC 5227) from a host without an address yet
+	}
+	return networkDevice{IP: ip.String(), MAC: net.HardwareAddr(arp[8:14]).String()}, true
+}
+
+// sweepTargets lists the host addresses of an IPv4 subnet, excluding the
+// network and broadcast addresses and self.
+func sweepTargets(subnet *net.IPNet, self net.IP) ([]net.IP, error) {
+	base := subnet.IP.To4()
+	ones, bits := subnet.Mask.Size()
+	if base == nil || bits != 32 {
+		return nil, fmt.Errorf("%s is not an IPv4 subnet", subnet)
+	}
+	size := uint64(1) << uint(bits-ones)
+	if size > maxSweepHosts+2 {
+		return nil, fmt.Errorf("subnet %s is too large to sweep (more than %d hosts)", subnet, maxSweepHosts)
+	}
+
+	start := binary.BigEndian.Uint32(base.Mask(subnet.Mask))
+	first, last := start+1, start+uint32(size)-2
+	if size <= 2 {
+		first, last = start, start+uint32(size)-1 // /31 and /32 have no network or broadcast address
+	}
+	var targets []net.IP
+	for n := first; n <= last && n >= first; n++ {
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, n)
+		if !ip.Equal(self) {
+			targets = append(targets, ip)
+		}
+	}
+	return targets, nil
+}
+
+// interfaceIPv4 returns the interface's first IPv4 address and its subnet.
+func interfaceIPv4(iface *net.Interface) (net.IP, *net.IPNet, error) {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok && ipnet.IP.To4() != nil {
+			return ipnet.IP.To4(), ipnet, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("interface %s has no IPv4 address", iface.Name)
+}
@@ -0,0 +1,116 @@
+//go:build linux
+
+package watchers
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// arpSweep sends an ARP request to every address of the interface's IPv4
+// subnet, at most rate per second, and returns every host that answered
+// (or sent any ARP traffic) before settle elapsed after the last request.
+// Needs CAP_NET_RAW.
+func arpSweep(ifaceName string, rate int, settle time.Duration) ([]networkDevice, error) {
+	iface, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return nil, err
+	}
+	if len(iface.HardwareAddr) != 6 {
+		return nil, fmt.Errorf("interface %s has no Ethernet address", ifaceName)
+	}
+	self, subnet, err := interfaceIPv4(iface)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := sweepTargets(subnet, self)
+	if err != nil {
+		return nil, err
+	}
+
+	proto := htons(ethPArp)
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW|unix.SOCK_CLOEXEC, int(proto))
+	if err != nil {
+		return nil, fmt.Errorf("packet socket: %w", err)
+	}
+	defer unix.Close(fd)
+	if err := unix.Bind(fd, &unix.SockaddrLinklayer{Protocol: proto, Ifindex: iface.Index}); err != nil {
+		return nil, fmt.Errorf("packet bind: %w", err)
+	}
+	// Wake the receiver regularly so it notices the sweep is over.
+	tv := unix.NsecToTimeval((200 * time.Millisecond).Nanoseconds())
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		return nil, fmt.Errorf("packet timeout: %w", err)
+	}
+
+	var (
+		mu    sync.Mutex
+		found = make(map[string]networkDevice) // "mac ip" → device
+		done  = make(chan struct{})
+		wg    sync.WaitGroup
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		buf := make([]byte, 1514)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			n, _, err := unix.Recvfrom(fd, buf, 0)
+			if err != nil {
+				continue // EAGAIN on timeout, EINTR
+			}
+			if d, ok := parseARPFrame(buf[:n]); ok && d.MAC != iface.HardwareAddr.String() {
+				mu.Lock()
+				found[d.MAC+" "+d.IP] = d
+				mu.Unlock()
+			}
+		}
+	}()
+
+	if rate <= 0 {
+		rate = 50
+	}
+	ticker := time.NewTicker(time.Second / time.Duration(rate))
+	dst := &unix.SockaddrLinklayer{
+		Protocol: proto,
+		Ifindex:  iface.Index,
+		Halen:    6,
+		Addr:     [8]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	}
+	var sendErr error
+	for _, ip := range targets {
+		<-ticker.C
+		if err := unix.Sendto(fd, buildARPRequest(iface.HardwareAddr, self, ip), 0, dst); err != nil {
+			sendErr = fmt.Errorf("packet send: %w", err)
+			break
+		}
+	}
+	ticker.Stop()
+	if sendErr == nil {
+		time.Sleep(settle)
+	}
+	close(done)
+	wg.Wait()
+
+	devices := make([]networkDevice, 0, len(found))
+	for _, d := range found {
+		devices = append(devices, d)
+	}
+	if sendErr != nil && len(devices) == 0 {
+		return nil, sendErr
+	}
+	return devices, nil
+}
+
+// htons converts a uint16 to network byte order for the AF_PACKET protocol.
+func htons(v uint16) uint16 {
+	return v<<8 | v>>8
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package watchers
+
+import "time"
+
+// arpSweep is unavailable off Linux; the scanner relies on the neighbour table.
+func arpSweep(ifaceName string, rate int, settle time.Duration) ([]networkDevice, error) {
+	return nil, errARPSweepUnsupported
+}
@@ -0,0 +1,146 @@
+package watchers
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/Fullex26/piguard/pkg/models"
+)
+
+func TestBuildARPRequest(t *testing.T) {
+	mac, _ := net.ParseMAC("b8:27:eb:00:00:01")
+	frame := buildARPRequest(mac, net.ParseIP("192.168.1.10"), net.ParseIP("192.168.1.77"))
+
+	if len(frame) != ethMinFrame {
+		t.Fatalf("frame length = %d, want %d", len(frame), ethMinFrame)
+	}
+	if got := net.HardwareAddr(frame[0:6]).String(); got != "ff:ff:ff:ff:ff:ff" {
+		t.Errorf("destination = %s, want broadcast", got)
+	}
+	if got := binary.BigEndian.Uint16(frame[12:14]); got != ethPArp {
+		t.Errorf("ethertype = %#x, want %#x", got, ethPArp)
+	}
+	arp := frame[ethHdrLen:]
+	if op := binary.BigEndian.Uint16(arp[6:8]); op != arpOpRequest {
+		t.Errorf("op = %d, want request", op)
+	}
+	if got := net.IP(arp[24:28]).String(); got != "192.168.1.77" {
+		t.Errorf("target IP = %s", got)
+	}
+
+	// A request is itself parseable: its sender is us.
+	d, ok := parseARPFrame(frame)
+	if !ok || d.IP != "192.168.1.10" || d.MAC != "b8:27:eb:00:00:01" {
+		t.Errorf("parseARPFrame(request) = %+v, %v", d, ok)
+	}
+}
+
+func arpReply(senderMAC, senderIP string) []byte {
+	mac, _ := net.ParseMAC(senderMAC)
+	frame := buildARPRequest(mac, net.ParseIP(senderIP), net.ParseIP("192.168.1.10"))
+	binary.BigEndian.PutUint16(frame[ethHdrLen+6:], arpOpReply)
+	return frame[:ethHdrLen+arpPacketLen] // unpadded, as received on some NICs
+}
+
+func TestParseARPFrame(t *testing.T) {
+	d, ok := parseARPFrame(arpReply("DE:AD:BE:EF:00:01", "192.168.1.77"))
+	if !ok || d.IP != "192.168.1.77" || d.MAC != "de:ad:be:ef:00:01" {
+		t.Errorf("reply = %+v, %v", d, ok)
+	}
+
+	probe := arpReply("de:ad:be:ef:00:01", "0.0.0.0")
+	if _, ok := parseARPFrame(probe); ok {
+		t.Error("ARP probe from 0.0.0.0 should be ignored")
+	}
+
+	notARP := arpReply("de:ad:be:ef:00:01", "192.168.1.77")
+	binary.BigEndian.PutUint16(notARP[12:14], 0x0800)
+	if _, ok := parseARPFrame(notARP); ok {
+		t.Error("IPv4 ethertype should be rejected")
+	}
+
+	badOp := arpReply("de:ad:be:ef:00:01", "192.168.1.77")
+	binary.BigEndian.PutUint16(badOp[ethHdrLen+6:], 3) // RARP request
+	if _, ok := parseARPFrame(badOp); ok {
+		t.Error("RARP op should be rejected")
+	}
+
+	if _, ok := parseARPFrame(make([]byte, 20)); ok {
+		t.Error("short frame should be rejected")
+	}
+}
+
+func TestSweepTargets(t *testing.T) {
+	_, subnet, _ := net.ParseCIDR("192.168.1.0/24")
+	targets, err := sweepTargets(subnet, net.ParseIP("192.168.1.10"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 253 {
+		t.Errorf("len = %d, want 253 (254 hosts minus self)", len(targets))
+	}
+	if targets[0].String() != "192.168.1.1" || targets[len(targets)-1].String() != "192.168.1.254" {
+		t.Errorf("range = %s..%s", targets[0], targets[len(targets)-1])
+	}
+	for _, ip := range targets {
+		if ip.String() == "192.168.1.10" {
+			t.Error("self should be skipped")
+		}
+	}
+
+	_, p2p, _ := net.ParseCIDR("10.0.0.0/31")
+	if targets, _ := sweepTargets(p2p, net.ParseIP("10.0.0.0")); len(targets) != 1 || targets[0].String() != "10.0.0.1" {
+		t.Errorf("/31 targets = %v", targets)
+	}
+
+	_, huge, _ := net.ParseCIDR("10.0.0.0/8")
+	if _, err := sweepTargets(huge, nil); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("/8 error = %v", err)
+	}
+
+	_, v6, _ := net.ParseCIDR("fd00::/120")
+	if _, err := sweepTargets(v6, nil); err == nil {
+		t.Error("IPv6 subnet should be rejected")
+	}
+}
+
+func TestNetworkScanWatcher_ActiveScan_FindsQuietDevice(t *testing.T) {
+	w, received := newTestNetworkWatcher(false, nil, func() ([]byte, error) {
+		return []byte(`192.168.1.1 dev eth0 lladdr aa:bb:cc:dd:ee:ff REACHABLE`), nil
+	})
+	seedNetworkBaseline(w, `192.168.1.1 dev eth0 lladdr aa:bb:cc:dd:ee:ff REACHABLE`)
+	w.activeScan = true
+	w.sweep = func() ([]networkDevice, error) {
+		return []networkDevice{
+			{IP: "192.168.1.1", MAC: "aa:bb:cc:dd:ee:ff"},
+			{IP: "192.168.1.66", MAC: "de:ad:be:ef:00:66"}, // never talks to the Pi
+		}, nil
+	}
+
+	w.check()
+
+	e := awaitEvent(t, received)
+	if e.Type != models.EventNetworkNewDevice || !strings.Contains(e.Message, "192.168.1.66") {
+		t.Errorf("unexpected event: %s %q", e.Type, e.Message)
+	}
+	expectNoEvent(t, received)
+}
+
+func TestNetworkScanWatcher_ActiveScan_SweepFails(t *testing.T) {
+	w, received := newTestNetworkWatcher(false, nil, func() ([]byte, error) {
+		return []byte(`192.168.1.1 dev eth0 lladdr aa:bb:cc:dd:ee:ff REACHABLE`), nil
+	})
+	seedNetworkBaseline(w, `192.168.1.1 dev eth0 lladdr aa:bb:cc:dd:ee:ff REACHABLE`)
+	w.activeScan = true
+	w.sweep = func() ([]networkDevice, error) { return nil, errors.New("operation not permitted") }
+
+	devices, err := w.scan()
+	if err != nil || len(devices) != 1 {
+		t.Errorf("scan() = %v, %v; want the neighbour table alone", devices, err)
+	}
+	w.check()
+	expectNoEvent(t, received)
+}
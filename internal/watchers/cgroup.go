@@ -0,0 +1,271 @@
+package watchers
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Fullex26/piguard/internal/dockerapi"
+)
+
+// cgroupRoot is where the unified (v2) cgroup hierarchy is mounted.
+const cgroupRoot = "/sys/fs/cgroup"
+
+// containerCgroupGlobs locate container cgroups under the root: Docker with
+// the systemd and cgroupfs drivers, then rootful Podman (systemd and
+// cgroupfs) and rootless Podman under each user's systemd instance.
+var containerCgroupGlobs = []string{
+	"system.slice/docker-*.scope",
+	"docker/*",
+	"machine.slice/libpod-*.scope",
+	"libpod_parent/libpod-*",
+	"user.slice/user-*.slice/user@*.service/user.slice/libpod-*.scope",
+}
+
+// cgroupUsage is one reading of a container's cgroup counters.
+type cgroupUsage struct {
+	CPUUsec     uint64 // cumulative CPU time
+	MemoryBytes int64  // memory.current minus inactive_file, as docker stats reports
+	MemoryLimit int64  // 0 = unlimited
+	Pids        int64
+	IOBytes     uint64 // cumulative bytes read and written
+	At          time.Time
+}
+
+// containerStats is a container's resource usage over one sampling interval.
+type containerStats struct {
+	ID            string
+	Name          string
+	CPUPercent    float64 // 100 = one full core
+	MemoryBytes   int64
+	MemoryLimit   int64   // 0 = unlimited
+	MemoryPercent float64 // of MemoryLimit, or of host RAM when unlimited
+	Pids          int64
+	IOBytesPerSec float64
+}
+
+// findContainerCgroups maps full container IDs to their cgroup directories.
+func findContainerCgroups(root string) map[string]string {
+	dirs := make(map[string]string)
+	for _, pattern := range containerCgroupGlobs {
+		matches, _ := filepath.Glob(filepath.Join(root, pattern))
+		for _, dir := range matches {
+			id := strings.TrimSuffix(filepath.Base(dir), ".scope")
+			id = strings.TrimPrefix(strings.TrimPrefix(id, "docker-"), "libpod-")
+			if isContainerID(id) { // skips libpod-conmon-<id>.scope
+				dirs[id] = dir
+			}
+		}
+	}
+	return dirs
+}
+
+// isContainerID reports whether s is a full 64-character hex container ID.
+func isContainerID(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// readCgroupUsage reads the cgroup v2 counters in dir. Controllers that are
+// not enabled for the cgroup read as zero.
+func readCgroupUsage(dir string) (cgroupUsage, error) {
+	u := cgroupUsage{At: time.Now()}
+
+	cpu, err := readKeyedFile(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return u, err // the cgroup is gone
+	}
+	u.CPUUsec = uint64(cpu["usage_usec"])
+
+	u.MemoryBytes = readIntFile(filepath.Join(dir, "memory.current"))
+	if mem, err := readKeyedFile(filepath.Join(dir, "memory.stat")); err == nil && mem["inactive_file"] < u.MemoryBytes {
+		u.MemoryBytes -= mem["inactive_file"]
+	}
+	u.MemoryLimit = readIntFile(filepath.Join(dir, "memory.max")) // "max" reads as 0
+	u.Pids = readIntFile(filepath.Join(dir, "pids.current"))
+	u.IOBytes = readIOBytes(filepath.Join(dir, "io.stat"))
+	return u, nil
+}
+
+// readKeyedFile parses "key value" lines such as cpu.stat and memory.stat.
+func readKeyedFile(path string) (map[string]int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]int64)
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if v, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			values[fields[0]] = v
+		}
+	}
+	return values, nil
+}
+
+// readIntFile reads a single-value file; missing files and "max" read as 0.
+func readIntFile(path string) int64 {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	v, _ := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	return v
+}
+
+// readIOBytes sums rbytes and wbytes over all devices in io.stat, whose lines
+// look like "8:0 rbytes=1459200 wbytes=314773504 rios=192 wios=353 ...".
+func readIOBytes(path string) uint64 {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	var total uint64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		for _, field := range strings.Fields(scanner.Text()) {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok || (key != "rbytes" && key != "wbytes") {
+				continue
+			}
+			n, _ := strconv.ParseUint(value, 10, 64)
+			total += n
+		}
+	}
+	return total
+}
+
+// usageStats computes rates between two readings. hostMemory (bytes) is the
+// denominator for containers without a memory limit. A reset counter (the
+// container restarted) yields zero rates rather than a huge negative delta.
+func usageStats(prev, cur cgroupUsage, hostMemory int64) containerStats {
+	s := containerStats{
+		MemoryBytes: cur.MemoryBytes,
+		MemoryLimit: cur.MemoryLimit,
+		Pids:        cur.Pids,
+	}
+	limit := cur.MemoryLimit
+	if limit == 0 {
+		limit = hostMemory
+	}
+	if limit > 0 {
+		s.MemoryPercent = float64(cur.MemoryBytes) * 100 / float64(limit)
+	}
+
+	elapsed := cur.At.Sub(prev.At)
+	if elapsed <= 0 {
+		return s
+	}
+	if cur.CPUUsec >= prev.CPUUsec {
+		s.CPUPercent = float64(cur.CPUUsec-prev.CPUUsec) * 100 / float64(elapsed.Microseconds())
+	}
+	if cur.IOBytes >= prev.IOBytes {
+		s.IOBytesPerSec = float64(cur.IOBytes-prev.IOBytes) / elapsed.Seconds()
+	}
+	return s
+}
+
+// hostMemoryBytes returns MemTotal from /proc/meminfo, or 0.
+func hostMemoryBytes() int64 {
+	data, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, _ := strconv.ParseInt(fields[1], 10, 64)
+			return kb * 1024
+		}
+	}
+	return 0
+}
+
+// containerNames maps container IDs to names through the Engine API. It
+// returns an empty map when the daemon can't be reached; callers fall back
+// to short IDs.
+func containerNames(api *dockerapi.Client) map[string]string {
+	names := make(map[string]string)
+	if api == nil {
+		return names
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	list, err := api.ListContainers(ctx, false)
+	if err != nil {
+		return names
+	}
+	for _, c := range list {
+		names[c.ID] = c.Name()
+	}
+	return names
+}
+
+// displayName returns the container's name, or its short ID if unknown.
+func displayName(names map[string]string, id string) string {
+	if name := names[id]; name != "" {
+		return name
+	}
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// sampleContainerStats reads every container cgroup twice, interval apart,
+// and returns the usage sorted by memory, largest first.
+func sampleContainerStats(root string, api *dockerapi.Client, interval time.Duration) []containerStats {
+	dirs := findContainerCgroups(root)
+	first := make(map[string]cgroupUsage, len(dirs))
+	for id, dir := range dirs {
+		if u, err := readCgroupUsage(dir); err == nil {
+			first[id] = u
+		}
+	}
+	if len(first) == 0 {
+		return nil
+	}
+	time.Sleep(interval)
+
+	names := containerNames(api)
+	hostMem := hostMemoryBytes()
+	var stats []containerStats
+	for id, prev := range first {
+		cur, err := readCgroupUsage(dirs[id])
+		if err != nil {
+			continue
+		}
+		s := usageStats(prev, cur, hostMem)
+		s.ID, s.Name = id, displayName(names, id)
+		stats = append(stats, s)
+	}
+	sortByMemory(stats)
+	return stats
+}
+
+// sortByMemory orders stats by memory use, largest first.
+func sortByMemory(stats []containerStats) {
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].MemoryBytes != stats[j].MemoryBytes {
+			return stats[i].MemoryBytes > stats[j].MemoryBytes
+		}
+		return stats[i].Name < stats[j].Name
+	})
+}
@@ -0,0 +1,119 @@
+package watchers
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const (
+	testContainerA = "aaaaaaaaaaaa1111111111111111111111111111111111111111111111111111"
+	testContainerB = "bbbbbbbbbbbb2222222222222222222222222222222222222222222222222222"
+)
+
+// writeCgroup creates a fake cgroup directory under root with the given files.
+func writeCgroup(t *testing.T, root, rel string, files map[string]string) string {
+	t.Helper()
+	dir := filepath.Join(root, rel)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestFindContainerCgroups(t *testing.T) {
+	root := t.TempDir()
+	systemd := writeCgroup(t, root, "system.slice/docker-"+testContainerA+".scope", nil)
+	cgroupfs := writeCgroup(t, root, "docker/"+testContainerB, nil)
+	writeCgroup(t, root, "system.slice/docker.service", nil)
+	writeCgroup(t, root, "system.slice/docker-socket.scope", nil)
+
+	got := findContainerCgroups(root)
+	if len(got) != 2 || got[testContainerA] != systemd || got[testContainerB] != cgroupfs {
+		t.Errorf("cgroups = %v", got)
+	}
+}
+
+func TestFindContainerCgroups_Podman(t *testing.T) {
+	root := t.TempDir()
+	rootful := writeCgroup(t, root, "machine.slice/libpod-"+testContainerA+".scope", nil)
+	rootless := writeCgroup(t, root, "user.slice/user-1000.slice/user@1000.service/user.slice/libpod-"+testContainerB+".scope", nil)
+	writeCgroup(t, root, "machine.slice/libpod-conmon-"+testContainerA+".scope", nil)
+
+	got := findContainerCgroups(root)
+	if len(got) != 2 || got[testContainerA] != rootful || got[testContainerB] != rootless {
+		t.Errorf("cgroups = %v", got)
+	}
+}
+
+func TestReadCgroupUsage(t *testing.T) {
+	dir := writeCgroup(t, t.TempDir(), "docker/"+testContainerA, map[string]string{
+		"cpu.stat":       "usage_usec 5000000\nuser_usec 4000000\nsystem_usec 1000000\n",
+		"memory.current": "300000000\n",
+		"memory.stat":    "anon 200000000\nfile 100000000\ninactive_file 50000000\n",
+		"memory.max":     "536870912\n",
+		"pids.current":   "12\n",
+		"io.stat":        "8:0 rbytes=1000 wbytes=2000 rios=1 wios=2 dbytes=0 dios=0\n179:0 rbytes=500 wbytes=0 rios=1 wios=0\n",
+	})
+
+	u, err := readCgroupUsage(dir)
+	if err != nil {
+		t.Fatalf("readCgroupUsage: %v", err)
+	}
+	if u.CPUUsec != 5000000 || u.MemoryBytes != 250000000 || u.MemoryLimit != 512<<20 || u.Pids != 12 || u.IOBytes != 3500 {
+		t.Errorf("usage = %+v", u)
+	}
+}
+
+func TestReadCgroupUsage_UnlimitedAndMissingControllers(t *testing.T) {
+	dir := writeCgroup(t, t.TempDir(), "docker/"+testContainerA, map[string]string{
+		"cpu.stat":   "usage_usec 10\n",
+		"memory.max": "max\n",
+	})
+	u, err := readCgroupUsage(dir)
+	if err != nil {
+		t.Fatalf("readCgroupUsage: %v", err)
+	}
+	if u.MemoryLimit != 0 || u.MemoryBytes != 0 || u.IOBytes != 0 {
+		t.Errorf("usage = %+v, want zeros", u)
+	}
+
+	if _, err := readCgroupUsage(filepath.Join(t.TempDir(), "gone")); err == nil {
+		t.Error("expected an error for a removed cgroup")
+	}
+}
+
+func TestUsageStats(t *testing.T) {
+	t0 := time.Now()
+	prev := cgroupUsage{CPUUsec: 1_000_000, IOBytes: 0, At: t0}
+	cur := cgroupUsage{CPUUsec: 4_000_000, IOBytes: 20_000_000, MemoryBytes: 1 << 30, Pids: 7, At: t0.Add(2 * time.Second)}
+
+	s := usageStats(prev, cur, 4<<30)
+	if s.CPUPercent != 150 {
+		t.Errorf("CPUPercent = %v, want 150 (1.5 cores)", s.CPUPercent)
+	}
+	if s.IOBytesPerSec != 10_000_000 {
+		t.Errorf("IOBytesPerSec = %v, want 10e6", s.IOBytesPerSec)
+	}
+	if s.MemoryPercent != 25 {
+		t.Errorf("MemoryPercent = %v, want 25 (of host RAM)", s.MemoryPercent)
+	}
+
+	cur.MemoryLimit = 2 << 30
+	if s := usageStats(prev, cur, 4<<30); s.MemoryPercent != 50 {
+		t.Errorf("MemoryPercent = %v, want 50 (of limit)", s.MemoryPercent)
+	}
+
+	// Counters reset when the container restarts.
+	restarted := cgroupUsage{CPUUsec: 10, At: t0.Add(4 * time.Second)}
+	if s := usageStats(cur, restarted, 0); s.CPUPercent != 0 || math.IsNaN(s.MemoryPercent) {
+		t.Errorf("after reset = %+v, want zero rates", s)
+	}
+}
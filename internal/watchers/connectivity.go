@@ -3,44 +3,171 @@ package watchers
 import (
 	"context"
 	"fmt"
+	"html"
 	"log/slog"
 	"net"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/Fullex26/piguard/internal/config"
 	"github.com/Fullex26/piguard/internal/eventbus"
+	"github.com/Fullex26/piguard/internal/store"
 	"github.com/Fullex26/piguard/pkg/models"
 )
 
-// ConnectivityWatcher polls a set of TCP hosts and fires events when
-// connectivity is lost or restored.
+// minDegradedSamples is how many results a probe needs before its latency
+// and loss are judged, so one slow reply after startup isn't "degraded".
+const minDegradedSamples = 5
+
+// connState is the overall state of the link.
+type connState int
+
+const (
+	connOK connState = iota
+	connDegraded
+	connLost
+)
+
+// probeResult is the outcome of one probe run.
+type probeResult struct {
+	OK      bool
+	Latency time.Duration
+}
+
+// connProbe is one configured probe and its recent results.
+type connProbe struct {
+	Name         string
+	Type         string // "tcp", "icmp", "dns" or "http"
+	Target       string
+	Resolver     string
+	ExpectStatus int
+	Timeout      time.Duration
+
+	results []probeResult // oldest first, at most the watcher's window
+	lastErr string
+}
+
+// probeStats summarises a probe's window of results.
+type probeStats struct {
+	Samples int
+	Loss    int // percent
+	Avg     time.Duration
+	Max     time.Duration
+}
+
+func (p *connProbe) stats() probeStats {
+	s := probeStats{Samples: len(p.results)}
+	if s.Samples == 0 {
+		return s
+	}
+	var ok int
+	var total time.Duration
+	for _, r := range p.results {
+		if !r.OK {
+			continue
+		}
+		ok++
+		total += r.Latency
+		if r.Latency > s.Max {
+			s.Max = r.Latency
+		}
+	}
+	s.Loss = (s.Samples - ok) * 100 / s.Samples
+	if ok > 0 {
+		s.Avg = total / time.Duration(ok)
+	}
+	return s
+}
+
+// ConnectivityWatcher runs a set of TCP, ICMP, DNS and HTTP probes and
+// fires events when connectivity is lost, restored, or degraded by high
+// latency or packet loss. Outages are recorded in the store for /outages
+// and the availability figures in the reports.
 type ConnectivityWatcher struct {
 	Base
+	store       *store.Store // nil = outages not recorded
 	interval    time.Duration
 	hosts       []string
-	outageStart time.Time         // zero value means currently connected
-	dialFn      func(host string) bool // injectable for tests
+	probes      []*connProbe
+	window      int
+	maxLatency  time.Duration
+	maxLoss     int
+	outageStart time.Time // zero value means currently connected
+	outageID    int64     // store row of the current outage; 0 = not recorded
+
+	mu    sync.Mutex // guards probes' results and state for GetStatus
+	state connState
+
+	dialFn    func(host string, timeout time.Duration) bool             // injectable for tests
+	pingFn    func(host string, timeout time.Duration) error            // injectable for tests
+	resolveFn func(name, resolver string, timeout time.Duration) error  // injectable for tests
+	httpFn    func(url string, expect int, timeout time.Duration) error // injectable for tests
 }
 
-func NewConnectivityWatcher(cfg *config.Config, bus *eventbus.Bus) *ConnectivityWatcher {
-	interval, err := time.ParseDuration(cfg.Connectivity.PollInterval)
+func NewConnectivityWatcher(cfg *config.Config, bus *eventbus.Bus, db *store.Store) *ConnectivityWatcher {
+	cc := cfg.Connectivity
+	interval, err := time.ParseDuration(cc.PollInterval)
 	if err != nil || interval <= 0 {
 		interval = 30 * time.Second
 	}
 
-	hosts := cfg.Connectivity.Hosts
-	if len(hosts) == 0 {
+	hosts := cc.Hosts
+	if len(hosts) == 0 && len(cc.Probes) == 0 {
 		hosts = []string{"8.8.8.8:53", "1.1.1.1:53"}
 	}
 
+	window := cc.Window
+	if window <= 0 {
+		window = 20
+	}
+	maxLatency, err := time.ParseDuration(cc.DegradedLatency)
+	if err != nil || maxLatency <= 0 {
+		maxLatency = 500 * time.Millisecond
+	}
+	maxLoss := cc.DegradedLoss
+	if maxLoss <= 0 {
+		maxLoss = 20
+	}
+
 	w := &ConnectivityWatcher{
-		Base:     Base{Cfg: cfg, Bus: bus},
-		interval: interval,
-		hosts:    hosts,
+		Base:       Base{Cfg: cfg, Bus: bus},
+		store:      db,
+		interval:   interval,
+		hosts:      hosts,
+		window:     window,
+		maxLatency: maxLatency,
+		maxLoss:    maxLoss,
+		pingFn:     icmpEcho,
+		resolveFn:  resolveProbe,
+		httpFn:     httpProbe,
+	}
+	for _, h := range hosts {
+		w.probes = append(w.probes, &connProbe{Name: "tcp " + h, Type: "tcp", Target: h, Timeout: 3 * time.Second})
 	}
-	w.dialFn = func(host string) bool {
-		conn, err := net.DialTimeout("tcp", host, 3*time.Second)
+	for _, pc := range cc.Probes {
+		p := &connProbe{
+			Name:         pc.Name,
+			Type:         pc.Type,
+			Target:       pc.Target,
+			Resolver:     pc.Resolver,
+			ExpectStatus: pc.ExpectStatus,
+		}
+		if p.Name == "" {
+			p.Name = p.Type + " " + p.Target
+		}
+		if p.ExpectStatus == 0 {
+			p.ExpectStatus = 200
+		}
+		p.Timeout, err = time.ParseDuration(pc.Timeout)
+		if err != nil || p.Timeout <= 0 {
+			p.Timeout = 3 * time.Second
+		}
+		w.probes = append(w.probes, p)
+	}
+	w.dialFn = func(host string, timeout time.Duration) bool {
+		conn, err := net.DialTimeout("tcp", host, timeout)
 		if err != nil {
 			return false
 		}
@@ -54,7 +181,12 @@ func (w *ConnectivityWatcher) Name() string { return "connectivity" }
 func (w *ConnectivityWatcher) Stop() error  { return nil }
 
 func (w *ConnectivityWatcher) Start(ctx context.Context) error {
-	slog.Info("starting connectivity watcher", "interval", w.interval, "hosts", w.hosts)
+	names := make([]string, len(w.probes))
+	for i, p := range w.probes {
+		names[i] = p.Name
+	}
+	slog.Info("starting connectivity watcher", "interval", w.interval, "probes", names)
+	w.resumeOutage()
 
 	ticker := time.NewTicker(w.interval)
 	defer ticker.Stop()
@@ -69,12 +201,43 @@ func (w *ConnectivityWatcher) Start(ctx context.Context) error {
 	}
 }
 
+// run performs one probe and returns how long it took.
+func (w *ConnectivityWatcher) run(p *connProbe) (time.Duration, error) {
+	start := time.Now()
+	var err error
+	switch p.Type {
+	case "tcp":
+		if !w.dialFn(p.Target, p.Timeout) {
+			err = fmt.Errorf("no TCP connection to %s", p.Target)
+		}
+	case "icmp":
+		err = w.pingFn(p.Target, p.Timeout)
+	case "dns":
+		err = w.resolveFn(p.Target, p.Resolver, p.Timeout)
+	case "http":
+		err = w.httpFn(p.Target, p.ExpectStatus, p.Timeout)
+	default:
+		err = fmt.Errorf("unknown probe type %q", p.Type)
+	}
+	return time.Since(start), err
+}
+
 func (w *ConnectivityWatcher) check() {
 	reachable := false
-	for _, host := range w.hosts {
-		if w.dialFn(host) {
+	for _, p := range w.probes {
+		latency, err := w.run(p)
+		w.mu.Lock()
+		p.results = append(p.results, probeResult{OK: err == nil, Latency: latency})
+		if len(p.results) > w.window {
+			p.results = p.results[len(p.results)-w.window:]
+		}
+		p.lastErr = ""
+		if err != nil {
+			p.lastErr = err.Error()
+		}
+		w.mu.Unlock()
+		if err == nil {
 			reachable = true
-			break
 		}
 	}
 
@@ -83,14 +246,22 @@ func (w *ConnectivityWatcher) check() {
 	if !reachable && w.outageStart.IsZero() {
 		// Transition: connected → lost
 		w.outageStart = time.Now()
+		w.setState(connLost)
+		if w.store != nil {
+			id, err := w.store.StartOutage(w.outageStart)
+			if err != nil {
+				slog.Warn("recording outage failed", "error", err)
+			}
+			w.outageID = id
+		}
 		w.Bus.Publish(models.Event{
 			ID:        fmt.Sprintf("connectivity.lost-%d", w.outageStart.UnixNano()),
 			Type:      models.EventConnectivityLost,
 			Severity:  models.SeverityCritical,
 			Hostname:  hostname,
 			Timestamp: w.outageStart,
-			Message:   "Internet connectivity lost — all probe hosts unreachable",
-			Details:   fmt.Sprintf("Probed hosts: %v", w.hosts),
+			Message:   "Internet connectivity lost — all probes failed",
+			Details:   w.probeDetails(),
 			Suggested: "Check your router, ISP, or network interface",
 			Source:    "connectivity",
 		})
@@ -98,18 +269,177 @@ func (w *ConnectivityWatcher) check() {
 	}
 
 	if reachable && !w.outageStart.IsZero() {
-		// Transition: lost → restored
-		duration := time.Since(w.outageStart).Round(time.Second)
+		// Transition: lost → restored. The outage's failures would otherwise
+		// read as packet loss, so statistics start over.
+		now := time.Now()
+		duration := now.Sub(w.outageStart).Round(time.Second)
+		if w.store != nil && w.outageID != 0 {
+			if err := w.store.EndOutage(w.outageID, now); err != nil {
+				slog.Warn("recording outage end failed", "error", err)
+			}
+		}
+		w.mu.Lock()
+		for _, p := range w.probes {
+			p.results = p.results[len(p.results)-1:]
+		}
+		w.mu.Unlock()
+		w.setState(connOK)
 		w.Bus.Publish(models.Event{
-			ID:        fmt.Sprintf("connectivity.restored-%d", time.Now().UnixNano()),
+			ID:        fmt.Sprintf("connectivity.restored-%d", now.UnixNano()),
 			Type:      models.EventConnectivityRestored,
 			Severity:  models.SeverityInfo,
 			Hostname:  hostname,
-			Timestamp: time.Now(),
+			Timestamp: now,
 			Message:   fmt.Sprintf("Internet connectivity restored (outage: %s)", duration),
-			Details:   fmt.Sprintf("Probed hosts: %v", w.hosts),
+			Details:   w.probeDetails(),
 			Source:    "connectivity",
 		})
 		w.outageStart = time.Time{}
+		w.outageID = 0
+		return
+	}
+
+	if !reachable {
+		// Heartbeat: the outage lasts at least until now. If the daemon stops,
+		// this is where the outage is closed.
+		if w.store != nil && w.outageID != 0 {
+			if err := w.store.TouchOutage(w.outageID, time.Now()); err != nil {
+				slog.Warn("recording outage heartbeat failed", "error", err)
+			}
+		}
+		return
+	}
+	w.checkDegraded(hostname)
+}
+
+// resumeOutage picks up an outage that was still open when the daemon last
+// stopped. After a quick restart it is resumed, so its end is recorded (and
+// announced); otherwise it is closed at its last probe, since whether the
+// connection was down while the daemon or the Pi was off is unknown.
+func (w *ConnectivityWatcher) resumeOutage() {
+	if w.store == nil {
+		return
+	}
+	o, err := w.store.OpenOutage()
+	if err != nil {
+		return
+	}
+	last := o.Until(time.Now())
+	if time.Since(last) > 2*w.interval {
+		if err := w.store.EndOutage(o.ID, last); err != nil {
+			slog.Warn("closing stale outage failed", "error", err)
+		}
+		slog.Info("closed connectivity outage open since last run", "since", o.Start, "last_seen", last)
+		return
+	}
+	w.outageStart, w.outageID = o.Start, o.ID
+	w.setState(connLost)
+	slog.Info("resuming connectivity outage", "since", o.Start)
+}
+
+// checkDegraded moves between ok and degraded as probes cross the latency
+// and loss thresholds.
+func (w *ConnectivityWatcher) checkDegraded(hostname string) {
+	w.mu.Lock()
+	var reasons []string
+	for _, p := range w.probes {
+		s := p.stats()
+		if s.Samples < minDegradedSamples {
+			continue
+		}
+		switch {
+		case s.Loss > w.maxLoss:
+			reasons = append(reasons, fmt.Sprintf("%s %d%% loss", p.Name, s.Loss))
+		case s.Avg > w.maxLatency:
+			reasons = append(reasons, fmt.Sprintf("%s %s avg", p.Name, s.Avg.Round(time.Millisecond)))
+		}
+	}
+	prev := w.state
+	w.mu.Unlock()
+
+	switch {
+	case len(reasons) > 0 && prev == connOK:
+		w.setState(connDegraded)
+		w.Bus.Publish(models.Event{
+			ID:        fmt.Sprintf("connectivity.degraded-%d", time.Now().UnixNano()),
+			Type:      models.EventConnectivityDegraded,
+			Severity:  models.SeverityWarning,
+			Hostname:  hostname,
+			Timestamp: time.Now(),
+			Message:   "Internet connectivity degraded: " + strings.Join(reasons, ", "),
+			Details:   w.probeDetails(),
+			Suggested: fmt.Sprintf("Thresholds: %s average latency, %d%% loss over the last %d probes. "+
+				"Check Wi-Fi signal, cabling and router load; /status shows every probe", shortDuration(w.maxLatency), w.maxLoss, w.window),
+			Source: "connectivity",
+		})
+	case len(reasons) == 0 && prev == connDegraded:
+		w.setState(connOK)
+		w.Bus.Publish(models.Event{
+			ID:        fmt.Sprintf("connectivity.restored-%d", time.Now().UnixNano()),
+			Type:      models.EventConnectivityRestored,
+			Severity:  models.SeverityInfo,
+			Hostname:  hostname,
+			Timestamp: time.Now(),
+			Message:   "Internet connectivity no longer degraded",
+			Details:   w.probeDetails(),
+			Source:    "connectivity",
+		})
+	}
+}
+
+func (w *ConnectivityWatcher) setState(s connState) {
+	w.mu.Lock()
+	w.state = s
+	w.mu.Unlock()
+}
+
+// probeDetails renders every probe's statistics for an event's Details.
+func (w *ConnectivityWatcher) probeDetails() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	lines := make([]string, len(w.probes))
+	for i, p := range w.probes {
+		s := p.stats()
+		lines[i] = fmt.Sprintf("%s: %s avg, %s max, %d%% loss (%d probes)",
+			p.Name, s.Avg.Round(time.Millisecond), s.Max.Round(time.Millisecond), s.Loss, s.Samples)
+		if p.lastErr != "" {
+			lines[i] += " | last error: " + p.lastErr
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// GetStatus returns the link state and each probe's latency and loss for
+// the Telegram /status command.
+func (w *ConnectivityWatcher) GetStatus() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var b strings.Builder
+	switch w.state {
+	case connLost:
+		b.WriteString("🔴 Lost\n")
+	case connDegraded:
+		b.WriteString("⚠️ Degraded\n")
+	default:
+		b.WriteString("✅ OK\n")
+	}
+	for _, p := range w.probes {
+		s := p.stats()
+		icon := "✅"
+		switch {
+		case s.Samples == 0:
+			icon = "⏳"
+		case len(p.results) > 0 && !p.results[len(p.results)-1].OK:
+			icon = "❌"
+		case s.Samples >= minDegradedSamples && (s.Loss > w.maxLoss || s.Avg > w.maxLatency):
+			icon = "⚠️"
+		}
+		if s.Samples == 0 {
+			fmt.Fprintf(&b, "  %s %s: not probed yet\n", icon, html.EscapeString(p.Name))
+			continue
+		}
+		fmt.Fprintf(&b, "  %s %s: %s avg, %d%% loss\n", icon, html.EscapeString(p.Name), s.Avg.Round(time.Millisecond), s.Loss)
 	}
+	return strings.TrimSuffix(b.String(), "\n")
 }
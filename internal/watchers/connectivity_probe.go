@@ -0,0 +1,58 @@
+package watchers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// newResolver returns a resolver that queries resolver ("ip" or "ip:port")
+// directly, or the system resolver when resolver is empty.
+func newResolver(resolver string) *net.Resolver {
+	if resolver == "" {
+		return net.DefaultResolver
+	}
+	if _, _, err := net.SplitHostPort(resolver); err != nil {
+		resolver = net.JoinHostPort(resolver, "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, resolver)
+		},
+	}
+}
+
+// resolveProbe looks name up through resolver, or the system resolver when
+// resolver is empty.
+func resolveProbe(name, resolver string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	addrs, err := newResolver(resolver).LookupHost(ctx, name)
+	if err != nil {
+		return err
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("no addresses for %s", name)
+	}
+	return nil
+}
+
+// httpProbe GETs url, following redirects, and checks the final status.
+func httpProbe(url string, expect int, timeout time.Duration) error {
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode != expect {
+		return fmt.Errorf("status %d, want %d", resp.StatusCode, expect)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package watchers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPProbe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	if err := httpProbe(srv.URL+"/health", 204, time.Second); err != nil {
+		t.Errorf("expected status: %v", err)
+	}
+	err := httpProbe(srv.URL+"/missing", 200, time.Second)
+	if err == nil || !strings.Contains(err.Error(), "status 404") {
+		t.Errorf("error = %v, want status 404", err)
+	}
+}
+
+func TestResolveProbe(t *testing.T) {
+	if err := resolveProbe("localhost", "", time.Second); err != nil {
+		t.Errorf("localhost via the system resolver: %v", err)
+	}
+	// Nothing listens on port 1, so the lookup through it must fail.
+	if err := resolveProbe("example.com", "127.0.0.1:1", time.Second); err == nil {
+		t.Error("lookup through an unreachable resolver succeeded")
+	}
+}
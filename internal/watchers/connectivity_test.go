@@ -1,6 +1,9 @@
 package watchers
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -21,8 +24,8 @@ func newTestConnectivityWatcher(hosts []string, dialFn func(host string) bool) (
 			Hosts:        hosts,
 		},
 	}
-	w := NewConnectivityWatcher(cfg, bus)
-	w.dialFn = dialFn
+	w := NewConnectivityWatcher(cfg, bus, nil)
+	w.dialFn = func(host string, _ time.Duration) bool { return dialFn(host) }
 	return w, received
 }
 
@@ -30,7 +33,7 @@ func TestConnectivityWatcher_Name(t *testing.T) {
 	cfg := &config.Config{
 		Connectivity: config.ConnectivityConfig{PollInterval: "30s", Hosts: []string{"8.8.8.8:53"}},
 	}
-	w := NewConnectivityWatcher(cfg, eventbus.New())
+	w := NewConnectivityWatcher(cfg, eventbus.New(), nil)
 	if got := w.Name(); got != "connectivity" {
 		t.Errorf("Name() = %q, want %q", got, "connectivity")
 	}
@@ -122,7 +125,7 @@ func TestConnectivityWatcher_NilHosts_DefaultsApplied(t *testing.T) {
 			Hosts:        nil, // empty — constructor should default
 		},
 	}
-	w := NewConnectivityWatcher(cfg, eventbus.New())
+	w := NewConnectivityWatcher(cfg, eventbus.New(), nil)
 	if len(w.hosts) == 0 {
 		t.Error("expected default hosts when config hosts is nil")
 	}
@@ -136,3 +139,251 @@ func TestConnectivityWatcher_NilHosts_DefaultsApplied(t *testing.T) {
 		}
 	}
 }
+
+func newTestProbeWatcher(cc config.ConnectivityConfig) (*ConnectivityWatcher, chan models.Event) {
+	bus := eventbus.New()
+	received := make(chan models.Event, 10)
+	bus.Subscribe(func(e models.Event) { received <- e })
+	if cc.PollInterval == "" {
+		cc.PollInterval = "30s"
+	}
+	w := NewConnectivityWatcher(&config.Config{Connectivity: cc}, bus, nil)
+	return w, received
+}
+
+func TestConnectivityWatcher_ProbeTypes(t *testing.T) {
+	w, received := newTestProbeWatcher(config.ConnectivityConfig{
+		Hosts: []string{},
+		Probes: []config.ProbeConfig{
+			{Type: "icmp", Target: "192.168.1.1", Timeout: "1s"},
+			{Type: "dns", Target: "example.com", Resolver: "1.1.1.1"},
+			{Name: "status page", Type: "http", Target: "https://example.com/health", ExpectStatus: 204},
+		},
+	})
+	var calls []string
+	w.pingFn = func(host string, timeout time.Duration) error {
+		calls = append(calls, "icmp "+host+" "+timeout.String())
+		return nil
+	}
+	w.resolveFn = func(name, resolver string, _ time.Duration) error {
+		calls = append(calls, "dns "+name+" via "+resolver)
+		return nil
+	}
+	w.httpFn = func(url string, expect int, _ time.Duration) error {
+		calls = append(calls, fmt.Sprintf("http %s %d", url, expect))
+		return errors.New("status 500, want 204")
+	}
+
+	w.check()
+	expectNoEvent(t, received)
+	want := []string{"icmp 192.168.1.1 1s", "dns example.com via 1.1.1.1", "http https://example.com/health 204"}
+	if strings.Join(calls, "|") != strings.Join(want, "|") {
+		t.Errorf("calls = %q, want %q", calls, want)
+	}
+	if w.probes[2].Name != "status page" || w.probes[1].Name != "dns example.com" {
+		t.Errorf("probe names = %q, %q", w.probes[2].Name, w.probes[1].Name)
+	}
+	if w.probes[2].lastErr == "" {
+		t.Error("failed probe should keep its last error")
+	}
+}
+
+func TestConnectivityWatcher_ProbesOnly_NoDefaultHosts(t *testing.T) {
+	w, _ := newTestProbeWatcher(config.ConnectivityConfig{
+		Probes: []config.ProbeConfig{{Type: "icmp", Target: "192.168.1.1"}},
+	})
+	if len(w.hosts) != 0 || len(w.probes) != 1 {
+		t.Errorf("hosts = %v, probes = %d; want only the configured probe", w.hosts, len(w.probes))
+	}
+}
+
+func TestConnectivityWatcher_PacketLoss_Degraded(t *testing.T) {
+	w, received := newTestProbeWatcher(config.ConnectivityConfig{
+		Hosts:        []string{"8.8.8.8:53", "1.1.1.1:53"},
+		Window:       5,
+		DegradedLoss: 20,
+	})
+	round := 0
+	w.dialFn = func(host string, _ time.Duration) bool {
+		return host == "8.8.8.8:53" || round%2 == 0 // 1.1.1.1 drops every other probe
+	}
+
+	for round = 0; round < minDegradedSamples; round++ {
+		w.check()
+	}
+	e := awaitEvent(t, received)
+	if e.Type != models.EventConnectivityDegraded || e.Severity != models.SeverityWarning {
+		t.Fatalf("event = %s/%s, want degraded warning", e.Type, e.Severity)
+	}
+	if !strings.Contains(e.Message, "tcp 1.1.1.1:53 40% loss") || strings.Contains(e.Message, "8.8.8.8") {
+		t.Errorf("message = %q", e.Message)
+	}
+
+	// Still lossy: no repeat.
+	w.check()
+	expectNoEvent(t, received)
+
+	// A full window of clean results brings it back.
+	w.dialFn = func(string, time.Duration) bool { return true }
+	for i := 0; i < 5; i++ {
+		w.check()
+	}
+	e = awaitEvent(t, received)
+	if e.Type != models.EventConnectivityRestored || !strings.Contains(e.Message, "no longer degraded") {
+		t.Errorf("event = %s %q, want restored from degraded", e.Type, e.Message)
+	}
+	expectNoEvent(t, received)
+}
+
+func TestConnectivityWatcher_HighLatency_Degraded(t *testing.T) {
+	w, received := newTestProbeWatcher(config.ConnectivityConfig{
+		Hosts:           []string{"8.8.8.8:53"},
+		DegradedLatency: "5ms",
+	})
+	w.dialFn = func(string, time.Duration) bool {
+		time.Sleep(10 * time.Millisecond)
+		return true
+	}
+	for i := 0; i < minDegradedSamples-1; i++ {
+		w.check()
+	}
+	expectNoEvent(t, received) // too few samples to judge
+
+	w.check()
+	e := awaitEvent(t, received)
+	if e.Type != models.EventConnectivityDegraded || !strings.Contains(e.Message, "avg") {
+		t.Errorf("event = %s %q, want degraded by latency", e.Type, e.Message)
+	}
+}
+
+func TestConnectivityWatcher_RestoredAfterOutage_StatsReset(t *testing.T) {
+	up := true
+	w, received := newTestProbeWatcher(config.ConnectivityConfig{Hosts: []string{"8.8.8.8:53"}, Window: 5})
+	w.dialFn = func(string, time.Duration) bool { return up }
+
+	for i := 0; i < 3; i++ {
+		w.check()
+	}
+	up = false
+	w.check()
+	w.check()
+	if e := awaitEvent(t, received); e.Type != models.EventConnectivityLost {
+		t.Fatalf("event = %s, want lost", e.Type)
+	}
+	up = true
+	w.check()
+	if e := awaitEvent(t, received); e.Type != models.EventConnectivityRestored {
+		t.Fatalf("event = %s, want restored", e.Type)
+	}
+	// The outage's failures must not read as 40% loss afterwards.
+	for i := 0; i < 5; i++ {
+		w.check()
+	}
+	expectNoEvent(t, received)
+}
+
+func TestConnectivityWatcher_GetStatus(t *testing.T) {
+	w, _ := newTestProbeWatcher(config.ConnectivityConfig{
+		Hosts:  []string{"8.8.8.8:53"},
+		Probes: []config.ProbeConfig{{Name: "a<b", Type: "icmp", Target: "192.168.1.1"}},
+	})
+	if got := w.GetStatus(); !strings.Contains(got, "✅ OK") || !strings.Contains(got, "not probed yet") {
+		t.Errorf("status before probing = %q", got)
+	}
+
+	w.dialFn = func(string, time.Duration) bool { return true }
+	w.pingFn = func(string, time.Duration) error { return errors.New("timeout") }
+	w.check()
+	got := w.GetStatus()
+	if !strings.Contains(got, "✅ tcp 8.8.8.8:53:") || !strings.Contains(got, "0% loss") {
+		t.Errorf("status missing healthy probe: %q", got)
+	}
+	if !strings.Contains(got, "❌ a&lt;b:") || !strings.Contains(got, "100% loss") {
+		t.Errorf("status missing failed probe (HTML-escaped): %q", got)
+	}
+}
+
+func TestConnectivityWatcher_OutageRecorded(t *testing.T) {
+	db := openNetworkTestStore(t)
+	up := false
+	w, received := newTestConnectivityWatcher([]string{"8.8.8.8:53"}, func(string) bool { return up })
+	w.store = db
+
+	w.check()
+	awaitEvent(t, received) // lost
+	open, err := db.OpenOutage()
+	if err != nil {
+		t.Fatalf("outage not recorded as open: %v", err)
+	}
+	if !open.Start.Equal(w.outageStart) {
+		t.Errorf("recorded start = %v, want %v", open.Start, w.outageStart)
+	}
+
+	up = true
+	w.check()
+	awaitEvent(t, received) // restored
+	outages, _ := db.ListOutages(10)
+	if len(outages) != 1 || outages[0].End.IsZero() {
+		t.Fatalf("outages = %+v, want one ended outage", outages)
+	}
+}
+
+func TestConnectivityWatcher_ResumesOpenOutage(t *testing.T) {
+	db := openNetworkTestStore(t)
+	start := time.Now().Add(-10 * time.Minute)
+	id, _ := db.StartOutage(start)
+	db.TouchOutage(id, time.Now().Add(-20*time.Second)) // stopped mid-outage, restarted at once
+
+	w, received := newTestConnectivityWatcher([]string{"8.8.8.8:53"}, func(string) bool { return true })
+	w.store = db
+	w.resumeOutage()
+	if w.outageID != id || !w.outageStart.Equal(start) {
+		t.Fatalf("resumed outage #%d from %v, want #%d from %v", w.outageID, w.outageStart, id, start)
+	}
+
+	w.check()
+	e := awaitEvent(t, received)
+	if e.Type != models.EventConnectivityRestored || !strings.Contains(e.Message, "outage: 10m") {
+		t.Errorf("event = %s %q, want restored after the 10m outage", e.Type, e.Message)
+	}
+	if _, err := db.OpenOutage(); err == nil {
+		t.Error("resumed outage still open after connectivity returned")
+	}
+}
+
+func TestConnectivityWatcher_ClosesStaleOutage(t *testing.T) {
+	db := openNetworkTestStore(t)
+	start := time.Now().Add(-10 * time.Hour)
+	id, _ := db.StartOutage(start)
+	lastSeen := start.Add(3 * time.Minute)
+	db.TouchOutage(id, lastSeen) // then the Pi was off for hours
+
+	w, received := newTestConnectivityWatcher([]string{"8.8.8.8:53"}, func(string) bool { return true })
+	w.store = db
+	w.resumeOutage()
+	if w.outageID != 0 {
+		t.Fatalf("stale outage #%d resumed", w.outageID)
+	}
+	outages, _ := db.ListOutages(10)
+	if len(outages) != 1 || !outages[0].End.Equal(lastSeen) {
+		t.Fatalf("outages = %+v, want one closed at its last probe", outages)
+	}
+
+	w.check()
+	expectNoEvent(t, received) // no "restored" for an outage nobody saw end
+}
+
+func TestConnectivityWatcher_OutageHeartbeat(t *testing.T) {
+	db := openNetworkTestStore(t)
+	w, received := newTestConnectivityWatcher([]string{"8.8.8.8:53"}, func(string) bool { return false })
+	w.store = db
+
+	w.check()
+	awaitEvent(t, received) // lost
+	first, _ := db.OpenOutage()
+	time.Sleep(10 * time.Millisecond)
+	w.check()
+	if o, _ := db.OpenOutage(); !o.LastSeen.After(first.LastSeen) {
+		t.Errorf("last seen %v not advanced past %v", o.LastSeen, first.LastSeen)
+	}
+}
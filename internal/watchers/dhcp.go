@@ -0,0 +1,365 @@
+package watchers
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Fullex26/piguard/internal/config"
+	"github.com/Fullex26/piguard/internal/eventbus"
+	"github.com/Fullex26/piguard/pkg/models"
+)
+
+// DHCP wire constants (RFC 2131, RFC 2132).
+const (
+	dhcpServerPort    = 67
+	dhcpClientPort    = 68
+	dhcpHeaderLen     = 236 // fixed BOOTP header before the magic cookie
+	dhcpMinPacket     = 300 // BOOTP minimum; some servers drop anything shorter
+	dhcpMagicCookie   = 0x63825363
+	dhcpOpRequest     = 1
+	dhcpOpReply       = 2
+	dhcpFlagBroadcast = 0x8000
+
+	dhcpOptPad          = 0
+	dhcpOptSubnetMask   = 1
+	dhcpOptRouter       = 3
+	dhcpOptDNS          = 6
+	dhcpOptDomainName   = 15
+	dhcpOptLeaseTime    = 51
+	dhcpOptMessageType  = 53
+	dhcpOptServerID     = 54
+	dhcpOptParamRequest = 55
+	dhcpOptEnd          = 255
+
+	dhcpMsgDiscover = 1
+	dhcpMsgOffer    = 2
+)
+
+var errDHCPCheckUnsupported = errors.New("DHCP check not supported on this platform")
+
+// dhcpOffer is what one DHCP server offered in reply to our discover.
+type dhcpOffer struct {
+	Server  string // server identifier option, or the sender's address
+	YourIP  string
+	Routers []string
+	DNS     []string
+	Domain  string
+	Lease   time.Duration
+}
+
+// buildDHCPDiscover encodes a DHCPDISCOVER from mac with the broadcast flag
+// set, so offers come back broadcast even though we hold no address.
+func buildDHCPDiscover(xid uint32, mac net.HardwareAddr) []byte {
+	pkt := make([]byte, dhcpHeaderLen, dhcpMinPacket)
+	pkt[0] = dhcpOpRequest
+	pkt[1] = 1 // Ethernet
+	pkt[2] = byte(len(mac))
+	binary.BigEndian.PutUint32(pkt[4:8], xid)
+	binary.BigEndian.PutUint16(pkt[10:12], dhcpFlagBroadcast)
+	copy(pkt[28:44], mac)
+
+	pkt = binary.BigEndian.AppendUint32(pkt, dhcpMagicCookie)
+	pkt = appendDHCPOption(pkt, dhcpOptMessageType, []byte{dhcpMsgDiscover})
+	pkt = appendDHCPOption(pkt, dhcpOptParamRequest, []byte{
+		dhcpOptSubnetMask, dhcpOptRouter, dhcpOptDNS, dhcpOptDomainName, dhcpOptLeaseTime, dhcpOptServerID,
+	})
+	pkt = append(pkt, dhcpOptEnd)
+	for len(pkt) < dhcpMinPacket {
+		pkt = append(pkt, dhcpOptPad)
+	}
+	return pkt
+}
+
+func appendDHCPOption(pkt []byte, code byte, value []byte) []byte {
+	pkt = append(pkt, code, byte(len(value)))
+	return append(pkt, value...)
+}
+
+// parseDHCPOffer decodes a DHCPOFFER answering transaction xid. Anything
+// else on the client port (other clients' ACKs, stray replies) is rejected.
+func parseDHCPOffer(pkt []byte, xid uint32) (dhcpOffer, bool) {
+	if len(pkt) < dhcpHeaderLen+4 || pkt[0] != dhcpOpReply ||
+		binary.BigEndian.Uint32(pkt[4:8]) != xid ||
+		binary.BigEndian.Uint32(pkt[dhcpHeaderLen:]) != dhcpMagicCookie {
+		return dhcpOffer{}, false
+	}
+	offer := dhcpOffer{YourIP: net.IP(pkt[16:20]).String()}
+	msgType := 0
+	opts := pkt[dhcpHeaderLen+4:]
+	for i := 0; i < len(opts); {
+		code := opts[i]
+		if code == dhcpOptPad {
+			i++
+			continue
+		}
+		if code == dhcpOptEnd || i+1 >= len(opts) {
+			break
+		}
+		n := int(opts[i+1])
+		if i+2+n > len(opts) {
+			return dhcpOffer{}, false
+		}
+		val := opts[i+2 : i+2+n]
+		i += 2 + n
+
+		switch code {
+		case dhcpOptMessageType:
+			if n == 1 {
+				msgType = int(val[0])
+			}
+		case dhcpOptServerID:
+			if n == 4 {
+				offer.Server = net.IP(val).String()
+			}
+		case dhcpOptRouter:
+			offer.Routers = ipv4List(val)
+		case dhcpOptDNS:
+			offer.DNS = ipv4List(val)
+		case dhcpOptDomainName:
+			offer.Domain = strings.TrimRight(string(val), "\x00")
+		case dhcpOptLeaseTime:
+			if n == 4 {
+				offer.Lease = time.Duration(binary.BigEndian.Uint32(val)) * time.Second
+			}
+		}
+	}
+	if msgType != dhcpMsgOffer {
+		return dhcpOffer{}, false
+	}
+	return offer, true
+}
+
+func ipv4List(val []byte) []string {
+	var ips []string
+	for i := 0; i+4 <= len(val); i += 4 {
+		ips = append(ips, net.IP(val[i:i+4]).String())
+	}
+	return ips
+}
+
+// DHCPWatcher periodically broadcasts a DHCPDISCOVER and checks every offer
+// that comes back. A second DHCP server, or one handing out a different
+// router or DNS server, can silently redirect every client on the LAN.
+type DHCPWatcher struct {
+	Base
+	interval time.Duration
+	servers  map[string]bool // allowed server IPs; empty = any one server
+	routers  []string        // expected routers; empty = the default gateway
+	dns      []string        // expected DNS servers; empty = not checked
+
+	discover func() ([]dhcpOffer, error) // injectable for tests
+	gateway  func() string               // injectable for tests
+
+	alerted map[string]bool // conditions already alerted, until they clear
+}
+
+func NewDHCPWatcher(cfg *config.Config, bus *eventbus.Bus) *DHCPWatcher {
+	dc := cfg.Network.DHCP
+	interval, err := time.ParseDuration(dc.Interval)
+	if err != nil || interval <= 0 {
+		interval = 15 * time.Minute
+	}
+	timeout, err := time.ParseDuration(dc.Timeout)
+	if err != nil || timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	servers := make(map[string]bool, len(dc.Servers))
+	for _, ip := range dc.Servers {
+		servers[ip] = true
+	}
+	w := &DHCPWatcher{
+		Base:     Base{Cfg: cfg, Bus: bus},
+		interval: interval,
+		servers:  servers,
+		routers:  dc.Routers,
+		dns:      dc.DNS,
+		alerted:  make(map[string]bool),
+	}
+	w.gateway = func() string {
+		data, err := os.ReadFile("/proc/net/route")
+		if err != nil {
+			return ""
+		}
+		gw, _ := parseProcRoute(string(data))
+		return gw
+	}
+	w.discover = func() ([]dhcpOffer, error) {
+		iface := dc.Interface
+		if iface == "" {
+			iface = cfg.Network.Interface
+		}
+		if iface == "" {
+			if data, err := os.ReadFile("/proc/net/route"); err == nil {
+				_, iface = parseProcRoute(string(data))
+			}
+			if iface == "" {
+				return nil, fmt.Errorf("no default route; set network.dhcp.interface")
+			}
+		}
+		return dhcpDiscover(iface, timeout)
+	}
+	return w
+}
+
+func (w *DHCPWatcher) Name() string { return "dhcp-check" }
+func (w *DHCPWatcher) Stop() error  { return nil }
+
+func (w *DHCPWatcher) Start(ctx context.Context) error {
+	slog.Info("starting DHCP check", "interval", w.interval)
+	hostname, _ := os.Hostname()
+
+	w.check(hostname)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			w.check(hostname)
+		}
+	}
+}
+
+// check sends one discover and alerts on every unexpected server or option
+// in the offers. Each finding is alerted once, and again only after it has
+// cleared.
+func (w *DHCPWatcher) check(hostname string) {
+	offers, err := w.discover()
+	if err != nil {
+		slog.Warn("DHCP check failed", "error", err)
+		return
+	}
+	if len(offers) == 0 {
+		slog.Debug("no DHCP offers received")
+		return
+	}
+
+	byServer := make(map[string]dhcpOffer)
+	var servers []string
+	for _, o := range offers {
+		if _, ok := byServer[o.Server]; !ok {
+			byServer[o.Server] = o
+			servers = append(servers, o.Server)
+		}
+	}
+	sort.Strings(servers)
+
+	current := make(map[string]bool)
+	if len(w.servers) > 0 {
+		for _, s := range servers {
+			if w.servers[s] {
+				continue
+			}
+			o := byServer[s]
+			w.alertOnce(current, "rogue "+s, hostname, models.EventRogueDHCPServer,
+				fmt.Sprintf("Rogue DHCP server: %s is offering addresses", s),
+				dhcpOfferDetails(o),
+				fmt.Sprintf("Clients that take this offer use %s as their router and DNS. Find the device with "+
+					"/devices and unplug it, or add %s to network.dhcp.servers if it is legitimate", dhcpList(o.Routers), s))
+		}
+	} else if len(servers) > 1 {
+		var details []string
+		for _, s := range servers {
+			details = append(details, dhcpOfferDetails(byServer[s]))
+		}
+		w.alertOnce(current, "multiple "+strings.Join(servers, ","), hostname, models.EventRogueDHCPServer,
+			fmt.Sprintf("%d DHCP servers are answering: %s", len(servers), strings.Join(servers, ", ")),
+			strings.Join(details, "\n"),
+			"A LAN normally has one DHCP server. List the real one in network.dhcp.servers so the others are flagged as rogue")
+	}
+
+	routers := w.routers
+	if len(routers) == 0 {
+		if gw := w.gateway(); gw != "" {
+			routers = []string{gw}
+		}
+	}
+	for _, s := range servers {
+		o := byServer[s]
+		if bad := unexpectedIPs(o.Routers, routers); len(bad) > 0 {
+			w.alertOnce(current, "router "+s+" "+strings.Join(bad, ","), hostname, models.EventDHCPOptionMismatch,
+				fmt.Sprintf("DHCP server %s offers router %s, expected %s", s, strings.Join(bad, ", "), strings.Join(routers, ", ")),
+				dhcpOfferDetails(o),
+				"Clients taking this lease send all their traffic through that router. Check the DHCP server's settings, "+
+					"or set network.dhcp.routers if the gateway moved")
+		}
+		if bad := unexpectedIPs(o.DNS, w.dns); len(bad) > 0 {
+			w.alertOnce(current, "dns "+s+" "+strings.Join(bad, ","), hostname, models.EventDHCPOptionMismatch,
+				fmt.Sprintf("DHCP server %s offers DNS %s, expected %s", s, strings.Join(bad, ", "), strings.Join(w.dns, ", ")),
+				dhcpOfferDetails(o),
+				"A changed DNS server in DHCP offers redirects every client's lookups. Check the DHCP server's settings, "+
+					"or update network.dhcp.dns if the change was intended")
+		}
+	}
+
+	w.alerted = current
+}
+
+// alertOnce records key as currently true and publishes the event unless it
+// was already alerted on the previous check.
+func (w *DHCPWatcher) alertOnce(current map[string]bool, key, hostname string, evType models.EventType,
+	msg, details, suggested string) {
+	current[key] = true
+	if w.alerted[key] {
+		return
+	}
+	w.Bus.Publish(models.Event{
+		ID:        fmt.Sprintf("%s-%d", string(evType), time.Now().UnixNano()),
+		Type:      evType,
+		Severity:  models.SeverityCritical,
+		Hostname:  hostname,
+		Timestamp: time.Now(),
+		Message:   msg,
+		Details:   details,
+		Suggested: suggested,
+		Source:    "dhcp-check",
+	})
+}
+
+func dhcpOfferDetails(o dhcpOffer) string {
+	details := fmt.Sprintf("Server: %s | Offered IP: %s | Router: %s | DNS: %s",
+		o.Server, o.YourIP, dhcpList(o.Routers), dhcpList(o.DNS))
+	if o.Domain != "" {
+		details += " | Domain: " + o.Domain
+	}
+	if o.Lease > 0 {
+		details += " | Lease: " + shortDuration(o.Lease)
+	}
+	return details
+}
+
+func dhcpList(ips []string) string {
+	if len(ips) == 0 {
+		return "none"
+	}
+	return strings.Join(ips, ", ")
+}
+
+// unexpectedIPs returns the offered addresses not in expected. Nothing is
+// unexpected when no expectation is set.
+func unexpectedIPs(offered, expected []string) []string {
+	if len(expected) == 0 {
+		return nil
+	}
+	want := make(map[string]bool, len(expected))
+	for _, ip := range expected {
+		want[ip] = true
+	}
+	var bad []string
+	for _, ip := range offered {
+		if !want[ip] {
+			bad = append(bad, ip)
+		}
+	}
+	return bad
+}
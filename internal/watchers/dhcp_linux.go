@@ -0,0 +1,80 @@
+//go:build linux
+
+package watchers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// dhcpDiscover broadcasts a DHCPDISCOVER on the interface and returns every
+// offer received within timeout. The client port is bound with SO_REUSEADDR
+// so a running DHCP client that also set it keeps working; binding port 68
+// and the interface needs root (or CAP_NET_BIND_SERVICE and CAP_NET_RAW).
+func dhcpDiscover(ifaceName string, timeout time.Duration) ([]dhcpOffer, error) {
+	iface, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return nil, err
+	}
+	if len(iface.HardwareAddr) != 6 {
+		return nil, fmt.Errorf("interface %s has no Ethernet address", ifaceName)
+	}
+
+	lc := net.ListenConfig{Control: func(network, address string, c syscall.RawConn) error {
+		var sockErr error
+		err := c.Control(func(fd uintptr) {
+			for _, opt := range []int{unix.SO_REUSEADDR, unix.SO_BROADCAST} {
+				if sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, opt, 1); sockErr != nil {
+					return
+				}
+			}
+			sockErr = unix.BindToDevice(int(fd), ifaceName)
+		})
+		if err != nil {
+			return err
+		}
+		return sockErr
+	}}
+	conn, err := lc.ListenPacket(context.Background(), "udp4", fmt.Sprintf(":%d", dhcpClientPort))
+	if err != nil {
+		return nil, fmt.Errorf("DHCP client port: %w", err)
+	}
+	defer conn.Close()
+
+	xid := rand.Uint32()
+	dst := &net.UDPAddr{IP: net.IPv4bcast, Port: dhcpServerPort}
+	if _, err := conn.WriteTo(buildDHCPDiscover(xid, iface.HardwareAddr), dst); err != nil {
+		return nil, fmt.Errorf("DHCP discover: %w", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(timeout))
+
+	var offers []dhcpOffer
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				return offers, nil
+			}
+			return offers, err
+		}
+		offer, ok := parseDHCPOffer(buf[:n], xid)
+		if !ok {
+			continue
+		}
+		if offer.Server == "" {
+			if addr, ok := from.(*net.UDPAddr); ok {
+				offer.Server = addr.IP.String()
+			}
+		}
+		offers = append(offers, offer)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package watchers
+
+import "time"
+
+// dhcpDiscover is unavailable off Linux, where binding the client port to
+// one interface isn't supported.
+func dhcpDiscover(ifaceName string, timeout time.Duration) ([]dhcpOffer, error) {
+	return nil, errDHCPCheckUnsupported
+}
@@ -0,0 +1,221 @@
+package watchers
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fullex26/piguard/internal/config"
+	"github.com/Fullex26/piguard/internal/eventbus"
+	"github.com/Fullex26/piguard/pkg/models"
+)
+
+// buildTestOffer encodes a DHCPOFFER as a server would send it.
+func buildTestOffer(xid uint32, server, yiaddr string, routers, dns []string) []byte {
+	pkt := make([]byte, dhcpHeaderLen)
+	pkt[0] = dhcpOpReply
+	pkt[1] = 1
+	pkt[2] = 6
+	binary.BigEndian.PutUint32(pkt[4:8], xid)
+	copy(pkt[16:20], net.ParseIP(yiaddr).To4())
+	pkt = binary.BigEndian.AppendUint32(pkt, dhcpMagicCookie)
+	pkt = appendDHCPOption(pkt, dhcpOptMessageType, []byte{dhcpMsgOffer})
+	if server != "" {
+		pkt = appendDHCPOption(pkt, dhcpOptServerID, net.ParseIP(server).To4())
+	}
+	ipOpt := func(ips []string) []byte {
+		var b []byte
+		for _, ip := range ips {
+			b = append(b, net.ParseIP(ip).To4()...)
+		}
+		return b
+	}
+	if len(routers) > 0 {
+		pkt = appendDHCPOption(pkt, dhcpOptRouter, ipOpt(routers))
+	}
+	if len(dns) > 0 {
+		pkt = appendDHCPOption(pkt, dhcpOptDNS, ipOpt(dns))
+	}
+	pkt = append(pkt, dhcpOptPad, dhcpOptPad) // padding between options is legal
+	pkt = appendDHCPOption(pkt, dhcpOptDomainName, []byte("lan"))
+	pkt = appendDHCPOption(pkt, dhcpOptLeaseTime, binary.BigEndian.AppendUint32(nil, 86400))
+	return append(pkt, dhcpOptEnd)
+}
+
+func TestBuildDHCPDiscover(t *testing.T) {
+	mac, _ := net.ParseMAC("b8:27:eb:12:34:56")
+	pkt := buildDHCPDiscover(0xdeadbeef, mac)
+
+	if len(pkt) < dhcpMinPacket {
+		t.Errorf("len = %d, want at least %d", len(pkt), dhcpMinPacket)
+	}
+	if pkt[0] != dhcpOpRequest || pkt[1] != 1 || pkt[2] != 6 {
+		t.Errorf("op/htype/hlen = %d/%d/%d, want 1/1/6", pkt[0], pkt[1], pkt[2])
+	}
+	if got := binary.BigEndian.Uint32(pkt[4:8]); got != 0xdeadbeef {
+		t.Errorf("xid = %#x", got)
+	}
+	if got := binary.BigEndian.Uint16(pkt[10:12]); got != dhcpFlagBroadcast {
+		t.Errorf("flags = %#x, want broadcast", got)
+	}
+	if got := net.HardwareAddr(pkt[28:34]).String(); got != "b8:27:eb:12:34:56" {
+		t.Errorf("chaddr = %s", got)
+	}
+	if got := binary.BigEndian.Uint32(pkt[dhcpHeaderLen:]); got != dhcpMagicCookie {
+		t.Errorf("magic cookie = %#x", got)
+	}
+	opts := pkt[dhcpHeaderLen+4:]
+	if opts[0] != dhcpOptMessageType || opts[1] != 1 || opts[2] != dhcpMsgDiscover {
+		t.Errorf("first option = % x, want message type DISCOVER", opts[:3])
+	}
+	if opts[3] != dhcpOptParamRequest {
+		t.Fatalf("second option = %d, want parameter request list", opts[3])
+	}
+	requested := opts[5 : 5+opts[4]]
+	for _, want := range []byte{dhcpOptRouter, dhcpOptDNS, dhcpOptServerID} {
+		if !strings.Contains(string(requested), string([]byte{want})) {
+			t.Errorf("parameter request list % x is missing option %d", requested, want)
+		}
+	}
+}
+
+func TestParseDHCPOffer(t *testing.T) {
+	pkt := buildTestOffer(42, "192.168.1.1", "192.168.1.77", []string{"192.168.1.1"}, []string{"192.168.1.2", "1.1.1.1"})
+	offer, ok := parseDHCPOffer(pkt, 42)
+	if !ok {
+		t.Fatal("valid offer rejected")
+	}
+	if offer.Server != "192.168.1.1" || offer.YourIP != "192.168.1.77" {
+		t.Errorf("server/yiaddr = %s/%s", offer.Server, offer.YourIP)
+	}
+	if strings.Join(offer.Routers, ",") != "192.168.1.1" {
+		t.Errorf("routers = %v", offer.Routers)
+	}
+	if strings.Join(offer.DNS, ",") != "192.168.1.2,1.1.1.1" {
+		t.Errorf("dns = %v", offer.DNS)
+	}
+	if offer.Domain != "lan" || offer.Lease != 24*time.Hour {
+		t.Errorf("domain/lease = %q/%s", offer.Domain, offer.Lease)
+	}
+
+	if _, ok := parseDHCPOffer(pkt, 43); ok {
+		t.Error("offer for another transaction accepted")
+	}
+	if _, ok := parseDHCPOffer(pkt[:100], 42); ok {
+		t.Error("truncated packet accepted")
+	}
+	ack := append([]byte(nil), pkt...)
+	ack[dhcpHeaderLen+6] = 5 // DHCPACK
+	if _, ok := parseDHCPOffer(ack, 42); ok {
+		t.Error("DHCPACK accepted as an offer")
+	}
+	bad := append([]byte(nil), pkt[:len(pkt)-1]...)
+	bad = append(bad, dhcpOptDNS, 200, 1, 2) // length runs past the end
+	if _, ok := parseDHCPOffer(bad, 42); ok {
+		t.Error("option overrunning the packet accepted")
+	}
+}
+
+func newTestDHCPWatcher(dc config.DHCPCheckConfig, offers ...dhcpOffer) (*DHCPWatcher, chan models.Event) {
+	bus := eventbus.New()
+	received := make(chan models.Event, 10)
+	bus.Subscribe(func(e models.Event) { received <- e })
+
+	w := NewDHCPWatcher(&config.Config{Network: config.NetworkConfig{DHCP: dc}}, bus)
+	w.gateway = func() string { return "192.168.1.1" }
+	w.discover = func() ([]dhcpOffer, error) { return offers, nil }
+	return w, received
+}
+
+var goodOffer = dhcpOffer{Server: "192.168.1.1", YourIP: "192.168.1.77", Routers: []string{"192.168.1.1"}, DNS: []string{"192.168.1.2"}}
+
+func TestDHCPWatcher_SingleExpectedServer(t *testing.T) {
+	w, received := newTestDHCPWatcher(config.DHCPCheckConfig{DNS: []string{"192.168.1.2"}}, goodOffer)
+	w.check("pi")
+	expectNoEvent(t, received)
+}
+
+func TestDHCPWatcher_ServerNotAllowed(t *testing.T) {
+	rogue := dhcpOffer{Server: "192.168.1.66", YourIP: "192.168.1.150", Routers: []string{"192.168.1.1"}}
+	w, received := newTestDHCPWatcher(config.DHCPCheckConfig{Servers: []string{"192.168.1.1"}}, goodOffer, rogue)
+
+	w.check("pi")
+	e := awaitEvent(t, received)
+	if e.Type != models.EventRogueDHCPServer || e.Severity != models.SeverityCritical {
+		t.Errorf("event = %s/%s, want rogue DHCP critical", e.Type, e.Severity)
+	}
+	if !strings.Contains(e.Message, "192.168.1.66") || !strings.Contains(e.Details, "Offered IP: 192.168.1.150") {
+		t.Errorf("message/details = %q / %q", e.Message, e.Details)
+	}
+	expectNoEvent(t, received)
+
+	// Still there on the next check: not alerted again.
+	w.check("pi")
+	expectNoEvent(t, received)
+
+	// Gone, then back: alerted again.
+	w.discover = func() ([]dhcpOffer, error) { return []dhcpOffer{goodOffer}, nil }
+	w.check("pi")
+	w.discover = func() ([]dhcpOffer, error) { return []dhcpOffer{goodOffer, rogue}, nil }
+	w.check("pi")
+	if e := awaitEvent(t, received); e.Type != models.EventRogueDHCPServer {
+		t.Errorf("event = %s, want rogue DHCP after it reappeared", e.Type)
+	}
+}
+
+func TestDHCPWatcher_MultipleServersWithoutAllowlist(t *testing.T) {
+	second := dhcpOffer{Server: "192.168.1.66", YourIP: "10.0.0.5", Routers: []string{"192.168.1.1"}}
+	w, received := newTestDHCPWatcher(config.DHCPCheckConfig{}, goodOffer, goodOffer, second)
+
+	w.check("pi")
+	e := awaitEvent(t, received)
+	if e.Type != models.EventRogueDHCPServer || !strings.Contains(e.Message, "2 DHCP servers") {
+		t.Errorf("event = %s %q, want 2 DHCP servers", e.Type, e.Message)
+	}
+	expectNoEvent(t, received)
+}
+
+func TestDHCPWatcher_RouterDiffersFromGateway(t *testing.T) {
+	hijacked := goodOffer
+	hijacked.Routers = []string{"192.168.1.66"}
+	w, received := newTestDHCPWatcher(config.DHCPCheckConfig{}, hijacked)
+
+	w.check("pi")
+	e := awaitEvent(t, received)
+	if e.Type != models.EventDHCPOptionMismatch {
+		t.Fatalf("event = %s, want DHCP option mismatch", e.Type)
+	}
+	if !strings.Contains(e.Message, "router 192.168.1.66, expected 192.168.1.1") {
+		t.Errorf("message = %q", e.Message)
+	}
+}
+
+func TestDHCPWatcher_ConfiguredRoutersOverrideGateway(t *testing.T) {
+	offer := goodOffer
+	offer.Routers = []string{"192.168.1.254"}
+	w, received := newTestDHCPWatcher(config.DHCPCheckConfig{Routers: []string{"192.168.1.254"}}, offer)
+	w.check("pi")
+	expectNoEvent(t, received)
+}
+
+func TestDHCPWatcher_UnexpectedDNS(t *testing.T) {
+	offer := goodOffer
+	offer.DNS = []string{"192.168.1.2", "203.0.113.53"}
+	w, received := newTestDHCPWatcher(config.DHCPCheckConfig{DNS: []string{"192.168.1.2"}}, offer)
+
+	w.check("pi")
+	e := awaitEvent(t, received)
+	if e.Type != models.EventDHCPOptionMismatch || !strings.Contains(e.Message, "DNS 203.0.113.53") {
+		t.Errorf("event = %s %q, want DNS mismatch for 203.0.113.53", e.Type, e.Message)
+	}
+}
+
+func TestDHCPWatcher_DiscoverFails(t *testing.T) {
+	w, received := newTestDHCPWatcher(config.DHCPCheckConfig{})
+	w.discover = func() ([]dhcpOffer, error) { return nil, errors.New("address in use") }
+	w.check("pi")
+	expectNoEvent(t, received)
+}
@@ -0,0 +1,338 @@
+package watchers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Fullex26/piguard/internal/config"
+	"github.com/Fullex26/piguard/internal/eventbus"
+	"github.com/Fullex26/piguard/internal/store"
+	"github.com/Fullex26/piguard/pkg/models"
+)
+
+// resolvedConf lists systemd-resolved's upstream servers; /etc/resolv.conf
+// only names its 127.0.0.53 stub.
+const resolvedConf = "/run/systemd/resolve/resolv.conf"
+
+// dnsStatePrefix persists each resolver file's nameservers so a change made
+// while the daemon was down is still caught.
+const dnsStatePrefix = "dns.resolvers:"
+
+// dnsCanary is a name whose answers are checked each poll.
+type dnsCanary struct {
+	Name   string
+	Expect []*net.IPNet // empty = compare with the trusted resolver
+}
+
+// dnsVerdict is a canary's outcome in one check.
+type dnsVerdict int
+
+const (
+	dnsOK dnsVerdict = iota
+	dnsFailed
+	dnsMismatched
+)
+
+// DNSWatcher watches the nameservers the system uses and resolves canary
+// names through them, alerting when resolvers change, when answers fall
+// outside the expected addresses or disagree with a trusted resolver
+// (hijacked or poisoned DNS), and when lookups fail.
+type DNSWatcher struct {
+	Base
+	store    *store.Store // nil = resolvers not persisted
+	interval time.Duration
+	timeout  time.Duration
+	trusted  string
+	canaries []dnsCanary
+	files    []string // resolv.conf files whose nameservers are tracked
+
+	resolvers map[string]string     // file → last seen nameservers
+	verdicts  map[string]dnsVerdict // canary name → last verdict
+
+	readFile func(string) ([]byte, error)                                         // injectable for tests
+	lookupFn func(name, resolver string, timeout time.Duration) ([]string, error) // injectable for tests
+}
+
+func NewDNSWatcher(cfg *config.Config, bus *eventbus.Bus, db *store.Store) *DNSWatcher {
+	dc := cfg.DNS
+	interval, err := time.ParseDuration(dc.PollInterval)
+	if err != nil || interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	timeout, err := time.ParseDuration(dc.Timeout)
+	if err != nil || timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	resolvConf := dc.ResolvConf
+	if resolvConf == "" {
+		resolvConf = "/etc/resolv.conf"
+	}
+
+	w := &DNSWatcher{
+		Base:      Base{Cfg: cfg, Bus: bus},
+		store:     db,
+		interval:  interval,
+		timeout:   timeout,
+		trusted:   dc.TrustedResolver,
+		files:     []string{resolvConf, resolvedConf},
+		resolvers: make(map[string]string),
+		verdicts:  make(map[string]dnsVerdict),
+		readFile:  os.ReadFile,
+		lookupFn:  lookupIPv4,
+	}
+	for _, c := range dc.Canaries {
+		canary := dnsCanary{Name: c.Name}
+		for _, e := range c.Expect {
+			if n := parseIPOrCIDR(e); n != nil {
+				canary.Expect = append(canary.Expect, n)
+			}
+		}
+		w.canaries = append(w.canaries, canary)
+	}
+	return w
+}
+
+func (w *DNSWatcher) Name() string { return "dns" }
+func (w *DNSWatcher) Stop() error  { return nil }
+
+func (w *DNSWatcher) Start(ctx context.Context) error {
+	slog.Info("starting DNS watcher", "interval", w.interval, "canaries", len(w.canaries), "trusted", w.trusted)
+	hostname, _ := os.Hostname()
+	w.check(hostname)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			w.check(hostname)
+		}
+	}
+}
+
+func (w *DNSWatcher) check(hostname string) {
+	for _, file := range w.files {
+		w.checkResolvers(hostname, file)
+	}
+	for _, c := range w.canaries {
+		w.checkCanary(hostname, c)
+	}
+}
+
+// checkResolvers compares a resolv.conf file's nameservers with the last
+// ones seen. A missing file (no systemd-resolved) is skipped.
+func (w *DNSWatcher) checkResolvers(hostname, file string) {
+	data, err := w.readFile(file)
+	if err != nil {
+		return
+	}
+	current := strings.Join(parseNameservers(string(data)), ", ")
+
+	prev, known := w.resolvers[file]
+	if !known && w.store != nil {
+		if saved, err := w.store.GetState(dnsStatePrefix + file); err == nil {
+			prev, known = saved, true
+		}
+	}
+	w.resolvers[file] = current
+	if known && prev == current {
+		return
+	}
+	if w.store != nil {
+		w.store.SetState(dnsStatePrefix+file, current)
+	}
+	if !known {
+		return // first sighting
+	}
+
+	w.emit(hostname, models.EventDNSResolverChanged, models.SeverityWarning, file,
+		fmt.Sprintf("DNS resolvers changed in %s: %s → %s", file, orNone(prev), orNone(current)),
+		fmt.Sprintf("File: %s | Previous: %s | Now: %s", file, orNone(prev), orNone(current)),
+		"If you didn't change DNS settings, check who did: DHCP (see /status and the DHCP check), "+
+			"NetworkManager or a process with root. Malware often points DNS at its own server")
+}
+
+// checkCanary resolves a canary through the system resolver and judges the
+// answers against its expected addresses or the trusted resolver. A change
+// of verdict is alerted once; a return to correct answers is reported.
+func (w *DNSWatcher) checkCanary(hostname string, c dnsCanary) {
+	verdict, msg, details := w.judge(c)
+	prev := w.verdicts[c.Name]
+	w.verdicts[c.Name] = verdict
+	if verdict == prev {
+		return
+	}
+
+	switch verdict {
+	case dnsMismatched:
+		w.emit(hostname, models.EventDNSMismatch, models.SeverityCritical, c.Name, msg, details,
+			fmt.Sprintf("DNS may be hijacked or poisoned. Compare `dig %s` with `dig @1.1.1.1 %s`, check the "+
+				"router's DNS settings and /etc/resolv.conf. If the address legitimately changed, update dns.canaries", c.Name, c.Name))
+	case dnsFailed:
+		w.emit(hostname, models.EventDNSFailure, models.SeverityWarning, c.Name, msg, details,
+			"Check the resolvers in /etc/resolv.conf answer (`resolvectl status`, `dig "+c.Name+"`); "+
+				"a local DNS server such as Pi-hole may be down")
+	case dnsOK:
+		w.emit(hostname, models.EventDNSRestored, models.SeverityInfo, c.Name,
+			fmt.Sprintf("DNS for %s resolves correctly again", c.Name), details, "")
+	}
+}
+
+// judge returns the canary's verdict with an alert message and details.
+// When the trusted resolver can't answer either, the internet is down
+// rather than DNS broken, so the previous verdict stands.
+func (w *DNSWatcher) judge(c dnsCanary) (dnsVerdict, string, string) {
+	got, err := w.lookupFn(c.Name, "", w.timeout)
+	if err != nil {
+		details := "Resolver: system"
+		if w.trusted != "" {
+			want, terr := w.lookupFn(c.Name, w.trusted, w.timeout)
+			if terr != nil {
+				return w.verdicts[c.Name], "", ""
+			}
+			details += fmt.Sprintf(" | Trusted resolver %s answered: %s", w.trusted, strings.Join(want, ", "))
+		}
+		return dnsFailed, fmt.Sprintf("DNS lookup of %s failed: %v", c.Name, err), details
+	}
+
+	if len(c.Expect) > 0 {
+		expect := make([]string, len(c.Expect))
+		for i, n := range c.Expect {
+			expect[i] = n.String()
+			if ones, bits := n.Mask.Size(); ones == bits {
+				expect[i] = n.IP.String()
+			}
+		}
+		details := fmt.Sprintf("Answers: %s | Expected: %s", strings.Join(got, ", "), strings.Join(expect, ", "))
+		if bad := outsideNets(got, c.Expect); len(bad) > 0 {
+			return dnsMismatched, fmt.Sprintf("DNS answer mismatch for %s: got %s", c.Name, strings.Join(bad, ", ")), details
+		}
+		return dnsOK, "", details
+	}
+
+	if w.trusted == "" {
+		return dnsOK, "", "Answers: " + strings.Join(got, ", ")
+	}
+	want, terr := w.lookupFn(c.Name, w.trusted, w.timeout)
+	if terr != nil {
+		return w.verdicts[c.Name], "", "" // nothing to compare with
+	}
+	details := fmt.Sprintf("System resolver: %s | Trusted resolver %s: %s",
+		strings.Join(got, ", "), w.trusted, strings.Join(want, ", "))
+	if !overlaps(got, want) {
+		return dnsMismatched, fmt.Sprintf("DNS answer mismatch for %s: got %s, trusted resolver %s says %s",
+			c.Name, strings.Join(got, ", "), w.trusted, strings.Join(want, ", ")), details
+	}
+	return dnsOK, "", details
+}
+
+func (w *DNSWatcher) emit(hostname string, evType models.EventType, sev models.Severity,
+	subject, msg, details, suggested string) {
+	w.Bus.Publish(models.Event{
+		ID:        fmt.Sprintf("%s-%s-%d", evType, subject, time.Now().UnixNano()),
+		Type:      evType,
+		Severity:  sev,
+		Hostname:  hostname,
+		Timestamp: time.Now(),
+		Message:   msg,
+		Details:   details,
+		Suggested: suggested,
+		Source:    "dns",
+	})
+}
+
+// lookupIPv4 returns name's IPv4 addresses from resolver, or the system
+// resolver when resolver is empty, sorted. Only A records are compared:
+// a host without IPv6 never sees AAAA answers go wrong.
+func lookupIPv4(name, resolver string, timeout time.Duration) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	ips, err := newResolver(resolver).LookupIP(ctx, "ip4", name)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]string, len(ips))
+	for i, ip := range ips {
+		addrs[i] = ip.String()
+	}
+	sort.Strings(addrs)
+	return addrs, nil
+}
+
+// parseNameservers returns the nameserver entries of a resolv.conf file.
+func parseNameservers(data string) []string {
+	var servers []string
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			servers = append(servers, fields[1])
+		}
+	}
+	return servers
+}
+
+// parseIPOrCIDR parses "10.0.0.0/8" or a bare address as a single-host net.
+func parseIPOrCIDR(s string) *net.IPNet {
+	if _, n, err := net.ParseCIDR(s); err == nil {
+		return n
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return &net.IPNet{IP: v4, Mask: net.CIDRMask(32, 32)}
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}
+}
+
+// outsideNets returns the addresses not contained in any of nets.
+func outsideNets(addrs []string, nets []*net.IPNet) []string {
+	var out []string
+	for _, a := range addrs {
+		ip := net.ParseIP(a)
+		inside := false
+		for _, n := range nets {
+			if ip != nil && n.Contains(ip) {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// overlaps reports whether the two answer sets share an address. CDNs and
+// round-robin names rarely return identical sets to two resolvers, but a
+// hijacked answer shares none.
+func overlaps(a, b []string) bool {
+	set := make(map[string]bool, len(b))
+	for _, s := range b {
+		set[s] = true
+	}
+	for _, s := range a {
+		if set[s] {
+			return true
+		}
+	}
+	return false
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
@@ -0,0 +1,177 @@
+package watchers
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fullex26/piguard/internal/config"
+	"github.com/Fullex26/piguard/internal/eventbus"
+	"github.com/Fullex26/piguard/internal/store"
+	"github.com/Fullex26/piguard/pkg/models"
+)
+
+// fakeDNS answers lookups per resolver ("" = system) and name; a missing
+// entry fails the lookup.
+type fakeDNS map[string]map[string][]string
+
+func (f fakeDNS) lookup(name, resolver string, _ time.Duration) ([]string, error) {
+	if addrs, ok := f[resolver][name]; ok {
+		return addrs, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func newTestDNSWatcher(dc config.DNSConfig, db *store.Store, files map[string]string, dns fakeDNS) (*DNSWatcher, chan models.Event) {
+	bus := eventbus.New()
+	received := make(chan models.Event, 10)
+	bus.Subscribe(func(e models.Event) { received <- e })
+
+	w := NewDNSWatcher(&config.Config{DNS: dc}, bus, db)
+	w.readFile = func(path string) ([]byte, error) {
+		if data, ok := files[path]; ok {
+			return []byte(data), nil
+		}
+		return nil, os.ErrNotExist
+	}
+	w.lookupFn = dns.lookup
+	return w, received
+}
+
+func TestParseNameservers(t *testing.T) {
+	data := "# Generated by NetworkManager\nsearch lan\nnameserver 192.168.1.1\n  nameserver   1.1.1.1 \noptions edns0\n"
+	got := parseNameservers(data)
+	if strings.Join(got, ",") != "192.168.1.1,1.1.1.1" {
+		t.Errorf("parseNameservers() = %v", got)
+	}
+}
+
+func TestDNSWatcher_ResolverChanged(t *testing.T) {
+	files := map[string]string{"/etc/resolv.conf": "nameserver 192.168.1.1\n"}
+	w, received := newTestDNSWatcher(config.DNSConfig{}, nil, files, nil)
+
+	w.check("pi")
+	expectNoEvent(t, received) // first sighting
+
+	files["/etc/resolv.conf"] = "nameserver 203.0.113.53\n"
+	w.check("pi")
+	e := awaitEvent(t, received)
+	if e.Type != models.EventDNSResolverChanged || e.Severity != models.SeverityWarning {
+		t.Errorf("event = %s/%s, want resolver changed warning", e.Type, e.Severity)
+	}
+	if !strings.Contains(e.Message, "192.168.1.1 → 203.0.113.53") {
+		t.Errorf("message = %q", e.Message)
+	}
+
+	w.check("pi")
+	expectNoEvent(t, received)
+}
+
+func TestDNSWatcher_ResolvedUpstreams(t *testing.T) {
+	files := map[string]string{
+		"/etc/resolv.conf": "nameserver 127.0.0.53\n",
+		resolvedConf:       "nameserver 192.168.1.1\n",
+	}
+	w, received := newTestDNSWatcher(config.DNSConfig{}, nil, files, nil)
+	w.check("pi")
+
+	files[resolvedConf] = "nameserver 192.168.1.1\nnameserver 198.51.100.7\n"
+	w.check("pi")
+	e := awaitEvent(t, received)
+	if e.Type != models.EventDNSResolverChanged || !strings.Contains(e.Message, resolvedConf) {
+		t.Errorf("event = %s %q, want change in %s", e.Type, e.Message, resolvedConf)
+	}
+}
+
+func TestDNSWatcher_ResolverChangedWhileDown(t *testing.T) {
+	db := openNetworkTestStore(t)
+	files := map[string]string{"/etc/resolv.conf": "nameserver 192.168.1.1\n"}
+	w, _ := newTestDNSWatcher(config.DNSConfig{}, db, files, nil)
+	w.check("pi")
+
+	files["/etc/resolv.conf"] = "nameserver 203.0.113.53\n"
+	w, received := newTestDNSWatcher(config.DNSConfig{}, db, files, nil)
+	w.check("pi")
+	if e := awaitEvent(t, received); e.Type != models.EventDNSResolverChanged {
+		t.Errorf("event = %s, want resolver changed after restart", e.Type)
+	}
+}
+
+func TestDNSWatcher_ExpectedAnswers(t *testing.T) {
+	dc := config.DNSConfig{Canaries: []config.DNSCanary{
+		{Name: "one.one.one.one", Expect: []string{"1.1.1.1", "1.0.0.1"}},
+		{Name: "nas.lan", Expect: []string{"192.168.1.0/24"}},
+	}}
+	dns := fakeDNS{"": {"one.one.one.one": {"1.0.0.1", "1.1.1.1"}, "nas.lan": {"192.168.1.20"}}}
+	w, received := newTestDNSWatcher(dc, nil, nil, dns)
+
+	w.check("pi")
+	expectNoEvent(t, received)
+
+	dns[""]["one.one.one.one"] = []string{"1.1.1.1", "10.0.0.66"}
+	w.check("pi")
+	e := awaitEvent(t, received)
+	if e.Type != models.EventDNSMismatch || e.Severity != models.SeverityCritical {
+		t.Fatalf("event = %s/%s, want DNS mismatch critical", e.Type, e.Severity)
+	}
+	if !strings.Contains(e.Message, "got 10.0.0.66") || !strings.Contains(e.Details, "Expected: 1.1.1.1, 1.0.0.1") {
+		t.Errorf("message/details = %q / %q", e.Message, e.Details)
+	}
+
+	w.check("pi")
+	expectNoEvent(t, received) // alerted once
+
+	dns[""]["one.one.one.one"] = []string{"1.1.1.1"}
+	w.check("pi")
+	if e := awaitEvent(t, received); e.Type != models.EventDNSRestored {
+		t.Errorf("event = %s, want DNS restored", e.Type)
+	}
+}
+
+func TestDNSWatcher_TrustedResolverDisagrees(t *testing.T) {
+	dc := config.DNSConfig{TrustedResolver: "1.1.1.1", Canaries: []config.DNSCanary{{Name: "bank.example"}}}
+	dns := fakeDNS{
+		"":        {"bank.example": {"198.51.100.10", "198.51.100.11"}},
+		"1.1.1.1": {"bank.example": {"198.51.100.11", "198.51.100.12"}},
+	}
+	w, received := newTestDNSWatcher(dc, nil, nil, dns)
+
+	w.check("pi")
+	expectNoEvent(t, received) // overlapping round-robin answers agree
+
+	dns[""]["bank.example"] = []string{"203.0.113.66"}
+	w.check("pi")
+	e := awaitEvent(t, received)
+	if e.Type != models.EventDNSMismatch || !strings.Contains(e.Message, "trusted resolver 1.1.1.1 says 198.51.100.11, 198.51.100.12") {
+		t.Errorf("event = %s %q", e.Type, e.Message)
+	}
+}
+
+func TestDNSWatcher_LookupFailure(t *testing.T) {
+	dc := config.DNSConfig{TrustedResolver: "1.1.1.1", Canaries: []config.DNSCanary{{Name: "example.com"}}}
+	dns := fakeDNS{"": {}, "1.1.1.1": {"example.com": {"93.184.215.14"}}}
+	w, received := newTestDNSWatcher(dc, nil, nil, dns)
+
+	w.check("pi")
+	e := awaitEvent(t, received)
+	if e.Type != models.EventDNSFailure || e.Severity != models.SeverityWarning {
+		t.Errorf("event = %s/%s, want DNS failure warning", e.Type, e.Severity)
+	}
+	if !strings.Contains(e.Details, "Trusted resolver 1.1.1.1 answered: 93.184.215.14") {
+		t.Errorf("details = %q", e.Details)
+	}
+	w.check("pi")
+	expectNoEvent(t, received)
+}
+
+func TestDNSWatcher_OutageIsNotDNSFailure(t *testing.T) {
+	dc := config.DNSConfig{TrustedResolver: "1.1.1.1", Canaries: []config.DNSCanary{
+		{Name: "one.one.one.one", Expect: []string{"1.1.1.1"}},
+		{Name: "example.com"},
+	}}
+	w, received := newTestDNSWatcher(dc, nil, nil, fakeDNS{})
+	w.check("pi")
+	expectNoEvent(t, received)
+}
@@ -10,10 +10,13 @@ import (
 	"os/exec"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Fullex26/piguard/internal/config"
+	"github.com/Fullex26/piguard/internal/dockerapi"
 	"github.com/Fullex26/piguard/internal/eventbus"
+	"github.com/Fullex26/piguard/internal/store"
 	"github.com/Fullex26/piguard/pkg/models"
 )
 
@@ -25,32 +28,92 @@ type containerState struct {
 	State   string `json:"State"`   // "running", "exited", "paused", "restarting"
 	Status  string `json:"Status"`  // "Up 2 hours (healthy)", "Exited (1) 3 min ago"
 	Ports   string `json:"Ports"`   // "0.0.0.0:8080->80/tcp, :::443->443/tcp"
+	Labels  string `json:"Labels"`  // "com.docker.compose.project=web,..."; parsed into the fields below
+
+	// Compose labels; empty for containers not started by Compose.
+	Project    string `json:"-"` // com.docker.compose.project
+	Service    string `json:"-"` // com.docker.compose.service
+	WorkingDir string `json:"-"` // com.docker.compose.project.working_dir
+
+	// Runtime details `docker ps` doesn't show, filled from inspect.
+	Inspected    bool  `json:"-"` // the fields below are valid
+	OOMKilled    bool  `json:"-"`
+	Killed       bool  `json:"-"` // stopped by docker stop/kill rather than exiting on its own; event stream only
+	RestartCount int   `json:"-"` // restarts performed by the restart policy
+	MemoryLimit  int64 `json:"-"` // bytes, 0 = unlimited
+}
+
+// runtimeInfo is the inspect data merged into polled container states.
+type runtimeInfo struct {
+	RestartCount int
+	OOMKilled    bool
+	MemoryLimit  int64
 }
 
-// DockerWatcher polls for container lifecycle events.
+// DockerWatcher follows container lifecycle events. It subscribes to the
+// Engine API's /events stream over the daemon socket and inspects each
+// container as it starts, dies or changes health. When the socket is
+// unavailable or the stream drops, it polls `docker ps` instead and keeps
+// trying to resubscribe.
 type DockerWatcher struct {
 	Base
+	store       *store.Store // nil = posture findings are reported again after a restart
 	interval    time.Duration
 	baseline    map[string]containerState // container ID → last known state
 	nameToImage map[string]string         // container name → ImageID from previous cycle
+	api         *dockerapi.Client         // nil = poll the docker CLI only
 	runDockerPS func() ([]byte, error)    // injectable for tests
+
+	inspectRuntime   func(ids []string) (map[string]runtimeInfo, error) // nil = skip
+	restarts         map[string][]time.Time                             // container ID → policy restarts within the window
+	loopAlerted      map[string]time.Time                               // container ID → last crash-loop alert
+	oomKilled        map[string]bool                                    // container ID → "oom" event seen before "die"
+	killed           map[string]bool                                    // container ID → "kill" event (docker stop/kill) seen before "die"
+	inspectContainer func(id string) (*dockerapi.ContainerJSON, error)  // nil = skip the posture audit
+	projects         map[string]*composeProject                         // compose projects at the last check, including removed ones
+	projectDown      map[string]map[string]bool                         // compose project → services down at the last check
+	downMu           sync.Mutex                                         // guards expectedDown, set from the Telegram bot
+	expectedDown     map[string]time.Time                               // compose project → when a deliberate compose down was started
+	execs            map[string]execRecord                              // exec ID → process config captured on exec_create
+	audits           map[string]containerAudit                          // container ID → healthcheck and user for the activity audit
+	postureReported  map[string][]string                                // container ID → posture checks already alerted
+	loopRestarts     int
+	loopWindow       time.Duration
 }
 
-func NewDockerWatcher(cfg *config.Config, bus *eventbus.Bus) *DockerWatcher {
+func NewDockerWatcher(cfg *config.Config, bus *eventbus.Bus, db *store.Store) *DockerWatcher {
 	interval, err := time.ParseDuration(cfg.Docker.PollInterval)
 	if err != nil || interval <= 0 {
 		interval = 10 * time.Second
 	}
+	window, err := time.ParseDuration(cfg.Docker.CrashLoopWindow)
+	if err != nil || window <= 0 {
+		window = 5 * time.Minute
+	}
 	w := &DockerWatcher{
-		Base:        Base{Cfg: cfg, Bus: bus},
-		interval:    interval,
-		baseline:    make(map[string]containerState),
-		nameToImage: make(map[string]string),
+		Base:         Base{Cfg: cfg, Bus: bus},
+		store:        db,
+		interval:     interval,
+		baseline:     make(map[string]containerState),
+		nameToImage:  make(map[string]string),
+		api:          dockerapi.New(cfg.Docker.Socket),
+		restarts:     make(map[string][]time.Time),
+		loopAlerted:  make(map[string]time.Time),
+		oomKilled:    make(map[string]bool),
+		killed:       make(map[string]bool),
+		projectDown:  make(map[string]map[string]bool),
+		expectedDown: make(map[string]time.Time),
+		execs:        make(map[string]execRecord),
+		audits:       make(map[string]containerAudit),
+		loopRestarts: cfg.Docker.CrashLoopRestarts,
+		loopWindow:   window,
 	}
 	w.runDockerPS = func() ([]byte, error) {
-		return exec.Command("docker", "ps", "--all", "--no-trunc",
-			"--format", "{{json .}}").Output()
+		rt := dockerapi.Runtime(cfg.Docker.Runtime)
+		return exec.Command(rt.CLI(), rt.PSArgs()...).Output()
 	}
+	w.inspectRuntime = w.defaultInspectRuntime
+	w.inspectContainer = w.defaultInspectContainer
 	return w
 }
 
@@ -66,7 +129,11 @@ func (w *DockerWatcher) Start(ctx context.Context) error {
 			w.baseline[c.ID] = c
 			w.nameToImage[c.Names] = c.ImageID
 		}
+		w.projects = composeProjects(w.baseline)
+		w.projectDown = downServices(w.baseline)
 		slog.Info("docker baseline established", "count", len(w.baseline))
+		hostname, _ := os.Hostname()
+		w.auditBaseline(hostname)
 	} else {
 		slog.Warn("docker not available at startup", "error", err)
 	}
@@ -75,6 +142,14 @@ func (w *DockerWatcher) Start(ctx context.Context) error {
 	defer ticker.Stop()
 
 	for {
+		if w.api != nil && w.api.Ping(ctx) == nil {
+			slog.Info("following docker events", "socket", w.api.Socket())
+			err := w.watchEvents(ctx)
+			if ctx.Err() != nil {
+				return nil
+			}
+			slog.Warn("docker event stream lost, polling", "error", err)
+		}
 		select {
 		case <-ctx.Done():
 			return nil
@@ -84,6 +159,101 @@ func (w *DockerWatcher) Start(ctx context.Context) error {
 	}
 }
 
+// watchEvents handles container events, plus image, volume and network
+// events for the activity audit, until the stream breaks or ctx is
+// cancelled. Right after subscribing it polls once, so anything that changed
+// while the stream was down is still reported.
+func (w *DockerWatcher) watchEvents(ctx context.Context) error {
+	types := []string{"container"}
+	if w.Cfg.Docker.ActivityAudit {
+		types = append(types, "image", "volume", "network")
+	}
+	events, errs := w.api.Events(ctx, map[string][]string{"type": types})
+	w.check()
+	for ev := range events {
+		w.handleEvent(ctx, ev)
+	}
+	return <-errs
+}
+
+// handleEvent applies one container event to the baseline, raising the same
+// alerts a poll would have raised for the transition. Exec, copy, image,
+// volume and network events go to the activity audit instead.
+func (w *DockerWatcher) handleEvent(ctx context.Context, ev dockerapi.Event) {
+	switch {
+	case ev.Type != "container", strings.HasPrefix(ev.Action, "exec_"),
+		ev.Action == "archive-path", ev.Action == "extract-to-dir":
+		w.auditEvent(ctx, ev)
+		return
+	case ev.Action == "destroy":
+		w.forget(ev.Actor.ID)
+		hostname, _ := os.Hostname()
+		w.checkProjects(hostname)
+		return
+	case ev.Action == "oom":
+		// Sent just before "die"; inspect may no longer show OOMKilled by
+		// then if the restart policy has already started it again.
+		w.oomKilled[ev.Actor.ID] = true
+		return
+	case ev.Action == "kill":
+		// docker stop, docker kill and compose stop|down signal the
+		// container before it dies; the OOM killer doesn't.
+		w.killed[ev.Actor.ID] = true
+		return
+	case ev.Action == "start", ev.Action == "die", strings.HasPrefix(ev.Action, "health_status"):
+	default:
+		return
+	}
+
+	c := w.eventState(ctx, ev)
+	if ev.Action == "die" && w.oomKilled[c.ID] {
+		c.OOMKilled = true
+		delete(w.oomKilled, c.ID)
+	}
+	if ev.Action == "die" && w.killed[c.ID] {
+		c.Killed = true
+		delete(w.killed, c.ID)
+	}
+	prev, known := w.baseline[c.ID]
+	hostname, _ := os.Hostname()
+	w.compare(hostname, prev, known, c)
+	w.baseline[c.ID] = c
+	w.nameToImage[c.Names] = c.ImageID
+	w.checkProjects(hostname)
+}
+
+// eventState builds the container's state at the time of the event. Inspect
+// supplies the details, but the event itself is authoritative for what just
+// happened: by the time inspect runs, a container with a restart policy may
+// already be running again.
+func (w *DockerWatcher) eventState(ctx context.Context, ev dockerapi.Event) containerState {
+	attrs := ev.Actor.Attributes
+	c, known := w.baseline[ev.Actor.ID]
+	if !known {
+		c = containerState{ID: ev.Actor.ID, Names: attrs["name"], Image: attrs["image"], State: "running", Status: "Up"}
+		c.setCompose(attrs) // container events carry the container's labels
+	}
+	c.OOMKilled = false
+	if info, err := w.api.InspectContainer(ctx, ev.Actor.ID); err == nil {
+		c = stateFromInspect(info)
+	} else {
+		slog.Debug("docker inspect failed", "container", ev.Actor.ID, "error", err)
+	}
+
+	switch {
+	case ev.Action == "start":
+		c.State = "running"
+	case ev.Action == "die":
+		code, _ := strconv.Atoi(attrs["exitCode"])
+		c.State = "exited"
+		c.Status = fmt.Sprintf("Exited (%d)", code)
+	case strings.HasPrefix(ev.Action, "health_status"):
+		health := strings.TrimSpace(strings.TrimPrefix(ev.Action, "health_status:"))
+		c.Status = fmt.Sprintf("Up (%s)", health)
+	}
+	return c
+}
+
 func (w *DockerWatcher) check() {
 	containers, err := w.fetchContainers()
 	if err != nil {
@@ -106,49 +276,156 @@ func (w *DockerWatcher) check() {
 
 	for id, c := range current {
 		prev, known := w.baseline[id]
-		if !known {
-			// Brand-new container ID — alert if it started running.
-			if c.State == "running" {
-				// Watchtower replaces a container: same name reappears with a different image digest.
-				if prevImage, seen := w.nameToImage[c.Names]; seen && prevImage != "" && prevImage != c.ImageID {
-					w.emit(hostname, models.EventContainerUpdated, models.SeverityInfo,
-						fmt.Sprintf("Container updated: %s (%s)", c.Names, c.Image),
-						"", c)
-				} else {
-					w.emit(hostname, models.EventContainerStart, models.SeverityInfo,
-						fmt.Sprintf("Container started: %s (%s)", c.Names, c.Image),
-						"", c)
-				}
-			}
-			continue
+		w.compare(hostname, prev, known, c)
+	}
+	for id := range w.baseline {
+		if _, ok := current[id]; !ok {
+			w.forget(id)
 		}
-		// State transitions from running
-		if prev.State == "running" && c.State == "exited" {
-			exitCode := parseExitCode(c.Status)
-			if exitCode != 0 {
-				w.emit(hostname, models.EventContainerDied, models.SeverityWarning,
-					fmt.Sprintf("Container crashed: %s (exit %d)", c.Names, exitCode),
-					"Check container logs: docker logs "+c.Names, c)
-			} else if w.Cfg.Docker.AlertOnStop {
-				w.emit(hostname, models.EventContainerStopped, models.SeverityInfo,
-					fmt.Sprintf("Container stopped: %s", c.Names), "", c)
+	}
+
+	w.baseline = current
+	w.nameToImage = newNameToImage
+	w.checkProjects(hostname)
+}
+
+// compare raises alerts for the transition from prev to c. known is false
+// for a container ID that has not been seen before.
+func (w *DockerWatcher) compare(hostname string, prev containerState, known bool, c containerState) {
+	if !known {
+		// Brand-new container ID — alert if it started running.
+		if c.State == "running" {
+			// Watchtower replaces a container: same name reappears with a different image digest.
+			if prevImage, seen := w.nameToImage[c.Names]; seen && prevImage != "" && c.ImageID != "" && prevImage != c.ImageID {
+				w.emit(hostname, models.EventContainerUpdated, models.SeverityInfo,
+					fmt.Sprintf("Container updated: %s (%s)", c.label(), c.Image),
+					"", c)
+			} else {
+				w.emit(hostname, models.EventContainerStart, models.SeverityInfo,
+					fmt.Sprintf("Container started: %s (%s)", c.label(), c.Image),
+					"", c)
 			}
+			w.auditPosture(hostname, c)
 		}
-		// Health transitions to unhealthy
-		if isUnhealthy(c.Status) && !isUnhealthy(prev.Status) {
-			w.emit(hostname, models.EventContainerHealth, models.SeverityWarning,
-				fmt.Sprintf("Container unhealthy: %s", c.Names),
+		return
+	}
+	// State transitions from running
+	if prev.State == "running" && c.State == "exited" {
+		exitCode := parseExitCode(c.Status)
+		if c.OOMKilled {
+			w.emitOOM(hostname, c, exitCode)
+		} else if exitCode == 137 && !c.Killed {
+			w.emitSIGKILL(hostname, c)
+		} else if exitCode != 0 && !(c.Killed && stoppedCleanly(c)) {
+			w.emit(hostname, models.EventContainerDied, models.SeverityWarning,
+				fmt.Sprintf("Container crashed: %s (exit %d)", c.label(), exitCode),
 				"Check container logs: docker logs "+c.Names, c)
+		} else if w.Cfg.Docker.AlertOnStop {
+			w.emit(hostname, models.EventContainerStopped, models.SeverityInfo,
+				fmt.Sprintf("Container stopped: %s", c.label()), "", c)
 		}
-		// Container restarted (was exited, now running again)
-		if prev.State == "exited" && c.State == "running" {
-			w.emit(hostname, models.EventContainerStart, models.SeverityInfo,
-				fmt.Sprintf("Container restarted: %s (%s)", c.Names, c.Image), "", c)
+	}
+	// Health transitions to unhealthy
+	if isUnhealthy(c.Status) && !isUnhealthy(prev.Status) {
+		w.emit(hostname, models.EventContainerHealth, models.SeverityWarning,
+			fmt.Sprintf("Container unhealthy: %s", c.label()),
+			"Check container logs: docker logs "+c.Names, c)
+	}
+	// Container restarted (was exited, now running again)
+	if prev.State == "exited" && c.State == "running" {
+		w.emit(hostname, models.EventContainerStart, models.SeverityInfo,
+			fmt.Sprintf("Container restarted: %s (%s)", c.label(), c.Image), "", c)
+	}
+	// Restarts by the restart policy, including any that happened between
+	// two polls while the container looked "running" both times.
+	if prev.Inspected && c.Inspected && c.RestartCount > prev.RestartCount {
+		w.trackRestarts(hostname, c, c.RestartCount-prev.RestartCount, time.Now())
+	}
+}
+
+// trackRestarts records n policy restarts at now and raises a crash-loop
+// alert once docker.crash_loop_restarts fall within the window. The alert
+// repeats at most once per window while the loop continues.
+func (w *DockerWatcher) trackRestarts(hostname string, c containerState, n int, now time.Time) {
+	if w.loopRestarts <= 0 {
+		return
+	}
+	cutoff := now.Add(-w.loopWindow)
+	recent := w.restarts[c.ID][:0]
+	for _, t := range w.restarts[c.ID] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
 		}
 	}
+	for i := 0; i < n; i++ {
+		recent = append(recent, now)
+	}
+	w.restarts[c.ID] = recent
 
-	w.baseline = current
-	w.nameToImage = newNameToImage
+	if len(recent) < w.loopRestarts || now.Sub(w.loopAlerted[c.ID]) < w.loopWindow {
+		return
+	}
+	w.loopAlerted[c.ID] = now
+	w.emit(hostname, models.EventContainerCrashLoop, models.SeverityCritical,
+		fmt.Sprintf("Container crash-looping: %s (%d restarts in %s)", c.label(), len(recent), shortDuration(w.loopWindow)),
+		fmt.Sprintf("Check why it keeps exiting: docker logs --tail 50 %s. To stop the loop: docker update --restart=no %s", c.Names, c.Names), c)
+}
+
+// shortDuration drops zero units from Duration.String: "5m" rather than "5m0s".
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// emitOOM reports a container killed by the kernel's OOM killer.
+func (w *DockerWatcher) emitOOM(hostname string, c containerState, exitCode int) {
+	suggested := "The host ran out of memory. Set a memory limit so one container can't starve the rest, and check docker stats " + c.Names
+	if c.MemoryLimit > 0 {
+		suggested = fmt.Sprintf("Raise the limit (--memory / deploy.resources.limits.memory) or look for a leak: docker stats %s", c.Names)
+	}
+	w.emit(hostname, models.EventContainerOOM, models.SeverityWarning,
+		fmt.Sprintf("Container out of memory: %s (exit %d, %s)", c.label(), exitCode, memoryLimit(c)),
+		suggested, c)
+}
+
+// emitSIGKILL reports an exit 137 that nobody asked for: OOMKilled isn't
+// set, but a host-wide OOM kill (or the flag lost to a quick restart) looks
+// exactly like this. When polling, docker stop|kill can't be told apart
+// either, hence "possible".
+func (w *DockerWatcher) emitSIGKILL(hostname string, c containerState) {
+	w.emit(hostname, models.EventContainerDied, models.SeverityWarning,
+		fmt.Sprintf("Container crashed: %s (exit 137, killed by SIGKILL, possible OOM, %s)", c.label(), memoryLimit(c)),
+		fmt.Sprintf("Check the kernel log for the OOM killer (dmesg | grep -i oom) and the container logs: docker logs %s", c.Names), c)
+}
+
+// memoryLimit describes the container's memory limit for an alert.
+func memoryLimit(c containerState) string {
+	if c.MemoryLimit > 0 {
+		return "limit " + formatKB(c.MemoryLimit/1024)
+	}
+	return "no memory limit"
+}
+
+// forget drops per-container tracking once a container is gone.
+func (w *DockerWatcher) forget(id string) {
+	delete(w.baseline, id)
+	delete(w.restarts, id)
+	delete(w.loopAlerted, id)
+	delete(w.oomKilled, id)
+	delete(w.killed, id)
+	delete(w.audits, id)
+	delete(w.postureReported, id)
+	for execID, rec := range w.execs {
+		if rec.ContainerID == id {
+			delete(w.execs, execID)
+		}
+	}
 }
 
 func (w *DockerWatcher) emit(hostname string, evType models.EventType, sev models.Severity,
@@ -164,22 +441,196 @@ func (w *DockerWatcher) emit(hostname string, evType models.EventType, sev model
 		Hostname:  hostname,
 		Timestamp: time.Now(),
 		Message:   msg,
-		Details:   fmt.Sprintf("Image: %s | Status: %s", c.Image, c.Status),
+		Details:   containerDetails(c),
 		Suggested: suggested,
 		Source:    "docker",
 	})
 }
 
+// containerDetails summarises a container for alert details.
+func containerDetails(c containerState) string {
+	details := fmt.Sprintf("Image: %s | Status: %s", c.Image, c.Status)
+	if c.Project != "" {
+		details = fmt.Sprintf("Project: %s | Service: %s | Container: %s | ", c.Project, c.Service, c.Names) + details
+	}
+	if c.OOMKilled && c.State != "running" {
+		details += " | OOM killed"
+	}
+	if c.RestartCount > 0 {
+		details += fmt.Sprintf(" | Restarts: %d", c.RestartCount)
+	}
+	return details
+}
+
+// containerCLI returns the CLI of the configured container runtime, docker
+// or podman.
+func containerCLI(cfg *config.Config) string {
+	if cfg == nil {
+		return "docker"
+	}
+	return dockerapi.Runtime(cfg.Docker.Runtime).CLI()
+}
+
+// fetchContainers lists all containers through the Engine API, falling back
+// to `docker ps` when the socket can't be reached.
 func (w *DockerWatcher) fetchContainers() ([]containerState, error) {
+	if w.api != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if list, err := w.api.ListContainers(ctx, true); err == nil {
+			containers := make([]containerState, 0, len(list))
+			for _, c := range list {
+				containers = append(containers, stateFromList(c))
+			}
+			return w.withRuntime(containers), nil
+		}
+	}
 	out, err := w.runDockerPS()
 	if err != nil {
 		return nil, err
 	}
-	return parseDockerOutput(string(out))
+	containers, err := parseDockerOutput(string(out))
+	if err != nil {
+		return nil, err
+	}
+	return w.withRuntime(containers), nil
+}
+
+// withRuntime fills restart counts, OOM state and memory limits from inspect.
+// Containers inspect can't describe are returned unchanged.
+func (w *DockerWatcher) withRuntime(containers []containerState) []containerState {
+	if w.inspectRuntime == nil || len(containers) == 0 {
+		return containers
+	}
+	ids := make([]string, len(containers))
+	for i, c := range containers {
+		ids[i] = c.ID
+	}
+	info, err := w.inspectRuntime(ids)
+	if err != nil {
+		slog.Debug("docker inspect failed", "error", err)
+	}
+	for i, c := range containers {
+		if ri, ok := info[c.ID]; ok {
+			containers[i].Inspected = true
+			containers[i].RestartCount = ri.RestartCount
+			containers[i].OOMKilled = ri.OOMKilled
+			containers[i].MemoryLimit = ri.MemoryLimit
+		}
+	}
+	return containers
+}
+
+// defaultInspectRuntime inspects through the Engine API, or with one
+// `docker inspect` (or `podman inspect`) call for all containers when the
+// socket is unavailable.
+func (w *DockerWatcher) defaultInspectRuntime(ids []string) (map[string]runtimeInfo, error) {
+	result := make(map[string]runtimeInfo, len(ids))
+	if w.api != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		var err error
+		for _, id := range ids {
+			info, ierr := w.api.InspectContainer(ctx, id)
+			if ierr != nil {
+				err = ierr
+				continue
+			}
+			result[id] = runtimeInfo{info.RestartCount, info.State.OOMKilled, info.HostConfig.Memory}
+		}
+		if len(result) > 0 || err == nil {
+			return result, err
+		}
+	}
+
+	id := "{{.Id}}"
+	if dockerapi.Runtime(w.Cfg.Docker.Runtime) == dockerapi.RuntimePodman {
+		id = "{{.ID}}" // podman templates see Go field names only
+	}
+	args := append([]string{"inspect", "--format",
+		id + " {{.RestartCount}} {{.State.OOMKilled}} {{.HostConfig.Memory}}"}, ids...)
+	out, err := exec.Command(containerCLI(w.Cfg), args...).Output()
+	return parseInspectRuntime(string(out), result), err
+}
+
+// parseInspectRuntime parses "<id> <restarts> <oomkilled> <memory>" lines.
+func parseInspectRuntime(output string, into map[string]runtimeInfo) map[string]runtimeInfo {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			continue
+		}
+		restarts, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		memory, _ := strconv.ParseInt(fields[3], 10, 64)
+		into[fields[0]] = runtimeInfo{RestartCount: restarts, OOMKilled: fields[2] == "true", MemoryLimit: memory}
+	}
+	return into
+}
+
+// stateFromList converts an API list entry to the `docker ps` shape.
+func stateFromList(c dockerapi.Container) containerState {
+	state := containerState{
+		ID:      c.ID,
+		Names:   c.Name(),
+		Image:   c.Image,
+		ImageID: c.ImageID,
+		State:   c.State,
+		Status:  c.Status,
+		Ports:   c.PortsString(),
+	}
+	state.setCompose(c.Labels)
+	return state
 }
 
-// parseDockerOutput parses `docker ps --format '{{json .}}'` output (one JSON object per line).
+// stateFromInspect converts inspect output to the `docker ps` shape, with a
+// Status rendered the way `docker ps` prints it so polled and event-driven
+// states compare alike.
+func stateFromInspect(info *dockerapi.ContainerJSON) containerState {
+	c := containerState{
+		ID:           info.ID,
+		Names:        strings.TrimPrefix(info.Name, "/"),
+		Image:        info.Config.Image,
+		ImageID:      info.Image,
+		State:        info.State.Status,
+		Inspected:    true,
+		OOMKilled:    info.State.OOMKilled,
+		RestartCount: info.RestartCount,
+		MemoryLimit:  info.HostConfig.Memory,
+	}
+	c.setCompose(info.Config.Labels)
+	switch info.State.Status {
+	case "running":
+		c.Status = "Up"
+		if h := info.State.Health; h != nil && h.Status != "" {
+			if h.Status == "starting" {
+				c.Status += " (health: starting)"
+			} else {
+				c.Status += " (" + h.Status + ")"
+			}
+		}
+	case "exited", "restarting":
+		c.Status = fmt.Sprintf("%s (%d)", strings.ToUpper(info.State.Status[:1])+info.State.Status[1:], info.State.ExitCode)
+	default:
+		if info.State.Status != "" {
+			c.Status = strings.ToUpper(info.State.Status[:1]) + info.State.Status[1:]
+		}
+	}
+	return c
+}
+
+// parseDockerOutput parses `docker ps --format '{{json .}}'` output (one JSON object per line),
+// or the single JSON array `podman ps --format json` prints.
 func parseDockerOutput(output string) ([]containerState, error) {
+	if list, ok, err := dockerapi.ParsePS([]byte(output)); ok {
+		containers := make([]containerState, 0, len(list))
+		for _, c := range list {
+			containers = append(containers, stateFromList(c))
+		}
+		return containers, err
+	}
 	var containers []containerState
 	scanner := bufio.NewScanner(strings.NewReader(output))
 	for scanner.Scan() {
@@ -191,6 +642,7 @@ func parseDockerOutput(output string) ([]containerState, error) {
 		if err := json.Unmarshal([]byte(line), &c); err != nil {
 			continue // skip malformed lines
 		}
+		c.setCompose(parseLabels(c.Labels))
 		containers = append(containers, c)
 	}
 	return containers, nil
@@ -0,0 +1,252 @@
+package watchers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Fullex26/piguard/internal/config"
+	"github.com/Fullex26/piguard/internal/dockerapi"
+	"github.com/Fullex26/piguard/pkg/models"
+)
+
+// execRecord is an exec's process configuration, captured on exec_create:
+// the daemon drops short-lived execs (healthchecks, quick `docker exec`
+// commands) soon after they exit, often before exec_start is handled.
+type execRecord struct {
+	ContainerID string
+	User        string // "" = the container's user
+	Privileged  bool
+	TTY         bool
+}
+
+// containerAudit is what the activity audit needs from a container's inspect.
+type containerAudit struct {
+	Healthcheck string // the exec command its healthcheck runs; "" = none
+	User        string // configured user; "" = root
+}
+
+// auditEvent records docker exec/cp, image and volume/network activity from
+// the daemon's event stream. The polling fallback can't see these actions.
+func (w *DockerWatcher) auditEvent(ctx context.Context, ev dockerapi.Event) {
+	if !w.Cfg.Docker.ActivityAudit {
+		return
+	}
+	hostname, _ := os.Hostname()
+	attrs := ev.Actor.Attributes
+	action, detail, _ := strings.Cut(ev.Action, ": ")
+
+	switch ev.Type + " " + action {
+	case "container exec_create":
+		w.recordExec(ctx, ev.Actor.ID, attrs["name"], attrs["execID"], detail)
+	case "container exec_start":
+		w.auditExec(ctx, hostname, ev.Actor.ID, attrs, detail)
+	case "container exec_die":
+		delete(w.execs, attrs["execID"])
+	case "container archive-path":
+		w.emitAudit(hostname, models.EventContainerCopy, models.SeverityWarning,
+			fmt.Sprintf("Files copied out of container %s: %s", w.auditLabel(ev.Actor.ID, attrs), attrs["path"]),
+			"Container: "+attrs["name"]+" | Image: "+attrs["image"],
+			"docker cp reads files without opening a shell. If this wasn't you, check who can reach the Docker socket (members of the docker group)")
+	case "container extract-to-dir":
+		w.emitAudit(hostname, models.EventContainerCopy, models.SeverityWarning,
+			fmt.Sprintf("Files copied into container %s: %s", w.auditLabel(ev.Actor.ID, attrs), attrs["path"]),
+			"Container: "+attrs["name"]+" | Image: "+attrs["image"],
+			"docker cp can plant files in a running container. If this wasn't you, check who can reach the Docker socket (members of the docker group)")
+	case "image pull":
+		w.emitAudit(hostname, models.EventImagePull, models.SeverityInfo,
+			"Docker image pulled: "+ev.Actor.ID, "", "")
+	case "image tag":
+		w.emitAudit(hostname, models.EventImageTag, models.SeverityInfo,
+			fmt.Sprintf("Docker image tagged: %s (%s)", attrs["name"], shortImageID(ev.Actor.ID)), "Image: "+ev.Actor.ID, "")
+	case "image delete":
+		msg := "Docker image deleted: " + shortImageID(ev.Actor.ID)
+		if name := attrs["name"]; name != "" && name != ev.Actor.ID {
+			msg = fmt.Sprintf("Docker image deleted: %s (%s)", name, shortImageID(ev.Actor.ID))
+		}
+		w.emitAudit(hostname, models.EventImageDelete, models.SeverityInfo, msg, "Image: "+ev.Actor.ID, "")
+	case "volume create":
+		if isContainerID(ev.Actor.ID) {
+			return // anonymous volume created for a container's VOLUME
+		}
+		w.emitAudit(hostname, models.EventVolumeCreate, models.SeverityInfo,
+			fmt.Sprintf("Docker volume created: %s (driver %s)", ev.Actor.ID, attrs["driver"]), "", "")
+	case "network create":
+		w.emitAudit(hostname, models.EventNetworkCreate, models.SeverityInfo,
+			fmt.Sprintf("Docker network created: %s (%s)", attrs["name"], attrs["type"]), "Network ID: "+ev.Actor.ID, "")
+	}
+}
+
+// recordExec captures the process configuration of a new exec, unless it is
+// routine and won't be reported anyway.
+func (w *DockerWatcher) recordExec(ctx context.Context, containerID, name, execID, command string) {
+	if execID == "" || w.routineExec(ctx, containerID, name, command) {
+		return
+	}
+	rec := execRecord{ContainerID: containerID}
+	if info, err := w.api.InspectExec(ctx, execID); err == nil {
+		rec.User = info.ProcessConfig.User
+		rec.Privileged = info.ProcessConfig.Privileged
+		rec.TTY = info.ProcessConfig.TTY
+	} else {
+		slog.Debug("docker exec inspect failed", "exec", execID, "error", err)
+	}
+	w.execs[execID] = rec
+}
+
+// auditExec reports a started exec with its command, user and whether it
+// is an interactive terminal.
+func (w *DockerWatcher) auditExec(ctx context.Context, hostname, containerID string, attrs map[string]string, command string) {
+	name, execID := attrs["name"], attrs["execID"]
+	if w.routineExec(ctx, containerID, name, command) {
+		return
+	}
+	rec, ok := w.execs[execID]
+	delete(w.execs, execID)
+	if !ok {
+		// exec_create was missed, e.g. the stream was still connecting.
+		if info, err := w.api.InspectExec(ctx, execID); err == nil {
+			rec = execRecord{User: info.ProcessConfig.User, Privileged: info.ProcessConfig.Privileged, TTY: info.ProcessConfig.TTY}
+		}
+	}
+	user := rec.User
+	if user == "" {
+		user = w.containerAudit(ctx, containerID).User
+	}
+	if user == "" {
+		user = "root"
+	}
+
+	label := w.auditLabel(containerID, attrs)
+	sev := models.SeverityInfo
+	msg := fmt.Sprintf("Command executed in container %s: %s (user %s)", label, truncate(command, 200), user)
+	if rec.TTY {
+		sev = models.SeverityWarning
+		msg = fmt.Sprintf("Interactive session opened in container %s: %s (user %s)", label, truncate(command, 200), user)
+	}
+	if rec.Privileged {
+		sev = models.SeverityWarning
+		msg = strings.TrimSuffix(msg, ")") + ", privileged)"
+	}
+	details := fmt.Sprintf("Container: %s | Image: %s | Command: %s | User: %s | TTY: %s | Privileged: %s",
+		name, attrs["image"], command, user, yesNo(rec.TTY), yesNo(rec.Privileged))
+	suggested := fmt.Sprintf("If this wasn't you, check who can reach the Docker socket (members of the docker group). "+
+		"Silence routine commands with docker.exec_allowlist: {container: %q, command: %q}", name, command)
+	w.emitAudit(hostname, models.EventContainerExec, sev, msg, details, suggested)
+}
+
+// routineExec reports whether an exec is the container's own healthcheck or
+// matches docker.exec_allowlist.
+func (w *DockerWatcher) routineExec(ctx context.Context, containerID, name, command string) bool {
+	command = strings.TrimSpace(command)
+	if hc := w.containerAudit(ctx, containerID).Healthcheck; hc != "" && hc == command {
+		return true
+	}
+	return execAllowed(w.Cfg.Docker.ExecAllowlist, name, command)
+}
+
+// execAllowed reports whether an allowlist rule matches the container name
+// and command.
+func execAllowed(rules []config.ExecRule, name, command string) bool {
+	for _, r := range rules {
+		if r.Container != "" {
+			if ok, _ := path.Match(r.Container, name); !ok {
+				continue
+			}
+		}
+		if commandMatch(r.Command, command) {
+			return true
+		}
+	}
+	return false
+}
+
+// commandMatch matches a command against a pattern in which "*" matches any
+// run of characters, slashes and spaces included.
+func commandMatch(pattern, command string) bool {
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+	ok, _ := regexp.MatchString(expr, strings.TrimSpace(command))
+	return ok
+}
+
+// containerAudit returns the container's healthcheck command and user,
+// inspecting it once and caching the result until the container is gone.
+func (w *DockerWatcher) containerAudit(ctx context.Context, id string) containerAudit {
+	if a, ok := w.audits[id]; ok {
+		return a
+	}
+	var a containerAudit
+	if w.api == nil {
+		return a
+	}
+	info, err := w.api.InspectContainer(ctx, id)
+	if err != nil {
+		return a // not cached: try again on the next exec
+	}
+	a.User = info.Config.User
+	a.Healthcheck = healthcheckCommand(info.Config.Healthcheck)
+	w.audits[id] = a
+	return a
+}
+
+// healthcheckCommand renders a healthcheck the way exec events show the
+// command it runs: CMD-SHELL probes run under "/bin/sh -c".
+func healthcheckCommand(hc *dockerapi.HealthConfig) string {
+	if hc == nil || len(hc.Test) < 2 {
+		return ""
+	}
+	switch hc.Test[0] {
+	case "CMD":
+		return strings.TrimSpace(strings.Join(hc.Test[1:], " "))
+	case "CMD-SHELL":
+		return "/bin/sh -c " + strings.TrimSpace(hc.Test[1])
+	}
+	return ""
+}
+
+// auditLabel names the container for an audit alert, as project/service
+// for Compose containers.
+func (w *DockerWatcher) auditLabel(id string, attrs map[string]string) string {
+	if c, ok := w.baseline[id]; ok {
+		return c.label()
+	}
+	c := containerState{Names: attrs["name"]}
+	c.setCompose(attrs)
+	return c.label()
+}
+
+// shortImageID shortens "sha256:<hex>" to the 12 characters docker shows.
+func shortImageID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func (w *DockerWatcher) emitAudit(hostname string, evType models.EventType, sev models.Severity,
+	msg, details, suggested string) {
+	w.Bus.Publish(models.Event{
+		ID:        fmt.Sprintf("%s-%d", string(evType), time.Now().UnixNano()),
+		Type:      evType,
+		Severity:  sev,
+		Hostname:  hostname,
+		Timestamp: time.Now(),
+		Message:   msg,
+		Details:   details,
+		Suggested: suggested,
+		Source:    "docker",
+	})
+}
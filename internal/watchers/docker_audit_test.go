@@ -0,0 +1,186 @@
+package watchers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Fullex26/piguard/internal/config"
+	"github.com/Fullex26/piguard/internal/dockerapi"
+	"github.com/Fullex26/piguard/pkg/models"
+)
+
+func dockerEvent(typ, action, id string, attrs map[string]string) dockerapi.Event {
+	var ev dockerapi.Event
+	ev.Type, ev.Action = typ, action
+	ev.Actor.ID = id
+	ev.Actor.Attributes = attrs
+	return ev
+}
+
+// newAuditWatcher returns a watcher whose daemon knows one container, "db",
+// with a CMD-SHELL healthcheck, and execs e-shell (interactive) and e-dump.
+func newAuditWatcher(t *testing.T) (*DockerWatcher, chan models.Event, *[]string) {
+	w, received := newTestDockerWatcher(false, nil)
+	w.Cfg.Docker.ActivityAudit = true
+	var inspected []string
+	w.api = fakeDockerSocket(t, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		inspected = append(inspected, r.URL.Path)
+		switch r.URL.Path {
+		case "/containers/db1/json":
+			fmt.Fprint(rw, `{"Id":"db1","Name":"/db","Config":{"User":"postgres",
+				"Healthcheck":{"Test":["CMD-SHELL","pg_isready -U app"]}}}`)
+		case "/exec/e-shell/json":
+			fmt.Fprint(rw, `{"ID":"e-shell","ContainerID":"db1","ProcessConfig":{"entrypoint":"bash","arguments":[],"user":"root","tty":true}}`)
+		case "/exec/e-dump/json":
+			fmt.Fprint(rw, `{"ID":"e-dump","ContainerID":"db1","ProcessConfig":{"entrypoint":"pg_dump","arguments":["app"],"user":"","tty":false}}`)
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(rw, `{"message":"not found"}`)
+		}
+	}))
+	return w, received, &inspected
+}
+
+func runExec(w *DockerWatcher, execID, command string) {
+	attrs := map[string]string{"name": "db", "image": "postgres:16", "execID": execID}
+	ctx := context.Background()
+	w.handleEvent(ctx, dockerEvent("container", "exec_create: "+command, "db1", attrs))
+	w.handleEvent(ctx, dockerEvent("container", "exec_start: "+command, "db1", attrs))
+	w.handleEvent(ctx, dockerEvent("container", "exec_die", "db1", attrs))
+}
+
+func TestDockerWatcher_Audit_InteractiveExec(t *testing.T) {
+	w, received, _ := newAuditWatcher(t)
+	runExec(w, "e-shell", "bash")
+
+	e := awaitEvent(t, received)
+	if e.Type != models.EventContainerExec || e.Severity != models.SeverityWarning {
+		t.Errorf("event = %s %v, want warning exec", e.Type, e.Severity)
+	}
+	if e.Message != "Interactive session opened in container db: bash (user root)" {
+		t.Errorf("message = %q", e.Message)
+	}
+	if !strings.Contains(e.Details, "TTY: yes") || !strings.Contains(e.Suggested, "docker.exec_allowlist") {
+		t.Errorf("details = %q, suggested = %q", e.Details, e.Suggested)
+	}
+	if len(w.execs) != 0 {
+		t.Errorf("exec records left behind: %v", w.execs)
+	}
+}
+
+func TestDockerWatcher_Audit_ExecUsesContainerUser(t *testing.T) {
+	w, received, _ := newAuditWatcher(t)
+	runExec(w, "e-dump", "pg_dump app")
+
+	e := awaitEvent(t, received)
+	if e.Severity != models.SeverityInfo || e.Message != "Command executed in container db: pg_dump app (user postgres)" {
+		t.Errorf("event = %v %q", e.Severity, e.Message)
+	}
+}
+
+func TestDockerWatcher_Audit_HealthcheckSkipped(t *testing.T) {
+	w, received, inspected := newAuditWatcher(t)
+	runExec(w, "e-hc1", "/bin/sh -c pg_isready -U app")
+	runExec(w, "e-hc2", "/bin/sh -c pg_isready -U app")
+	expectNoEvent(t, received)
+
+	// The container is inspected once; healthcheck execs never are.
+	if strings.Join(*inspected, ",") != "/containers/db1/json" {
+		t.Errorf("inspected = %v", *inspected)
+	}
+}
+
+func TestDockerWatcher_Audit_Allowlist(t *testing.T) {
+	w, received, _ := newAuditWatcher(t)
+	w.Cfg.Docker.ExecAllowlist = []config.ExecRule{{Container: "d*", Command: "pg_dump *"}}
+	runExec(w, "e-dump", "pg_dump app")
+	expectNoEvent(t, received)
+
+	w.Cfg.Docker.ExecAllowlist = []config.ExecRule{{Container: "web", Command: "pg_dump *"}}
+	runExec(w, "e-dump", "pg_dump app")
+	if e := awaitEvent(t, received); e.Type != models.EventContainerExec {
+		t.Errorf("event = %s, want exec (rule is for another container)", e.Type)
+	}
+}
+
+func TestDockerWatcher_Audit_Disabled(t *testing.T) {
+	w, received, _ := newAuditWatcher(t)
+	w.Cfg.Docker.ActivityAudit = false
+	runExec(w, "e-shell", "bash")
+	w.handleEvent(context.Background(), dockerEvent("image", "pull", "nginx:latest", map[string]string{"name": "nginx"}))
+	expectNoEvent(t, received)
+}
+
+func TestDockerWatcher_Audit_CopyImageVolumeNetwork(t *testing.T) {
+	w, received, _ := newAuditWatcher(t)
+	ctx := context.Background()
+	w.handleEvent(ctx, dockerEvent("container", "archive-path", "db1", map[string]string{"name": "db", "path": "/var/lib/postgresql"}))
+	w.handleEvent(ctx, dockerEvent("container", "extract-to-dir", "db1", map[string]string{"name": "db", "path": "/tmp"}))
+	w.handleEvent(ctx, dockerEvent("image", "pull", "nginx:latest", map[string]string{"name": "nginx"}))
+	w.handleEvent(ctx, dockerEvent("image", "tag", "sha256:0123456789abcdef", map[string]string{"name": "myapp:prod"}))
+	w.handleEvent(ctx, dockerEvent("image", "delete", "sha256:0123456789abcdef", map[string]string{"name": "myapp:old"}))
+	w.handleEvent(ctx, dockerEvent("volume", "create", "media_data", map[string]string{"driver": "local"}))
+	w.handleEvent(ctx, dockerEvent("volume", "create", strings.Repeat("ab", 32), map[string]string{"driver": "local"}))
+	w.handleEvent(ctx, dockerEvent("network", "create", "net123", map[string]string{"name": "media_default", "type": "bridge"}))
+	w.handleEvent(ctx, dockerEvent("network", "connect", "net123", map[string]string{"name": "media_default"}))
+
+	want := map[string]bool{
+		"Files copied out of container db: /var/lib/postgresql": true,
+		"Files copied into container db: /tmp":                  true,
+		"Docker image pulled: nginx:latest":                     true,
+		"Docker image tagged: myapp:prod (0123456789ab)":        true,
+		"Docker image deleted: myapp:old (0123456789ab)":        true,
+		"Docker volume created: media_data (driver local)":      true,
+		"Docker network created: media_default (bridge)":        true,
+	}
+	for range want {
+		e := awaitEvent(t, received)
+		if !want[e.Message] {
+			t.Errorf("unexpected event %s %q", e.Type, e.Message)
+		}
+		if e.Type == models.EventContainerCopy && e.Severity != models.SeverityWarning {
+			t.Errorf("copy severity = %v, want warning", e.Severity)
+		}
+	}
+	expectNoEvent(t, received) // anonymous volume and network connect
+}
+
+func TestHealthcheckCommand(t *testing.T) {
+	tests := []struct {
+		test []string
+		want string
+	}{
+		{[]string{"CMD-SHELL", "curl -f http://localhost/ || exit 1"}, "/bin/sh -c curl -f http://localhost/ || exit 1"},
+		{[]string{"CMD", "pg_isready", "-U", "app"}, "pg_isready -U app"},
+		{[]string{"NONE"}, ""},
+	}
+	for _, tt := range tests {
+		if got := healthcheckCommand(&dockerapi.HealthConfig{Test: tt.test}); got != tt.want {
+			t.Errorf("healthcheckCommand(%v) = %q, want %q", tt.test, got, tt.want)
+		}
+	}
+	if got := healthcheckCommand(nil); got != "" {
+		t.Errorf("healthcheckCommand(nil) = %q", got)
+	}
+}
+
+func TestCommandMatch(t *testing.T) {
+	tests := []struct {
+		pattern, command string
+		want             bool
+	}{
+		{"/usr/local/bin/probe *", "/usr/local/bin/probe --quick", true},
+		{"pg_isready", "pg_isready", true},
+		{"pg_isready", "pg_isready -U app", false},
+		{"*", "anything at/all", true},
+		{"sh -c [x]", "sh -c [x]", true}, // regexp metacharacters are literal
+	}
+	for _, tt := range tests {
+		if got := commandMatch(tt.pattern, tt.command); got != tt.want {
+			t.Errorf("commandMatch(%q, %q) = %v, want %v", tt.pattern, tt.command, got, tt.want)
+		}
+	}
+}
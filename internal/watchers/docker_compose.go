@@ -0,0 +1,312 @@
+package watchers
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Fullex26/piguard/internal/dockerapi"
+	"github.com/Fullex26/piguard/pkg/models"
+)
+
+// Labels Compose sets on every container it creates.
+const (
+	composeProjectLabel     = "com.docker.compose.project"
+	composeServiceLabel     = "com.docker.compose.service"
+	composeWorkingDirLabel  = "com.docker.compose.project.working_dir"
+	composeConfigFilesLabel = "com.docker.compose.project.config_files"
+)
+
+// parseLabels parses the Labels column of `docker ps`, "k1=v1,k2=v2". A
+// value may itself contain commas (config_files lists several paths), so a
+// piece without "=" continues the previous value.
+func parseLabels(s string) map[string]string {
+	labels := make(map[string]string)
+	last := ""
+	for _, part := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(part, "=")
+		if !ok {
+			if last != "" {
+				labels[last] += "," + part
+			}
+			continue
+		}
+		labels[k] = v
+		last = k
+	}
+	return labels
+}
+
+// setCompose copies the Compose labels into the state.
+func (c *containerState) setCompose(labels map[string]string) {
+	c.Project = labels[composeProjectLabel]
+	c.Service = labels[composeServiceLabel]
+	c.WorkingDir = labels[composeWorkingDirLabel]
+}
+
+// label names the container in alerts: "project/service" for Compose
+// containers, otherwise the container name.
+func (c containerState) label() string {
+	if c.Project != "" && c.Service != "" {
+		return c.Project + "/" + c.Service
+	}
+	return c.Names
+}
+
+// serviceUp reports whether a container counts as a healthy service: running
+// and not unhealthy, finished with exit code 0 (one-shot services such as
+// migrations), or deliberately stopped. Crashed, restarting, dead and
+// unhealthy containers are down.
+func serviceUp(c containerState) bool {
+	switch c.State {
+	case "running":
+		return !isUnhealthy(c.Status)
+	case "exited":
+		return stoppedCleanly(c)
+	case "created", "paused":
+		return true // not started yet, or deliberately paused
+	}
+	return false
+}
+
+// stoppedCleanly reports whether an exited container finished or was
+// stopped rather than crashed: exit 0, or 143 and 137 from the SIGTERM that
+// `docker stop` and `compose stop|down` send and the SIGKILL that follows
+// when it times out. An OOM kill also exits 137 but is a crash.
+func stoppedCleanly(c containerState) bool {
+	switch parseExitCode(c.Status) {
+	case 0, 143:
+		return true
+	case 137:
+		return !c.OOMKilled
+	}
+	return false
+}
+
+// composeProject summarises the services of one Compose project.
+type composeProject struct {
+	Name       string
+	WorkingDir string
+	Services   map[string]bool // service → up (any of its containers up)
+}
+
+// down lists the project's down services, sorted.
+func (p composeProject) down() []string {
+	var down []string
+	for svc, up := range p.Services {
+		if !up {
+			down = append(down, svc)
+		}
+	}
+	sort.Strings(down)
+	return down
+}
+
+// composeProjects groups containers by Compose project. Standalone
+// containers are left out.
+func composeProjects(containers map[string]containerState) map[string]*composeProject {
+	projects := make(map[string]*composeProject)
+	for _, c := range containers {
+		if c.Project == "" || c.Service == "" {
+			continue
+		}
+		p := projects[c.Project]
+		if p == nil {
+			p = &composeProject{Name: c.Project, Services: make(map[string]bool)}
+			projects[c.Project] = p
+		}
+		if p.WorkingDir == "" {
+			p.WorkingDir = c.WorkingDir
+		}
+		p.Services[c.Service] = p.Services[c.Service] || serviceUp(c)
+	}
+	return projects
+}
+
+// downServices maps each project to its currently down services.
+func downServices(containers map[string]containerState) map[string]map[string]bool {
+	return projectDownSets(composeProjects(containers))
+}
+
+func projectDownSets(projects map[string]*composeProject) map[string]map[string]bool {
+	down := make(map[string]map[string]bool)
+	for name, p := range projects {
+		set := make(map[string]bool)
+		for _, svc := range p.down() {
+			set[svc] = true
+		}
+		down[name] = set
+	}
+	return down
+}
+
+// projectDownGrace is how long after ExpectProjectDown a project's removal
+// is still taken as the deliberate down; compose actions time out sooner.
+const projectDownGrace = 15 * time.Minute
+
+// ExpectProjectDown marks project as being taken down on purpose, as the
+// Telegram Down action does, so its removal is not alerted.
+func (w *DockerWatcher) ExpectProjectDown(project string) {
+	w.downMu.Lock()
+	defer w.downMu.Unlock()
+	w.expectedDown[project] = time.Now()
+}
+
+// takeExpectedDown reports whether project's removal was expected and
+// clears the mark, dropping marks too old to apply.
+func (w *DockerWatcher) takeExpectedDown(project string) bool {
+	w.downMu.Lock()
+	defer w.downMu.Unlock()
+	expected := false
+	for name, at := range w.expectedDown {
+		if time.Since(at) > projectDownGrace {
+			delete(w.expectedDown, name)
+		} else if name == project {
+			expected = true
+			delete(w.expectedDown, name)
+		}
+	}
+	return expected
+}
+
+// checkProjects compares each project's down services with the previous
+// check. A project is reported degraded when a service goes down (again
+// whenever another one follows) and recovered once every service is back.
+// A project whose containers were all removed is reported once with every
+// service down and then forgotten, so it counts as new if it comes back;
+// a removal asked for with ExpectProjectDown is forgotten silently.
+func (w *DockerWatcher) checkProjects(hostname string) {
+	projects := composeProjects(w.baseline)
+	removed := make(map[string]bool)
+	for name, prev := range w.projects {
+		if _, ok := projects[name]; ok || w.takeExpectedDown(name) {
+			continue
+		}
+		gone := &composeProject{Name: name, WorkingDir: prev.WorkingDir, Services: make(map[string]bool, len(prev.Services))}
+		for svc := range prev.Services {
+			gone.Services[svc] = false
+		}
+		projects[name] = gone
+		removed[name] = true
+	}
+
+	for name, p := range projects {
+		down := p.down()
+		prev := w.projectDown[name]
+
+		var newlyDown bool
+		for _, svc := range down {
+			if !prev[svc] {
+				newlyDown = true
+			}
+		}
+		up := len(p.Services) - len(down)
+		switch {
+		case newlyDown && removed[name]:
+			w.emitProject(hostname, models.EventProjectDegraded, models.SeverityWarning, p,
+				fmt.Sprintf("Compose project degraded: %s (all containers removed, %d services down)", name, len(p.Services)),
+				removedSuggestion(p))
+		case newlyDown:
+			w.emitProject(hostname, models.EventProjectDegraded, models.SeverityWarning, p,
+				fmt.Sprintf("Compose project degraded: %s (%s down, %d/%d services up)",
+					name, strings.Join(down, ", "), up, len(p.Services)),
+				composeSuggestion(p, down))
+		case len(down) == 0 && len(prev) > 0:
+			w.emitProject(hostname, models.EventProjectRecovered, models.SeverityInfo, p,
+				fmt.Sprintf("Compose project recovered: %s (%d/%d services up)", name, up, len(p.Services)), "")
+		}
+	}
+	for name := range removed {
+		delete(projects, name)
+	}
+	w.projects = projects
+	w.projectDown = projectDownSets(projects)
+}
+
+// composeSuggestion points at the logs of the down services.
+func composeSuggestion(p *composeProject, down []string) string {
+	cmd := "docker compose -p " + p.Name
+	if p.WorkingDir != "" {
+		cmd = "cd " + p.WorkingDir + " && docker compose"
+	}
+	return fmt.Sprintf("Check the logs: %s logs --tail 50 %s. Restart from Telegram with /docker project %s up",
+		cmd, strings.Join(down, " "), p.Name)
+}
+
+// removedSuggestion tells how to bring back a project whose containers are
+// gone; Telegram can't, as the compose files are only known from labels.
+func removedSuggestion(p *composeProject) string {
+	if p.WorkingDir == "" {
+		return "If this wasn't a deliberate docker compose down, bring it back with docker compose up -d in its directory"
+	}
+	return fmt.Sprintf("If this wasn't a deliberate docker compose down, bring it back with: cd %s && docker compose up -d", p.WorkingDir)
+}
+
+func (w *DockerWatcher) emitProject(hostname string, evType models.EventType, sev models.Severity,
+	p *composeProject, msg, suggested string) {
+	details := "Project: " + p.Name
+	if p.WorkingDir != "" {
+		details += " | Directory: " + p.WorkingDir
+	}
+	w.Bus.Publish(models.Event{
+		ID:        fmt.Sprintf("%s-%s-%d", string(evType), p.Name, time.Now().UnixNano()),
+		Type:      evType,
+		Severity:  sev,
+		Hostname:  hostname,
+		Timestamp: time.Now(),
+		Message:   msg,
+		Details:   details,
+		Suggested: suggested,
+		Source:    "docker",
+	})
+}
+
+// composeProjectContainers lists all containers of a Compose project through
+// the Engine API, or the runtime's CLI when the socket can't be reached.
+func composeProjectContainers(api *dockerapi.Client, rt dockerapi.Runtime, project string) ([]dockerapi.Container, error) {
+	filter := composeProjectLabel + "=" + project
+	if api != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if list, err := api.ListContainers(ctx, true); err == nil {
+			var matched []dockerapi.Container
+			for _, c := range list {
+				if c.Labels[composeProjectLabel] == project {
+					matched = append(matched, c)
+				}
+			}
+			return matched, nil
+		}
+	}
+	out, err := exec.Command(rt.CLI(), append(rt.PSArgs(), "--filter", "label="+filter)...).Output()
+	if err != nil {
+		return nil, err
+	}
+	states, _ := parseDockerOutput(string(out))
+	containers := make([]dockerapi.Container, 0, len(states))
+	for _, s := range states {
+		containers = append(containers, dockerapi.Container{
+			ID: s.ID, Names: []string{"/" + s.Names}, Image: s.Image, State: s.State, Status: s.Status,
+			Labels: parseLabels(s.Labels),
+		})
+	}
+	return containers, nil
+}
+
+// composeArgs builds the `docker compose` arguments that address a project
+// from its labels: the project name, its directory and its compose files.
+func composeArgs(labels map[string]string) []string {
+	args := []string{"compose", "--project-name", labels[composeProjectLabel]}
+	if dir := labels[composeWorkingDirLabel]; dir != "" {
+		args = append(args, "--project-directory", dir)
+	}
+	if files := labels[composeConfigFilesLabel]; files != "" {
+		for _, f := range strings.Split(files, ",") {
+			args = append(args, "--file", f)
+		}
+	}
+	return args
+}
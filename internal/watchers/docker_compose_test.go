@@ -0,0 +1,397 @@
+package watchers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fullex26/piguard/pkg/models"
+)
+
+const composeLabels = `com.docker.compose.project=media,com.docker.compose.service=%s,` +
+	`com.docker.compose.project.working_dir=/opt/media,` +
+	`com.docker.compose.project.config_files=/opt/media/compose.yml,/opt/media/compose.override.yml`
+
+func composeJSON(id, service, state, status string) string {
+	return fmt.Sprintf(`{"ID":%q,"Names":"media-%s-1","Image":"%s:latest","State":%q,"Status":%q,"Labels":%q}`,
+		id, service, service, state, status, fmt.Sprintf(composeLabels, service))
+}
+
+func TestParseLabels(t *testing.T) {
+	labels := parseLabels(fmt.Sprintf(composeLabels, "plex") + ",maintainer=someone")
+	want := map[string]string{
+		composeProjectLabel:     "media",
+		composeServiceLabel:     "plex",
+		composeWorkingDirLabel:  "/opt/media",
+		composeConfigFilesLabel: "/opt/media/compose.yml,/opt/media/compose.override.yml",
+		"maintainer":            "someone",
+	}
+	if len(labels) != len(want) {
+		t.Errorf("labels = %v, want %v", labels, want)
+	}
+	for k, v := range want {
+		if labels[k] != v {
+			t.Errorf("labels[%s] = %q, want %q", k, labels[k], v)
+		}
+	}
+	if got := parseLabels(""); len(got) != 0 {
+		t.Errorf("parseLabels(\"\") = %v, want empty", got)
+	}
+}
+
+func TestContainerState_Label(t *testing.T) {
+	c := containerState{Names: "media-plex-1"}
+	if got := c.label(); got != "media-plex-1" {
+		t.Errorf("standalone label = %q", got)
+	}
+	c.setCompose(parseLabels(fmt.Sprintf(composeLabels, "plex")))
+	if got := c.label(); got != "media/plex" {
+		t.Errorf("compose label = %q, want media/plex", got)
+	}
+	if got := containerDetails(c); !strings.HasPrefix(got, "Project: media | Service: plex | Container: media-plex-1 | ") {
+		t.Errorf("details = %q", got)
+	}
+}
+
+func TestServiceUp(t *testing.T) {
+	tests := []struct {
+		state, status string
+		want          bool
+	}{
+		{"running", "Up 2 hours", true},
+		{"running", "Up 2 hours (healthy)", true},
+		{"running", "Up 2 hours (unhealthy)", false},
+		{"exited", "Exited (0) 1 hour ago", true},
+		{"exited", "Exited (1) 1 hour ago", false},
+		{"exited", "Exited (143) 1 hour ago", true}, // compose stop
+		{"exited", "Exited (137) 1 hour ago", true}, // compose stop after the timeout
+		{"restarting", "Restarting (1) 5 seconds ago", false},
+		{"created", "Created", true},
+		{"dead", "Dead", false},
+	}
+	for _, tt := range tests {
+		if got := serviceUp(containerState{State: tt.state, Status: tt.status}); got != tt.want {
+			t.Errorf("serviceUp(%s, %q) = %v, want %v", tt.state, tt.status, got, tt.want)
+		}
+	}
+	if serviceUp(containerState{State: "exited", Status: "Exited (137) 1 hour ago", OOMKilled: true}) {
+		t.Error("an OOM-killed container should be down")
+	}
+}
+
+func TestComposeArgs(t *testing.T) {
+	got := strings.Join(composeArgs(parseLabels(fmt.Sprintf(composeLabels, "plex"))), " ")
+	want := "compose --project-name media --project-directory /opt/media --file /opt/media/compose.yml --file /opt/media/compose.override.yml"
+	if got != want {
+		t.Errorf("composeArgs = %q, want %q", got, want)
+	}
+	if got := strings.Join(composeArgs(map[string]string{composeProjectLabel: "x"}), " "); got != "compose --project-name x" {
+		t.Errorf("composeArgs without labels = %q", got)
+	}
+}
+
+func TestDockerWatcher_Check_ProjectDegradedAndRecovered(t *testing.T) {
+	healthy := composeJSON("plex1", "plex", "running", "Up 1 hour") + "\n" +
+		composeJSON("db1", "db", "running", "Up 1 hour") + "\n" +
+		composeJSON("init1", "init", "exited", "Exited (0) 1 hour ago")
+	crashed := composeJSON("plex1", "plex", "running", "Up 1 hour") + "\n" +
+		composeJSON("db1", "db", "exited", "Exited (1) 2 seconds ago") + "\n" +
+		composeJSON("init1", "init", "exited", "Exited (0) 1 hour ago")
+	output := crashed
+	w, received := newTestDockerWatcher(false, func() ([]byte, error) { return []byte(output), nil })
+	seedBaseline(w, healthy)
+	w.projectDown = downServices(w.baseline)
+
+	w.check()
+	var degraded models.Event
+	for i := 0; i < 2; i++ {
+		e := awaitEvent(t, received)
+		switch e.Type {
+		case models.EventContainerDied:
+			if !strings.Contains(e.Message, "media/db") || !strings.Contains(e.Details, "Container: media-db-1") {
+				t.Errorf("crash alert = %q / %q, want project/service naming", e.Message, e.Details)
+			}
+		case models.EventProjectDegraded:
+			degraded = e
+		default:
+			t.Errorf("unexpected event %s", e.Type)
+		}
+	}
+	if degraded.Message != "Compose project degraded: media (db down, 2/3 services up)" {
+		t.Errorf("degraded message = %q", degraded.Message)
+	}
+	if degraded.Severity != models.SeverityWarning || !strings.Contains(degraded.Suggested, "cd /opt/media && docker compose logs --tail 50 db") {
+		t.Errorf("degraded event = %v %q", degraded.Severity, degraded.Suggested)
+	}
+
+	// Still down: no repeat.
+	w.check()
+	expectNoEvent(t, received)
+
+	output = healthy
+	w.check()
+	var recovered bool
+	for i := 0; i < 2; i++ {
+		if e := awaitEvent(t, received); e.Type == models.EventProjectRecovered {
+			recovered = e.Message == "Compose project recovered: media (3/3 services up)" && e.Severity == models.SeverityInfo
+		}
+	}
+	if !recovered {
+		t.Error("expected a recovered event")
+	}
+}
+
+func TestDockerWatcher_Check_ProjectStoppedNotDegraded(t *testing.T) {
+	running := composeJSON("plex1", "plex", "running", "Up 1 hour") + "\n" +
+		composeJSON("db1", "db", "running", "Up 1 hour")
+	stopped := composeJSON("plex1", "plex", "exited", "Exited (143) 1 second ago") + "\n" +
+		composeJSON("db1", "db", "exited", "Exited (137) 1 second ago")
+	w, received := newTestDockerWatcher(false, func() ([]byte, error) { return []byte(stopped), nil })
+	seedBaseline(w, running)
+	w.projectDown = downServices(w.baseline)
+
+	w.check()
+	deadline := time.After(200 * time.Millisecond)
+	for {
+		select {
+		case e := <-received:
+			if e.Type == models.EventProjectDegraded {
+				t.Errorf("compose stop reported as %q", e.Message)
+			}
+			continue
+		case <-deadline:
+		}
+		break
+	}
+}
+
+func TestDockerWatcher_Check_ProjectRemoved(t *testing.T) {
+	running := composeJSON("plex1", "plex", "running", "Up 1 hour") + "\n" +
+		composeJSON("db1", "db", "running", "Up 1 hour")
+	output := ""
+	w, received := newTestDockerWatcher(false, func() ([]byte, error) { return []byte(output), nil })
+	seedBaseline(w, running)
+	w.projects = composeProjects(w.baseline)
+	w.projectDown = downServices(w.baseline)
+
+	w.check()
+	e := awaitEvent(t, received)
+	if e.Type != models.EventProjectDegraded || e.Message != "Compose project degraded: media (all containers removed, 2 services down)" {
+		t.Errorf("event = %s %q, want degraded for the removed project", e.Type, e.Message)
+	}
+	if !strings.Contains(e.Suggested, "cd /opt/media && docker compose up -d") {
+		t.Errorf("suggestion = %q", e.Suggested)
+	}
+
+	// Still gone: no repeat, and the project is no longer tracked.
+	w.check()
+	expectNoEvent(t, received)
+	if _, ok := w.projects["media"]; ok {
+		t.Error("removed project should be dropped after its alert")
+	}
+	if _, ok := w.projectDown["media"]; ok {
+		t.Error("removed project should leave projectDown")
+	}
+
+	// Coming back is a new project: container starts, no project event.
+	output = running
+	w.check()
+	for i := 0; i < 2; i++ {
+		if e := awaitEvent(t, received); e.Type != models.EventContainerStart {
+			t.Errorf("event = %s %q, want only container starts", e.Type, e.Message)
+		}
+	}
+	expectNoEvent(t, received)
+}
+
+func TestDockerWatcher_Check_ProjectDownFromTelegram(t *testing.T) {
+	running := composeJSON("plex1", "plex", "running", "Up 1 hour")
+	output := ""
+	w, received := newTestDockerWatcher(false, func() ([]byte, error) { return []byte(output), nil })
+	seedBaseline(w, running)
+	w.projects = composeProjects(w.baseline)
+	w.projectDown = downServices(w.baseline)
+
+	w.ExpectProjectDown("media")
+	w.check()
+	expectNoEvent(t, received)
+	if _, ok := w.projects["media"]; ok {
+		t.Error("deliberately downed project should be dropped")
+	}
+	if len(w.expectedDown) != 0 {
+		t.Errorf("expectedDown = %v, want the mark consumed", w.expectedDown)
+	}
+
+	// A stale mark no longer hides a removal.
+	seedBaseline(w, running)
+	w.projects = composeProjects(w.baseline)
+	w.projectDown = downServices(w.baseline)
+	w.expectedDown["media"] = time.Now().Add(-projectDownGrace - time.Minute)
+	w.check()
+	if e := awaitEvent(t, received); e.Type != models.EventProjectDegraded {
+		t.Errorf("event = %s %q, want degraded", e.Type, e.Message)
+	}
+}
+
+func TestDockerWatcher_Check_StandaloneNoProjectEvents(t *testing.T) {
+	exited := `{"ID":"abc","Names":"nginx","Image":"nginx","State":"exited","Status":"Exited (1) 1 second ago"}`
+	w, received := newTestDockerWatcher(false, func() ([]byte, error) { return []byte(exited), nil })
+	seedBaseline(w, `{"ID":"abc","Names":"nginx","Image":"nginx","State":"running","Status":"Up 1 hour"}`)
+	w.projectDown = downServices(w.baseline)
+
+	w.check()
+	if e := awaitEvent(t, received); e.Type != models.EventContainerDied || !strings.Contains(e.Message, "nginx") {
+		t.Errorf("event = %s %q, want crash of nginx", e.Type, e.Message)
+	}
+	expectNoEvent(t, received)
+}
+
+const composeListJSON = `[
+	{"Id":"plex1","Names":["/media-plex-1"],"Image":"plex","State":"running","Status":"Up 1 hour",
+	 "Labels":{"com.docker.compose.project":"media","com.docker.compose.service":"plex",
+	           "com.docker.compose.project.working_dir":"/opt/media"}},
+	{"Id":"db1","Names":["/media-db-1"],"Image":"postgres","State":"exited","Status":"Exited (1) 1 minute ago",
+	 "Labels":{"com.docker.compose.project":"media","com.docker.compose.service":"db",
+	           "com.docker.compose.project.working_dir":"/opt/media"}},
+	{"Id":"ng1","Names":["/nginx"],"Image":"nginx","State":"running","Status":"Up 3 days"}
+]`
+
+func TestCmdDocker_GroupsByProject(t *testing.T) {
+	w := &TelegramBotWatcher{docker: fakeDockerSocket(t, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(rw, composeListJSON)
+	}))}
+
+	result := w.cmdDocker()
+	for _, want := range []string{
+		"📦 <b>media</b> — 1/2 services up",
+		"   ✅ plex — Up 1 hour",
+		"   🔴 db — Exited (1) 1 minute ago",
+		"✅ <b>nginx</b>",
+	} {
+		if !containsString(result, want) {
+			t.Errorf("cmdDocker missing %q:\n%s", want, result)
+		}
+	}
+	if strings.Index(result, "media") > strings.Index(result, "nginx") {
+		t.Errorf("projects should be listed before standalone containers:\n%s", result)
+	}
+}
+
+func TestComposeProjectAction(t *testing.T) {
+	var calls []string
+	w := &TelegramBotWatcher{docker: fakeDockerSocket(t, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			fmt.Fprint(rw, composeListJSON)
+			return
+		}
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		rw.WriteHeader(http.StatusNoContent)
+	}))}
+	var ran []string
+	w.composeExec = func(args []string) ([]byte, error) {
+		ran = append(ran, strings.Join(args, " "))
+		return nil, nil
+	}
+
+	if result := w.composeProjectAction("media", "restart"); !containsString(result, "restarted (2 containers)") {
+		t.Errorf("restart result = %q", result)
+	}
+	if len(calls) != 2 || calls[0] != "POST /containers/plex1/restart" {
+		t.Errorf("API calls = %v", calls)
+	}
+
+	if result := w.composeProjectAction("media", "up"); !containsString(result, "is up") {
+		t.Errorf("up result = %q", result)
+	}
+	if result := w.composeProjectAction("media", "pull"); !containsString(result, "Images pulled") {
+		t.Errorf("pull result = %q", result)
+	}
+	w.DockerWatcher, _ = newTestDockerWatcher(false, nil)
+	if result := w.composeProjectAction("media", "down"); !containsString(result, "is down") {
+		t.Errorf("down result = %q", result)
+	}
+	if _, ok := w.DockerWatcher.expectedDown["media"]; !ok {
+		t.Error("down should tell the Docker watcher to expect the removal")
+	}
+	want := []string{
+		"compose --project-name media --project-directory /opt/media up --detach",
+		"compose --project-name media --project-directory /opt/media pull --quiet",
+		"compose --project-name media --project-directory /opt/media down",
+	}
+	if strings.Join(ran, "\n") != strings.Join(want, "\n") {
+		t.Errorf("compose commands = %q, want %q", ran, want)
+	}
+
+	if result := w.composeProjectAction("ghost", "up"); !containsString(result, "No containers found") {
+		t.Errorf("unknown project result = %q", result)
+	}
+}
+
+func TestBuildProjectConfirm(t *testing.T) {
+	w := &TelegramBotWatcher{}
+	text, buttons, ok := w.buildProjectConfirm("media", "pull")
+	if !ok || !containsString(text, "Pull the latest images for Compose project <b>media</b>") {
+		t.Fatalf("confirm = %v %q", ok, text)
+	}
+	ref := projectRef("media")
+	if buttons[0][0].Data != "d:p!:pull:"+ref || buttons[0][1].Data != "d:p:"+ref {
+		t.Errorf("buttons = %+v", buttons)
+	}
+	if text, _, ok := w.buildProjectConfirm("media", "down"); !ok || !containsString(text, "volumes are kept") {
+		t.Errorf("down confirm = %v %q", ok, text)
+	}
+	if _, _, ok := w.buildProjectConfirm("media", "rm"); ok {
+		t.Error("unknown action should not produce a confirmation")
+	}
+
+	long := strings.Repeat("x", 60)
+	_, buttons, ok = w.buildProjectConfirm(long, "restart")
+	if !ok || len(buttons[0][0].Data) > 64 {
+		t.Errorf("long project name: ok=%v, callback %q over 64 bytes", ok, buttons[0][0].Data)
+	}
+}
+
+func TestBuildProjectList_LongNames(t *testing.T) {
+	long := strings.Repeat("homeassistant-stack-", 4)
+	w := &TelegramBotWatcher{docker: fakeDockerSocket(t, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(rw, strings.ReplaceAll(composeListJSON, `"com.docker.compose.project":"media"`, `"com.docker.compose.project":"`+long+`"`))
+	}))}
+
+	_, buttons := w.buildProjectList()
+	if len(buttons) != 1 || buttons[0][0].Data != "d:p:"+projectRef(long) {
+		t.Fatalf("buttons = %+v, want one button for the long-named project", buttons)
+	}
+	if got, ok := w.resolveProjectRef(projectRef(long)); !ok || got != long {
+		t.Errorf("resolveProjectRef = %q, %v", got, ok)
+	}
+}
+
+func TestHandleDockerAction_ProjectConfirmExecutes(t *testing.T) {
+	w := &TelegramBotWatcher{docker: fakeDockerSocket(t, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(rw, composeListJSON)
+	}))}
+	done := make(chan string, 1)
+	w.composeExec = func(args []string) ([]byte, error) {
+		done <- strings.Join(args, " ")
+		return nil, nil
+	}
+
+	w.handleDockerAction("d:pc:up:" + projectRef("media")) // confirmation only
+	select {
+	case <-done:
+		t.Fatal("compose ran before confirmation")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	w.handleDockerAction("d:p!:up:" + projectRef("media"))
+	select {
+	case got := <-done:
+		if !strings.HasSuffix(got, "up --detach") {
+			t.Errorf("ran %q", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("compose up was not run after confirmation")
+	}
+}
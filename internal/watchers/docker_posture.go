@@ -0,0 +1,303 @@
+package watchers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Fullex26/piguard/internal/dockerapi"
+	"github.com/Fullex26/piguard/pkg/models"
+)
+
+// postureStateKey persists the posture findings already alerted per
+// container ID as JSON, so restarting the daemon doesn't repeat the startup
+// audit's alerts for containers that haven't changed.
+const postureStateKey = "docker.posture_reported"
+
+// postureFinding is one risky setting in a container's configuration.
+type postureFinding struct {
+	Check     string // stable ID used by docker.posture_ignore, e.g. "privileged"
+	Severity  models.Severity
+	Problem   string // completes "Insecure container: <name> <problem>"
+	Suggested string
+}
+
+// auditContainer checks an inspected container for settings that weaken the
+// isolation between it and the host.
+func auditContainer(info *dockerapi.ContainerJSON) []postureFinding {
+	var findings []postureFinding
+	hc := info.HostConfig
+
+	if hc.Privileged {
+		findings = append(findings, postureFinding{
+			Check:     "privileged",
+			Severity:  models.SeverityCritical,
+			Problem:   "runs in privileged mode",
+			Suggested: "Privileged containers have full access to host devices and can escape to the host. Drop --privileged (privileged: true) and grant only what it needs with --cap-add or --device",
+		})
+	} else if granted := sysAdminCap(hc.CapAdd); granted != "" {
+		// Privileged already implies every capability.
+		findings = append(findings, postureFinding{
+			Check:     "cap_sys_admin",
+			Severity:  models.SeverityCritical,
+			Problem:   "has " + granted + " added",
+			Suggested: "CAP_SYS_ADMIN allows mounting filesystems and most container escapes. Remove it from --cap-add (cap_add) and add the narrower capability the workload needs",
+		})
+	}
+
+	if hc.NetworkMode == "host" {
+		findings = append(findings, postureFinding{
+			Check:     "host_network",
+			Severity:  models.SeverityWarning,
+			Problem:   "shares the host network namespace",
+			Suggested: "With --network host every port it opens is a host port, outside Docker's firewall rules. Use a bridge network and publish only the ports it needs",
+		})
+	}
+	if hc.PidMode == "host" {
+		findings = append(findings, postureFinding{
+			Check:     "host_pid",
+			Severity:  models.SeverityWarning,
+			Problem:   "shares the host PID namespace",
+			Suggested: "With --pid host it can see, and with enough privileges signal or trace, every process on the host. Remove --pid host (pid: host) unless it is a monitoring agent",
+		})
+	}
+
+	for _, m := range info.Mounts {
+		switch {
+		case isDockerSocket(m.Source):
+			findings = append(findings, postureFinding{
+				Check:     "docker_socket",
+				Severity:  models.SeverityCritical,
+				Problem:   "has the Docker socket mounted at " + m.Destination,
+				Suggested: "Access to docker.sock is root on the host, even when mounted read-only. Remove the mount, or put a filtering socket proxy in front of it that allows only the API calls it needs",
+			})
+		case m.Type == "bind" && m.RW && isSensitiveHostPath(m.Source):
+			findings = append(findings, postureFinding{
+				Check:     "writable_host_mount",
+				Severity:  models.SeverityCritical,
+				Problem:   fmt.Sprintf("mounts host %s writable at %s", path.Clean(m.Source), m.Destination),
+				Suggested: fmt.Sprintf("A writable %s mount lets the container change host users, services and boot files. Mount it read-only (%s:%s:ro) or bind only the files it needs", path.Clean(m.Source), m.Source, m.Destination),
+			})
+		}
+	}
+
+	if isRootUser(info.Config.User) {
+		findings = append(findings, postureFinding{
+			Check:     "root_user",
+			Severity:  models.SeverityWarning,
+			Problem:   "runs as root",
+			Suggested: "Root in the container is root on the host if it ever escapes. Run as an unprivileged user with --user 1000:1000 (user:) or a USER line in the Dockerfile",
+		})
+	}
+
+	if ports := publicBindings(hc.PortBindings); len(ports) > 0 {
+		findings = append(findings, postureFinding{
+			Check:     "public_port",
+			Severity:  models.SeverityWarning,
+			Problem:   "publishes " + strings.Join(ports, ", ") + " on all interfaces",
+			Suggested: "Ports published on 0.0.0.0 bypass UFW through the FORWARD chain. Bind them to an address, e.g. -p 127.0.0.1:8080:80, or filter them in the DOCKER-USER chain",
+		})
+	}
+	return findings
+}
+
+// sysAdminCap returns how CAP_SYS_ADMIN was granted in a --cap-add list, or "".
+func sysAdminCap(caps []string) string {
+	for _, c := range caps {
+		switch strings.TrimPrefix(strings.ToUpper(c), "CAP_") {
+		case "SYS_ADMIN":
+			return "CAP_SYS_ADMIN"
+		case "ALL":
+			return "all capabilities (including CAP_SYS_ADMIN)"
+		}
+	}
+	return ""
+}
+
+// isDockerSocket reports whether a host path is the Docker daemon socket.
+func isDockerSocket(source string) bool {
+	return path.Base(source) == "docker.sock"
+}
+
+// isSensitiveHostPath reports whether a bind mount source is the host root
+// filesystem or /etc.
+func isSensitiveHostPath(source string) bool {
+	switch path.Clean(source) {
+	case "/", "/etc":
+		return true
+	}
+	return false
+}
+
+// isRootUser reports whether a container's configured user is root. An empty
+// user means the image did not set one, so the process runs as root.
+func isRootUser(user string) bool {
+	name, _, _ := strings.Cut(user, ":")
+	return name == "" || name == "root" || name == "0"
+}
+
+// publicBindings lists the host ports published on every interface, sorted,
+// e.g. ["443/tcp", "8080/tcp"].
+func publicBindings(bindings map[string][]dockerapi.PortBinding) []string {
+	seen := make(map[string]bool)
+	var ports []string
+	for containerPort, hostBindings := range bindings {
+		_, proto, ok := strings.Cut(containerPort, "/")
+		if !ok {
+			proto = "tcp"
+		}
+		for _, b := range hostBindings {
+			if b.HostIP != "" && b.HostIP != "0.0.0.0" && b.HostIP != "::" {
+				continue
+			}
+			key := b.HostPort + "/" + proto
+			if b.HostPort == "" || b.HostPort == "0" {
+				key = containerPort + " (random host port)" // -P or "-p 80"
+			}
+			if !seen[key] {
+				seen[key] = true
+				ports = append(ports, key)
+			}
+		}
+	}
+	sort.Strings(ports)
+	return ports
+}
+
+// postureIgnored reports whether docker.posture_ignore suppresses check for
+// the named container. Entries are "check", "container:check" or "container:*".
+func postureIgnored(ignore []string, name, check string) bool {
+	for _, entry := range ignore {
+		container, ignored, scoped := strings.Cut(entry, ":")
+		if !scoped {
+			container, ignored = "", entry
+		}
+		if container != "" {
+			if ok, _ := path.Match(container, name); !ok {
+				continue
+			}
+		}
+		if ignored == check || ignored == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// auditBaseline audits the containers already running when the watcher
+// starts, which never pass through compare as new. Findings reported before
+// a restart are not repeated.
+func (w *DockerWatcher) auditBaseline(hostname string) {
+	if !w.Cfg.Docker.PostureAudit || w.inspectContainer == nil {
+		return
+	}
+	w.loadPostureReported()
+	for id := range w.postureReported {
+		if _, ok := w.baseline[id]; !ok {
+			delete(w.postureReported, id) // removed while the daemon was down
+		}
+	}
+	for _, c := range w.baseline {
+		if c.State == "running" {
+			w.auditPosture(hostname, c)
+		}
+	}
+	w.savePostureReported()
+}
+
+// auditPosture inspects a running container and raises one
+// docker.container_insecure event per risky setting not yet reported for it.
+func (w *DockerWatcher) auditPosture(hostname string, c containerState) {
+	if !w.Cfg.Docker.PostureAudit || w.inspectContainer == nil {
+		return
+	}
+	info, err := w.inspectContainer(c.ID)
+	if err != nil {
+		slog.Debug("docker posture audit skipped", "container", c.Names, "error", err)
+		return
+	}
+	var checks []string
+	changed := false
+	for _, f := range auditContainer(info) {
+		if f.Check == "root_user" && !w.Cfg.Docker.PostureRootUser {
+			continue
+		}
+		if postureIgnored(w.Cfg.Docker.PostureIgnore, c.Names, f.Check) {
+			continue
+		}
+		checks = append(checks, f.Check)
+		if slices.Contains(w.postureReported[c.ID], f.Check) {
+			continue
+		}
+		changed = true
+		w.emit(hostname, models.EventContainerInsecure, f.Severity,
+			fmt.Sprintf("Insecure container: %s %s", c.label(), f.Problem),
+			f.Suggested+fmt.Sprintf(". To accept this, add \"%s:%s\" to docker.posture_ignore", c.Names, f.Check), c)
+	}
+	if changed || len(checks) != len(w.postureReported[c.ID]) {
+		if w.postureReported == nil {
+			w.postureReported = make(map[string][]string)
+		}
+		w.postureReported[c.ID] = checks
+		w.savePostureReported()
+	}
+}
+
+func (w *DockerWatcher) loadPostureReported() {
+	w.postureReported = make(map[string][]string)
+	if w.store == nil {
+		return
+	}
+	saved, err := w.store.GetState(postureStateKey)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal([]byte(saved), &w.postureReported); err != nil {
+		slog.Warn("discarding saved docker posture findings", "error", err)
+		w.postureReported = make(map[string][]string)
+	}
+}
+
+func (w *DockerWatcher) savePostureReported() {
+	if w.store == nil {
+		return
+	}
+	data, err := json.Marshal(w.postureReported)
+	if err != nil {
+		return
+	}
+	if err := w.store.SetState(postureStateKey, string(data)); err != nil {
+		slog.Warn("saving docker posture findings failed", "error", err)
+	}
+}
+
+// defaultInspectContainer inspects through the Engine API, falling back to
+// `docker inspect`, which prints the same JSON.
+func (w *DockerWatcher) defaultInspectContainer(id string) (*dockerapi.ContainerJSON, error) {
+	if w.api != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if info, err := w.api.InspectContainer(ctx, id); err == nil {
+			return info, nil
+		}
+	}
+	out, err := exec.Command(containerCLI(w.Cfg), "inspect", id).Output()
+	if err != nil {
+		return nil, err
+	}
+	var infos []dockerapi.ContainerJSON
+	if err := json.Unmarshal(out, &infos); err != nil {
+		return nil, fmt.Errorf("parsing docker inspect: %w", err)
+	}
+	if len(infos) == 0 {
+		return nil, fmt.Errorf("docker inspect %s: no such container", id)
+	}
+	return &infos[0], nil
+}
@@ -0,0 +1,216 @@
+package watchers
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Fullex26/piguard/internal/dockerapi"
+	"github.com/Fullex26/piguard/pkg/models"
+)
+
+func inspectFixture(t *testing.T, data string) *dockerapi.ContainerJSON {
+	t.Helper()
+	var info dockerapi.ContainerJSON
+	if err := json.Unmarshal([]byte(data), &info); err != nil {
+		t.Fatalf("fixture: %v", err)
+	}
+	return &info
+}
+
+func findingChecks(findings []postureFinding) []string {
+	checks := make([]string, len(findings))
+	for i, f := range findings {
+		checks[i] = f.Check
+	}
+	return checks
+}
+
+func TestAuditContainer(t *testing.T) {
+	tests := []struct {
+		name    string
+		inspect string
+		want    []string
+	}{
+		{"hardened", `{"Config":{"User":"1000:1000"},"HostConfig":{"NetworkMode":"bridge",
+			"PortBindings":{"80/tcp":[{"HostIp":"127.0.0.1","HostPort":"8080"}]}},
+			"Mounts":[{"Type":"bind","Source":"/etc/nginx","Destination":"/etc/nginx","RW":false},
+			          {"Type":"volume","Source":"/var/lib/docker/volumes/data/_data","Destination":"/data","RW":true}]}`, nil},
+		{"default root user", `{"Config":{"User":""}}`, []string{"root_user"}},
+		{"named root user", `{"Config":{"User":"root:root"}}`, []string{"root_user"}},
+		{"privileged hides caps", `{"Config":{"User":"app"},"HostConfig":{"Privileged":true,"CapAdd":["SYS_ADMIN"]}}`, []string{"privileged"}},
+		{"cap sys admin", `{"Config":{"User":"app"},"HostConfig":{"CapAdd":["NET_ADMIN","CAP_SYS_ADMIN"]}}`, []string{"cap_sys_admin"}},
+		{"cap all", `{"Config":{"User":"app"},"HostConfig":{"CapAdd":["ALL"]}}`, []string{"cap_sys_admin"}},
+		{"host namespaces", `{"Config":{"User":"app"},"HostConfig":{"NetworkMode":"host","PidMode":"host"}}`, []string{"host_network", "host_pid"}},
+		{"docker socket read-only", `{"Config":{"User":"app"},
+			"Mounts":[{"Type":"bind","Source":"/run/docker.sock","Destination":"/var/run/docker.sock","RW":false}]}`, []string{"docker_socket"}},
+		{"writable host root and etc", `{"Config":{"User":"app"},
+			"Mounts":[{"Type":"bind","Source":"/","Destination":"/host","RW":true},
+			          {"Type":"bind","Source":"/etc/","Destination":"/host-etc","RW":true},
+			          {"Type":"bind","Source":"/","Destination":"/rootfs","RW":false}]}`, []string{"writable_host_mount", "writable_host_mount"}},
+		{"public port", `{"Config":{"User":"app"},"HostConfig":{"PortBindings":{"80/tcp":[{"HostIp":"","HostPort":"8080"}]}}}`, []string{"public_port"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findingChecks(auditContainer(inspectFixture(t, tt.inspect)))
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("checks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditContainer_Severities(t *testing.T) {
+	info := inspectFixture(t, `{"Config":{"User":""},"HostConfig":{"Privileged":true,"NetworkMode":"host"},
+		"Mounts":[{"Type":"bind","Source":"/var/run/docker.sock","Destination":"/var/run/docker.sock","RW":true}]}`)
+	want := map[string]models.Severity{
+		"privileged":    models.SeverityCritical,
+		"docker_socket": models.SeverityCritical,
+		"host_network":  models.SeverityWarning,
+		"root_user":     models.SeverityWarning,
+	}
+	for _, f := range auditContainer(info) {
+		if f.Severity != want[f.Check] {
+			t.Errorf("%s severity = %v, want %v", f.Check, f.Severity, want[f.Check])
+		}
+		if f.Suggested == "" {
+			t.Errorf("%s has no suggested fix", f.Check)
+		}
+	}
+}
+
+func TestPublicBindings(t *testing.T) {
+	got := publicBindings(map[string][]dockerapi.PortBinding{
+		"80/tcp":   {{HostIP: "0.0.0.0", HostPort: "8080"}, {HostIP: "::", HostPort: "8080"}},
+		"53/udp":   {{HostIP: "", HostPort: "53"}},
+		"443/tcp":  {{HostIP: "127.0.0.1", HostPort: "443"}},
+		"9000/tcp": {{HostIP: "", HostPort: ""}},
+	})
+	want := "53/udp, 8080/tcp, 9000/tcp (random host port)"
+	if strings.Join(got, ", ") != want {
+		t.Errorf("publicBindings = %q, want %q", strings.Join(got, ", "), want)
+	}
+}
+
+func TestPostureIgnored(t *testing.T) {
+	ignore := []string{"root_user", "watchtower:docker_socket", "netdata*:*"}
+	tests := []struct {
+		name, check string
+		want        bool
+	}{
+		{"nginx", "root_user", true},
+		{"nginx", "docker_socket", false},
+		{"watchtower", "docker_socket", true},
+		{"watchtower", "privileged", false},
+		{"netdata-agent", "host_pid", true},
+	}
+	for _, tt := range tests {
+		if got := postureIgnored(ignore, tt.name, tt.check); got != tt.want {
+			t.Errorf("postureIgnored(%s, %s) = %v, want %v", tt.name, tt.check, got, tt.want)
+		}
+	}
+}
+
+func TestDockerWatcher_Check_PostureAudit(t *testing.T) {
+	running := `{"ID":"risky123","Names":"portainer","Image":"portainer/portainer-ce","State":"running","Status":"Up 1 second"}`
+	w, received := newTestDockerWatcher(false, func() ([]byte, error) { return []byte(running), nil })
+	w.Cfg.Docker.PostureAudit = true
+	w.Cfg.Docker.PostureIgnore = []string{"root_user"}
+	inspected := 0
+	w.inspectContainer = func(id string) (*dockerapi.ContainerJSON, error) {
+		inspected++
+		return inspectFixture(t, `{"Config":{"User":""},"HostConfig":{"Privileged":true},
+			"Mounts":[{"Type":"bind","Source":"/var/run/docker.sock","Destination":"/var/run/docker.sock","RW":true}]}`), nil
+	}
+
+	w.check()
+	counts := make(map[models.EventType]int)
+	var messages []string
+	for i := 0; i < 3; i++ {
+		e := awaitEvent(t, received)
+		counts[e.Type]++
+		if e.Type == models.EventContainerInsecure {
+			messages = append(messages, e.Message)
+			if e.Severity != models.SeverityCritical || !strings.Contains(e.Suggested, "docker.posture_ignore") {
+				t.Errorf("event = %v %q, want critical with ignore hint", e.Severity, e.Suggested)
+			}
+		}
+	}
+	expectNoEvent(t, received) // root_user is ignored
+	if counts[models.EventContainerStart] != 1 || counts[models.EventContainerInsecure] != 2 {
+		t.Errorf("events = %v, want 1 start and 2 insecure", counts)
+	}
+	joined := strings.Join(messages, "\n")
+	if !strings.Contains(joined, "Insecure container: portainer runs in privileged mode") ||
+		!strings.Contains(joined, "Docker socket mounted at /var/run/docker.sock") {
+		t.Errorf("messages = %q", joined)
+	}
+
+	// Already known on the next poll: not audited again.
+	w.check()
+	expectNoEvent(t, received)
+	if inspected != 1 {
+		t.Errorf("inspected %d times, want 1", inspected)
+	}
+}
+
+func TestDockerWatcher_AuditBaseline(t *testing.T) {
+	db := openNetworkTestStore(t)
+	newWatcher := func() (*DockerWatcher, chan models.Event, *int) {
+		w, received := newTestDockerWatcher(false, nil)
+		w.store = db
+		w.Cfg.Docker.PostureAudit = true
+		inspected := 0
+		w.inspectContainer = func(id string) (*dockerapi.ContainerJSON, error) {
+			inspected++
+			return inspectFixture(t, `{"Config":{"User":""},"HostConfig":{"Privileged":true}}`), nil
+		}
+		w.baseline["risky123"] = containerState{ID: "risky123", Names: "portainer", State: "running"}
+		w.baseline["stopped1"] = containerState{ID: "stopped1", Names: "old", State: "exited"}
+		return w, received, &inspected
+	}
+
+	// Containers already running at startup are audited; root_user is off
+	// by default.
+	w, received, inspected := newWatcher()
+	w.auditBaseline("host")
+	e := awaitEvent(t, received)
+	if e.Type != models.EventContainerInsecure || !strings.Contains(e.Message, "privileged mode") {
+		t.Errorf("event = %s %q, want the privileged finding", e.Type, e.Message)
+	}
+	expectNoEvent(t, received)
+	if *inspected != 1 {
+		t.Errorf("inspected %d containers, want only the running one", *inspected)
+	}
+
+	// After a restart the same finding is not reported again.
+	w, received, _ = newWatcher()
+	w.auditBaseline("host")
+	expectNoEvent(t, received)
+
+	// Opting in to root_user reports the new finding only.
+	w, received, _ = newWatcher()
+	w.Cfg.Docker.PostureRootUser = true
+	w.auditBaseline("host")
+	if e := awaitEvent(t, received); !strings.Contains(e.Message, "runs as root") {
+		t.Errorf("message = %q, want the root_user finding", e.Message)
+	}
+	expectNoEvent(t, received)
+}
+
+func TestDockerWatcher_Check_PostureAuditDisabled(t *testing.T) {
+	running := `{"ID":"risky123","Names":"portainer","Image":"portainer/portainer-ce","State":"running","Status":"Up 1 second"}`
+	w, received := newTestDockerWatcher(false, func() ([]byte, error) { return []byte(running), nil })
+	w.Cfg.Docker.PostureAudit = false
+	w.inspectContainer = func(id string) (*dockerapi.ContainerJSON, error) {
+		t.Error("inspected although posture_audit is off")
+		return nil, errors.New("unexpected")
+	}
+
+	w.check()
+	if e := awaitEvent(t, received); e.Type != models.EventContainerStart {
+		t.Errorf("event = %s, want only the start alert", e.Type)
+	}
+	expectNoEvent(t, received)
+}
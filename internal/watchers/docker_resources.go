@@ -0,0 +1,259 @@
+package watchers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Fullex26/piguard/internal/config"
+	"github.com/Fullex26/piguard/internal/dockerapi"
+	"github.com/Fullex26/piguard/internal/eventbus"
+	"github.com/Fullex26/piguard/pkg/models"
+)
+
+// resourceLimits are the thresholds that apply to one container.
+type resourceLimits struct {
+	CPUPercent    int
+	MemoryPercent int
+	Pids          int
+	IOMBps        int
+	Sustained     time.Duration
+}
+
+// DockerResourceWatcher reads each container's CPU, memory, pids and block
+// I/O from its cgroup v2 files and alerts when a threshold stays exceeded
+// for the sustained period. It never runs the docker CLI; the Engine API is
+// only used to put names on container IDs.
+type DockerResourceWatcher struct {
+	Base
+	interval   time.Duration
+	sustained  time.Duration
+	root       string            // cgroup v2 mount point
+	api        *dockerapi.Client // nil = report short IDs
+	hostMemory func() int64      // bytes; injectable for tests
+
+	prev    map[string]cgroupUsage // container ID → previous reading
+	breach  map[string]time.Time   // "<id>.<metric>" → when the threshold was first exceeded
+	alerted map[string]bool        // "<id>.<metric>" → alert sent for the current breach
+}
+
+func NewDockerResourceWatcher(cfg *config.Config, bus *eventbus.Bus) *DockerResourceWatcher {
+	interval, err := time.ParseDuration(cfg.Docker.Resources.Interval)
+	if err != nil || interval <= 0 {
+		interval = 30 * time.Second
+	}
+	sustained, err := time.ParseDuration(cfg.Docker.Resources.Sustained)
+	if err != nil || sustained < 0 {
+		sustained = 5 * time.Minute
+	}
+	return &DockerResourceWatcher{
+		Base:       Base{Cfg: cfg, Bus: bus},
+		interval:   interval,
+		sustained:  sustained,
+		root:       cgroupRoot,
+		api:        dockerapi.New(cfg.Docker.Socket),
+		hostMemory: hostMemoryBytes,
+		prev:       make(map[string]cgroupUsage),
+		breach:     make(map[string]time.Time),
+		alerted:    make(map[string]bool),
+	}
+}
+
+func (w *DockerResourceWatcher) Name() string { return "docker-resources" }
+func (w *DockerResourceWatcher) Stop() error  { return nil }
+
+func (w *DockerResourceWatcher) Start(ctx context.Context) error {
+	if _, err := os.Stat(path.Join(w.root, "cgroup.controllers")); err != nil {
+		slog.Warn("cgroup v2 not available, container resource monitoring disabled", "root", w.root)
+		return nil
+	}
+	slog.Info("starting docker resource watcher", "interval", w.interval, "sustained", w.sustained)
+
+	w.check(time.Now())
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			w.check(now)
+		}
+	}
+}
+
+// check takes one reading of every container cgroup at now and evaluates
+// the thresholds against the rates since the previous reading.
+func (w *DockerResourceWatcher) check(now time.Time) {
+	dirs := findContainerCgroups(w.root)
+	current := make(map[string]cgroupUsage, len(dirs))
+	var names map[string]string
+	hostname, _ := os.Hostname()
+
+	for id, dir := range dirs {
+		cur, err := readCgroupUsage(dir)
+		if err != nil {
+			continue
+		}
+		cur.At = now
+		current[id] = cur
+		prev, ok := w.prev[id]
+		if !ok {
+			continue // rates need two readings
+		}
+		if names == nil {
+			names = containerNames(w.api)
+		}
+		s := usageStats(prev, cur, w.hostMemory())
+		s.ID, s.Name = id, displayName(names, id)
+		w.evaluate(hostname, s, w.limitsFor(s.Name), now)
+	}
+
+	for key := range w.breach {
+		id, _, _ := strings.Cut(key, ".")
+		if _, ok := current[id]; !ok {
+			delete(w.breach, key)
+			delete(w.alerted, key)
+		}
+	}
+	w.prev = current
+}
+
+// evaluate updates the breach timers for one container and raises an alert
+// for each metric that has been over its threshold for the sustained period.
+func (w *DockerResourceWatcher) evaluate(hostname string, s containerStats, lim resourceLimits, now time.Time) {
+	metrics := []struct {
+		name  string
+		value float64
+		limit int
+	}{
+		{"cpu", s.CPUPercent, lim.CPUPercent},
+		{"memory", s.MemoryPercent, lim.MemoryPercent},
+		{"pids", float64(s.Pids), lim.Pids},
+		{"io", s.IOBytesPerSec / 1e6, lim.IOMBps},
+	}
+	for _, m := range metrics {
+		key := s.ID + "." + m.name
+		if m.limit <= 0 || m.value <= float64(m.limit) {
+			delete(w.breach, key)
+			delete(w.alerted, key)
+			continue
+		}
+		since, ok := w.breach[key]
+		if !ok {
+			since = now
+			w.breach[key] = now
+		}
+		if now.Sub(since) < lim.Sustained || w.alerted[key] {
+			continue
+		}
+		w.alerted[key] = true
+		msg, suggested := resourceAlert(m.name, s, m.limit)
+		if lim.Sustained > 0 {
+			msg += " for " + shortDuration(now.Sub(since).Round(time.Second))
+		}
+		w.Bus.Publish(models.Event{
+			ID:        fmt.Sprintf("%s-%s-%s-%d", models.EventContainerResource, m.name, displayName(nil, s.ID), now.UnixNano()),
+			Type:      models.EventContainerResource,
+			Severity:  models.SeverityWarning,
+			Hostname:  hostname,
+			Timestamp: now,
+			Message:   msg,
+			Details:   statsLine(s),
+			Suggested: suggested,
+			Source:    "docker",
+		})
+	}
+}
+
+// resourceAlert returns the message and suggested fix for a breached metric.
+func resourceAlert(metric string, s containerStats, limit int) (string, string) {
+	switch metric {
+	case "cpu":
+		return fmt.Sprintf("Container %s CPU at %.0f%% (threshold: %d%%)", s.Name, s.CPUPercent, limit),
+			fmt.Sprintf("See what it is running: docker top %s. Cap it with --cpus (deploy.resources.limits.cpus)", s.Name)
+	case "memory":
+		if s.MemoryLimit > 0 {
+			return fmt.Sprintf("Container %s memory at %.0f%% of its %s limit (threshold: %d%%)", s.Name, s.MemoryPercent, formatKB(s.MemoryLimit/1024), limit),
+				"It will be OOM-killed at the limit. Raise --memory or look for a leak: docker stats " + s.Name
+		}
+		return fmt.Sprintf("Container %s using %.0f%% of host memory (threshold: %d%%)", s.Name, s.MemoryPercent, limit),
+			"Set a memory limit (--memory / deploy.resources.limits.memory) so one container can't starve the host"
+	case "pids":
+		return fmt.Sprintf("Container %s running %d processes (threshold: %d)", s.Name, s.Pids, limit),
+			fmt.Sprintf("A climbing process count usually means leaked workers or a fork bomb: docker top %s. Cap it with --pids-limit", s.Name)
+	default:
+		return fmt.Sprintf("Container %s disk I/O at %.1f MB/s (threshold: %d MB/s)", s.Name, s.IOBytesPerSec/1e6, limit),
+			fmt.Sprintf("Check what it is writing: docker logs --tail 50 %s. Sustained writes wear out SD cards", s.Name)
+	}
+}
+
+// limitsFor applies the first matching docker.resources.containers entry to
+// the default thresholds.
+func (w *DockerResourceWatcher) limitsFor(name string) resourceLimits {
+	rc := w.Cfg.Docker.Resources
+	lim := resourceLimits{
+		CPUPercent:    rc.CPUPercent,
+		MemoryPercent: rc.MemoryPercent,
+		Pids:          rc.Pids,
+		IOMBps:        rc.IOMBps,
+		Sustained:     w.sustained,
+	}
+	for _, o := range rc.Containers {
+		if ok, _ := path.Match(o.Name, name); !ok {
+			continue
+		}
+		override := func(dst *int, v int) {
+			if v != 0 {
+				*dst = v // -1 disables, like 0 in the defaults
+			}
+		}
+		override(&lim.CPUPercent, o.CPUPercent)
+		override(&lim.MemoryPercent, o.MemoryPercent)
+		override(&lim.Pids, o.Pids)
+		override(&lim.IOMBps, o.IOMBps)
+		if d, err := time.ParseDuration(o.Sustained); err == nil && d >= 0 {
+			lim.Sustained = d
+		}
+		break
+	}
+	return lim
+}
+
+// statsLine summarises a container's usage on one line.
+func statsLine(s containerStats) string {
+	mem := formatKB(s.MemoryBytes / 1024)
+	if s.MemoryLimit > 0 {
+		mem += " / " + formatKB(s.MemoryLimit/1024)
+	}
+	return fmt.Sprintf("CPU %.1f%% | Memory %s (%.0f%%) | PIDs %d | I/O %s/s",
+		s.CPUPercent, mem, s.MemoryPercent, s.Pids, formatKB(int64(s.IOBytesPerSec)/1024))
+}
+
+// topMemoryContainers returns up to n containers by memory use from a single
+// cgroup reading, for context in host memory alerts.
+func topMemoryContainers(root string, api *dockerapi.Client, n int) []containerStats {
+	dirs := findContainerCgroups(root)
+	if len(dirs) == 0 {
+		return nil
+	}
+	names := containerNames(api)
+	stats := make([]containerStats, 0, len(dirs))
+	for id, dir := range dirs {
+		u, err := readCgroupUsage(dir)
+		if err != nil {
+			continue
+		}
+		stats = append(stats, containerStats{ID: id, Name: displayName(names, id), MemoryBytes: u.MemoryBytes, MemoryLimit: u.MemoryLimit})
+	}
+	sortByMemory(stats)
+	if len(stats) > n {
+		stats = stats[:n]
+	}
+	return stats
+}
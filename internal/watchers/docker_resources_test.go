@@ -0,0 +1,162 @@
+package watchers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fullex26/piguard/internal/config"
+	"github.com/Fullex26/piguard/internal/eventbus"
+	"github.com/Fullex26/piguard/pkg/models"
+)
+
+func newTestResourceWatcher(t *testing.T, rc config.DockerResourcesConfig) (*DockerResourceWatcher, chan models.Event, string) {
+	t.Helper()
+	bus := eventbus.New()
+	received := make(chan models.Event, 10)
+	bus.Subscribe(func(e models.Event) { received <- e })
+
+	cfg := &config.Config{Docker: config.DockerConfig{Resources: rc}}
+	w := NewDockerResourceWatcher(cfg, bus)
+	w.root = t.TempDir()
+	w.api = nil
+	w.hostMemory = func() int64 { return 4 << 30 }
+	return w, received, w.root
+}
+
+// setUsage writes cumulative counters for a cgroupfs-driver container.
+func setUsage(t *testing.T, root, id string, cpuUsec, memBytes, pids int64) {
+	t.Helper()
+	writeCgroup(t, root, "docker/"+id, map[string]string{
+		"cpu.stat":       fmt.Sprintf("usage_usec %d\n", cpuUsec),
+		"memory.current": fmt.Sprintf("%d\n", memBytes),
+		"memory.max":     "max\n",
+		"pids.current":   fmt.Sprintf("%d\n", pids),
+	})
+}
+
+func TestDockerResourceWatcher_SustainedCPU(t *testing.T) {
+	w, received, root := newTestResourceWatcher(t, config.DockerResourcesConfig{
+		Sustained: "1m", CPUPercent: 90,
+	})
+	t0 := time.Now()
+	step := 30 * time.Second
+
+	// 1.5 cores busy every interval.
+	for i := int64(0); i <= 3; i++ {
+		setUsage(t, root, testContainerA, i*45_000_000, 100<<20, 5)
+		w.check(t0.Add(time.Duration(i) * step))
+		if i < 3 {
+			expectNoEvent(t, received) // first reading, then 0s and 30s over
+		}
+	}
+	e := awaitEvent(t, received)
+	if e.Type != models.EventContainerResource || e.Severity != models.SeverityWarning {
+		t.Fatalf("event = %s/%v", e.Type, e.Severity)
+	}
+	if want := "Container aaaaaaaaaaaa CPU at 150% (threshold: 90%) for 1m"; e.Message != want {
+		t.Errorf("message = %q, want %q", e.Message, want)
+	}
+	if !strings.Contains(e.Details, "CPU 150.0%") {
+		t.Errorf("details = %q", e.Details)
+	}
+
+	// Still high: one alert per breach.
+	setUsage(t, root, testContainerA, 4*45_000_000, 100<<20, 5)
+	w.check(t0.Add(4 * step))
+	expectNoEvent(t, received)
+}
+
+func TestDockerResourceWatcher_DipResetsTimer(t *testing.T) {
+	w, received, root := newTestResourceWatcher(t, config.DockerResourcesConfig{
+		Sustained: "1m", CPUPercent: 90,
+	})
+	t0 := time.Now()
+	step := 30 * time.Second
+	cpu := []int64{0, 45_000_000, 90_000_000, 91_000_000, 136_000_000, 181_000_000}
+	for i, usec := range cpu {
+		setUsage(t, root, testContainerA, usec, 100<<20, 5)
+		w.check(t0.Add(time.Duration(i) * step))
+	}
+	// Over at 30s and 60s, idle at 90s, over again at 120s and 150s: never
+	// a full minute.
+	expectNoEvent(t, received)
+}
+
+func TestDockerResourceWatcher_MemoryAndPidsImmediately(t *testing.T) {
+	w, received, root := newTestResourceWatcher(t, config.DockerResourcesConfig{
+		Sustained: "0s", MemoryPercent: 50, Pids: 100,
+	})
+	t0 := time.Now()
+	setUsage(t, root, testContainerA, 0, 3<<30, 500)
+	w.check(t0)
+	w.check(t0.Add(30 * time.Second))
+
+	msgs := []string{awaitEvent(t, received).Message, awaitEvent(t, received).Message}
+	joined := strings.Join(msgs, "\n")
+	if !strings.Contains(joined, "Container aaaaaaaaaaaa using 75% of host memory (threshold: 50%)") ||
+		!strings.Contains(joined, "Container aaaaaaaaaaaa running 500 processes (threshold: 100)") {
+		t.Errorf("messages = %q", joined)
+	}
+	if strings.Contains(joined, " for ") {
+		t.Errorf("messages = %q, want no duration without a sustained period", joined)
+	}
+}
+
+func TestDockerResourceWatcher_PerContainerOverrides(t *testing.T) {
+	w, received, root := newTestResourceWatcher(t, config.DockerResourcesConfig{
+		Sustained: "0s", CPUPercent: 90, Pids: 100,
+		Containers: []config.ContainerThresholds{
+			{Name: "aaaa*", CPUPercent: 300, Pids: -1},
+		},
+	})
+	t0 := time.Now()
+	setUsage(t, root, testContainerA, 0, 1<<20, 500)
+	setUsage(t, root, testContainerB, 0, 1<<20, 5)
+	w.check(t0)
+	setUsage(t, root, testContainerA, 60_000_000, 1<<20, 500) // 200%: under its own 300%
+	setUsage(t, root, testContainerB, 60_000_000, 1<<20, 5)   // 200%: over the default
+	w.check(t0.Add(30 * time.Second))
+
+	e := awaitEvent(t, received)
+	if !strings.HasPrefix(e.Message, "Container bbbbbbbbbbbb CPU at 200%") {
+		t.Errorf("message = %q, want only container B's CPU alert", e.Message)
+	}
+	expectNoEvent(t, received)
+}
+
+func TestDockerResourceWatcher_ForgetsRemovedContainers(t *testing.T) {
+	w, _, root := newTestResourceWatcher(t, config.DockerResourcesConfig{
+		Sustained: "5m", CPUPercent: 90,
+	})
+	t0 := time.Now()
+	setUsage(t, root, testContainerA, 0, 1<<20, 1)
+	w.check(t0)
+	setUsage(t, root, testContainerA, 60_000_000, 1<<20, 1)
+	w.check(t0.Add(30 * time.Second))
+	if len(w.breach) != 1 {
+		t.Fatalf("breach = %v, want CPU timer running", w.breach)
+	}
+
+	if err := os.RemoveAll(filepath.Join(root, "docker")); err != nil {
+		t.Fatal(err)
+	}
+	w.check(t0.Add(time.Minute))
+	if len(w.breach) != 0 || len(w.prev) != 0 {
+		t.Errorf("state kept for removed container: breach=%v prev=%v", w.breach, w.prev)
+	}
+}
+
+func TestTopMemoryContainers(t *testing.T) {
+	root := t.TempDir()
+	setUsage(t, root, testContainerA, 0, 100<<20, 1)
+	setUsage(t, root, testContainerB, 0, 900<<20, 1)
+
+	top := topMemoryContainers(root, nil, 1)
+	if len(top) != 1 || top[0].ID != testContainerB || top[0].Name != "bbbbbbbbbbbb" {
+		t.Errorf("top = %+v, want container B only", top)
+	}
+}
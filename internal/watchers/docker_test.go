@@ -1,12 +1,18 @@
 package watchers
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/Fullex26/piguard/internal/config"
+	"github.com/Fullex26/piguard/internal/dockerapi"
+	"github.com/Fullex26/piguard/internal/dockerapi/dockertest"
 	"github.com/Fullex26/piguard/internal/eventbus"
 	"github.com/Fullex26/piguard/pkg/models"
 )
@@ -134,7 +140,7 @@ func TestIsUnhealthy(t *testing.T) {
 
 func TestDockerWatcher_Name(t *testing.T) {
 	cfg := &config.Config{Docker: config.DockerConfig{PollInterval: "10s"}}
-	w := NewDockerWatcher(cfg, eventbus.New())
+	w := NewDockerWatcher(cfg, eventbus.New(), nil)
 	if got := w.Name(); got != "docker" {
 		t.Errorf("Name() = %q, want %q", got, "docker")
 	}
@@ -153,8 +159,11 @@ func newTestDockerWatcher(alertOnStop bool, stub func() ([]byte, error)) (*Docke
 			AlertOnStop:  alertOnStop,
 		},
 	}
-	w := NewDockerWatcher(cfg, bus)
+	w := NewDockerWatcher(cfg, bus, nil)
+	w.api = nil // exercise the docker CLI path
 	w.runDockerPS = stub
+	w.inspectRuntime = nil
+	w.inspectContainer = nil
 	return w, received
 }
 
@@ -349,6 +358,20 @@ func TestParseDockerOutput_PortsAndImageID(t *testing.T) {
 	}
 }
 
+func TestParseDockerOutput_Podman(t *testing.T) {
+	input := `[{"Id":"abc123","Names":["nginx"],"Image":"docker.io/library/nginx:latest","State":"running",
+		"Status":"Up 1 hour (healthy)","Labels":{"com.docker.compose.project":"site","com.docker.compose.service":"web"},
+		"Ports":[{"host_ip":"127.0.0.1","container_port":80,"host_port":8080,"protocol":"tcp"}]}]`
+	got, err := parseDockerOutput(input)
+	if err != nil || len(got) != 1 {
+		t.Fatalf("expected 1 container, got %v err=%v", got, err)
+	}
+	c := got[0]
+	if c.Names != "nginx" || c.Status != "Up 1 hour (healthy)" || c.Ports != "127.0.0.1:8080->80/tcp" || c.label() != "site/web" {
+		t.Errorf("container = %+v", c)
+	}
+}
+
 // ── Watchtower update detection ───────────────────────────────────────────────
 
 func TestDockerWatcher_Check_WatchtowerUpdate(t *testing.T) {
@@ -411,3 +434,298 @@ func TestDockerWatcher_Check_BrandNewContainer_NotWatchtower(t *testing.T) {
 		t.Errorf("event type = %q, want %q", e.Type, models.EventContainerStart)
 	}
 }
+
+// ── Engine API / event stream ────────────────────────────────────────────────
+
+// fakeDockerSocket serves handler on a unix socket, standing in for dockerd.
+func fakeDockerSocket(t *testing.T, handler http.Handler) *dockerapi.Client {
+	t.Helper()
+	return dockerapi.New(dockertest.Socket(t, handler))
+}
+
+func TestDockerWatcher_FetchContainers_API(t *testing.T) {
+	w, _ := newTestDockerWatcher(false, func() ([]byte, error) {
+		t.Error("docker CLI should not be used when the API answers")
+		return nil, errors.New("unexpected")
+	})
+	w.api = fakeDockerSocket(t, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(rw, `[{"Id":"abc123","Names":["/nginx"],"Image":"nginx:latest","ImageID":"sha256:aaa",
+			"State":"running","Status":"Up 1 hour","Ports":[{"IP":"0.0.0.0","PrivatePort":80,"PublicPort":8080,"Type":"tcp"}]}]`)
+	}))
+
+	got, err := w.fetchContainers()
+	if err != nil || len(got) != 1 {
+		t.Fatalf("fetchContainers = %v, %v", got, err)
+	}
+	if got[0].Names != "nginx" || got[0].Ports != "0.0.0.0:8080->80/tcp" || got[0].ImageID != "sha256:aaa" {
+		t.Errorf("container = %+v", got[0])
+	}
+}
+
+func TestDockerWatcher_FetchContainers_FallsBackToCLI(t *testing.T) {
+	w, _ := newTestDockerWatcher(false, func() ([]byte, error) {
+		return []byte(`{"ID":"abc123","Names":"nginx","State":"running","Status":"Up 1 hour"}`), nil
+	})
+	w.api = dockerapi.New(filepath.Join(t.TempDir(), "missing.sock"))
+
+	got, err := w.fetchContainers()
+	if err != nil || len(got) != 1 || got[0].Names != "nginx" {
+		t.Fatalf("fetchContainers = %v, %v; want CLI result", got, err)
+	}
+}
+
+func TestDockerWatcher_WatchEvents(t *testing.T) {
+	w, received := newTestDockerWatcher(false, func() ([]byte, error) {
+		return nil, errors.New("no docker CLI")
+	})
+	seedBaseline(w, `{"ID":"web123","Names":"web","Image":"web:1","State":"running","Status":"Up 1 hour"}`)
+
+	w.api = fakeDockerSocket(t, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/json":
+			fmt.Fprint(rw, `[{"Id":"web123","Names":["/web"],"Image":"web:1","State":"running","Status":"Up 1 hour"}]`)
+		case "/containers/web123/json":
+			fmt.Fprint(rw, `{"Id":"web123","Name":"/web","Image":"sha256:aaa","RestartCount":2,
+				"State":{"Status":"running","ExitCode":137},"Config":{"Image":"web:1"}}`)
+		case "/containers/gone456/json":
+			rw.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(rw, `{"message":"No such container"}`)
+		case "/events":
+			flusher := rw.(http.Flusher)
+			for _, action := range []string{"die", "start", "health_status: unhealthy"} {
+				fmt.Fprintf(rw, `{"Type":"container","Action":%q,"Actor":{"ID":"web123","Attributes":{"name":"web","exitCode":"137"}}}`+"\n", action)
+				flusher.Flush()
+			}
+			// A --rm container that is already gone when inspected.
+			fmt.Fprintln(rw, `{"Type":"container","Action":"start","Actor":{"ID":"gone456","Attributes":{"name":"job","image":"job:1"}}}`)
+			fmt.Fprintln(rw, `{"Type":"container","Action":"destroy","Actor":{"ID":"gone456","Attributes":{"name":"job"}}}`)
+		default:
+			http.NotFound(rw, r)
+		}
+	}))
+
+	if err := w.watchEvents(context.Background()); err == nil {
+		t.Error("expected an error when the stream ends")
+	}
+
+	got := make(map[string]models.Event)
+	for i := 0; i < 4; i++ {
+		e := awaitEvent(t, received)
+		got[e.Message] = e
+	}
+	expectNoEvent(t, received)
+
+	crash, ok := got["Container crashed: web (exit 137, killed by SIGKILL, possible OOM, no memory limit)"]
+	if !ok || crash.Type != models.EventContainerDied {
+		t.Fatalf("missing crash event, got %v", got)
+	}
+	if !strings.Contains(crash.Details, "Restarts: 2") {
+		t.Errorf("crash details = %q, want inspect data", crash.Details)
+	}
+	if _, ok := got["Container restarted: web (web:1)"]; !ok {
+		t.Errorf("missing restart event, got %v", got)
+	}
+	if e, ok := got["Container unhealthy: web"]; !ok || e.Type != models.EventContainerHealth {
+		t.Errorf("missing unhealthy event, got %v", got)
+	}
+	if _, ok := got["Container started: job (job:1)"]; !ok {
+		t.Errorf("missing start for uninspectable container, got %v", got)
+	}
+	if _, ok := w.baseline["gone456"]; ok {
+		t.Error("destroyed container should leave the baseline")
+	}
+}
+
+// ── crash loops and OOM kills ────────────────────────────────────────────────
+
+func TestDockerWatcher_Check_CrashLoopBetweenPolls(t *testing.T) {
+	running := `{"ID":"loop123","Names":"worker","Image":"worker:1","State":"running","Status":"Up 2 seconds"}`
+	w, received := newTestDockerWatcher(false, func() ([]byte, error) { return []byte(running), nil })
+	w.loopRestarts = 3
+
+	restarts := 0
+	w.inspectRuntime = func(ids []string) (map[string]runtimeInfo, error) {
+		return map[string]runtimeInfo{"loop123": {RestartCount: restarts}}, nil
+	}
+	w.check()               // baseline: restart count 0
+	awaitEvent(t, received) // "Container started"
+
+	// Each poll sees "running", but the restart count keeps climbing.
+	restarts = 2
+	w.check()
+	expectNoEvent(t, received)
+
+	restarts = 3
+	w.check()
+	e := awaitEvent(t, received)
+	if e.Type != models.EventContainerCrashLoop || e.Severity != models.SeverityCritical {
+		t.Errorf("event = %s/%v, want crash loop critical", e.Type, e.Severity)
+	}
+	if !strings.Contains(e.Message, "worker") || !strings.Contains(e.Message, "3 restarts in 5m") {
+		t.Errorf("message = %q", e.Message)
+	}
+
+	// Still looping: no repeat within the window.
+	restarts = 5
+	w.check()
+	expectNoEvent(t, received)
+}
+
+func TestDockerWatcher_Check_ExistingRestartsNotALoop(t *testing.T) {
+	running := `{"ID":"old123","Names":"veteran","Image":"app:1","State":"running","Status":"Up 3 days"}`
+	w, received := newTestDockerWatcher(false, func() ([]byte, error) { return []byte(running), nil })
+	w.loopRestarts = 3
+	seedBaseline(w, running) // seen before inspect data was available
+	w.inspectRuntime = func(ids []string) (map[string]runtimeInfo, error) {
+		return map[string]runtimeInfo{"old123": {RestartCount: 40}}, nil
+	}
+
+	w.check()
+	expectNoEvent(t, received)
+}
+
+func TestDockerWatcher_Check_CrashLoopDisabled(t *testing.T) {
+	running := `{"ID":"loop123","Names":"worker","Image":"worker:1","State":"running","Status":"Up 2 seconds"}`
+	w, received := newTestDockerWatcher(false, func() ([]byte, error) { return []byte(running), nil })
+	w.loopRestarts = 0
+	restarts := 0
+	w.inspectRuntime = func(ids []string) (map[string]runtimeInfo, error) {
+		return map[string]runtimeInfo{"loop123": {RestartCount: restarts}}, nil
+	}
+	w.check()
+	awaitEvent(t, received) // "Container started"
+	restarts = 10
+	w.check()
+	expectNoEvent(t, received)
+}
+
+func TestDockerWatcher_Check_OOMKilled(t *testing.T) {
+	running := `{"ID":"mem123","Names":"db","Image":"postgres:16","State":"running","Status":"Up 1 hour"}`
+	killed := `{"ID":"mem123","Names":"db","Image":"postgres:16","State":"exited","Status":"Exited (137) 2 seconds ago"}`
+	w, received := newTestDockerWatcher(false, func() ([]byte, error) { return []byte(killed), nil })
+	seedBaseline(w, running)
+	w.inspectRuntime = func(ids []string) (map[string]runtimeInfo, error) {
+		return map[string]runtimeInfo{"mem123": {OOMKilled: true, MemoryLimit: 256 << 20}}, nil
+	}
+
+	w.check()
+	e := awaitEvent(t, received)
+	if e.Type != models.EventContainerOOM {
+		t.Fatalf("event type = %s, want %s", e.Type, models.EventContainerOOM)
+	}
+	if !strings.Contains(e.Message, "exit 137") || !strings.Contains(e.Message, "limit 256 MB") {
+		t.Errorf("message = %q, want exit code and memory limit", e.Message)
+	}
+	expectNoEvent(t, received) // no separate crash alert
+}
+
+func TestDockerWatcher_Check_Exit137WithoutOOMIsCrash(t *testing.T) {
+	running := `{"ID":"kill123","Names":"app","Image":"app:1","State":"running","Status":"Up 1 hour"}`
+	killed := `{"ID":"kill123","Names":"app","Image":"app:1","State":"exited","Status":"Exited (137) 2 seconds ago"}`
+	w, received := newTestDockerWatcher(false, func() ([]byte, error) { return []byte(killed), nil })
+	seedBaseline(w, running)
+	w.inspectRuntime = func(ids []string) (map[string]runtimeInfo, error) {
+		return map[string]runtimeInfo{"kill123": {MemoryLimit: 512 << 20}}, nil
+	}
+
+	w.check()
+	e := awaitEvent(t, received)
+	if e.Type != models.EventContainerDied {
+		t.Errorf("event type = %s, want %s (OOMKilled isn't set)", e.Type, models.EventContainerDied)
+	}
+	if !strings.Contains(e.Message, "SIGKILL, possible OOM") || !strings.Contains(e.Message, "limit 512 MB") {
+		t.Errorf("message = %q, want possible OOM and memory limit", e.Message)
+	}
+}
+
+func TestDockerWatcher_Events_Exit137AfterStopIsNotCrash(t *testing.T) {
+	w, received := newTestDockerWatcher(true, func() ([]byte, error) { return nil, errors.New("no CLI") })
+	seedBaseline(w, `{"ID":"kill123","Names":"app","Image":"app:1","State":"running","Status":"Up 1 hour"}`)
+	w.api = fakeDockerSocket(t, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(rw, `{"Id":"kill123","Name":"/app","State":{"Status":"exited","ExitCode":137},"Config":{"Image":"app:1"}}`)
+	}))
+	event := func(action string, attrs map[string]string) dockerapi.Event {
+		var ev dockerapi.Event
+		ev.Type, ev.Action = "container", action
+		ev.Actor.ID = "kill123"
+		ev.Actor.Attributes = attrs
+		return ev
+	}
+	ctx := context.Background()
+
+	// docker stop: SIGTERM, then SIGKILL once the timeout runs out.
+	w.handleEvent(ctx, event("kill", map[string]string{"name": "app", "signal": "15"}))
+	w.handleEvent(ctx, event("kill", map[string]string{"name": "app", "signal": "9"}))
+	w.handleEvent(ctx, event("die", map[string]string{"name": "app", "exitCode": "137"}))
+
+	if e := awaitEvent(t, received); e.Type != models.EventContainerStopped {
+		t.Errorf("event type = %s (%q), want %s", e.Type, e.Message, models.EventContainerStopped)
+	}
+	expectNoEvent(t, received)
+	if len(w.killed) != 0 {
+		t.Errorf("killed = %v, want it consumed by the die event", w.killed)
+	}
+}
+
+func TestDockerWatcher_Events_OOMThenRestartLoop(t *testing.T) {
+	w, received := newTestDockerWatcher(false, func() ([]byte, error) { return nil, errors.New("no CLI") })
+	w.loopRestarts = 2
+	seedBaseline(w, `{"ID":"mem123","Names":"db","Image":"postgres:16","State":"running","Status":"Up 1 hour"}`)
+	c := w.baseline["mem123"]
+	c.Inspected = true
+	w.baseline["mem123"] = c
+
+	restarts := 0
+	w.api = fakeDockerSocket(t, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		// By the time inspect runs the restart policy has already started it
+		// again, so OOMKilled is no longer set.
+		fmt.Fprintf(rw, `{"Id":"mem123","Name":"/db","RestartCount":%d,"State":{"Status":"running"},
+			"Config":{"Image":"postgres:16"},"HostConfig":{"Memory":0}}`, restarts)
+	}))
+	event := func(action string) dockerapi.Event {
+		var ev dockerapi.Event
+		ev.Type, ev.Action = "container", action
+		ev.Actor.ID = "mem123"
+		ev.Actor.Attributes = map[string]string{"name": "db", "exitCode": "137"}
+		return ev
+	}
+	ctx := context.Background()
+
+	w.handleEvent(ctx, event("oom"))
+	w.handleEvent(ctx, event("die"))
+	restarts = 1
+	w.handleEvent(ctx, event("start"))
+	w.handleEvent(ctx, event("die"))
+	restarts = 2
+	w.handleEvent(ctx, event("start"))
+
+	// The bus delivers asynchronously, so compare counts rather than order.
+	counts := make(map[models.EventType]int)
+	for i := 0; i < 5; i++ {
+		counts[awaitEvent(t, received).Type]++
+	}
+	expectNoEvent(t, received)
+	want := map[models.EventType]int{
+		models.EventContainerOOM:       1, // first die, flagged by the oom event
+		models.EventContainerDied:      1, // second die is a plain crash
+		models.EventContainerStart:     2, // "Container restarted" twice
+		models.EventContainerCrashLoop: 1, // two policy restarts
+	}
+	if fmt.Sprint(counts) != fmt.Sprint(want) {
+		t.Errorf("events = %v, want %v", counts, want)
+	}
+}
+
+func TestParseInspectRuntime(t *testing.T) {
+	out := "abc 3 true 268435456\ndef 0 false 0\ngarbage\n"
+	got := parseInspectRuntime(out, make(map[string]runtimeInfo))
+	if len(got) != 2 {
+		t.Fatalf("entries = %d, want 2", len(got))
+	}
+	if got["abc"] != (runtimeInfo{RestartCount: 3, OOMKilled: true, MemoryLimit: 256 << 20}) {
+		t.Errorf("abc = %+v", got["abc"])
+	}
+	if got["def"] != (runtimeInfo{}) {
+		t.Errorf("def = %+v", got["def"])
+	}
+}
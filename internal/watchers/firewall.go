@@ -13,18 +13,22 @@ import (
 
 	"github.com/Fullex26/piguard/internal/config"
 	"github.com/Fullex26/piguard/internal/eventbus"
+	"github.com/Fullex26/piguard/internal/firewall"
+	"github.com/Fullex26/piguard/internal/store"
 	"github.com/Fullex26/piguard/pkg/models"
 )
 
 // FirewallWatcher monitors iptables chains for unexpected changes
 type FirewallWatcher struct {
 	Base
+	store        *store.Store      // nil = no baseline snapshot
 	baselines    map[string]string // chain -> rule hash
 	interval     time.Duration
 	execIptables func(table, chain string) ([]byte, error)
+	captureFn    func() (string, []byte, error) // full ruleset dump for snapshots
 }
 
-func NewFirewallWatcher(cfg *config.Config, bus *eventbus.Bus) *FirewallWatcher {
+func NewFirewallWatcher(cfg *config.Config, bus *eventbus.Bus, db *store.Store) *FirewallWatcher {
 	interval, _ := time.ParseDuration(cfg.Firewall.CheckInterval)
 	if interval == 0 {
 		interval = 60 * time.Second
@@ -32,11 +36,13 @@ func NewFirewallWatcher(cfg *config.Config, bus *eventbus.Bus) *FirewallWatcher
 
 	return &FirewallWatcher{
 		Base:      Base{Cfg: cfg, Bus: bus},
+		store:     db,
 		baselines: make(map[string]string),
 		interval:  interval,
 		execIptables: func(table, chain string) ([]byte, error) {
 			return exec.Command("iptables", "-t", table, "-L", chain, "-n").Output()
 		},
+		captureFn: firewall.New().Capture,
 	}
 }
 
@@ -54,6 +60,7 @@ func (w *FirewallWatcher) Start(ctx context.Context) error {
 		}
 		w.baselines[chain.Chain] = hashRules(rules)
 	}
+	w.snapshotBaseline()
 
 	// Check configured expectations immediately
 	w.checkExpectations()
@@ -73,6 +80,41 @@ func (w *FirewallWatcher) Start(ctx context.Context) error {
 
 func (w *FirewallWatcher) Stop() error { return nil }
 
+// snapshotBaseline saves the ruleset the watcher starts from as known good,
+// so "Restore last known good" has something to restore without a manual
+// snapshot. Unchanged rules are not saved again.
+func (w *FirewallWatcher) snapshotBaseline() {
+	if w.store == nil {
+		return
+	}
+	id, saved, err := saveFirewallSnapshot(w.store, w.captureFn, store.SnapshotBaseline, "baseline at startup")
+	if err != nil {
+		slog.Warn("cannot snapshot firewall baseline", "error", err)
+		return
+	}
+	if saved {
+		slog.Info("firewall baseline snapshot saved", "snapshot", id)
+	}
+}
+
+// saveFirewallSnapshot captures the live ruleset and saves it as kind, unless
+// it matches the last known good snapshot, whose ID is returned instead.
+func saveFirewallSnapshot(db *store.Store, capture func() (string, []byte, error), kind, label string) (int64, bool, error) {
+	backend, rules, err := capture()
+	if err != nil {
+		return 0, false, err
+	}
+	if last, err := db.GetLastGoodFirewallSnapshot(); err == nil &&
+		last.Backend == backend && firewall.SameRules(last.Rules, string(rules)) {
+		return last.ID, false, nil
+	}
+	id, err := db.SaveFirewallSnapshot(kind, label, backend, string(rules))
+	if err != nil {
+		return 0, false, err
+	}
+	return id, true, nil
+}
+
 func (w *FirewallWatcher) check() {
 	w.checkExpectations()
 	w.checkDrift()
@@ -169,6 +211,7 @@ func (w *FirewallWatcher) checkDrift() {
 				Timestamp: time.Now(),
 				Message:   fmt.Sprintf("Firewall rules changed in %s chain", chain.Chain),
 				Details:   "Rules differ from baseline. Run `piguard baseline reset` to accept current state.",
+				Suggested: "Restore a saved ruleset: sudo piguard firewall list && sudo piguard firewall restore <id>",
 				Source:    "firewall",
 				Firewall: &models.FirewallState{
 					Chain:    chain.Chain,
@@ -2,12 +2,14 @@ package watchers
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/Fullex26/piguard/internal/config"
 	"github.com/Fullex26/piguard/internal/eventbus"
+	"github.com/Fullex26/piguard/internal/store"
 	"github.com/Fullex26/piguard/pkg/models"
 )
 
@@ -71,7 +73,7 @@ func newTestFirewallWatcher(cfg *config.Config, fakeExec func(table, chain strin
 		cap.events = append(cap.events, e)
 	})
 
-	w := NewFirewallWatcher(cfg, bus)
+	w := NewFirewallWatcher(cfg, bus, nil)
 	w.execIptables = fakeExec
 	return w, cap
 }
@@ -345,3 +347,40 @@ func TestFirewallWatcher_Drift_UpdatesBaseline(t *testing.T) {
 		t.Errorf("expected 1 drift event (baseline updated), got %d", len(events))
 	}
 }
+
+func TestFirewallWatcher_BaselineSnapshot(t *testing.T) {
+	db := openNetworkTestStore(t)
+	w, _ := newTestFirewallWatcher(config.DefaultConfig(), func(table, chain string) ([]byte, error) {
+		return fakeIptablesOutput("DROP", nil), nil
+	})
+	w.store = db
+	packets := 0
+	rule := "-A INPUT -p tcp --dport 22 -j ACCEPT"
+	w.captureFn = func() (string, []byte, error) {
+		packets += 100
+		return "iptables", []byte(fmt.Sprintf("# Generated %d\n*filter\n:INPUT DROP [%d:0]\n%s\nCOMMIT\n", packets, packets, rule)), nil
+	}
+
+	w.snapshotBaseline()
+	w.snapshotBaseline() // restart with the same rules, new counters
+	if snaps, _ := db.ListFirewallSnapshots(); len(snaps) != 1 || snaps[0].Kind != store.SnapshotBaseline {
+		t.Fatalf("snapshots = %+v, want one baseline", snaps)
+	}
+
+	// A restore saves the replaced rules; they must not become "known good".
+	db.SaveFirewallSnapshot(store.SnapshotPreRestore, "pre-restore of #1", "iptables", "*filter\nCOMMIT\n")
+	if last, _ := db.GetLastGoodFirewallSnapshot(); last.ID != 1 {
+		t.Errorf("last good = #%d, want the baseline", last.ID)
+	}
+
+	if msg := acceptFirewallRules(db, w.captureFn); !strings.Contains(msg, "already match") {
+		t.Errorf("accepting unchanged rules = %q", msg)
+	}
+	rule = "-A INPUT -p tcp --dport 8080 -j ACCEPT"
+	if msg := acceptFirewallRules(db, w.captureFn); !strings.Contains(msg, "accepted") {
+		t.Errorf("accepting changed rules = %q", msg)
+	}
+	if last, _ := db.GetLastGoodFirewallSnapshot(); last.Kind != store.SnapshotAccepted || !strings.Contains(last.Rules, "8080") {
+		t.Errorf("last good after accept = %+v", last)
+	}
+}
@@ -9,9 +9,11 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 	"unsafe"
@@ -20,6 +22,7 @@ import (
 
 	"github.com/Fullex26/piguard/internal/config"
 	"github.com/Fullex26/piguard/internal/eventbus"
+	"github.com/Fullex26/piguard/internal/store"
 	"github.com/Fullex26/piguard/pkg/models"
 )
 
@@ -40,21 +43,46 @@ type watchEntry struct {
 	description string
 	severity    models.Severity
 	isDir       bool
+	recursive   bool // subdirectories are watched too, including new ones
+	sub         bool // subdirectory of a recursive path rather than a configured one
 }
 
 // InotifyWatcher monitors file system paths for unauthorized changes using Linux inotify.
 type InotifyWatcher struct {
 	Base
-	mu     sync.RWMutex
-	wds    map[int]watchEntry
-	hashes map[string]string // path → SHA256 baseline
+	store        *store.Store  // nil = no hash database or full scans
+	scanInterval time.Duration // 0 = full scan at startup only
+	mu           sync.RWMutex
+	fd           int
+	wds          map[int]watchEntry
+	paths        map[string]int    // watched path → wd
+	hashes       map[string]string // path → SHA256 baseline
+	maxWatches   int               // 0 = no limit
+	budgetHit    bool              // max_watches warning logged
+	diffMaxSize  int64             // largest file whose content is kept; 0 = none
+	diffExclude  []string          // globs of files whose content is never kept
 }
 
-func NewInotifyWatcher(cfg *config.Config, bus *eventbus.Bus) *InotifyWatcher {
+func NewInotifyWatcher(cfg *config.Config, bus *eventbus.Bus, db *store.Store) *InotifyWatcher {
+	var scanInterval time.Duration
+	if s := cfg.FileIntegrity.ScanInterval; s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			d = 24 * time.Hour
+		}
+		scanInterval = max(d, 0)
+	}
 	return &InotifyWatcher{
-		Base:   Base{Cfg: cfg, Bus: bus},
-		wds:    make(map[int]watchEntry),
-		hashes: make(map[string]string),
+		Base:         Base{Cfg: cfg, Bus: bus},
+		store:        db,
+		scanInterval: scanInterval,
+		fd:           -1,
+		wds:          make(map[int]watchEntry),
+		paths:        make(map[string]int),
+		hashes:       make(map[string]string),
+		maxWatches:   cfg.FileIntegrity.MaxWatches,
+		diffMaxSize:  int64(cfg.FileIntegrity.DiffMaxSize),
+		diffExclude:  cfg.FileIntegrity.DiffExclude,
 	}
 }
 
@@ -66,6 +94,7 @@ func (w *InotifyWatcher) Start(ctx context.Context) error {
 		return fmt.Errorf("inotify_init1: %w", err)
 	}
 	defer unix.Close(fd)
+	w.fd = fd
 
 	// Self-pipe trick: goroutine writes to [1] on ctx cancel, unblocking Poll on [0].
 	var pipe [2]int
@@ -81,12 +110,22 @@ func (w *InotifyWatcher) Start(ctx context.Context) error {
 	}()
 
 	for _, wp := range w.Cfg.FileIntegrity.Paths {
-		w.addWatch(fd, wp)
+		w.addWatch(wp)
 	}
 
 	hostname, _ := os.Hostname()
 	buf := make([]byte, inotifyBufSize)
-	slog.Info("file integrity monitoring active", "watches", len(w.wds))
+	slog.Info("file integrity monitoring active", "watches", len(w.wds), "scan_interval", w.scanInterval)
+
+	// Full scans run between reads rather than alongside them, so the
+	// database is only ever updated from this goroutine.
+	var nextScan time.Time
+	if w.store != nil {
+		w.scan(hostname)
+		if w.scanInterval > 0 {
+			nextScan = time.Now().Add(w.scanInterval)
+		}
+	}
 
 	for {
 		fds := []unix.PollFd{
@@ -94,7 +133,12 @@ func (w *InotifyWatcher) Start(ctx context.Context) error {
 			{Fd: int32(pipe[0]), Events: unix.POLLIN},
 		}
 
-		_, err := unix.Poll(fds, -1)
+		timeout := -1
+		if !nextScan.IsZero() {
+			// Capped so the millisecond count fits poll's C int.
+			timeout = int(min(max(time.Until(nextScan), 0), time.Hour) / time.Millisecond)
+		}
+		_, err := unix.Poll(fds, timeout)
 		if err != nil {
 			if err == unix.EINTR {
 				continue
@@ -107,6 +151,11 @@ func (w *InotifyWatcher) Start(ctx context.Context) error {
 			return nil
 		}
 
+		if !nextScan.IsZero() && !time.Now().Before(nextScan) {
+			w.scan(hostname)
+			nextScan = time.Now().Add(w.scanInterval)
+		}
+
 		if fds[0].Revents&unix.POLLIN == 0 {
 			continue
 		}
@@ -125,32 +174,27 @@ func (w *InotifyWatcher) Start(ctx context.Context) error {
 
 func (w *InotifyWatcher) Stop() error { return nil }
 
-func (w *InotifyWatcher) addWatch(fd int, wp config.WatchPath) {
+func (w *InotifyWatcher) addWatch(wp config.WatchPath) {
 	info, err := os.Stat(wp.Path)
 	if err != nil {
 		slog.Debug("file_integrity: path not found, skipping", "path", wp.Path)
 		return
 	}
 
-	wd, err := unix.InotifyAddWatch(fd, wp.Path, watchMask)
-	if err != nil {
-		slog.Warn("file_integrity: failed to add watch", "path", wp.Path, "error", err)
-		return
-	}
-
-	sev := models.SeverityWarning
-	if wp.Severity == "critical" {
-		sev = models.SeverityCritical
-	}
-
-	w.mu.Lock()
-	w.wds[wd] = watchEntry{
+	entry := watchEntry{
 		path:        wp.Path,
 		description: wp.Description,
-		severity:    sev,
+		severity:    watchSeverity(wp),
 		isDir:       info.IsDir(),
+		recursive:   wp.Recursive && info.IsDir(),
+	}
+	if entry.recursive {
+		w.addTree(entry, false)
+		return
+	}
+	if !w.watch(entry) {
+		return
 	}
-	w.mu.Unlock()
 
 	// Hash regular files for change detection.
 	if !info.IsDir() {
@@ -162,6 +206,102 @@ func (w *InotifyWatcher) addWatch(fd int, wp config.WatchPath) {
 	}
 }
 
+// addTree watches entry.path and every directory below it, and hashes the
+// regular files found so later modifications are detected; with record they
+// are also saved to the hash database. Symlinks are not followed. Walking
+// stops when the max_watches budget runs out.
+func (w *InotifyWatcher) addTree(entry watchEntry, record bool) {
+	root := entry.path
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // unreadable: skip it, keep walking
+		}
+		if !d.IsDir() {
+			if d.Type().IsRegular() {
+				if h := hashFile(path); h != "" {
+					w.mu.Lock()
+					w.hashes[path] = h
+					w.mu.Unlock()
+				}
+				if record {
+					w.record(path)
+				}
+			}
+			return nil
+		}
+		dir := entry
+		dir.path = path
+		dir.sub = entry.sub || path != root
+		if !w.watch(dir) {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+}
+
+// watch adds an inotify watch for entry, unless its path is already watched.
+// It returns false when the watch could not be added.
+func (w *InotifyWatcher) watch(entry watchEntry) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.paths[entry.path]; ok {
+		return true
+	}
+	if w.maxWatches > 0 && len(w.wds) >= w.maxWatches {
+		if !w.budgetHit {
+			w.budgetHit = true
+			slog.Warn("file_integrity: max_watches reached, further directories are not watched",
+				"max_watches", w.maxWatches, "path", entry.path)
+		}
+		return false
+	}
+
+	wd, err := unix.InotifyAddWatch(w.fd, entry.path, watchMask)
+	if err != nil {
+		if err == unix.ENOSPC {
+			slog.Warn("file_integrity: kernel inotify watch limit reached (raise fs.inotify.max_user_watches)", "path", entry.path)
+		} else {
+			slog.Warn("file_integrity: failed to add watch", "path", entry.path, "error", err)
+		}
+		return false
+	}
+	w.wds[wd] = entry
+	w.paths[entry.path] = wd
+	return true
+}
+
+// unwatchTree removes the watches on path and every directory below it.
+func (w *InotifyWatcher) unwatchTree(path string) {
+	prefix := path + string(filepath.Separator)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for p, wd := range w.paths {
+		if p == path || strings.HasPrefix(p, prefix) {
+			// A deleted directory's watch is already gone; the error is expected.
+			unix.InotifyRmWatch(w.fd, uint32(wd))
+			delete(w.paths, p)
+			delete(w.wds, wd)
+		}
+	}
+	if len(w.wds) < w.maxWatches {
+		w.budgetHit = false
+	}
+}
+
+// forget drops a watch the kernel has removed (IN_IGNORED).
+func (w *InotifyWatcher) forget(wd int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if entry, ok := w.wds[wd]; ok {
+		delete(w.wds, wd)
+		if w.paths[entry.path] == wd {
+			delete(w.paths, entry.path)
+		}
+	}
+}
+
 func (w *InotifyWatcher) parseAndDispatch(buf []byte, hostname string) {
 	offset := 0
 	for offset < len(buf) {
@@ -186,6 +326,11 @@ func (w *InotifyWatcher) parseAndDispatch(buf []byte, hostname string) {
 }
 
 func (w *InotifyWatcher) dispatchEvent(wd int, mask uint32, name, hostname string) {
+	if mask&unix.IN_IGNORED != 0 {
+		w.forget(wd)
+		return
+	}
+
 	w.mu.RLock()
 	entry, ok := w.wds[wd]
 	w.mu.RUnlock()
@@ -193,22 +338,55 @@ func (w *InotifyWatcher) dispatchEvent(wd int, mask uint32, name, hostname strin
 		return
 	}
 
+	// Changes to a subdirectory itself are reported by its parent's watch.
+	if entry.sub && name == "" {
+		return
+	}
+
 	target := entry.path
 	if name != "" {
 		target = filepath.Join(entry.path, name)
 	}
 
+	// Keep recursive trees in step with their directories.
+	isDir := mask&unix.IN_ISDIR != 0
+	if entry.recursive && isDir && name != "" {
+		switch {
+		case mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0:
+			sub := entry
+			sub.path = target
+			sub.sub = true
+			w.addTree(sub, true)
+		case mask&(unix.IN_DELETE|unix.IN_MOVED_FROM) != 0:
+			w.unwatchTree(target)
+		}
+	}
+
+	// Keep the hash database in step, so full scans only report changes
+	// inotify missed.
+	switch {
+	case mask&(unix.IN_DELETE|unix.IN_MOVED_FROM) != 0:
+		w.untrack(target)
+	case mask&(unix.IN_CREATE|unix.IN_MOVED_TO|unix.IN_ATTRIB) != 0 && !isDir:
+		w.record(target)
+	}
+
 	var msg, details, suggested string
-	var changeType string
+	var changeType, diff string
 
 	switch {
 	case mask&unix.IN_CLOSE_WRITE != 0:
-		newHash := hashFile(target)
-		w.mu.Lock()
+		w.mu.RLock()
 		oldHash := w.hashes[target]
-		if newHash != "" {
-			w.hashes[target] = newHash
+		w.mu.RUnlock()
+		prev := w.lastRecord(target)
+		cur := w.record(target)
+		newHash := cur.Hash
+		if newHash == "" {
+			return
 		}
+		w.mu.Lock()
+		w.hashes[target] = newHash
 		w.mu.Unlock()
 		// Skip if hash is unchanged (e.g. touch) or no baseline yet.
 		if oldHash == "" || oldHash == newHash {
@@ -217,7 +395,11 @@ func (w *InotifyWatcher) dispatchEvent(wd int, mask uint32, name, hostname strin
 		changeType = "modified"
 		msg = fmt.Sprintf("File modified: %s", target)
 		details = fmt.Sprintf("SHA256 %s → %s", oldHash[:12], newHash[:12])
-		suggested = fmt.Sprintf("Review changes: sudo diff /dev/null %s", target)
+		diff = contentDiff(target, prev, cur)
+		suggested = "Review the file: sudo less " + target
+		if diff != "" {
+			suggested = "If you didn't make this change, restore the previous version from a backup"
+		}
 
 	case mask&unix.IN_ATTRIB != 0:
 		info, err := os.Stat(target)
@@ -237,31 +419,50 @@ func (w *InotifyWatcher) dispatchEvent(wd int, mask uint32, name, hostname strin
 	case mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0:
 		changeType = "created"
 		msg = fmt.Sprintf("File created in watched directory: %s", target)
+		if isDir {
+			msg = fmt.Sprintf("Directory created in watched directory: %s", target)
+		}
 		details = fmt.Sprintf("In: %s", entry.path)
 		suggested = "Review: ls -la " + target
 
 	case mask&(unix.IN_DELETE|unix.IN_MOVED_FROM) != 0:
 		changeType = "removed"
 		msg = fmt.Sprintf("File removed from watched directory: %s", target)
+		if isDir {
+			msg = fmt.Sprintf("Directory removed from watched directory: %s", target)
+		}
 		details = fmt.Sprintf("From: %s", entry.path)
 
 	default:
 		return
 	}
 
+	w.publish(hostname, entry.severity, changeType, msg, details, suggested,
+		&models.FileChange{Path: target, Diff: diff})
+}
+
+func (w *InotifyWatcher) publish(hostname string, sev models.Severity, changeType, msg, details, suggested string, file *models.FileChange) {
 	w.Bus.Publish(models.Event{
 		ID:        fmt.Sprintf("fim-%d-%s", time.Now().UnixNano(), changeType),
 		Type:      models.EventFileChanged,
-		Severity:  entry.severity,
+		Severity:  sev,
 		Hostname:  hostname,
 		Timestamp: time.Now(),
 		Message:   msg,
 		Details:   details,
 		Suggested: suggested,
 		Source:    w.Name(),
+		File:      file,
 	})
 }
 
+func watchSeverity(wp config.WatchPath) models.Severity {
+	if wp.Severity == "critical" {
+		return models.SeverityCritical
+	}
+	return models.SeverityWarning
+}
+
 func hashFile(path string) string {
 	f, err := os.Open(path)
 	if err != nil {
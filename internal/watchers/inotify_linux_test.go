@@ -0,0 +1,203 @@
+//go:build linux
+
+package watchers
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/Fullex26/piguard/internal/config"
+	"github.com/Fullex26/piguard/internal/eventbus"
+	"github.com/Fullex26/piguard/internal/store"
+	"github.com/Fullex26/piguard/pkg/models"
+)
+
+func newTestInotifyWatcher(t *testing.T, db *store.Store, maxWatches int, paths ...config.WatchPath) (*InotifyWatcher, chan models.Event) {
+	t.Helper()
+	bus := eventbus.New()
+	received := make(chan models.Event, 50)
+	bus.Subscribe(func(e models.Event) { received <- e })
+
+	cfg := &config.Config{FileIntegrity: config.FileIntegrityConfig{Paths: paths, MaxWatches: maxWatches}}
+	w := NewInotifyWatcher(cfg, bus, db)
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		t.Fatalf("inotify_init1: %v", err)
+	}
+	t.Cleanup(func() { unix.Close(fd) })
+	w.fd = fd
+	for _, wp := range paths {
+		w.addWatch(wp)
+	}
+	return w, received
+}
+
+// pumpInotify dispatches the inotify events queued so far and returns the
+// messages of the alerts they raised.
+func pumpInotify(w *InotifyWatcher, received chan models.Event) []string {
+	var msgs []string
+	for _, e := range pumpInotifyEvents(w, received) {
+		msgs = append(msgs, e.Message)
+	}
+	return msgs
+}
+
+// pumpInotifyEvents is pumpInotify returning the alerts themselves.
+func pumpInotifyEvents(w *InotifyWatcher, received chan models.Event) []models.Event {
+	buf := make([]byte, inotifyBufSize)
+	for {
+		fds := []unix.PollFd{{Fd: int32(w.fd), Events: unix.POLLIN}}
+		if n, _ := unix.Poll(fds, 100); n <= 0 {
+			break
+		}
+		n, err := unix.Read(w.fd, buf)
+		if err != nil {
+			break
+		}
+		w.parseAndDispatch(buf[:n], "pi")
+	}
+	return drainEvents(received)
+}
+
+// drainEvents returns the events published so far.
+func drainEvents(received chan models.Event) []models.Event {
+	// The bus delivers asynchronously; collect until it goes quiet.
+	var events []models.Event
+	for {
+		select {
+		case e := <-received:
+			events = append(events, e)
+		case <-time.After(50 * time.Millisecond):
+			return events
+		}
+	}
+}
+
+func mkdirAll(t *testing.T, dirs ...string) {
+	t.Helper()
+	for _, d := range dirs {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInotifyWatcher_RecursiveTree(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "sshd_config.d", "local")
+	mkdirAll(t, nested)
+	key := filepath.Join(nested, "override.conf")
+	writeFile(t, key, "PermitRootLogin no\n")
+
+	flat, flatEvents := newTestInotifyWatcher(t, nil, 0, config.WatchPath{Path: root})
+	w, received := newTestInotifyWatcher(t, nil, 0, config.WatchPath{Path: root, Recursive: true})
+	if len(w.paths) != 3 {
+		t.Errorf("recursive watches = %d, want 3 (%v)", len(w.paths), w.paths)
+	}
+
+	writeFile(t, key, "PermitRootLogin yes\n")
+	msgs := pumpInotify(w, received)
+	if len(msgs) != 1 || msgs[0] != "File modified: "+key {
+		t.Errorf("recursive alerts = %q, want the nested modification", msgs)
+	}
+	if msgs := pumpInotify(flat, flatEvents); len(msgs) != 0 {
+		t.Errorf("non-recursive watch saw nested change: %q", msgs)
+	}
+}
+
+func TestInotifyWatcher_NewSubdirectory(t *testing.T) {
+	root := t.TempDir()
+	w, received := newTestInotifyWatcher(t, nil, 0, config.WatchPath{Path: root, Recursive: true})
+
+	sub := filepath.Join(root, ".ssh")
+	mkdirAll(t, sub)
+	msgs := pumpInotify(w, received)
+	if len(msgs) != 1 || msgs[0] != "Directory created in watched directory: "+sub {
+		t.Fatalf("alerts = %q, want the new directory", msgs)
+	}
+	if _, ok := w.paths[sub]; !ok {
+		t.Fatal("new subdirectory not watched")
+	}
+
+	keys := filepath.Join(sub, "authorized_keys")
+	writeFile(t, keys, "ssh-ed25519 AAAA attacker\n")
+	msgs = pumpInotify(w, received)
+	if len(msgs) != 1 || msgs[0] != "File created in watched directory: "+keys {
+		t.Errorf("alerts = %q, want the file in the new directory", msgs)
+	}
+
+	writeFile(t, keys, "ssh-ed25519 AAAA attacker\nssh-ed25519 BBBB second\n")
+	if msgs := pumpInotify(w, received); len(msgs) != 1 || !strings.HasPrefix(msgs[0], "File modified") {
+		t.Errorf("alerts = %q, want the modification", msgs)
+	}
+
+	if err := os.RemoveAll(sub); err != nil {
+		t.Fatal(err)
+	}
+	msgs = pumpInotify(w, received)
+	sort.Strings(msgs)
+	want := []string{
+		"Directory removed from watched directory: " + sub,
+		"File removed from watched directory: " + keys,
+	}
+	if strings.Join(msgs, "\n") != strings.Join(want, "\n") {
+		t.Errorf("alerts = %q, want %q", msgs, want)
+	}
+	if len(w.paths) != 1 || len(w.wds) != 1 {
+		t.Errorf("watches after removal: paths %v, wds %d", w.paths, len(w.wds))
+	}
+}
+
+func TestInotifyWatcher_MovedOutSubdirectory(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "cron.d")
+	mkdirAll(t, filepath.Join(sub, "nested"))
+	w, received := newTestInotifyWatcher(t, nil, 0, config.WatchPath{Path: root, Recursive: true})
+
+	if err := os.Rename(sub, filepath.Join(t.TempDir(), "moved")); err != nil {
+		t.Fatal(err)
+	}
+	msgs := pumpInotify(w, received)
+	if len(msgs) != 1 || msgs[0] != "Directory removed from watched directory: "+sub {
+		t.Errorf("alerts = %q, want the directory moved out", msgs)
+	}
+	if len(w.paths) != 1 {
+		t.Errorf("watches after move = %v, want only the root", w.paths)
+	}
+}
+
+func TestInotifyWatcher_MaxWatches(t *testing.T) {
+	root := t.TempDir()
+	mkdirAll(t, filepath.Join(root, "a", "b"), filepath.Join(root, "c"), filepath.Join(root, "d"))
+	w, received := newTestInotifyWatcher(t, nil, 3, config.WatchPath{Path: root, Recursive: true})
+	if len(w.wds) != 3 || !w.budgetHit {
+		t.Fatalf("watches = %d (budget hit %v), want 3 and the budget hit", len(w.wds), w.budgetHit)
+	}
+
+	// Removing a watched directory frees its watches for new ones.
+	if err := os.RemoveAll(filepath.Join(root, "a")); err != nil {
+		t.Fatal(err)
+	}
+	pumpInotify(w, received)
+	if w.budgetHit || len(w.wds) >= 3 {
+		t.Errorf("watches after removal = %d (budget hit %v)", len(w.wds), w.budgetHit)
+	}
+	mkdirAll(t, filepath.Join(root, "e"))
+	pumpInotify(w, received)
+	if _, ok := w.paths[filepath.Join(root, "e")]; !ok {
+		t.Error("new directory not watched after watches were freed")
+	}
+}
@@ -0,0 +1,321 @@
+//go:build linux
+
+package watchers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Fullex26/piguard/internal/config"
+	"github.com/Fullex26/piguard/internal/store"
+	"github.com/Fullex26/piguard/pkg/models"
+)
+
+// integrityStatePrefix marks a configured path whose files are in the hash
+// database, so the files of a newly configured path are recorded silently
+// rather than reported as created.
+const integrityStatePrefix = "file_integrity.baselined:"
+
+// scan compares every watched file with its record in the hash database,
+// AIDE-style, and reports what changed while inotify wasn't watching: while
+// the daemon was stopped, before a reboot, or beyond max_watches.
+func (w *InotifyWatcher) scan(hostname string) {
+	start := time.Now()
+	paths := w.Cfg.FileIntegrity.Paths
+	seen := make(map[string]bool)
+	for _, wp := range paths {
+		_, err := w.store.GetState(integrityStatePrefix + wp.Path)
+		baselined := err == nil
+		for _, path := range scanFiles(wp) {
+			seen[path] = true
+			w.scanFile(hostname, wp, path, baselined)
+		}
+		if !baselined {
+			w.store.SetState(integrityStatePrefix+wp.Path, time.Now().Format(time.RFC3339))
+		}
+	}
+
+	records, err := w.store.ListFileRecords()
+	if err != nil {
+		slog.Warn("file_integrity: failed to read hash database", "error", err)
+		return
+	}
+	for _, r := range records {
+		if seen[r.Path] {
+			continue
+		}
+		w.untrack(r.Path)
+		wp, ok := coveringPath(paths, r.Path)
+		if !ok {
+			continue // its path is no longer configured
+		}
+		w.publish(hostname, watchSeverity(wp), "scan-deleted",
+			fmt.Sprintf("File deleted while not monitored: %s", r.Path),
+			fmt.Sprintf("Last recorded: SHA256 %s, %s, owner %d:%d", shortHash(r.Hash), fs.FileMode(r.Mode), r.UID, r.GID),
+			"Investigate: sudo journalctl -n 50; check who was logged in with last",
+			&models.FileChange{Path: r.Path})
+	}
+	slog.Debug("file_integrity: full scan done", "files", len(seen), "took", time.Since(start))
+}
+
+// scanFile hashes one file, compares it with its record and saves the new
+// state.
+func (w *InotifyWatcher) scanFile(hostname string, wp config.WatchPath, path string, baselined bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	cur, err := w.fileRecord(path, info)
+	if err != nil {
+		return
+	}
+	w.mu.Lock()
+	w.hashes[path] = cur.Hash
+	w.mu.Unlock()
+
+	prev, err := w.store.GetFileRecord(path)
+	if err := w.store.PutFileRecord(cur); err != nil {
+		slog.Warn("file_integrity: failed to save file record", "path", path, "error", err)
+	}
+
+	switch {
+	case errors.Is(err, store.ErrNoFileRecord):
+		if !baselined {
+			return
+		}
+		w.publish(hostname, watchSeverity(wp), "scan-created",
+			fmt.Sprintf("File created while not monitored: %s", path),
+			fmt.Sprintf("SHA256 %s, %s, owner %d:%d", shortHash(cur.Hash), fs.FileMode(cur.Mode), cur.UID, cur.GID),
+			"Review: ls -la "+path,
+			&models.FileChange{Path: path})
+	case err != nil:
+		return
+	default:
+		changes := describeFileChanges(prev, cur)
+		if changes == "" {
+			return
+		}
+		w.publish(hostname, watchSeverity(wp), "scan-modified",
+			fmt.Sprintf("File changed while not monitored: %s", path), changes,
+			fmt.Sprintf("If you didn't change it, compare with a backup or dpkg --verify, and check who was logged in with last: ls -la %s", path),
+			&models.FileChange{Path: path, Diff: contentDiff(path, prev, cur)})
+	}
+}
+
+// record reads a file's current state and saves it to the hash database, so
+// the next full scan compares against it, and returns it. A hash baseline
+// already held for the file is refreshed too: after a rename-over it would
+// otherwise describe the replaced file.
+func (w *InotifyWatcher) record(path string) store.FileRecord {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return store.FileRecord{}
+	}
+	r, err := w.fileRecord(path, info)
+	if err != nil {
+		return store.FileRecord{}
+	}
+	w.mu.Lock()
+	if _, ok := w.hashes[path]; ok {
+		w.hashes[path] = r.Hash
+	}
+	w.mu.Unlock()
+	if w.store != nil {
+		if err := w.store.PutFileRecord(r); err != nil {
+			slog.Warn("file_integrity: failed to save file record", "path", path, "error", err)
+		}
+	}
+	return r
+}
+
+// lastRecord returns the saved state of path, or a zero record.
+func (w *InotifyWatcher) lastRecord(path string) store.FileRecord {
+	if w.store == nil {
+		return store.FileRecord{}
+	}
+	r, _ := w.store.GetFileRecord(path)
+	return r
+}
+
+// untrack forgets path, and everything below it when it is a directory, in
+// memory and in the hash database.
+func (w *InotifyWatcher) untrack(path string) {
+	prefix := path + string(filepath.Separator)
+	w.mu.Lock()
+	for p := range w.hashes {
+		if p == path || strings.HasPrefix(p, prefix) {
+			delete(w.hashes, p)
+		}
+	}
+	w.mu.Unlock()
+	if w.store != nil {
+		w.store.DeleteFileRecords(path)
+	}
+}
+
+// scanFiles returns the regular files a configured path covers: the file
+// itself, a directory's entries, or a recursive directory's whole tree.
+// Symlinks inside directories are not followed.
+func scanFiles(wp config.WatchPath) []string {
+	info, err := os.Stat(wp.Path)
+	if err != nil {
+		return nil
+	}
+	if info.Mode().IsRegular() {
+		return []string{wp.Path}
+	}
+	if !info.IsDir() {
+		return nil
+	}
+
+	var files []string
+	if wp.Recursive {
+		filepath.WalkDir(wp.Path, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && d.Type().IsRegular() {
+				files = append(files, path)
+			}
+			return nil
+		})
+		return files
+	}
+	entries, _ := os.ReadDir(wp.Path)
+	for _, e := range entries {
+		if e.Type().IsRegular() {
+			files = append(files, filepath.Join(wp.Path, e.Name()))
+		}
+	}
+	return files
+}
+
+// coveringPath returns the configured path that covers path, as scanFiles
+// would list it.
+func coveringPath(paths []config.WatchPath, path string) (config.WatchPath, bool) {
+	for _, wp := range paths {
+		root := filepath.Clean(wp.Path)
+		switch {
+		case path == root,
+			wp.Recursive && strings.HasPrefix(path, root+string(filepath.Separator)),
+			!wp.Recursive && filepath.Dir(path) == root:
+			return wp, true
+		}
+	}
+	return config.WatchPath{}, false
+}
+
+// fileRecord hashes a file and, when its text is kept for diffs, takes it
+// from the same read, so the hash and content always describe one version.
+func (w *InotifyWatcher) fileRecord(path string, info os.FileInfo) (store.FileRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return store.FileRecord{}, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	var content string
+	if w.keepContent(path, info) {
+		data, err := io.ReadAll(io.LimitReader(f, w.diffMaxSize+1))
+		if err != nil {
+			return store.FileRecord{}, err
+		}
+		h.Write(data)
+		if int64(len(data)) <= w.diffMaxSize && utf8.Valid(data) && bytes.IndexByte(data, 0) < 0 {
+			content = string(data)
+		}
+	}
+	if _, err := io.Copy(h, f); err != nil { // all of it, or what grew past the cap
+		return store.FileRecord{}, err
+	}
+
+	r := store.FileRecord{
+		Path:      path,
+		Hash:      hex.EncodeToString(h.Sum(nil)),
+		Mode:      uint32(info.Mode()),
+		Size:      info.Size(),
+		ModTime:   info.ModTime(),
+		CheckedAt: time.Now(),
+		Content:   content,
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		r.UID, r.GID = int(st.Uid), int(st.Gid)
+	}
+	return r, nil
+}
+
+// keepContent reports whether a file's text is kept for diffs: only with a
+// store, up to diff_max_size, and never for secrets.
+func (w *InotifyWatcher) keepContent(path string, info os.FileInfo) bool {
+	return w.store != nil && w.diffMaxSize > 0 && info.Size() <= w.diffMaxSize &&
+		!diffExcluded(w.diffExclude, path)
+}
+
+// secretFiles are never kept or diffed, whatever diff_exclude says: password
+// hashes, including the backups shadow(5) tools and Debian's cron job leave.
+var secretFiles = []string{"shadow", "shadow-", "shadow.*", "gshadow", "gshadow-", "gshadow.*"}
+
+// diffExcluded reports whether path is a secret file or matches one of the
+// diff_exclude patterns.
+func diffExcluded(patterns []string, path string) bool {
+	return matchesAny(secretFiles, path) || matchesAny(patterns, path)
+}
+
+// matchesAny reports whether path matches one of the glob patterns. Patterns
+// without a slash match the file name alone.
+func matchesAny(patterns []string, path string) bool {
+	for _, p := range patterns {
+		name := path
+		if !strings.Contains(p, "/") {
+			name = filepath.Base(path)
+		}
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// contentDiff returns the unified diff between two records of a file, or ""
+// when either's content was not kept.
+func contentDiff(path string, prev, cur store.FileRecord) string {
+	if prev.Content == "" || cur.Content == "" {
+		return ""
+	}
+	return unifiedDiff(path, prev.Content, cur.Content)
+}
+
+// describeFileChanges lists what differs between two records of a file. A
+// new modification time alone (touch) is not a change.
+func describeFileChanges(prev, cur store.FileRecord) string {
+	var parts []string
+	if prev.Hash != cur.Hash {
+		parts = append(parts, fmt.Sprintf("SHA256 %s → %s (modified %s)",
+			shortHash(prev.Hash), shortHash(cur.Hash), cur.ModTime.Format("02 Jan 15:04")))
+	}
+	if prev.Mode != cur.Mode {
+		parts = append(parts, fmt.Sprintf("Mode %s → %s", fs.FileMode(prev.Mode), fs.FileMode(cur.Mode)))
+	}
+	if prev.UID != cur.UID || prev.GID != cur.GID {
+		parts = append(parts, fmt.Sprintf("Owner %d:%d → %d:%d", prev.UID, prev.GID, cur.UID, cur.GID))
+	}
+	return strings.Join(parts, " | ")
+}
+
+// shortHash abbreviates a hash for alerts.
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
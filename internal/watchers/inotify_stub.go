@@ -7,6 +7,7 @@ import (
 
 	"github.com/Fullex26/piguard/internal/config"
 	"github.com/Fullex26/piguard/internal/eventbus"
+	"github.com/Fullex26/piguard/internal/store"
 )
 
 // InotifyWatcher is a no-op on non-Linux platforms (inotify is Linux-only).
@@ -14,7 +15,7 @@ type InotifyWatcher struct {
 	Base
 }
 
-func NewInotifyWatcher(cfg *config.Config, bus *eventbus.Bus) *InotifyWatcher {
+func NewInotifyWatcher(cfg *config.Config, bus *eventbus.Bus, db *store.Store) *InotifyWatcher {
 	return &InotifyWatcher{Base: Base{Cfg: cfg, Bus: bus}}
 }
 
@@ -14,36 +14,124 @@ import (
 
 	"github.com/Fullex26/piguard/internal/analysers"
 	"github.com/Fullex26/piguard/internal/config"
+	"github.com/Fullex26/piguard/internal/dockerapi"
 	"github.com/Fullex26/piguard/internal/eventbus"
 	"github.com/Fullex26/piguard/pkg/models"
 )
 
-// NetlinkWatcher monitors listening sockets using periodic ss polling
-// with smart diffing. While true netlink SOCK_DIAG monitoring is possible,
-// polling ss every 2 seconds is simpler, reliable, and still sub-second
-// detection for our use case. We can upgrade to raw netlink later.
+// NetlinkWatcher monitors listening sockets every 2 seconds with smart
+// diffing. Sockets are listed with inet_diag queries over a NETLINK_SOCK_DIAG
+// socket and owners are resolved through /proc, so no process is forked per
+// poll. When netlink is unavailable for a protocol (non-Linux, restricted
+// sandbox, udp_diag or raw_diag not loaded) that protocol falls back to
+// parsing `ss -lnp`; both paths feed the same diff. TCP listeners and
+// unconnected UDP sockets are watched by default; raw sockets can be
+// enabled with ports.protocols.
 type NetlinkWatcher struct {
 	Base
 	labeller *analysers.PortLabeller
-	baseline map[string]models.PortInfo // addr -> port info
+	exposure *analysers.ExposureAnalyser // nil = ports.exposure_analysis off
+	baseline map[string]models.PortInfo // protocol:addr -> port info
 	interval time.Duration
 	runSS    func() ([]byte, error)
+	sockDiag func(family, protocol uint8, states uint32) ([]sockDiagEntry, error) // nil = use ss
+	ssOnly   map[string]bool // protocols sock_diag failed for, listed with ss instead
+	udpSeen  map[string]bool // new UDP sockets seen at the last poll, not yet reported
+	inodes   *inodePIDResolver
+	published func() ([]models.PortInfo, error) // container ports from the runtime; nil = listeners only
+}
+
+// sockProto describes how to list "listening" sockets of one protocol.
+type sockProto struct {
+	name   string
+	proto  uint8
+	states uint32
+	ssFlag string
+}
+
+var sockProtos = []sockProto{
+	{name: "tcp", proto: ipprotoTCP, states: 1 << tcpListen, ssFlag: "-t"},
+	{name: "udp", proto: ipprotoUDP, states: 1 << tcpClose, ssFlag: "-u"},
+	{name: "raw", proto: ipprotoRaw, states: 1 << tcpClose, ssFlag: "-w"},
 }
 
 func NewNetlinkWatcher(cfg *config.Config, bus *eventbus.Bus) *NetlinkWatcher {
-	return &NetlinkWatcher{
+	w := &NetlinkWatcher{
 		Base:     Base{Cfg: cfg, Bus: bus},
 		labeller: analysers.NewPortLabeller(),
 		baseline: make(map[string]models.PortInfo),
 		interval: 2 * time.Second,
-		runSS:    func() ([]byte, error) { return exec.Command("ss", "-tlnp").Output() },
+		sockDiag: sockDiagDump,
+		inodes:   newInodePIDResolver("/proc"),
+	}
+	w.runSS = func() ([]byte, error) { return exec.Command("ss", w.ssArgs()...).Output() }
+	w.labeller.SetKnownPorts(knownPorts(cfg))
+	w.labeller.SetDockerSocket(cfg.Docker.Socket)
+	w.labeller.SetRuntime(dockerapi.Runtime(cfg.Docker.Runtime))
+	w.published = w.labeller.PublishedPorts
+	if cfg.Ports.ExposureAnalysis {
+		w.exposure = analysers.NewExposureAnalyser()
+	}
+	return w
+}
+
+// knownPorts converts ports.known into registry entries for the labeller.
+func knownPorts(cfg *config.Config) []analysers.KnownPort {
+	if cfg == nil {
+		return nil
+	}
+	known := make([]analysers.KnownPort, 0, len(cfg.Ports.Known))
+	for _, k := range cfg.Ports.Known {
+		known = append(known, analysers.KnownPort{Addr: k.Addr, Label: k.Label, Risk: k.Risk})
+	}
+	return known
+}
+
+// protocols returns the configured protocols, defaulting to TCP and UDP.
+func (w *NetlinkWatcher) protocols() []sockProto {
+	names := []string{"tcp", "udp"}
+	if w.Cfg != nil && len(w.Cfg.Ports.Protocols) > 0 {
+		names = w.Cfg.Ports.Protocols
+	}
+	var protos []sockProto
+	for _, sp := range sockProtos {
+		for _, n := range names {
+			if strings.EqualFold(n, sp.name) {
+				protos = append(protos, sp)
+				break
+			}
+		}
+	}
+	return protos
+}
+
+// ssProtocols returns the protocols listed with ss: all of them without
+// netlink, otherwise those sock_diag failed for.
+func (w *NetlinkWatcher) ssProtocols() []sockProto {
+	if w.sockDiag == nil {
+		return w.protocols()
+	}
+	var protos []sockProto
+	for _, sp := range w.protocols() {
+		if w.ssOnly[sp.name] {
+			protos = append(protos, sp)
+		}
 	}
+	return protos
+}
+
+func (w *NetlinkWatcher) ssArgs() []string {
+	args := []string{"-lnp"}
+	for _, sp := range w.ssProtocols() {
+		args = append(args, sp.ssFlag)
+	}
+	return args
 }
 
 func (w *NetlinkWatcher) Name() string { return "netlink" }
 
 func (w *NetlinkWatcher) Start(ctx context.Context) error {
-	slog.Info("starting port watcher", "interval", w.interval)
+	slog.Info("starting port watcher", "interval", w.interval, "netlink", w.sockDiag != nil)
 
 	// Initial scan to build baseline
 	ports, err := w.scanPorts()
@@ -52,7 +140,7 @@ func (w *NetlinkWatcher) Start(ctx context.Context) error {
 	}
 
 	for _, p := range ports {
-		w.baseline[p.Address] = p
+		w.baseline[p.Key()] = p
 	}
 	slog.Info("port baseline established", "count", len(w.baseline))
 
@@ -80,40 +168,194 @@ func (w *NetlinkWatcher) check() {
 
 	currentMap := make(map[string]models.PortInfo)
 	for _, p := range current {
-		currentMap[p.Address] = p
+		currentMap[p.Key()] = p
 	}
 
 	// Detect new ports
-	for addr, port := range currentMap {
-		if _, exists := w.baseline[addr]; !exists {
-			if w.isIgnored(addr) {
+	udpSeen := make(map[string]bool)
+	for key, port := range currentMap {
+		if _, exists := w.baseline[key]; !exists {
+			if w.isIgnored(port) {
 				continue
 			}
+			if port.Protocol == "udp" && !w.udpSeen[key] {
+				// Resolvers and NTP clients send single queries from
+				// unconnected sockets on random ports; a UDP service is
+				// still bound at the next poll.
+				udpSeen[key] = true
+				delete(currentMap, key)
+				continue
+			}
+			if w.exposure != nil {
+				port = w.exposure.Analyse(port)
+			}
 			w.emitPortOpened(port)
 		}
 	}
 
 	// Detect closed ports
-	for addr, port := range w.baseline {
-		if _, exists := currentMap[addr]; !exists {
+	for key, port := range w.baseline {
+		if _, exists := currentMap[key]; !exists {
 			w.emitPortClosed(port)
 		}
 	}
 
 	// Update baseline
 	w.baseline = currentMap
+	w.udpSeen = udpSeen
 }
 
 func (w *NetlinkWatcher) scanPorts() ([]models.PortInfo, error) {
+	ports, err := w.scanListeners()
+	if err != nil {
+		return nil, err
+	}
+	return w.addPublished(ports), nil
+}
+
+// addPublished adds the container ports the runtime publishes without a
+// listening proxy process, so DNAT-only ports are evaluated on FORWARD too.
+// A port already held by a container proxy is reported once, as that
+// listener.
+func (w *NetlinkWatcher) addPublished(ports []models.PortInfo) []models.PortInfo {
+	if w.published == nil {
+		return ports
+	}
+	published, err := w.published()
+	if err != nil {
+		slog.Debug("listing published container ports failed", "error", err)
+		return ports
+	}
+	wanted := make(map[string]bool)
+	for _, sp := range w.protocols() {
+		wanted[sp.name] = true
+	}
+	held := make(map[string]bool, len(ports)) // keys, and proto/port held by a container proxy
+	for _, p := range ports {
+		held[p.Key()] = true
+		if analysers.IsContainerProxy(p.ProcessName) {
+			held[p.Protocol+"/"+p.Address[strings.LastIndex(p.Address, ":")+1:]] = true
+		}
+	}
+	for _, p := range published {
+		portNum := p.Address[strings.LastIndex(p.Address, ":")+1:]
+		if !wanted[p.Protocol] || held[p.Key()] || held[p.Protocol+"/"+portNum] {
+			continue
+		}
+		held[p.Key()] = true
+		ports = append(ports, p)
+	}
+	return ports
+}
+
+// scanListeners lists listening sockets through sock_diag, and through ss
+// for the protocols sock_diag can't list.
+func (w *NetlinkWatcher) scanListeners() ([]models.PortInfo, error) {
+	if w.sockDiag == nil {
+		return w.scanSS()
+	}
+	ports := w.scanSockDiag()
+	if len(w.ssProtocols()) == 0 {
+		return ports, nil
+	}
+	listed, err := w.scanSS()
+	if err != nil {
+		return nil, err
+	}
+	return append(ports, listed...), nil
+}
+
+// scanSockDiag lists listeners of every configured protocol for IPv4 and
+// IPv6 via inet_diag. A protocol whose dump fails is handed to ss for good:
+// a missing diag module won't appear later in the process lifetime.
+func (w *NetlinkWatcher) scanSockDiag() []models.PortInfo {
+	type protoEntry struct {
+		proto string
+		sockDiagEntry
+	}
+	var entries []protoEntry
+	for _, sp := range w.protocols() {
+		if w.ssOnly[sp.name] {
+			continue
+		}
+		var batch []protoEntry
+		var err error
+		for _, family := range []uint8{afInet, afInet6} {
+			var found []sockDiagEntry
+			if found, err = w.sockDiag(family, sp.proto, sp.states); err != nil {
+				break
+			}
+			for _, e := range found {
+				if e.DstPort != 0 {
+					continue // connected, so not a listener
+				}
+				batch = append(batch, protoEntry{proto: sp.name, sockDiagEntry: e})
+			}
+		}
+		if err != nil {
+			slog.Warn("netlink sock_diag unavailable, falling back to ss", "protocol", sp.name, "error", err)
+			if w.ssOnly == nil {
+				w.ssOnly = make(map[string]bool)
+			}
+			w.ssOnly[sp.name] = true
+			continue
+		}
+		entries = append(entries, batch...)
+	}
+
+	wanted := make(map[uint64]bool, len(entries))
+	for _, e := range entries {
+		if e.Inode != 0 {
+			wanted[e.Inode] = true
+		}
+	}
+	var pids map[uint64]int
+	if w.inodes != nil {
+		pids = w.inodes.resolve(wanted)
+	}
+
+	ports := make([]models.PortInfo, 0, len(entries))
+	for _, e := range entries {
+		port := models.PortInfo{
+			Address:   formatSockAddr(e.SrcIP, e.SrcPort),
+			Protocol:  e.proto,
+			PID:       pids[e.Inode],
+			IsExposed: e.SrcIP.IsUnspecified(),
+		}
+		ports = append(ports, w.labeller.Label(port))
+	}
+	return ports
+}
+
+// scanSS lists listeners by parsing `ss -lnp` output.
+func (w *NetlinkWatcher) scanSS() ([]models.PortInfo, error) {
 	out, err := w.runSS()
 	if err != nil {
 		return nil, fmt.Errorf("running ss: %w", err)
 	}
 
+	ports := w.parseSsOutput(out)
+	for i := range ports {
+		// Enrich with process/container info
+		ports[i] = w.labeller.Label(ports[i])
+	}
+	return ports, nil
+}
+
+// parseSsOutput parses the output of `ss` run with ssArgs.
+func (w *NetlinkWatcher) parseSsOutput(out []byte) []models.PortInfo {
+	// ss only prints the Netid column when asked for more than one
+	// protocol; otherwise every line belongs to the single one requested.
+	defaultProto := "tcp"
+	if protos := w.ssProtocols(); len(protos) == 1 {
+		defaultProto = protos[0].name
+	}
+
 	var ports []models.PortInfo
 	scanner := bufio.NewScanner(strings.NewReader(string(out)))
 
 	scanner.Scan() // skip header line
+	hasNetid := strings.HasPrefix(scanner.Text(), "Netid")
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -121,42 +363,38 @@ func (w *NetlinkWatcher) scanPorts() ([]models.PortInfo, error) {
 		if err != nil {
 			continue // skip unparseable lines
 		}
-		// Enrich with process/container info
-		port = w.labeller.Label(port)
+		if !hasNetid {
+			port.Protocol = defaultProto
+		}
 		ports = append(ports, port)
 	}
-
-	return ports, nil
+	return ports
 }
 
-// parseSsLine parses a line from `ss -tlnp` output
-// Format: State  Recv-Q  Send-Q  Local Address:Port  Peer Address:Port  Process
+// parseSsLine parses a line from `ss -lnp` output
+// Format: [Netid]  State  Recv-Q  Send-Q  Local Address:Port  Peer Address:Port  Process
+// Without a Netid column the line is assumed to be TCP.
 func (w *NetlinkWatcher) parseSsLine(line string) (models.PortInfo, error) {
 	fields := strings.Fields(line)
+	protocol := "tcp"
+	if len(fields) > 0 && isSsNetid(fields[0]) {
+		protocol = fields[0]
+		fields = fields[1:]
+	}
 	if len(fields) < 5 {
 		return models.PortInfo{}, fmt.Errorf("too few fields")
 	}
 
 	localAddr := fields[3]
+	if !strings.HasSuffix(fields[4], ":*") {
+		return models.PortInfo{}, fmt.Errorf("connected socket") // has a peer, so not a listener
+	}
 
 	// Extract PID from process field if present
 	pid := 0
 	procName := ""
 	if len(fields) >= 6 {
-		procField := fields[5]
-		// Format: users:(("name",pid=123,fd=4))
-		if idx := strings.Index(procField, "pid="); idx >= 0 {
-			pidStr := procField[idx+4:]
-			if end := strings.IndexAny(pidStr, ",)"); end > 0 {
-				pid, _ = strconv.Atoi(pidStr[:end])
-			}
-		}
-		if idx := strings.Index(procField, "((\""); idx >= 0 {
-			nameStr := procField[idx+3:]
-			if end := strings.Index(nameStr, "\""); end > 0 {
-				procName = nameStr[:end]
-			}
-		}
+		pid, procName = parseSsProcess(fields[5])
 	}
 
 	// Determine if exposed (bound to 0.0.0.0 or ::)
@@ -172,22 +410,68 @@ func (w *NetlinkWatcher) parseSsLine(line string) (models.PortInfo, error) {
 
 	return models.PortInfo{
 		Address:     localAddr,
-		Protocol:    "tcp",
+		Protocol:    protocol,
 		PID:         pid,
 		ProcessName: procName,
 		IsExposed:   isExposed,
 	}, nil
 }
 
-func (w *NetlinkWatcher) isIgnored(addr string) bool {
+// parseSsProcess extracts the first owner from an ss process field.
+// Format: users:(("name",pid=123,fd=4))
+func parseSsProcess(procField string) (pid int, name string) {
+	if idx := strings.Index(procField, "pid="); idx >= 0 {
+		pidStr := procField[idx+4:]
+		if end := strings.IndexAny(pidStr, ",)"); end > 0 {
+			pid, _ = strconv.Atoi(pidStr[:end])
+		}
+	}
+	if idx := strings.Index(procField, "((\""); idx >= 0 {
+		nameStr := procField[idx+3:]
+		if end := strings.Index(nameStr, "\""); end > 0 {
+			name = nameStr[:end]
+		}
+	}
+	return pid, name
+}
+
+// isSsNetid reports whether an ss field is a Netid value, for the protocols
+// ports.protocols accepts. States are upper-case (LISTEN, UNCONN), so the
+// two can't be confused.
+func isSsNetid(field string) bool {
+	switch field {
+	case "tcp", "udp", "raw":
+		return true
+	}
+	return false
+}
+
+// isIgnored checks ports.ignore. Patterns may be scoped to one protocol
+// ("udp:0.0.0.0:5353"); unscoped patterns ("127.0.0.1:*") match any.
+func (w *NetlinkWatcher) isIgnored(port models.PortInfo) bool {
 	for _, pattern := range w.Cfg.Ports.Ignore {
-		if matchAddrPattern(addr, pattern) {
+		proto, addrPattern := splitProtoPattern(pattern)
+		if proto != "" && !strings.EqualFold(proto, port.Protocol) {
+			continue
+		}
+		if matchAddrPattern(port.Address, addrPattern) {
 			return true
 		}
 	}
 	return false
 }
 
+// splitProtoPattern splits an optional "tcp:", "udp:" or "raw:" prefix off an
+// ignore pattern.
+func splitProtoPattern(pattern string) (proto, addr string) {
+	for _, sp := range sockProtos {
+		if rest, ok := strings.CutPrefix(pattern, sp.name+":"); ok {
+			return sp.name, rest
+		}
+	}
+	return "", pattern
+}
+
 // matchAddrPattern checks if an address matches a pattern like "127.0.0.1:*"
 func matchAddrPattern(addr, pattern string) bool {
 	if pattern == addr {
@@ -204,26 +488,51 @@ func matchAddrPattern(addr, pattern string) bool {
 func (w *NetlinkWatcher) emitPortOpened(port models.PortInfo) {
 	severity := port.RiskLevel()
 
-	msg := fmt.Sprintf("New listening port: %s → %s", port.Address, port.ProcessName)
+	addr := port.Key()
+	if port.Label != "" {
+		addr = fmt.Sprintf("%s (%s)", addr, port.Label)
+	}
+	msg := fmt.Sprintf("New listening port: %s → %s", addr, port.ProcessName)
 	details := ""
 	suggested := ""
 
 	if port.ContainerName != "" {
 		msg = fmt.Sprintf("New listening port: %s → %s (container: %s)",
-			port.Address, port.ProcessName, port.ContainerName)
+			addr, port.ProcessName, port.ContainerName)
+	}
+	if port.Published && port.PID == 0 {
+		// DNAT only: no process holds the port on the host.
+		msg = fmt.Sprintf("New published container port: %s → %s", addr, port.ContainerName)
 	}
 
-	if port.IsExposed {
+	switch {
+	case port.Reachable == models.ReachBypass:
+		hostPort := port.Address[strings.LastIndex(port.Address, ":")+1:]
+		details = "Published by Docker — bypasses the INPUT chain via FORWARD, so the host firewall does not protect it"
+		suggested = fmt.Sprintf("Filter it in DOCKER-USER (-m conntrack --ctorigdstport %s) or publish it on 127.0.0.1", hostPort)
+	case port.Reachable == models.ReachFiltered:
+		details = "Bound to all interfaces, but the firewall drops new inbound connections ✓"
+		if severity != models.SeverityInfo && port.Label != "" {
+			details = fmt.Sprintf("%s bound to all interfaces; only the firewall keeps it private", port.Label)
+			suggested = fmt.Sprintf("Bind %s to 127.0.0.1 so a firewall change can't expose it", port.Label)
+		}
+	case port.IsExposed:
 		details = "Bound to all interfaces — accessible from network"
+		if port.Reachable == models.ReachLAN {
+			details = "Bound to all interfaces — reachable from private networks only"
+		}
 		suggested = "If this should be local-only, bind to 127.0.0.1 instead of 0.0.0.0"
-		severity = models.SeverityWarning
-	} else {
+		if severity == models.SeverityCritical && port.Label != "" {
+			details = fmt.Sprintf("%s bound to all interfaces — a high-risk service to expose", port.Label)
+			suggested = fmt.Sprintf("Bind %s to 127.0.0.1 or block the port in the firewall", port.Label)
+		}
+	default:
 		details = "Localhost only — not network accessible ✓"
 	}
 
 	hostname, _ := os.Hostname()
 	w.Bus.Publish(models.Event{
-		ID:        fmt.Sprintf("port-open-%s-%d", port.Address, time.Now().Unix()),
+		ID:        fmt.Sprintf("port-open-%s-%d", port.Key(), time.Now().Unix()),
 		Type:      models.EventPortOpened,
 		Severity:  severity,
 		Hostname:  hostname,
@@ -239,12 +548,12 @@ func (w *NetlinkWatcher) emitPortOpened(port models.PortInfo) {
 func (w *NetlinkWatcher) emitPortClosed(port models.PortInfo) {
 	hostname, _ := os.Hostname()
 	w.Bus.Publish(models.Event{
-		ID:        fmt.Sprintf("port-close-%s-%d", port.Address, time.Now().Unix()),
+		ID:        fmt.Sprintf("port-close-%s-%d", port.Key(), time.Now().Unix()),
 		Type:      models.EventPortClosed,
 		Severity:  models.SeverityInfo,
 		Hostname:  hostname,
 		Timestamp: time.Now(),
-		Message:   fmt.Sprintf("Port closed: %s → %s", port.Address, port.ProcessName),
+		Message:   fmt.Sprintf("Port closed: %s → %s", port.Key(), port.ProcessName),
 		Source:    "netlink",
 		Port:     &port,
 	})
@@ -1,6 +1,7 @@
 package watchers
 
 import (
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -8,6 +9,7 @@ import (
 	"github.com/Fullex26/piguard/internal/analysers"
 	"github.com/Fullex26/piguard/internal/config"
 	"github.com/Fullex26/piguard/internal/eventbus"
+	"github.com/Fullex26/piguard/internal/firewall"
 	"github.com/Fullex26/piguard/pkg/models"
 )
 
@@ -66,6 +68,11 @@ func TestParseSsLine(t *testing.T) {
 			line:    `LISTEN 0`,
 			wantErr: true,
 		},
+		{
+			name:    "connected socket",
+			line:    `ESTAB 0 0 192.168.1.2:41000 192.168.1.1:53`,
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -99,6 +106,244 @@ func TestParseSsLine(t *testing.T) {
 	}
 }
 
+func TestParseSsLine_Netid(t *testing.T) {
+	w := &NetlinkWatcher{}
+	tests := []struct {
+		line      string
+		wantProto string
+		wantAddr  string
+		wantPID   int
+	}{
+		{`udp UNCONN 0 0 0.0.0.0:5353 0.0.0.0:* users:(("avahi-daemon",pid=412,fd=12))`, "udp", "0.0.0.0:5353", 412},
+		{`tcp LISTEN 0 128 0.0.0.0:22 0.0.0.0:* users:(("sshd",pid=1234,fd=3))`, "tcp", "0.0.0.0:22", 1234},
+		{`raw UNCONN 0 0 0.0.0.0:1 0.0.0.0:*`, "raw", "0.0.0.0:1", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.wantProto, func(t *testing.T) {
+			port, err := w.parseSsLine(tt.line)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if port.Protocol != tt.wantProto || port.Address != tt.wantAddr || port.PID != tt.wantPID {
+				t.Errorf("got %s pid=%d, want %s:%s pid=%d", port.Key(), port.PID, tt.wantProto, tt.wantAddr, tt.wantPID)
+			}
+		})
+	}
+}
+
+func TestNetlinkWatcher_ScanSS_SingleProtocol(t *testing.T) {
+	cfg := &config.Config{Ports: config.PortConfig{Protocols: []string{"udp"}}}
+	w := &NetlinkWatcher{
+		Base:     Base{Cfg: cfg, Bus: eventbus.New()},
+		labeller: analysers.NewPortLabeller(),
+		runSS: func() ([]byte, error) {
+			return []byte("State Recv-Q Send-Q Local Address:Port Peer Address:Port Process\n" +
+				"UNCONN 0 0 0.0.0.0:161 0.0.0.0:*\n"), nil
+		},
+	}
+	if got := w.ssArgs(); strings.Join(got, " ") != "-lnp -u" {
+		t.Errorf("ssArgs() = %v, want [-lnp -u]", got)
+	}
+
+	ports, err := w.scanPorts()
+	if err != nil {
+		t.Fatalf("scanPorts: %v", err)
+	}
+	if len(ports) != 1 || ports[0].Key() != "udp:0.0.0.0:161" {
+		t.Errorf("ports = %+v, want udp:0.0.0.0:161", ports)
+	}
+}
+
+func TestNetlinkWatcher_Check_SameAddrDifferentProtocol(t *testing.T) {
+	bus := eventbus.New()
+	var captured []models.Event
+	var mu sync.Mutex
+	bus.Subscribe(func(e models.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		captured = append(captured, e)
+	})
+
+	w := &NetlinkWatcher{
+		Base:     Base{Cfg: &config.Config{}, Bus: bus},
+		labeller: analysers.NewPortLabeller(),
+		baseline: map[string]models.PortInfo{
+			"tcp:0.0.0.0:53": {Address: "0.0.0.0:53", Protocol: "tcp"},
+		},
+		runSS: func() ([]byte, error) {
+			return []byte("Netid State Recv-Q Send-Q Local Address:Port Peer Address:Port Process\n" +
+				"tcp LISTEN 0 128 0.0.0.0:53 0.0.0.0:*\n" +
+				"udp UNCONN 0 0 0.0.0.0:53 0.0.0.0:*\n"), nil
+		},
+	}
+
+	w.check() // new UDP sockets are reported once still bound at the next poll
+	w.check()
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(captured) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(captured))
+	}
+	e := captured[0]
+	if e.Type != models.EventPortOpened || e.Port.Protocol != "udp" {
+		t.Errorf("got %s for %s, want port.opened for udp", e.Type, e.Port.Key())
+	}
+}
+
+func TestNetlinkWatcher_Check_KnownRiskyPortIsCritical(t *testing.T) {
+	bus := eventbus.New()
+	var captured []models.Event
+	var mu sync.Mutex
+	bus.Subscribe(func(e models.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		captured = append(captured, e)
+	})
+
+	cfg := &config.Config{
+		Ports: config.PortConfig{Known: []config.KnownPort{
+			{Addr: "0.0.0.0:8123", Label: "Home Assistant UI", Risk: "info"},
+		}},
+	}
+	labeller := analysers.NewPortLabeller()
+	labeller.SetKnownPorts(knownPorts(cfg))
+
+	w := &NetlinkWatcher{
+		Base:     Base{Cfg: cfg, Bus: bus},
+		labeller: labeller,
+		baseline: make(map[string]models.PortInfo),
+		runSS: func() ([]byte, error) {
+			return []byte("State Recv-Q Send-Q Local Address:Port Peer Address:Port Process\n" +
+				"LISTEN 0 128 0.0.0.0:6379 0.0.0.0:*\n" +
+				"LISTEN 0 128 0.0.0.0:8123 0.0.0.0:*\n"), nil
+		},
+	}
+
+	w.check()
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	got := make(map[string]models.Event)
+	for _, e := range captured {
+		got[e.Port.Address] = e
+	}
+	if e := got["0.0.0.0:6379"]; e.Severity != models.SeverityCritical || !strings.Contains(e.Message, "Redis") {
+		t.Errorf("redis event = %v %q, want critical mentioning Redis", e.Severity, e.Message)
+	}
+	if e := got["0.0.0.0:8123"]; e.Severity != models.SeverityInfo || !strings.Contains(e.Message, "Home Assistant UI") {
+		t.Errorf("home assistant event = %v %q, want info mentioning label", e.Severity, e.Message)
+	}
+}
+
+func TestNetlinkWatcher_Check_ExposureVerdicts(t *testing.T) {
+	bus := eventbus.New()
+	var captured []models.Event
+	var mu sync.Mutex
+	bus.Subscribe(func(e models.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		captured = append(captured, e)
+	})
+
+	rules, err := firewall.ParseIPTablesSave([]byte("*filter\n:INPUT DROP [0:0]\n:FORWARD ACCEPT [0:0]\n" +
+		"-A INPUT -p tcp -m tcp --dport 22 -j ACCEPT\nCOMMIT\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exposure := analysers.NewExposureAnalyser()
+	exposure.LoadRulesFn(func() (*firewall.Ruleset, *firewall.Ruleset, error) { return rules, nil, nil })
+
+	w := &NetlinkWatcher{
+		Base:     Base{Cfg: &config.Config{}, Bus: bus},
+		labeller: analysers.NewPortLabeller(),
+		exposure: exposure,
+		baseline: make(map[string]models.PortInfo),
+		runSS: func() ([]byte, error) {
+			return []byte("State Recv-Q Send-Q Local Address:Port Peer Address:Port Process\n" +
+				`LISTEN 0 128 0.0.0.0:22 0.0.0.0:* users:(("sshd",pid=10,fd=3))` + "\n" +
+				`LISTEN 0 128 0.0.0.0:3000 0.0.0.0:* users:(("node",pid=11,fd=3))` + "\n" +
+				`LISTEN 0 128 0.0.0.0:8080 0.0.0.0:* users:(("docker-proxy",pid=12,fd=3))` + "\n"), nil
+		},
+	}
+
+	w.check()
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	got := make(map[string]models.Event)
+	for _, e := range captured {
+		got[e.Port.Address] = e
+	}
+	if e := got["0.0.0.0:22"]; e.Severity != models.SeverityWarning || e.Port.Reachable != models.ReachWAN {
+		t.Errorf("ssh event = %v %q, want warning/wan", e.Severity, e.Port.Reachable)
+	}
+	if e := got["0.0.0.0:3000"]; e.Severity != models.SeverityInfo || !strings.Contains(e.Details, "firewall drops") {
+		t.Errorf("filtered event = %v %q, want info noting the firewall", e.Severity, e.Details)
+	}
+	e := got["0.0.0.0:8080"]
+	if e.Severity != models.SeverityCritical || e.Port.Reachable != models.ReachBypass {
+		t.Errorf("docker event = %v %q, want critical/bypass", e.Severity, e.Port.Reachable)
+	}
+	if !strings.Contains(e.Suggested, "DOCKER-USER") || !strings.Contains(e.Suggested, "--ctorigdstport 8080") {
+		t.Errorf("docker suggestion = %q", e.Suggested)
+	}
+}
+
+func TestNetlinkWatcher_Check_PublishedWithoutProxy(t *testing.T) {
+	bus := eventbus.New()
+	received := make(chan models.Event, 10)
+	bus.Subscribe(func(e models.Event) { received <- e })
+
+	rules, err := firewall.ParseIPTablesSave([]byte("*filter\n:INPUT DROP [0:0]\n:FORWARD ACCEPT [0:0]\nCOMMIT\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exposure := analysers.NewExposureAnalyser()
+	exposure.LoadRulesFn(func() (*firewall.Ruleset, *firewall.Ruleset, error) { return rules, nil, nil })
+
+	w := &NetlinkWatcher{
+		Base:     Base{Cfg: &config.Config{}, Bus: bus},
+		labeller: analysers.NewPortLabeller(),
+		exposure: exposure,
+		baseline: make(map[string]models.PortInfo),
+		runSS: func() ([]byte, error) {
+			return []byte("Netid State Recv-Q Send-Q Local Address:Port Peer Address:Port Process\n" +
+				`tcp LISTEN 0 128 0.0.0.0:9000 0.0.0.0:* users:(("docker-proxy",pid=12,fd=3))` + "\n"), nil
+		},
+		published: func() ([]models.PortInfo, error) {
+			return []models.PortInfo{
+				// Held by docker-proxy above: reported once, as that listener.
+				{Address: "0.0.0.0:9000", Protocol: "tcp", ProcessName: "docker", ContainerName: "portainer", IsExposed: true, Published: true},
+				// userland-proxy: false, so nothing listens on it.
+				{Address: "0.0.0.0:8080", Protocol: "tcp", ProcessName: "docker", ContainerName: "web", IsExposed: true, Published: true},
+			}, nil
+		},
+	}
+
+	w.check()
+	got := make(map[string]models.Event)
+	for i := 0; i < 2; i++ {
+		e := awaitEvent(t, received)
+		got[e.Port.Address] = e
+	}
+	expectNoEvent(t, received)
+
+	e := got["0.0.0.0:8080"]
+	if e.Severity != models.SeverityCritical || e.Port.Reachable != models.ReachBypass {
+		t.Errorf("DNAT-only port = %v %q, want critical/bypass", e.Severity, e.Port.Reachable)
+	}
+	if e.Message != "New published container port: tcp:0.0.0.0:8080 → web" {
+		t.Errorf("message = %q", e.Message)
+	}
+	if e := got["0.0.0.0:9000"]; e.Port.Reachable != models.ReachBypass || e.Port.ProcessName != "docker-proxy" {
+		t.Errorf("proxied port = %q %s, want bypass via docker-proxy", e.Port.Reachable, e.Port.ProcessName)
+	}
+}
+
 func TestMatchAddrPattern(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -270,7 +515,7 @@ func TestNetlinkWatcher_Check_IgnoredPortSkipped(t *testing.T) {
 func TestNetlinkWatcher_IsIgnored(t *testing.T) {
 	cfg := &config.Config{
 		Ports: config.PortConfig{
-			Ignore: []string{"127.0.0.1:*", "::1:*"},
+			Ignore: []string{"127.0.0.1:*", "::1:*", "udp:0.0.0.0:5353"},
 		},
 	}
 	w := &NetlinkWatcher{
@@ -278,19 +523,24 @@ func TestNetlinkWatcher_IsIgnored(t *testing.T) {
 	}
 
 	tests := []struct {
-		addr string
-		want bool
+		proto string
+		addr  string
+		want  bool
 	}{
-		{"127.0.0.1:8080", true},
-		{"::1:5432", true},
-		{"0.0.0.0:80", false},
-		{"192.168.1.1:22", false},
+		{"tcp", "127.0.0.1:8080", true},
+		{"tcp", "::1:5432", true},
+		{"udp", "127.0.0.1:53", true},
+		{"tcp", "0.0.0.0:80", false},
+		{"tcp", "192.168.1.1:22", false},
+		{"udp", "0.0.0.0:5353", true},
+		{"tcp", "0.0.0.0:5353", false},
 	}
 
 	for _, tt := range tests {
-		t.Run(tt.addr, func(t *testing.T) {
-			if got := w.isIgnored(tt.addr); got != tt.want {
-				t.Errorf("isIgnored(%q) = %v, want %v", tt.addr, got, tt.want)
+		t.Run(tt.proto+":"+tt.addr, func(t *testing.T) {
+			port := models.PortInfo{Address: tt.addr, Protocol: tt.proto}
+			if got := w.isIgnored(port); got != tt.want {
+				t.Errorf("isIgnored(%s) = %v, want %v", port.Key(), got, tt.want)
 			}
 		})
 	}
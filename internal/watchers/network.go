@@ -11,6 +11,8 @@ import (
 
 	"github.com/Fullex26/piguard/internal/config"
 	"github.com/Fullex26/piguard/internal/eventbus"
+	"github.com/Fullex26/piguard/internal/netdevice"
+	"github.com/Fullex26/piguard/internal/store"
 	"github.com/Fullex26/piguard/pkg/models"
 )
 
@@ -21,34 +23,97 @@ type networkDevice struct {
 
 // NetworkScanWatcher monitors the local ARP neighbour table for unknown devices.
 // It uses `ip neigh show` (iproute2) which requires no root and no extra packages.
+// Every device seen is recorded in the store's device inventory, so devices
+// stay known across restarts and carry the names and trust set from Telegram.
 type NetworkScanWatcher struct {
 	Base
+	store      *store.Store // nil = in-memory baseline only
 	interval   time.Duration
 	alertLeave bool
-	ignoreMACs map[string]bool          // lowercase MAC → true
-	baseline   map[string]networkDevice // MAC → device
-	runIPNeigh func() ([]byte, error)   // injectable for tests
+	ignoreMACs map[string]bool                         // lowercase MAC → true
+	baseline   map[string]networkDevice                // MAC → device present at the last scan
+	runIPNeigh func() ([]byte, error)                  // injectable for tests
+	identify   func(mac, ip string) netdevice.Identity // vendor and hostname; injectable for tests
+	lookups    chan struct{}                           // bounds identify calls running off the poll path
+
+	// ARP spoofing detection (network_spoof.go)
+	spoofDetection bool
+	macIPLimit     int
+	flapWindow     time.Duration
+	gatewayIP      string
+	gatewayMAC     string
+	ipOwners       map[string]string      // IPv4 → MAC at the last scan
+	ipMoves        map[string][]ipMove    // IPv4 → recent changes of owner
+	flapAlerted    map[string]time.Time   // IPv4 → last flapping alert
+	multiAlerted   map[string]bool        // MAC → already flagged for holding too many IPs
+	readRoutes     func() ([]byte, error) // /proc/net/route; injectable for tests
+	runIPRoute     func() ([]byte, error) // `ip route show default`; injectable for tests
+
+	// Active ARP sweep (arpscan.go)
+	activeScan bool
+	sweep      func() ([]networkDevice, error) // injectable for tests
 }
 
-func NewNetworkScanWatcher(cfg *config.Config, bus *eventbus.Bus) *NetworkScanWatcher {
+func NewNetworkScanWatcher(cfg *config.Config, bus *eventbus.Bus, db *store.Store) *NetworkScanWatcher {
 	interval, err := time.ParseDuration(cfg.Network.PollInterval)
 	if err != nil || interval <= 0 {
 		interval = 5 * time.Minute
 	}
+	flapWindow, err := time.ParseDuration(cfg.Network.IPFlapWindow)
+	if err != nil || flapWindow <= 0 {
+		flapWindow = time.Hour
+	}
 	ignore := make(map[string]bool, len(cfg.Network.IgnoreMACs))
 	for _, mac := range cfg.Network.IgnoreMACs {
 		ignore[strings.ToLower(mac)] = true
 	}
 	w := &NetworkScanWatcher{
 		Base:       Base{Cfg: cfg, Bus: bus},
+		store:      db,
 		interval:   interval,
 		alertLeave: cfg.Network.AlertOnLeave,
 		ignoreMACs: ignore,
 		baseline:   make(map[string]networkDevice),
+		lookups:    make(chan struct{}, identifyWorkers),
+
+		spoofDetection: cfg.Network.SpoofDetection,
+		macIPLimit:     cfg.Network.MACIPLimit,
+		flapWindow:     flapWindow,
+		ipOwners:       make(map[string]string),
+		ipMoves:        make(map[string][]ipMove),
+		flapAlerted:    make(map[string]time.Time),
+		multiAlerted:   make(map[string]bool),
+		readRoutes:     func() ([]byte, error) { return os.ReadFile("/proc/net/route") },
+		runIPRoute: func() ([]byte, error) {
+			return exec.Command("ip", "-4", "route", "show", "default").Output()
+		},
 	}
 	w.runIPNeigh = func() ([]byte, error) {
 		return exec.Command("ip", "neigh", "show").Output()
 	}
+	w.activeScan = cfg.Network.ActiveScan
+	w.sweep = func() ([]networkDevice, error) {
+		iface := cfg.Network.Interface
+		if iface == "" {
+			data, err := w.readRoutes()
+			if err == nil {
+				_, iface = parseProcRoute(string(data))
+			}
+			if iface == "" {
+				return nil, fmt.Errorf("no default route to sweep; set network.interface")
+			}
+		}
+		return arpSweep(iface, cfg.Network.ScanRate, 2*time.Second)
+	}
+	ident, err := netdevice.NewIdentifier(cfg.Network.OUIFile, cfg.Network.LeaseFiles)
+	if err != nil {
+		slog.Warn("loading network.oui_file failed, using the embedded vendor registry", "file", cfg.Network.OUIFile, "error", err)
+	}
+	w.identify = func(mac, ip string) netdevice.Identity {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return ident.Identify(ctx, mac, ip)
+	}
 	return w
 }
 
@@ -58,11 +123,26 @@ func (w *NetworkScanWatcher) Stop() error  { return nil }
 func (w *NetworkScanWatcher) Start(ctx context.Context) error {
 	slog.Info("starting network scan watcher", "interval", w.interval)
 
-	// Build initial baseline silently (no alerts for pre-existing devices).
-	if out, err := w.runIPNeigh(); err == nil {
-		for _, d := range parseIPNeigh(string(out)) {
+	// Build initial baseline. Devices already on the LAN are only alerted
+	// when an existing inventory doesn't know them; on the first run (or
+	// without a store) they are recorded silently.
+	silent := true
+	if w.store != nil {
+		if devices, err := w.store.ListDevices(); err == nil && len(devices) > 0 {
+			silent = false
+		}
+	}
+	if devices, err := w.scan(); err == nil {
+		hostname, _ := os.Hostname()
+		for _, d := range devices {
+			if w.record(d) && !silent && !w.ignoreMACs[d.MAC] {
+				w.alertNew(hostname, d)
+			}
 			w.baseline[d.MAC] = d
 		}
+		if w.spoofDetection {
+			w.checkSpoofing(hostname, devices)
+		}
 		slog.Info("network baseline established", "count", len(w.baseline))
 	} else {
 		slog.Warn("ip neigh not available at startup", "error", err)
@@ -82,14 +162,14 @@ func (w *NetworkScanWatcher) Start(ctx context.Context) error {
 }
 
 func (w *NetworkScanWatcher) check() {
-	out, err := w.runIPNeigh()
+	devices, err := w.scan()
 	if err != nil {
 		slog.Debug("ip neigh check skipped", "error", err)
 		return
 	}
 
 	current := make(map[string]networkDevice)
-	for _, d := range parseIPNeigh(string(out)) {
+	for _, d := range devices {
 		current[d.MAC] = d
 	}
 
@@ -97,19 +177,13 @@ func (w *NetworkScanWatcher) check() {
 
 	// Detect new devices (unknown MAC).
 	for mac, d := range current {
-		if w.ignoreMACs[mac] {
-			continue
+		_, present := w.baseline[mac]
+		isNew := w.record(d)
+		if w.store == nil {
+			isNew = !present
 		}
-		if _, known := w.baseline[mac]; !known {
-			w.Bus.Publish(models.Event{
-				ID:        fmt.Sprintf("%s-%s-%d", string(models.EventNetworkNewDevice), mac, time.Now().UnixNano()),
-				Type:      models.EventNetworkNewDevice,
-				Severity:  models.SeverityInfo,
-				Hostname:  hostname,
-				Timestamp: time.Now(),
-				Message:   fmt.Sprintf("New device on network: %s (%s)", d.IP, mac),
-				Source:    "network-scan",
-			})
+		if isNew && !w.ignoreMACs[mac] && !w.trusted(mac) {
+			w.alertNew(hostname, d)
 		}
 	}
 
@@ -126,7 +200,7 @@ func (w *NetworkScanWatcher) check() {
 					Severity:  models.SeverityInfo,
 					Hostname:  hostname,
 					Timestamp: time.Now(),
-					Message:   fmt.Sprintf("Device left network: %s (%s)", d.IP, mac),
+					Message:   "Device left network: " + w.label(d),
 					Source:    "network-scan",
 				})
 				delete(w.baseline, mac)
@@ -141,6 +215,111 @@ func (w *NetworkScanWatcher) check() {
 	for mac, d := range current {
 		w.baseline[mac] = d
 	}
+
+	if w.spoofDetection {
+		w.checkSpoofing(hostname, devices)
+	}
+}
+
+// scan returns the devices in the neighbour table plus, with active_scan,
+// every host that answered an ARP sweep of the subnet. Quiet devices the Pi
+// never talks to only show up in the sweep.
+func (w *NetworkScanWatcher) scan() ([]networkDevice, error) {
+	out, err := w.runIPNeigh()
+	var devices []networkDevice
+	if err == nil {
+		devices = parseIPNeigh(string(out))
+	}
+	if !w.activeScan {
+		return devices, err
+	}
+
+	swept, sweepErr := w.sweep()
+	if sweepErr != nil {
+		slog.Warn("ARP sweep failed", "error", sweepErr)
+		return devices, err
+	}
+	seen := make(map[string]bool, len(devices))
+	for _, d := range devices {
+		seen[d.MAC+" "+d.IP] = true
+	}
+	for _, d := range swept {
+		if !seen[d.MAC+" "+d.IP] {
+			seen[d.MAC+" "+d.IP] = true
+			devices = append(devices, d)
+		}
+	}
+	return devices, nil
+}
+
+// record adds or refreshes the device in the inventory and reports whether
+// the inventory had never seen it.
+func (w *NetworkScanWatcher) record(d networkDevice) bool {
+	if w.store == nil {
+		return false
+	}
+	isNew, err := w.store.RecordDevice(d.MAC, d.IP, time.Now())
+	if err != nil {
+		slog.Warn("recording network device failed", "mac", d.MAC, "error", err)
+		_, known := w.baseline[d.MAC]
+		return !known
+	}
+	return isNew
+}
+
+// identifyWorkers is how many device lookups may run at once. A lookup
+// can take seconds of reverse DNS and mDNS timeouts.
+const identifyWorkers = 4
+
+// alertNew publishes a new-device alert once the device is identified.
+func (w *NetworkScanWatcher) alertNew(hostname string, d networkDevice) {
+	seen := time.Now()
+	w.withIdentity(d.MAC, d.IP, func(id netdevice.Identity) {
+		w.Bus.Publish(models.Event{
+			ID:        fmt.Sprintf("%s-%s-%d", string(models.EventNetworkNewDevice), d.MAC, seen.UnixNano()),
+			Type:      models.EventNetworkNewDevice,
+			Severity:  models.SeverityInfo,
+			Hostname:  hostname,
+			Timestamp: seen,
+			Message:   fmt.Sprintf("New device on network: %s (%s)", d.IP, d.MAC),
+			Details:   id.Details(),
+			Suggested: fmt.Sprintf("If you recognise it, label it with /device name %s <name> and mark it trusted with /device trust %s", d.MAC, d.MAC),
+			Source:    "network-scan",
+		})
+	})
+}
+
+// withIdentity identifies the device off the poll path and hands the result
+// to fn, so slow lookups never delay the next scan.
+func (w *NetworkScanWatcher) withIdentity(mac, ip string, fn func(netdevice.Identity)) {
+	go func() {
+		w.lookups <- struct{}{}
+		defer func() { <-w.lookups }()
+		fn(w.identify(mac, ip))
+	}()
+}
+
+// trusted reports whether the device was marked trusted with /device trust.
+// Trusted devices raise no new-device or spoofing alerts.
+func (w *NetworkScanWatcher) trusted(mac string) bool {
+	if w.store == nil {
+		return false
+	}
+	dev, err := w.store.GetDevice(mac)
+	return err == nil && dev.Trusted
+}
+
+// label names a device for alerts, with the name given in the inventory
+// when there is one: "Living room TV, 192.168.1.20 (aa:bb:…)".
+func (w *NetworkScanWatcher) label(d networkDevice) string {
+	plain := fmt.Sprintf("%s (%s)", d.IP, d.MAC)
+	if w.store == nil {
+		return plain
+	}
+	if dev, err := w.store.GetDevice(d.MAC); err == nil && dev.Name != "" {
+		return dev.Name + ", " + plain
+	}
+	return plain
 }
 
 // parseIPNeigh parses output from `ip neigh show` and returns devices with known MACs.
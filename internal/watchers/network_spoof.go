@@ -0,0 +1,254 @@
+package watchers
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Fullex26/piguard/internal/netdevice"
+	"github.com/Fullex26/piguard/pkg/models"
+)
+
+// gatewayStateKey persists the gateway's IP and MAC ("<ip> <mac>") so a
+// change made while the daemon was down is still caught.
+const gatewayStateKey = "network.gateway"
+
+// ipMove is an IP address changing hands to MAC at At.
+type ipMove struct {
+	MAC string
+	At  time.Time
+}
+
+// checkSpoofing looks for the signatures of ARP poisoning in one scan of the
+// neighbour table: the default gateway answering from a new MAC, one MAC
+// claiming many addresses, and an address moving back and forth between MACs.
+func (w *NetworkScanWatcher) checkSpoofing(hostname string, devices []networkDevice) {
+	owners := make(map[string][]string) // MAC → IPv4 addresses
+	for _, d := range devices {
+		if ip := net.ParseIP(d.IP); ip == nil || ip.To4() == nil {
+			continue // IPv6 hosts legitimately hold several addresses
+		}
+		owners[d.MAC] = append(owners[d.MAC], d.IP)
+	}
+
+	w.checkGateway(hostname, devices, owners)
+	w.checkMultipleIPs(hostname, owners)
+	w.checkFlapping(hostname, devices)
+}
+
+// checkGateway raises a critical alert when the default gateway's address
+// resolves to a different MAC than before.
+func (w *NetworkScanWatcher) checkGateway(hostname string, devices []networkDevice, owners map[string][]string) {
+	gw := w.defaultGateway()
+	if gw == "" {
+		return
+	}
+	var mac string
+	for _, d := range devices {
+		if d.IP == gw {
+			mac = d.MAC
+			break
+		}
+	}
+	if mac == "" {
+		return // no ARP entry for the gateway yet
+	}
+
+	if w.gatewayMAC == "" && w.store != nil {
+		if saved, err := w.store.GetState(gatewayStateKey); err == nil {
+			w.gatewayIP, w.gatewayMAC, _ = strings.Cut(saved, " ")
+		}
+	}
+	prevIP, prevMAC := w.gatewayIP, w.gatewayMAC
+	w.gatewayIP, w.gatewayMAC = gw, mac
+	if prevIP != gw || prevMAC != mac {
+		if w.store != nil {
+			w.store.SetState(gatewayStateKey, gw+" "+mac)
+		}
+	}
+	if prevIP != gw || prevMAC == "" || prevMAC == mac {
+		return // first sighting, a different network, or unchanged
+	}
+	others := otherIPs(owners[mac], gw)
+	newMAC := mac
+	if w.trusted(mac) {
+		// Still critical: a trusted host taking over the gateway address is
+		// exactly what a compromised one would do.
+		newMAC += " (trusted device)"
+	}
+	w.withIdentity(mac, gw, func(id netdevice.Identity) {
+		details := fmt.Sprintf("Gateway: %s | Previous MAC: %s | New MAC: %s | %s",
+			gw, prevMAC, newMAC, id.Details())
+		if len(others) > 0 {
+			details += " | New MAC also answers for: " + strings.Join(others, ", ")
+		}
+		w.emitSpoof(hostname, models.EventGatewayMACChanged, models.SeverityCritical,
+			fmt.Sprintf("Gateway MAC changed: %s moved from %s to %s", gw, prevMAC, mac),
+			details,
+			fmt.Sprintf("If the router wasn't replaced, another host may be poisoning ARP to intercept traffic. "+
+				"Find %s with /devices and `ip neigh show`, and pin the real gateway: "+
+				"sudo ip neigh replace %s lladdr %s nud permanent dev <iface>", mac, gw, prevMAC))
+	})
+}
+
+// checkMultipleIPs flags a MAC holding more IPv4 addresses than the limit,
+// once until it drops back under it. Ignored MACs are skipped.
+func (w *NetworkScanWatcher) checkMultipleIPs(hostname string, owners map[string][]string) {
+	if w.macIPLimit <= 0 {
+		return
+	}
+	for mac, ips := range owners {
+		if len(ips) <= w.macIPLimit || w.ignoreMACs[mac] {
+			delete(w.multiAlerted, mac)
+			continue
+		}
+		if w.multiAlerted[mac] {
+			continue
+		}
+		w.multiAlerted[mac] = true
+		sort.Strings(ips)
+		label := mac
+		if w.store != nil {
+			if dev, err := w.store.GetDevice(mac); err == nil {
+				label = dev.Label()
+			}
+		}
+		w.emitSpoof(hostname, models.EventMACMultipleIPs, models.SeverityWarning,
+			fmt.Sprintf("MAC %s claims %d IP addresses: %s", label, len(ips), strings.Join(ips, ", ")),
+			fmt.Sprintf("MAC: %s | Addresses: %s | Limit: %d", mac, strings.Join(ips, ", "), w.macIPLimit),
+			fmt.Sprintf("An ARP spoofer answers for the addresses it intercepts. If %s is a router, VM host or "+
+				"multi-homed server, add it to network.ignore_macs", mac))
+	}
+}
+
+// checkFlapping flags an IPv4 address that changed MAC twice within the
+// flap window: once is a DHCP reassignment, back and forth is contention.
+func (w *NetworkScanWatcher) checkFlapping(hostname string, devices []networkDevice) {
+	now := time.Now()
+	seen := make(map[string]bool)
+	for _, d := range devices {
+		if ip := net.ParseIP(d.IP); ip == nil || ip.To4() == nil || seen[d.IP] {
+			continue
+		}
+		seen[d.IP] = true
+		prev, known := w.ipOwners[d.IP]
+		w.ipOwners[d.IP] = d.MAC
+		if !known || prev == d.MAC {
+			continue
+		}
+
+		var moves []ipMove
+		for _, m := range w.ipMoves[d.IP] {
+			if now.Sub(m.At) <= w.flapWindow {
+				moves = append(moves, m)
+			}
+		}
+		if len(moves) == 0 {
+			moves = append(moves, ipMove{MAC: prev, At: now}) // owner before the first move
+		}
+		moves = append(moves, ipMove{MAC: d.MAC, At: now})
+		w.ipMoves[d.IP] = moves
+		if len(moves) < 3 {
+			continue
+		}
+		if last, ok := w.flapAlerted[d.IP]; ok && now.Sub(last) < w.flapWindow {
+			continue
+		}
+
+		macs := make([]string, len(moves))
+		for i, m := range moves {
+			macs[i] = m.MAC
+		}
+		w.flapAlerted[d.IP] = now
+		w.emitSpoof(hostname, models.EventIPFlapping, models.SeverityWarning,
+			fmt.Sprintf("IP %s is flapping between MACs: %s", d.IP, strings.Join(macs, " → ")),
+			fmt.Sprintf("Address: %s | Changes: %d within %s", d.IP, len(moves)-1, shortDuration(w.flapWindow)),
+			"Two hosts answering for one address is an IP conflict or ARP poisoning. Check both MACs with /devices; "+
+				"a static IP inside the DHCP range also causes this")
+	}
+}
+
+// defaultGateway returns the IPv4 default gateway from /proc/net/route, or
+// from `ip route show default` when that can't be read.
+func (w *NetworkScanWatcher) defaultGateway() string {
+	if data, err := w.readRoutes(); err == nil {
+		if gw, _ := parseProcRoute(string(data)); gw != "" {
+			return gw
+		}
+	}
+	out, err := w.runIPRoute()
+	if err != nil {
+		slog.Debug("default gateway lookup failed", "error", err)
+		return ""
+	}
+	return parseIPRouteDefault(string(out))
+}
+
+// parseProcRoute returns the gateway and interface of the first default
+// route in /proc/net/route, where addresses are little-endian hex.
+func parseProcRoute(data string) (gateway, iface string) {
+	const rtfGateway = 0x2
+	for _, line := range strings.Split(data, "\n")[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[1] != "00000000" {
+			continue
+		}
+		flags, err := strconv.ParseUint(fields[3], 16, 32)
+		if err != nil || flags&rtfGateway == 0 {
+			continue
+		}
+		raw, err := hex.DecodeString(fields[2])
+		if err != nil || len(raw) != 4 {
+			continue
+		}
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, binary.LittleEndian.Uint32(raw))
+		return ip.String(), fields[0]
+	}
+	return "", ""
+}
+
+// parseIPRouteDefault parses "default via 192.168.1.1 dev eth0 ...".
+func parseIPRouteDefault(out string) string {
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		for i := 0; i+1 < len(fields); i++ {
+			if fields[0] == "default" && fields[i] == "via" {
+				return fields[i+1]
+			}
+		}
+	}
+	return ""
+}
+
+func otherIPs(ips []string, except string) []string {
+	var out []string
+	for _, ip := range ips {
+		if ip != except {
+			out = append(out, ip)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (w *NetworkScanWatcher) emitSpoof(hostname string, evType models.EventType, sev models.Severity,
+	msg, details, suggested string) {
+	w.Bus.Publish(models.Event{
+		ID:        fmt.Sprintf("%s-%d", string(evType), time.Now().UnixNano()),
+		Type:      evType,
+		Severity:  sev,
+		Hostname:  hostname,
+		Timestamp: time.Now(),
+		Message:   msg,
+		Details:   details,
+		Suggested: suggested,
+		Source:    "network-scan",
+	})
+}
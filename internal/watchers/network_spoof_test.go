@@ -0,0 +1,213 @@
+package watchers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fullex26/piguard/pkg/models"
+)
+
+const procRouteDefault = `Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask		MTU	Window	IRTT
+eth0	00000000	0101A8C0	0003	0	0	100	00000000	0	0	0
+eth0	0001A8C0	00000000	0001	0	0	100	00FFFFFF	0	0	0
+`
+
+func TestParseProcRoute(t *testing.T) {
+	if gw, iface := parseProcRoute(procRouteDefault); gw != "192.168.1.1" || iface != "eth0" {
+		t.Errorf("parseProcRoute = %q, %q; want 192.168.1.1, eth0", gw, iface)
+	}
+	noDefault := "Iface\tDestination\tGateway\tFlags\neth0\t0001A8C0\t00000000\t0001\n"
+	if gw, _ := parseProcRoute(noDefault); gw != "" {
+		t.Errorf("parseProcRoute without default = %q, want empty", gw)
+	}
+}
+
+func TestParseIPRouteDefault(t *testing.T) {
+	out := "default via 10.0.0.1 dev wlan0 proto dhcp src 10.0.0.23 metric 600\n"
+	if got := parseIPRouteDefault(out); got != "10.0.0.1" {
+		t.Errorf("parseIPRouteDefault = %q, want 10.0.0.1", got)
+	}
+	if got := parseIPRouteDefault(""); got != "" {
+		t.Errorf("parseIPRouteDefault empty = %q", got)
+	}
+}
+
+func TestDefaultGateway_FallsBackToIPRoute(t *testing.T) {
+	w, _ := newTestSpoofWatcher(t)
+	w.readRoutes = func() ([]byte, error) { return nil, errors.New("no /proc") }
+	w.runIPRoute = func() ([]byte, error) { return []byte("default via 10.0.0.1 dev wlan0\n"), nil }
+	if got := w.defaultGateway(); got != "10.0.0.1" {
+		t.Errorf("defaultGateway = %q, want 10.0.0.1", got)
+	}
+}
+
+func newTestSpoofWatcher(t *testing.T) (*NetworkScanWatcher, chan models.Event) {
+	t.Helper()
+	w, received := newTestNetworkWatcher(false, nil, nil)
+	w.spoofDetection = true
+	w.macIPLimit = 2
+	w.readRoutes = func() ([]byte, error) { return []byte(procRouteDefault), nil }
+	return w, received
+}
+
+func scan(w *NetworkScanWatcher, output string) {
+	w.runIPNeigh = func() ([]byte, error) { return []byte(output), nil }
+	w.check()
+}
+
+func TestNetworkScanWatcher_GatewayMACChanged(t *testing.T) {
+	w, received := newTestSpoofWatcher(t)
+	w.store = openNetworkTestStore(t)
+
+	scan(w, `192.168.1.1 dev eth0 lladdr aa:aa:aa:aa:aa:01 REACHABLE`)
+
+	scan(w, `192.168.1.1 dev eth0 lladdr de:ad:be:ef:00:66 REACHABLE
+192.168.1.30 dev eth0 lladdr de:ad:be:ef:00:66 REACHABLE`)
+
+	var got models.Event
+	for {
+		e := awaitEvent(t, received)
+		if e.Type == models.EventGatewayMACChanged {
+			got = e
+			break
+		}
+	}
+	if got.Severity != models.SeverityCritical {
+		t.Errorf("severity = %v, want Critical", got.Severity)
+	}
+	if !strings.Contains(got.Message, "aa:aa:aa:aa:aa:01") || !strings.Contains(got.Message, "de:ad:be:ef:00:66") {
+		t.Errorf("message = %q", got.Message)
+	}
+	if !strings.Contains(got.Details, "also answers for: 192.168.1.30") {
+		t.Errorf("details = %q", got.Details)
+	}
+
+	// The new MAC is remembered across restarts.
+	if saved, _ := w.store.GetState(gatewayStateKey); saved != "192.168.1.1 de:ad:be:ef:00:66" {
+		t.Errorf("saved gateway = %q", saved)
+	}
+}
+
+func TestNetworkScanWatcher_GatewayChangedWhileDown(t *testing.T) {
+	w, received := newTestSpoofWatcher(t)
+	w.store = openNetworkTestStore(t)
+	w.store.SetState(gatewayStateKey, "192.168.1.1 aa:aa:aa:aa:aa:01")
+
+	w.checkSpoofing("host", parseIPNeigh(`192.168.1.1 dev eth0 lladdr de:ad:be:ef:00:66 REACHABLE`))
+
+	if e := awaitEvent(t, received); e.Type != models.EventGatewayMACChanged {
+		t.Errorf("event type = %s, want %s", e.Type, models.EventGatewayMACChanged)
+	}
+}
+
+func TestNetworkScanWatcher_GatewayUnchanged_NoAlert(t *testing.T) {
+	w, received := newTestSpoofWatcher(t)
+	out := `192.168.1.1 dev eth0 lladdr aa:aa:aa:aa:aa:01 REACHABLE`
+	w.checkSpoofing("host", parseIPNeigh(out))
+	w.checkSpoofing("host", parseIPNeigh(out))
+	expectNoEvent(t, received)
+}
+
+func TestNetworkScanWatcher_MACMultipleIPs(t *testing.T) {
+	w, received := newTestSpoofWatcher(t)
+	out := `192.168.1.20 dev eth0 lladdr de:ad:be:ef:00:66 REACHABLE
+192.168.1.21 dev eth0 lladdr de:ad:be:ef:00:66 REACHABLE
+192.168.1.22 dev eth0 lladdr de:ad:be:ef:00:66 STALE
+fe80::1 dev eth0 lladdr de:ad:be:ef:00:66 STALE`
+
+	w.checkSpoofing("host", parseIPNeigh(out))
+	e := awaitEvent(t, received)
+	if e.Type != models.EventMACMultipleIPs || !strings.Contains(e.Message, "claims 3 IP addresses") {
+		t.Errorf("unexpected event: %s %q", e.Type, e.Message)
+	}
+
+	// Alerted once until it drops back under the limit.
+	w.checkSpoofing("host", parseIPNeigh(out))
+	expectNoEvent(t, received)
+}
+
+func TestNetworkScanWatcher_MACMultipleIPs_IgnoredAndUnderLimit(t *testing.T) {
+	w, received := newTestSpoofWatcher(t)
+	w.ignoreMACs["aa:aa:aa:aa:aa:01"] = true
+	w.checkSpoofing("host", parseIPNeigh(`192.168.1.2 dev eth0 lladdr aa:aa:aa:aa:aa:01 REACHABLE
+192.168.1.3 dev eth0 lladdr aa:aa:aa:aa:aa:01 REACHABLE
+192.168.1.4 dev eth0 lladdr aa:aa:aa:aa:aa:01 REACHABLE
+192.168.1.20 dev eth0 lladdr de:ad:be:ef:00:01 REACHABLE
+192.168.1.21 dev eth0 lladdr de:ad:be:ef:00:01 STALE`))
+	expectNoEvent(t, received)
+}
+
+func TestNetworkScanWatcher_IPFlapping(t *testing.T) {
+	w, received := newTestSpoofWatcher(t)
+	w.flapWindow = time.Hour
+	a := `192.168.1.50 dev eth0 lladdr aa:aa:aa:aa:aa:50 REACHABLE`
+	b := `192.168.1.50 dev eth0 lladdr bb:bb:bb:bb:bb:50 REACHABLE`
+
+	w.checkSpoofing("host", parseIPNeigh(a))
+	w.checkSpoofing("host", parseIPNeigh(b)) // one change: DHCP reassignment
+	expectNoEvent(t, received)
+
+	w.checkSpoofing("host", parseIPNeigh(a))
+	e := awaitEvent(t, received)
+	if e.Type != models.EventIPFlapping {
+		t.Fatalf("event type = %s, want %s", e.Type, models.EventIPFlapping)
+	}
+	want := "IP 192.168.1.50 is flapping between MACs: aa:aa:aa:aa:aa:50 → bb:bb:bb:bb:bb:50 → aa:aa:aa:aa:aa:50"
+	if e.Message != want {
+		t.Errorf("message = %q, want %q", e.Message, want)
+	}
+
+	// Not repeated within the window.
+	w.checkSpoofing("host", parseIPNeigh(b))
+	expectNoEvent(t, received)
+}
+
+func TestNetworkScanWatcher_TrustedMACs_StillSpoofAlerts(t *testing.T) {
+	w, received := newTestSpoofWatcher(t)
+	w.store = openNetworkTestStore(t)
+	w.store.RecordDevice("aa:aa:aa:aa:aa:02", "192.168.1.2", time.Now())
+	w.store.SetDeviceTrusted("aa:aa:aa:aa:aa:02", true)
+
+	// Trust only silences new-device alerts: a trusted host claiming many
+	// addresses is still flagged.
+	w.checkSpoofing("host", parseIPNeigh(`192.168.1.2 dev eth0 lladdr aa:aa:aa:aa:aa:02 REACHABLE
+192.168.1.3 dev eth0 lladdr aa:aa:aa:aa:aa:02 REACHABLE
+192.168.1.4 dev eth0 lladdr aa:aa:aa:aa:aa:02 REACHABLE`))
+	if e := awaitEvent(t, received); e.Type != models.EventMACMultipleIPs {
+		t.Errorf("event type = %s, want %s", e.Type, models.EventMACMultipleIPs)
+	}
+}
+
+func TestNetworkScanWatcher_GatewayChangedToTrustedMAC_StillCritical(t *testing.T) {
+	w, received := newTestSpoofWatcher(t)
+	w.store = openNetworkTestStore(t)
+	w.store.SetState(gatewayStateKey, "192.168.1.1 aa:aa:aa:aa:aa:01")
+	w.store.RecordDevice("aa:aa:aa:aa:aa:02", "192.168.1.2", time.Now())
+	w.store.SetDeviceTrusted("aa:aa:aa:aa:aa:02", true)
+
+	w.checkSpoofing("host", parseIPNeigh(`192.168.1.1 dev eth0 lladdr aa:aa:aa:aa:aa:02 REACHABLE`))
+	e := awaitEvent(t, received)
+	if e.Type != models.EventGatewayMACChanged || e.Severity != models.SeverityCritical {
+		t.Fatalf("event = %s %v, want critical %s", e.Type, e.Severity, models.EventGatewayMACChanged)
+	}
+	if !strings.Contains(e.Details, "New MAC: aa:aa:aa:aa:aa:02 (trusted device)") {
+		t.Errorf("details = %q, want the trusted flag", e.Details)
+	}
+}
+
+func TestNetworkScanWatcher_IPFlapping_OutsideWindow(t *testing.T) {
+	w, received := newTestSpoofWatcher(t)
+	w.flapWindow = time.Hour
+	a := `192.168.1.50 dev eth0 lladdr aa:aa:aa:aa:aa:50 REACHABLE`
+	b := `192.168.1.50 dev eth0 lladdr bb:bb:bb:bb:bb:50 REACHABLE`
+
+	w.checkSpoofing("host", parseIPNeigh(a))
+	w.checkSpoofing("host", parseIPNeigh(b))
+	for i := range w.ipMoves["192.168.1.50"] {
+		w.ipMoves["192.168.1.50"][i].At = time.Now().Add(-2 * time.Hour)
+	}
+	w.checkSpoofing("host", parseIPNeigh(a))
+	expectNoEvent(t, received)
+}
@@ -1,12 +1,17 @@
 package watchers
 
 import (
+	"context"
 	"fmt"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/Fullex26/piguard/internal/config"
 	"github.com/Fullex26/piguard/internal/eventbus"
+	"github.com/Fullex26/piguard/internal/netdevice"
+	"github.com/Fullex26/piguard/internal/store"
 	"github.com/Fullex26/piguard/pkg/models"
 )
 
@@ -93,8 +98,9 @@ func newTestNetworkWatcher(alertLeave bool, ignoreMACList []string, stub func()
 			IgnoreMACs:   ignoreMACList,
 		},
 	}
-	w := NewNetworkScanWatcher(cfg, bus)
+	w := NewNetworkScanWatcher(cfg, bus, nil)
 	w.runIPNeigh = stub
+	w.identify = func(mac, ip string) netdevice.Identity { return netdevice.Identity{} }
 	return w, received
 }
 
@@ -231,8 +237,177 @@ func TestNetworkScanWatcher_IPNeighUnavailable(t *testing.T) {
 
 func TestNetworkScanWatcher_Name(t *testing.T) {
 	cfg := &config.Config{Network: config.NetworkConfig{PollInterval: "5m"}}
-	w := NewNetworkScanWatcher(cfg, eventbus.New())
+	w := NewNetworkScanWatcher(cfg, eventbus.New(), nil)
 	if got := w.Name(); got != "network-scan" {
 		t.Errorf("Name() = %q, want %q", got, "network-scan")
 	}
 }
+
+// ── device inventory ─────────────────────────────────────────────────────────
+
+func openNetworkTestStore(t *testing.T) *store.Store {
+	t.Helper()
+	db, err := store.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// startNetworkWatcher runs Start until the initial baseline is built.
+func startNetworkWatcher(t *testing.T, w *NetworkScanWatcher) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { w.Start(ctx); close(done) }()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	<-done
+}
+
+func TestNetworkScanWatcher_Inventory_FirstRunSilent(t *testing.T) {
+	output := `192.168.1.1 dev eth0 lladdr aa:bb:cc:dd:ee:ff REACHABLE`
+	w, received := newTestNetworkWatcher(false, nil, func() ([]byte, error) {
+		return []byte(output), nil
+	})
+	w.store = openNetworkTestStore(t)
+
+	startNetworkWatcher(t, w)
+
+	select {
+	case e := <-received:
+		t.Errorf("unexpected event on first run: type=%s", e.Type)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if _, err := w.store.GetDevice("aa:bb:cc:dd:ee:ff"); err != nil {
+		t.Errorf("device not recorded: %v", err)
+	}
+}
+
+func TestNetworkScanWatcher_Inventory_SurvivesRestart(t *testing.T) {
+	db := openNetworkTestStore(t)
+	db.RecordDevice("aa:bb:cc:dd:ee:ff", "192.168.1.1", time.Now().Add(-time.Hour))
+
+	// The router is in the inventory from a previous run; the laptop joined
+	// while the daemon was down.
+	output := `192.168.1.1 dev eth0 lladdr aa:bb:cc:dd:ee:ff REACHABLE
+192.168.1.60 dev eth0 lladdr de:ad:be:ef:00:02 REACHABLE`
+	w, received := newTestNetworkWatcher(false, nil, func() ([]byte, error) {
+		return []byte(output), nil
+	})
+	w.store = db
+
+	startNetworkWatcher(t, w)
+
+	select {
+	case e := <-received:
+		if e.Type != models.EventNetworkNewDevice || !strings.Contains(e.Message, "de:ad:be:ef:00:02") {
+			t.Errorf("unexpected event: type=%s msg=%s", e.Type, e.Message)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for new device event")
+	}
+	select {
+	case e := <-received:
+		t.Errorf("unexpected second event: %s", e.Message)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestNetworkScanWatcher_Inventory_KnownDeviceReturns_NoAlert(t *testing.T) {
+	db := openNetworkTestStore(t)
+	db.RecordDevice("de:ad:be:ef:00:01", "192.168.1.50", time.Now().Add(-24*time.Hour))
+
+	w, received := newTestNetworkWatcher(false, nil, func() ([]byte, error) {
+		return []byte(`192.168.1.77 dev eth0 lladdr de:ad:be:ef:00:01 REACHABLE`), nil
+	})
+	w.store = db
+
+	w.check()
+
+	select {
+	case e := <-received:
+		t.Errorf("unexpected event for known device: %s", e.Message)
+	case <-time.After(50 * time.Millisecond):
+	}
+	d, _ := db.GetDevice("de:ad:be:ef:00:01")
+	if d.IP != "192.168.1.77" || len(d.IPs) != 2 {
+		t.Errorf("IP history not updated: %+v", d)
+	}
+}
+
+func TestNetworkScanWatcher_DeviceLeft_ShowsName(t *testing.T) {
+	knownDevice := `192.168.1.10 dev eth0 lladdr ff:ff:ff:ff:ff:01 REACHABLE`
+	db := openNetworkTestStore(t)
+	db.RecordDevice("ff:ff:ff:ff:ff:01", "192.168.1.10", time.Now())
+	db.SetDeviceName("ff:ff:ff:ff:ff:01", "Doorbell")
+
+	w, received := newTestNetworkWatcher(true, nil, func() ([]byte, error) {
+		return []byte(""), nil
+	})
+	w.store = db
+	seedNetworkBaseline(w, knownDevice)
+
+	w.check()
+
+	select {
+	case e := <-received:
+		want := "Device left network: Doorbell, 192.168.1.10 (ff:ff:ff:ff:ff:01)"
+		if e.Message != want {
+			t.Errorf("message = %q, want %q", e.Message, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for device-left event")
+	}
+}
+
+func TestNetworkScanWatcher_NewDevice_IdentityInDetails(t *testing.T) {
+	w, received := newTestNetworkWatcher(false, nil, func() ([]byte, error) {
+		return []byte(`192.168.1.50 dev eth0 lladdr b8:27:eb:00:00:01 REACHABLE`), nil
+	})
+	w.identify = func(mac, ip string) netdevice.Identity {
+		return netdevice.Identity{Vendor: "Raspberry Pi Foundation", Hostname: "octopi", Source: "DHCP lease"}
+	}
+
+	w.check()
+
+	select {
+	case e := <-received:
+		want := "Vendor: Raspberry Pi Foundation | Hostname: octopi (DHCP lease)"
+		if e.Details != want {
+			t.Errorf("Details = %q, want %q", e.Details, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for new device event")
+	}
+}
+
+func TestNetworkScanWatcher_NewDevice_IdentifyOffPollPath(t *testing.T) {
+	w, received := newTestNetworkWatcher(false, nil, func() ([]byte, error) {
+		return []byte(`192.168.1.50 dev eth0 lladdr b8:27:eb:00:00:01 REACHABLE`), nil
+	})
+	release := make(chan struct{})
+	w.identify = func(mac, ip string) netdevice.Identity {
+		<-release // reverse DNS and mDNS timing out
+		return netdevice.Identity{Hostname: "octopi", Source: "mDNS"}
+	}
+
+	done := make(chan struct{})
+	go func() { w.check(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("check() blocked on the device lookup")
+	}
+
+	close(release)
+	select {
+	case e := <-received:
+		if !strings.Contains(e.Details, "octopi") {
+			t.Errorf("Details = %q, want the looked-up hostname", e.Details)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for new device event")
+	}
+}
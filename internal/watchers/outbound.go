@@ -0,0 +1,657 @@
+package watchers
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Fullex26/piguard/internal/config"
+	"github.com/Fullex26/piguard/internal/dockerapi"
+	"github.com/Fullex26/piguard/internal/eventbus"
+	"github.com/Fullex26/piguard/internal/store"
+	"github.com/Fullex26/piguard/pkg/models"
+)
+
+// OutboundWatcher tracks established outbound TCP and UDP flows and learns,
+// per process or container, which destinations are normal. Host sockets come
+// from the same sock_diag dump as NetlinkWatcher (falling back to `ss -tunap`),
+// and sockets inside container network namespaces are read from
+// /proc/<pid>/net. After the learning period it alerts on new destinations, on
+// owners that had never connected out before, and at any time on connections
+// to outbound.blocked_ports. Destinations are keyed by remote port (and
+// optionally network) rather than address, expire when unused, and are kept
+// in the store so the baseline survives restarts.
+type OutboundWatcher struct {
+	Base
+	store           *store.Store // nil = the baseline is relearned after a restart
+	interval        time.Duration
+	learnUntil      time.Time
+	baseline        map[string]map[string]time.Time // owner -> destination key -> last seen
+	size            int                             // destinations in baseline
+	ttl             time.Duration
+	maxDests        int // 0 = no cap
+	lastExpire      time.Time
+	selfPID         int
+	blocked         map[uint16]bool
+	procRoot        string
+	runSS           func() ([]byte, error)
+	sockDiag        func(family, protocol uint8, states uint32) ([]sockDiagEntry, error) // nil = use ss
+	inodes          *inodePIDResolver
+	readProcessName func(pid int) string
+	inspectName     func(containerID string) string
+	containerNames  map[string]string // container ID -> name
+}
+
+// outboundSock is a socket from any source before its owner is resolved.
+// ss reports the owner itself; netlink and /proc only give an inode.
+type outboundSock struct {
+	proto string
+	sockDiagEntry
+	pid  int
+	comm string
+}
+
+// outboundFlow is an attributed outbound connection.
+type outboundFlow struct {
+	models.ConnectionInfo
+	remoteIP   net.IP
+	remotePort uint16
+}
+
+func NewOutboundWatcher(cfg *config.Config, bus *eventbus.Bus, db *store.Store) *OutboundWatcher {
+	interval, err := time.ParseDuration(cfg.Outbound.PollInterval)
+	if err != nil || interval <= 0 {
+		interval = 10 * time.Second
+	}
+	ttl, err := time.ParseDuration(cfg.Outbound.BaselineTTL)
+	if err != nil || ttl <= 0 {
+		ttl = 30 * 24 * time.Hour
+	}
+	w := &OutboundWatcher{
+		Base:           Base{Cfg: cfg, Bus: bus},
+		store:          db,
+		interval:       interval,
+		baseline:       make(map[string]map[string]time.Time),
+		ttl:            ttl,
+		maxDests:       cfg.Outbound.BaselineMax,
+		selfPID:        os.Getpid(),
+		blocked:        make(map[uint16]bool),
+		procRoot:       "/proc",
+		sockDiag:       sockDiagDump,
+		inodes:         newInodePIDResolver("/proc"),
+		containerNames: make(map[string]string),
+	}
+	for _, p := range cfg.Outbound.BlockedPorts {
+		w.blocked[uint16(p)] = true
+	}
+	w.runSS = func() ([]byte, error) { return exec.Command("ss", "-tunap").Output() }
+	w.readProcessName = w.procComm
+	api := dockerapi.New(cfg.Docker.Socket)
+	w.inspectName = func(id string) string {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if info, err := api.InspectContainer(ctx, id); err == nil {
+			return strings.TrimPrefix(info.Name, "/")
+		}
+		out, err := exec.Command(containerCLI(cfg), "inspect", "--format", "{{.Name}}", id).Output()
+		if err != nil {
+			return ""
+		}
+		return strings.TrimPrefix(strings.TrimSpace(string(out)), "/")
+	}
+	return w
+}
+
+func (w *OutboundWatcher) Name() string { return "outbound" }
+func (w *OutboundWatcher) Stop() error  { return nil }
+
+func (w *OutboundWatcher) Start(ctx context.Context) error {
+	learning, err := time.ParseDuration(w.Cfg.Outbound.LearningPeriod)
+	if err != nil || learning < 0 {
+		learning = time.Hour
+	}
+	w.learnUntil = time.Now().Add(learning)
+	if n := w.loadBaseline(time.Now()); n > 0 {
+		// Learning only fills an empty baseline.
+		w.learnUntil = time.Now()
+		learning = 0
+		slog.Info("outbound baseline restored", "destinations", n)
+	}
+	slog.Info("starting outbound watcher", "interval", w.interval, "learning", learning, "netlink", w.sockDiag != nil)
+
+	w.check(time.Now())
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.expire(time.Now()) // save last-seen times
+			return nil
+		case now := <-ticker.C:
+			w.check(now)
+		}
+	}
+}
+
+func (w *OutboundWatcher) check(now time.Time) {
+	flows, err := w.scanFlows()
+	if err != nil {
+		slog.Error("outbound scan failed", "error", err)
+		return
+	}
+
+	learning := now.Before(w.learnUntil)
+	fresh := make(map[string]bool) // owners first seen in this scan
+	var added []store.OutboundDest
+	for _, f := range flows {
+		if w.isIgnored(f) {
+			continue
+		}
+		owner, dest := f.Owner(), w.destKey(f)
+		seen, known := w.baseline[owner]
+		if _, ok := seen[dest]; ok {
+			seen[dest] = now
+			continue
+		}
+		if !known {
+			seen = make(map[string]time.Time)
+			w.baseline[owner] = seen
+		}
+		seen[dest] = now
+		w.size++
+		added = append(added, store.OutboundDest{Owner: owner, Destination: dest, LastSeen: now})
+
+		switch {
+		case w.blocked[f.remotePort]:
+			w.emitBlocked(f)
+		case learning:
+			// Record silently until the learning period ends.
+		case !known:
+			fresh[owner] = true
+			w.emitFirstConnection(f)
+		case fresh[owner]:
+			// One alert per newly active owner, not one per destination.
+		default:
+			w.emitNewDestination(f, dest)
+		}
+	}
+
+	if w.maxDests > 0 && w.size > w.maxDests {
+		w.evict(w.size - w.maxDests)
+	}
+	if w.store != nil && len(added) > 0 {
+		if err := w.store.SaveOutboundDests(added); err != nil {
+			slog.Warn("saving outbound baseline failed", "error", err)
+		}
+	}
+	if now.Sub(w.lastExpire) >= time.Hour {
+		w.expire(now)
+	}
+}
+
+// destKey is the baseline key for a flow's destination: the protocol, the
+// remote /24 (/48 for IPv6) and port, e.g. "tcp:198.51.100.0/24:443", so a
+// new host on a port the owner already uses still alerts. Exact addresses
+// would make every rotation within a CDN's block look new. destination_key
+// "port" opts out to the port alone, e.g. "tcp:*:443".
+func (w *OutboundWatcher) destKey(f outboundFlow) string {
+	port := strconv.Itoa(int(f.remotePort))
+	if w.Cfg.Outbound.DestinationKey == "port" {
+		return f.Protocol + ":*:" + port
+	}
+	mask := net.CIDRMask(24, 32)
+	if f.remoteIP.To4() == nil {
+		mask = net.CIDRMask(48, 128)
+	}
+	network := net.IPNet{IP: f.remoteIP.Mask(mask), Mask: mask}
+	return f.Protocol + ":" + network.String() + ":" + port
+}
+
+// loadBaseline restores the destinations seen within the TTL from the store
+// and returns how many there are.
+func (w *OutboundWatcher) loadBaseline(now time.Time) int {
+	w.lastExpire = now
+	if w.store == nil {
+		return 0
+	}
+	dests, err := w.store.ListOutboundDests(now.Add(-w.ttl))
+	if err != nil {
+		slog.Warn("loading outbound baseline failed", "error", err)
+		return 0
+	}
+	for _, d := range dests {
+		seen, ok := w.baseline[d.Owner]
+		if !ok {
+			seen = make(map[string]time.Time)
+			w.baseline[d.Owner] = seen
+		}
+		if _, ok := seen[d.Destination]; !ok {
+			w.size++
+		}
+		seen[d.Destination] = d.LastSeen
+	}
+	return len(dests)
+}
+
+// expire forgets destinations not seen within the TTL and saves the
+// last-seen times of the rest.
+func (w *OutboundWatcher) expire(now time.Time) {
+	w.lastExpire = now
+	cutoff := now.Add(-w.ttl)
+	var keep []store.OutboundDest
+	for owner, seen := range w.baseline {
+		for dest, last := range seen {
+			if last.Before(cutoff) {
+				delete(seen, dest)
+				w.size--
+				continue
+			}
+			keep = append(keep, store.OutboundDest{Owner: owner, Destination: dest, LastSeen: last})
+		}
+		if len(seen) == 0 {
+			delete(w.baseline, owner)
+		}
+	}
+	if w.store == nil {
+		return
+	}
+	if err := w.store.SaveOutboundDests(keep); err != nil {
+		slog.Warn("saving outbound baseline failed", "error", err)
+		return
+	}
+	if _, err := w.store.PruneOutboundDests(cutoff, w.maxDests); err != nil {
+		slog.Warn("pruning outbound baseline failed", "error", err)
+	}
+}
+
+// evict drops the n least recently seen destinations.
+func (w *OutboundWatcher) evict(n int) {
+	var all []store.OutboundDest
+	for owner, seen := range w.baseline {
+		for dest, last := range seen {
+			all = append(all, store.OutboundDest{Owner: owner, Destination: dest, LastSeen: last})
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].LastSeen.Before(all[j].LastSeen) })
+	for _, d := range all[:min(n, len(all))] {
+		delete(w.baseline[d.Owner], d.Destination)
+		if len(w.baseline[d.Owner]) == 0 {
+			delete(w.baseline, d.Owner)
+		}
+		w.size--
+	}
+}
+
+// scanFlows lists outbound flows in the host namespace and in every
+// container namespace, with owners resolved.
+func (w *OutboundWatcher) scanFlows() ([]outboundFlow, error) {
+	host, err := w.hostSockets()
+	if err != nil {
+		return nil, err
+	}
+	socks := outboundOnly(host)
+	for _, pid := range foreignNetNamespaces(w.procRoot) {
+		socks = append(socks, outboundOnly(w.namespaceSockets(pid))...)
+	}
+
+	wanted := make(map[uint64]bool, len(socks))
+	for _, s := range socks {
+		if s.pid == 0 && s.Inode != 0 {
+			wanted[s.Inode] = true
+		}
+	}
+	var pids map[uint64]int
+	if w.inodes != nil && len(wanted) > 0 {
+		pids = w.inodes.resolve(wanted)
+	}
+
+	flows := make([]outboundFlow, 0, len(socks))
+	for _, s := range socks {
+		if s.pid == 0 {
+			s.pid = pids[s.Inode]
+		}
+		if s.pid == 0 {
+			continue // owner already gone, or a kernel socket
+		}
+		flows = append(flows, w.attribute(s))
+	}
+	return flows, nil
+}
+
+// hostSockets lists TCP and UDP sockets in the host namespace: established
+// and connecting flows plus TCP listeners, which outboundOnly needs to tell
+// accepted inbound connections apart.
+func (w *OutboundWatcher) hostSockets() ([]outboundSock, error) {
+	if w.sockDiag != nil {
+		socks, err := w.scanSockDiag()
+		if err == nil {
+			return socks, nil
+		}
+		slog.Warn("netlink sock_diag unavailable, falling back to ss", "error", err)
+		w.sockDiag = nil
+	}
+	return w.scanSS()
+}
+
+func (w *OutboundWatcher) scanSockDiag() ([]outboundSock, error) {
+	queries := []struct {
+		name   string
+		proto  uint8
+		states uint32
+	}{
+		{"tcp", ipprotoTCP, 1<<tcpEstablished | 1<<tcpSynSent | 1<<tcpListen},
+		{"udp", ipprotoUDP, 1 << tcpEstablished},
+	}
+	var socks []outboundSock
+	for _, q := range queries {
+		for _, family := range []uint8{afInet, afInet6} {
+			batch, err := w.sockDiag(family, q.proto, q.states)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", q.name, err)
+			}
+			for _, e := range batch {
+				socks = append(socks, outboundSock{proto: q.name, sockDiagEntry: e})
+			}
+		}
+	}
+	return socks, nil
+}
+
+// scanSS parses `ss -tunap`:
+// Netid  State  Recv-Q  Send-Q  Local Address:Port  Peer Address:Port  Process
+func (w *OutboundWatcher) scanSS() ([]outboundSock, error) {
+	out, err := w.runSS()
+	if err != nil {
+		return nil, fmt.Errorf("running ss: %w", err)
+	}
+
+	var socks []outboundSock
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	scanner.Scan() // skip header line
+	for scanner.Scan() {
+		if s, ok := parseSsFlowLine(scanner.Text()); ok {
+			socks = append(socks, s)
+		}
+	}
+	return socks, nil
+}
+
+func parseSsFlowLine(line string) (outboundSock, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 6 || !isSsNetid(fields[0]) {
+		return outboundSock{}, false
+	}
+	var state uint8
+	switch fields[1] {
+	case "ESTAB":
+		state = tcpEstablished
+	case "SYN-SENT":
+		state = tcpSynSent
+	case "LISTEN":
+		state = tcpListen
+	default:
+		return outboundSock{}, false
+	}
+	srcIP, srcPort, ok := parseSsAddr(fields[4])
+	if !ok {
+		return outboundSock{}, false
+	}
+	dstIP, dstPort, ok := parseSsAddr(fields[5])
+	if !ok {
+		return outboundSock{}, false
+	}
+	s := outboundSock{
+		proto: fields[0],
+		sockDiagEntry: sockDiagEntry{
+			State:   state,
+			SrcIP:   srcIP,
+			SrcPort: srcPort,
+			DstIP:   dstIP,
+			DstPort: dstPort,
+		},
+	}
+	if len(fields) >= 7 {
+		s.pid, s.comm = parseSsProcess(fields[6])
+	}
+	return s, true
+}
+
+// parseSsAddr parses ss addresses: "10.0.0.2:22", "[::1]:22", ":::22",
+// "fe80::1%eth0:546" and "*:*".
+func parseSsAddr(addr string) (net.IP, uint16, bool) {
+	idx := strings.LastIndex(addr, ":")
+	if idx < 0 {
+		return nil, 0, false
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(addr[:idx], "["), "]")
+	host, _, _ = strings.Cut(host, "%")
+	var port uint64
+	if p := addr[idx+1:]; p != "*" {
+		var err error
+		if port, err = strconv.ParseUint(p, 10, 16); err != nil {
+			return nil, 0, false
+		}
+	}
+	if host == "*" {
+		return net.IPv4zero, uint16(port), true
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, 0, false
+	}
+	return ip, uint16(port), true
+}
+
+// namespaceSockets reads the socket tables of the namespace pid lives in.
+func (w *OutboundWatcher) namespaceSockets(pid int) []outboundSock {
+	tables := []struct {
+		file   string
+		proto  string
+		family uint8
+	}{
+		{"tcp", "tcp", afInet},
+		{"tcp6", "tcp", afInet6},
+		{"udp", "udp", afInet},
+		{"udp6", "udp", afInet6},
+	}
+	var socks []outboundSock
+	for _, t := range tables {
+		data, err := os.ReadFile(filepath.Join(w.procRoot, strconv.Itoa(pid), "net", t.file))
+		if err != nil {
+			continue // process exited, or IPv6 disabled
+		}
+		for _, e := range parseProcNetTable(data, t.family) {
+			socks = append(socks, outboundSock{proto: t.proto, sockDiagEntry: e})
+		}
+	}
+	return socks
+}
+
+// outboundOnly keeps the flows this host initiated. Sockets from a single
+// namespace must be passed together: a TCP connection whose local port has a
+// listener was accepted, not dialled. Loopback traffic is dropped.
+func outboundOnly(socks []outboundSock) []outboundSock {
+	listening := make(map[uint16]bool)
+	for _, s := range socks {
+		if s.proto == "tcp" && s.State == tcpListen {
+			listening[s.SrcPort] = true
+		}
+	}
+	var out []outboundSock
+	for _, s := range socks {
+		if s.State != tcpEstablished && s.State != tcpSynSent {
+			continue
+		}
+		if s.proto == "tcp" && listening[s.SrcPort] {
+			continue
+		}
+		if s.DstIP == nil || s.DstIP.IsLoopback() || s.DstIP.IsUnspecified() {
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// attribute resolves the process and container behind a socket.
+func (w *OutboundWatcher) attribute(s outboundSock) outboundFlow {
+	c := models.ConnectionInfo{
+		Protocol:    s.proto,
+		LocalAddr:   formatSockAddr(s.SrcIP, s.SrcPort),
+		RemoteAddr:  formatSockAddr(s.DstIP, s.DstPort),
+		PID:         s.pid,
+		ProcessName: s.comm,
+	}
+	if c.ProcessName == "" {
+		c.ProcessName = w.readProcessName(s.pid)
+	}
+	if id := containerIDFromCgroup(w.procRoot, s.pid); id != "" {
+		c.ContainerID = id
+		c.ContainerName = w.containerName(id)
+	}
+	return outboundFlow{ConnectionInfo: c, remoteIP: s.DstIP, remotePort: s.DstPort}
+}
+
+// containerName returns a container's name, falling back to its short ID.
+func (w *OutboundWatcher) containerName(id string) string {
+	if name, ok := w.containerNames[id]; ok {
+		return name
+	}
+	name := w.inspectName(id)
+	if name == "" {
+		name = id[:12]
+	}
+	w.containerNames[id] = name
+	return name
+}
+
+// isSelf reports whether pid is this daemon, which talks to the Telegram API
+// and notifiers, or a command it started.
+func (w *OutboundWatcher) isSelf(pid int) bool {
+	if pid == w.selfPID {
+		return true
+	}
+	data, err := os.ReadFile(filepath.Join(w.procRoot, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return false
+	}
+	// "pid (comm) state ppid ...": comm may contain spaces and parentheses.
+	stat := string(data)
+	i := strings.LastIndex(stat, ") ")
+	if i < 0 {
+		return false
+	}
+	fields := strings.Fields(stat[i+2:])
+	return len(fields) >= 2 && fields[1] == strconv.Itoa(w.selfPID)
+}
+
+func (w *OutboundWatcher) procComm(pid int) string {
+	data, err := os.ReadFile(filepath.Join(w.procRoot, strconv.Itoa(pid), "comm"))
+	if err != nil {
+		return "unknown"
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// isIgnored checks outbound.ignore_self, outbound.ignore_processes (process
+// name or "container:<name>") and outbound.ignore_destinations.
+func (w *OutboundWatcher) isIgnored(f outboundFlow) bool {
+	if w.Cfg.Outbound.IgnoreSelf && w.isSelf(f.PID) {
+		return true
+	}
+	for _, p := range w.Cfg.Outbound.IgnoreProcesses {
+		if p == f.ProcessName || p == f.Owner() {
+			return true
+		}
+	}
+	for _, pattern := range w.Cfg.Outbound.IgnoreDestinations {
+		if matchDestPattern(f, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchDestPattern matches "[proto:]host:port" where host is an IP, a CIDR
+// or "*" and port is a number or "*", e.g. "udp:*:123" or "10.0.0.0/8:*".
+func matchDestPattern(f outboundFlow, pattern string) bool {
+	proto, addr := splitProtoPattern(pattern)
+	if proto != "" && proto != f.Protocol {
+		return false
+	}
+	idx := strings.LastIndex(addr, ":")
+	if idx < 0 {
+		return false
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(addr[:idx], "["), "]")
+	if port := addr[idx+1:]; port != "*" && port != strconv.Itoa(int(f.remotePort)) {
+		return false
+	}
+	switch {
+	case host == "*":
+		return true
+	case strings.Contains(host, "/"):
+		_, cidr, err := net.ParseCIDR(host)
+		return err == nil && cidr.Contains(f.remoteIP)
+	default:
+		ip := net.ParseIP(host)
+		return ip != nil && ip.Equal(f.remoteIP)
+	}
+}
+
+// describeOwner renders the owner for alert text.
+func describeOwner(c models.ConnectionInfo) string {
+	if c.ContainerName != "" {
+		return fmt.Sprintf("%s (container: %s)", c.ProcessName, c.ContainerName)
+	}
+	return c.ProcessName
+}
+
+func (w *OutboundWatcher) emitBlocked(f outboundFlow) {
+	w.publish(f, models.EventOutboundBlocked, models.SeverityCritical,
+		fmt.Sprintf("Outbound connection to blocked port: %s → %s", describeOwner(f.ConnectionInfo), f.Destination()),
+		fmt.Sprintf("Remote port %d is listed in outbound.blocked_ports (mining pools, IRC, Tor and similar)", f.remotePort),
+		fmt.Sprintf("Check what PID %d is doing; stop it or block %s in the firewall", f.PID, f.RemoteAddr))
+}
+
+func (w *OutboundWatcher) emitFirstConnection(f outboundFlow) {
+	w.publish(f, models.EventOutboundFirst, models.SeverityWarning,
+		fmt.Sprintf("First outbound connection from %s → %s", describeOwner(f.ConnectionInfo), f.Destination()),
+		"This owner made no outbound connections while the baseline was learned",
+		"If expected, add it to outbound.ignore_processes")
+}
+
+func (w *OutboundWatcher) emitNewDestination(f outboundFlow, dest string) {
+	w.publish(f, models.EventOutboundNewDest, models.SeverityWarning,
+		fmt.Sprintf("New outbound destination: %s → %s", describeOwner(f.ConnectionInfo), f.Destination()),
+		fmt.Sprintf("%s has not connected to %s before", f.Owner(), dest),
+		"If expected, add the destination to outbound.ignore_destinations")
+}
+
+func (w *OutboundWatcher) publish(f outboundFlow, typ models.EventType, sev models.Severity, msg, details, suggested string) {
+	conn := f.ConnectionInfo
+	hostname, _ := os.Hostname()
+	w.Bus.Publish(models.Event{
+		ID:         fmt.Sprintf("%s-%s-%s-%d", typ, conn.Owner(), conn.Destination(), time.Now().Unix()),
+		Type:       typ,
+		Severity:   sev,
+		Hostname:   hostname,
+		Timestamp:  time.Now(),
+		Message:    msg,
+		Details:    details,
+		Suggested:  suggested,
+		Source:     "outbound",
+		Connection: &conn,
+	})
+}
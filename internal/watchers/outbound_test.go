@@ -0,0 +1,422 @@
+package watchers
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Fullex26/piguard/internal/config"
+	"github.com/Fullex26/piguard/internal/eventbus"
+	"github.com/Fullex26/piguard/pkg/models"
+)
+
+const ssFlowHeader = "Netid State Recv-Q Send-Q Local Address:Port Peer Address:Port Process\n"
+
+// newTestOutboundWatcher returns a watcher reading ss output from *ssOut, with
+// an empty /proc (no container namespaces) and events collected into the
+// returned function.
+func newTestOutboundWatcher(t *testing.T, cfg *config.Config, ssOut *string) (*OutboundWatcher, func() []models.Event) {
+	t.Helper()
+	bus := eventbus.New()
+	var captured []models.Event
+	var mu sync.Mutex
+	bus.Subscribe(func(e models.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		captured = append(captured, e)
+	})
+
+	w := NewOutboundWatcher(cfg, bus, nil)
+	w.procRoot = t.TempDir()
+	w.inodes = nil
+	w.sockDiag = nil
+	w.runSS = func() ([]byte, error) { return []byte(*ssOut), nil }
+	w.inspectName = func(string) string { return "" }
+
+	events := func() []models.Event {
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		out := captured
+		captured = nil
+		return out
+	}
+	return w, events
+}
+
+func TestOutboundWatcher_Name(t *testing.T) {
+	w := &OutboundWatcher{}
+	if got := w.Name(); got != "outbound" {
+		t.Errorf("Name() = %q, want %q", got, "outbound")
+	}
+}
+
+func TestParseSsFlowLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		ok       bool
+		state    uint8
+		dst      string
+		pid      int
+		procName string
+	}{
+		{
+			name:     "tcp established",
+			line:     `tcp ESTAB 0 0 192.168.1.10:51234 203.0.113.7:443 users:(("curl",pid=42,fd=5))`,
+			ok:       true,
+			state:    tcpEstablished,
+			dst:      "203.0.113.7:443",
+			pid:      42,
+			procName: "curl",
+		},
+		{
+			name:  "ipv6 bracketed",
+			line:  `tcp SYN-SENT 0 1 [2001:db8::2]:40000 [2001:db8::1]:3333`,
+			ok:    true,
+			state: tcpSynSent,
+			dst:   "2001:db8::1:3333",
+		},
+		{
+			name:  "listener",
+			line:  `tcp LISTEN 0 128 0.0.0.0:22 0.0.0.0:*`,
+			ok:    true,
+			state: tcpListen,
+			dst:   "0.0.0.0:0",
+		},
+		{name: "unconnected udp", line: `udp UNCONN 0 0 0.0.0.0:5353 0.0.0.0:*`},
+		{name: "no netid", line: `ESTAB 0 0 10.0.0.2:22 10.0.0.3:5000`},
+		{name: "too short", line: `tcp ESTAB`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, ok := parseSsFlowLine(tt.line)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if s.State != tt.state {
+				t.Errorf("state = %d, want %d", s.State, tt.state)
+			}
+			if got := formatSockAddr(s.DstIP, s.DstPort); got != tt.dst {
+				t.Errorf("dst = %q, want %q", got, tt.dst)
+			}
+			if s.pid != tt.pid || s.comm != tt.procName {
+				t.Errorf("owner = %d/%q, want %d/%q", s.pid, s.comm, tt.pid, tt.procName)
+			}
+		})
+	}
+}
+
+func TestOutboundOnly(t *testing.T) {
+	sock := func(proto string, state uint8, sport uint16, dst string) outboundSock {
+		return outboundSock{proto: proto, sockDiagEntry: sockDiagEntry{
+			State: state, SrcIP: net.ParseIP("192.168.1.10"), SrcPort: sport, DstIP: net.ParseIP(dst), DstPort: 443,
+		}}
+	}
+	socks := []outboundSock{
+		sock("tcp", tcpListen, 22, "0.0.0.0"),
+		sock("tcp", tcpEstablished, 22, "192.168.1.50"),   // accepted SSH session
+		sock("tcp", tcpEstablished, 51000, "203.0.113.7"), // dialled
+		sock("tcp", tcpSynSent, 51001, "203.0.113.8"),     // still connecting
+		sock("tcp", tcpEstablished, 51002, "127.0.0.1"),   // loopback
+		sock("udp", tcpEstablished, 22, "203.0.113.9"),    // UDP ignores TCP listeners
+	}
+
+	got := outboundOnly(socks)
+	var dsts []string
+	for _, s := range got {
+		dsts = append(dsts, s.DstIP.String())
+	}
+	want := []string{"203.0.113.7", "203.0.113.8", "203.0.113.9"}
+	if len(dsts) != len(want) {
+		t.Fatalf("outbound = %v, want %v", dsts, want)
+	}
+	for i := range want {
+		if dsts[i] != want[i] {
+			t.Errorf("outbound[%d] = %s, want %s", i, dsts[i], want[i])
+		}
+	}
+}
+
+func TestOutboundWatcher_Check_LearnsThenAlerts(t *testing.T) {
+	ss := ssFlowHeader +
+		`tcp ESTAB 0 0 192.168.1.10:51000 203.0.113.7:443 users:(("node",pid=10,fd=5))` + "\n"
+	w, events := newTestOutboundWatcher(t, &config.Config{}, &ss)
+
+	start := time.Now()
+	w.learnUntil = start.Add(time.Hour)
+	w.check(start)
+	if got := events(); len(got) != 0 {
+		t.Fatalf("learning period should be silent, got %d events", len(got))
+	}
+
+	ss = ssFlowHeader +
+		`tcp ESTAB 0 0 192.168.1.10:51000 203.0.113.7:443 users:(("node",pid=10,fd=5))` + "\n" +
+		`tcp ESTAB 0 0 192.168.1.10:51001 198.51.100.9:8443 users:(("node",pid=10,fd=6))` + "\n" +
+		`tcp ESTAB 0 0 192.168.1.10:51002 198.51.100.1:443 users:(("backdoor",pid=66,fd=3))` + "\n" +
+		`tcp ESTAB 0 0 192.168.1.10:51003 198.51.100.2:443 users:(("backdoor",pid=66,fd=4))` + "\n"
+	w.check(start.Add(2 * time.Hour))
+
+	got := events()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 events, got %d: %+v", len(got), got)
+	}
+	byType := make(map[models.EventType]models.Event)
+	for _, e := range got {
+		byType[e.Type] = e
+	}
+	nd, ok := byType[models.EventOutboundNewDest]
+	if !ok || nd.Connection.Destination() != "tcp:198.51.100.9:8443" || nd.Connection.ProcessName != "node" {
+		t.Errorf("new destination event = %+v", nd)
+	}
+	first, ok := byType[models.EventOutboundFirst]
+	if !ok || first.Connection.ProcessName != "backdoor" || first.Severity != models.SeverityWarning {
+		t.Errorf("first connection event = %+v", first)
+	}
+
+	// Everything is now in the baseline.
+	w.check(start.Add(3 * time.Hour))
+	if got := events(); len(got) != 0 {
+		t.Errorf("repeat scan produced %d events", len(got))
+	}
+}
+
+func TestOutboundWatcher_Check_BlockedPortAlertsWhileLearning(t *testing.T) {
+	cfg := config.DefaultConfig()
+	ss := ssFlowHeader +
+		`tcp ESTAB 0 0 172.17.0.2:40112 203.0.113.7:3333 users:(("xmrig",pid=77,fd=9))` + "\n"
+	w, events := newTestOutboundWatcher(t, cfg, &ss)
+
+	now := time.Now()
+	w.learnUntil = now.Add(time.Hour)
+	w.check(now)
+
+	got := events()
+	if len(got) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(got))
+	}
+	if got[0].Type != models.EventOutboundBlocked || got[0].Severity != models.SeverityCritical {
+		t.Errorf("got %s/%s, want critical outbound.blocked_port", got[0].Type, got[0].Severity)
+	}
+}
+
+func TestOutboundWatcher_Check_Ignores(t *testing.T) {
+	cfg := &config.Config{Outbound: config.OutboundConfig{
+		IgnoreProcesses:    []string{"apt-get"},
+		IgnoreDestinations: []string{"10.0.0.0/8:*", "udp:*:123"},
+	}}
+	ss := ssFlowHeader
+	w, events := newTestOutboundWatcher(t, cfg, &ss)
+	w.check(time.Now()) // learning ends immediately; nothing yet
+
+	ss = ssFlowHeader +
+		`tcp ESTAB 0 0 192.168.1.10:51000 151.101.2.132:80 users:(("apt-get",pid=5,fd=3))` + "\n" +
+		`tcp ESTAB 0 0 192.168.1.10:51001 10.1.2.3:9000 users:(("agent",pid=6,fd=3))` + "\n" +
+		`udp ESTAB 0 0 192.168.1.10:40000 162.159.200.1:123 users:(("ntpd",pid=7,fd=3))` + "\n" +
+		`tcp ESTAB 0 0 192.168.1.10:51002 162.159.200.1:123 users:(("ntpd",pid=7,fd=4))` + "\n"
+	w.check(time.Now())
+
+	got := events()
+	if len(got) != 1 {
+		t.Fatalf("expected 1 event, got %d: %+v", len(got), got)
+	}
+	if got[0].Connection.Destination() != "tcp:162.159.200.1:123" {
+		t.Errorf("alerted on %s, want only the unscoped TCP flow", got[0].Connection.Destination())
+	}
+}
+
+func TestOutboundWatcher_Check_DestinationKey(t *testing.T) {
+	for _, tt := range []struct {
+		key  string
+		want int
+	}{
+		{"", 1},        // default: network
+		{"network", 1}, // 198.51.100.0/24 is a new network
+		{"port", 0},    // opt-out: any address on a known port
+	} {
+		cfg := &config.Config{Outbound: config.OutboundConfig{DestinationKey: tt.key}}
+		ss := ssFlowHeader +
+			`tcp ESTAB 0 0 192.168.1.10:51000 203.0.113.7:443 users:(("node",pid=10,fd=5))` + "\n"
+		w, events := newTestOutboundWatcher(t, cfg, &ss)
+		w.learnUntil = time.Now().Add(time.Hour)
+		w.check(time.Now())
+
+		// The CDN rotates the address within its /24, then to another network.
+		ss = ssFlowHeader +
+			`tcp ESTAB 0 0 192.168.1.10:51001 203.0.113.99:443 users:(("node",pid=10,fd=6))` + "\n" +
+			`tcp ESTAB 0 0 192.168.1.10:51002 198.51.100.5:443 users:(("node",pid=10,fd=7))` + "\n"
+		w.check(time.Now().Add(2 * time.Hour))
+		if got := events(); len(got) != tt.want {
+			t.Errorf("%s: got %d events, want %d: %+v", tt.key, len(got), tt.want, got)
+		}
+	}
+}
+
+func TestOutboundWatcher_Check_IgnoresSelf(t *testing.T) {
+	cfg := &config.Config{Outbound: config.OutboundConfig{IgnoreSelf: true}}
+	ss := ssFlowHeader
+	w, events := newTestOutboundWatcher(t, cfg, &ss)
+	w.selfPID = 100
+	os.MkdirAll(filepath.Join(w.procRoot, "101"), 0o755)
+	os.WriteFile(filepath.Join(w.procRoot, "101", "stat"), []byte("101 (curl (x)) S 100 101 1 0"), 0o644)
+	w.check(time.Now())
+
+	ss = ssFlowHeader +
+		`tcp ESTAB 0 0 192.168.1.10:51000 149.154.167.220:443 users:(("piguard",pid=100,fd=5))` + "\n" +
+		`tcp ESTAB 0 0 192.168.1.10:51001 198.51.100.5:443 users:(("curl",pid=101,fd=3))` + "\n"
+	w.check(time.Now())
+	if got := events(); len(got) != 0 {
+		t.Errorf("own connections alerted: %+v", got)
+	}
+}
+
+func TestOutboundWatcher_BaselinePersistedCappedAndExpired(t *testing.T) {
+	db := openNetworkTestStore(t)
+	cfg := &config.Config{Outbound: config.OutboundConfig{BaselineMax: 2}}
+	ss := ssFlowHeader +
+		`tcp ESTAB 0 0 192.168.1.10:51000 203.0.113.7:443 users:(("node",pid=10,fd=5))` + "\n" +
+		`tcp ESTAB 0 0 192.168.1.10:51001 203.0.113.7:22 users:(("ssh",pid=11,fd=5))` + "\n"
+	w, _ := newTestOutboundWatcher(t, cfg, &ss)
+	w.store = db
+	w.maxDests = 2
+	start := time.Now()
+	w.check(start)
+
+	// A third destination evicts the least recently seen one.
+	ss = ssFlowHeader +
+		`tcp ESTAB 0 0 192.168.1.10:51000 203.0.113.7:443 users:(("node",pid=10,fd=5))` + "\n" +
+		`tcp ESTAB 0 0 192.168.1.10:51002 203.0.113.7:53 users:(("node",pid=10,fd=6))` + "\n"
+	w.check(start.Add(time.Minute))
+	if w.size != 2 || w.baseline["ssh"] != nil {
+		t.Errorf("baseline = %v (size %d), want ssh evicted", w.baseline, w.size)
+	}
+
+	// Restored after a restart, so learning is skipped and nothing alerts.
+	w.expire(start.Add(2 * time.Minute))
+	w2, events := newTestOutboundWatcher(t, cfg, &ss)
+	w2.store = db
+	if n := w2.loadBaseline(start.Add(3 * time.Minute)); n != 2 {
+		t.Fatalf("restored %d destinations, want 2", n)
+	}
+	w2.check(start.Add(3 * time.Minute))
+	if got := events(); len(got) != 0 {
+		t.Errorf("restored baseline alerted: %+v", got)
+	}
+
+	// Destinations unused for the TTL are forgotten.
+	w2.expire(start.Add(w2.ttl + time.Hour))
+	if w2.size != 0 || len(w2.baseline) != 0 {
+		t.Errorf("baseline after TTL = %v (size %d)", w2.baseline, w2.size)
+	}
+	if dests, _ := db.ListOutboundDests(time.Time{}); len(dests) != 0 {
+		t.Errorf("store still holds %+v", dests)
+	}
+}
+
+func TestOutboundWatcher_ScanFlows_SockDiag(t *testing.T) {
+	ss := ""
+	w, _ := newTestOutboundWatcher(t, &config.Config{}, &ss)
+	w.runSS = func() ([]byte, error) {
+		t.Fatal("ss should not be called when netlink works")
+		return nil, nil
+	}
+	w.readProcessName = func(pid int) string { return map[int]string{1234: "curl"}[pid] }
+	w.inodes = &inodePIDResolver{procRoot: w.procRoot, cache: map[uint64]int{1: 1234}}
+	w.sockDiag = func(family, protocol uint8, states uint32) ([]sockDiagEntry, error) {
+		if protocol != ipprotoTCP || family != afInet {
+			return nil, nil
+		}
+		if states&(1<<tcpEstablished) == 0 || states&(1<<tcpListen) == 0 {
+			t.Errorf("tcp states = %#x, want established and listen", states)
+		}
+		return []sockDiagEntry{
+			{Family: afInet, State: tcpEstablished, SrcIP: net.ParseIP("192.168.1.10").To4(), SrcPort: 50000,
+				DstIP: net.ParseIP("203.0.113.7").To4(), DstPort: 443, Inode: 1},
+			{Family: afInet, State: tcpEstablished, SrcIP: net.ParseIP("192.168.1.10").To4(), SrcPort: 50001,
+				DstIP: net.ParseIP("203.0.113.8").To4(), DstPort: 443, Inode: 2}, // owner unknown
+		}, nil
+	}
+
+	flows, err := w.scanFlows()
+	if err != nil {
+		t.Fatalf("scanFlows: %v", err)
+	}
+	if len(flows) != 1 {
+		t.Fatalf("expected 1 attributed flow, got %d", len(flows))
+	}
+	f := flows[0]
+	if f.ProcessName != "curl" || f.PID != 1234 || f.RemoteAddr != "203.0.113.7:443" || f.LocalAddr != "192.168.1.10:50000" {
+		t.Errorf("flow = %+v", f.ConnectionInfo)
+	}
+}
+
+func TestOutboundWatcher_ScanFlows_FallsBackToSS(t *testing.T) {
+	ss := ssFlowHeader +
+		`tcp ESTAB 0 0 192.168.1.10:51000 203.0.113.7:443 users:(("curl",pid=10,fd=5))` + "\n"
+	w, _ := newTestOutboundWatcher(t, &config.Config{}, &ss)
+	calls := 0
+	w.sockDiag = func(family, protocol uint8, states uint32) ([]sockDiagEntry, error) {
+		calls++
+		return nil, errors.New("permission denied")
+	}
+
+	for i := 0; i < 2; i++ {
+		flows, err := w.scanFlows()
+		if err != nil {
+			t.Fatalf("scanFlows: %v", err)
+		}
+		if len(flows) != 1 || flows[0].ProcessName != "curl" {
+			t.Errorf("flows = %+v, want ss result", flows)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("netlink tried %d times, want 1 (fallback should stick)", calls)
+	}
+}
+
+func TestOutboundWatcher_ContainerAttribution(t *testing.T) {
+	ss := ""
+	w, _ := newTestOutboundWatcher(t, &config.Config{}, &ss)
+	id := "0123456789ab" + "cdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	writeProcFile(t, w.procRoot, "300/cgroup", "0::/system.slice/docker-"+id+".scope\n")
+
+	lookups := 0
+	w.inspectName = func(got string) string {
+		lookups++
+		if got != id {
+			t.Errorf("inspect %q, want %q", got, id)
+		}
+		return "miner"
+	}
+
+	s := outboundSock{proto: "tcp", pid: 300, comm: "xmrig", sockDiagEntry: sockDiagEntry{
+		SrcIP: net.ParseIP("172.17.0.2"), SrcPort: 40000, DstIP: net.ParseIP("203.0.113.7"), DstPort: 3333,
+	}}
+	for i := 0; i < 2; i++ {
+		f := w.attribute(s)
+		if f.Owner() != "container:miner" || f.ContainerID != id {
+			t.Errorf("flow = %+v, want container miner", f.ConnectionInfo)
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("container name looked up %d times, want 1 (cached)", lookups)
+	}
+}
+
+func writeProcFile(t *testing.T, root, name, content string) {
+	t.Helper()
+	path := filepath.Join(root, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
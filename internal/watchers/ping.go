@@ -0,0 +1,49 @@
+package watchers
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// ICMP echo constants (RFC 792).
+const (
+	icmpEchoReply   = 0
+	icmpEchoRequest = 8
+	icmpHeaderLen   = 8
+)
+
+var errPingUnsupported = errors.New("ICMP probes not supported on this platform")
+
+// buildICMPEcho encodes an echo request. On a Linux ping socket the kernel
+// replaces id with the socket's own and fills in the checksum again.
+func buildICMPEcho(id, seq uint16, payload []byte) []byte {
+	msg := make([]byte, icmpHeaderLen, icmpHeaderLen+len(payload))
+	msg[0] = icmpEchoRequest
+	binary.BigEndian.PutUint16(msg[4:6], id)
+	binary.BigEndian.PutUint16(msg[6:8], seq)
+	msg = append(msg, payload...)
+	binary.BigEndian.PutUint16(msg[2:4], icmpChecksum(msg))
+	return msg
+}
+
+// parseICMPEchoReply reports whether msg, an ICMP message without its IP
+// header as ping sockets deliver it, is the echo reply to seq.
+func parseICMPEchoReply(msg []byte, seq uint16) bool {
+	return len(msg) >= icmpHeaderLen && msg[0] == icmpEchoReply && msg[1] == 0 &&
+		binary.BigEndian.Uint16(msg[6:8]) == seq
+}
+
+// icmpChecksum is the Internet checksum (RFC 1071).
+func icmpChecksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(b[i:]))
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return ^uint16(sum)
+}
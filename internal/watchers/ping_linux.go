@@ -0,0 +1,78 @@
+//go:build linux
+
+package watchers
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// icmpEcho sends one echo request to host over an unprivileged ping socket
+// and waits for the reply. Ping sockets need the daemon's group inside
+// net.ipv4.ping_group_range (systemd allows every group); otherwise a raw
+// socket is used, which needs root or CAP_NET_RAW.
+func icmpEcho(host string, timeout time.Duration) error {
+	addr, err := net.ResolveIPAddr("ip4", host)
+	if err != nil {
+		return err
+	}
+	raw := false
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, unix.IPPROTO_ICMP)
+	if err != nil {
+		// Fall back to a raw socket, which root has even when ping sockets
+		// are disabled.
+		var rawErr error
+		if fd, rawErr = unix.Socket(unix.AF_INET, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.IPPROTO_ICMP); rawErr != nil {
+			return fmt.Errorf("ping socket: %w (check sysctl net.ipv4.ping_group_range)", err)
+		}
+		raw = true
+	}
+	defer unix.Close(fd)
+	tv := unix.NsecToTimeval(timeout.Nanoseconds())
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		return fmt.Errorf("ping timeout: %w", err)
+	}
+
+	id, seq := uint16(rand.Uint32()), uint16(rand.Uint32())
+	dst := &unix.SockaddrInet4{}
+	copy(dst.Addr[:], addr.IP.To4())
+	if err := unix.Sendto(fd, buildICMPEcho(id, seq, []byte("piguard")), 0, dst); err != nil {
+		return fmt.Errorf("ping send: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	buf := make([]byte, 1500)
+	for time.Now().Before(deadline) {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		switch {
+		case errors.Is(err, unix.EINTR):
+			continue
+		case errors.Is(err, unix.EAGAIN):
+			return fmt.Errorf("no echo reply from %s within %s", host, timeout)
+		case err != nil:
+			return fmt.Errorf("ping receive: %w", err)
+		}
+		msg := buf[:n]
+		if raw {
+			// Raw sockets see every ICMP message with its IP header, so
+			// strip the header and match our id as well.
+			if n < 20 || n < int(msg[0]&0x0f)*4+icmpHeaderLen {
+				continue
+			}
+			msg = msg[int(msg[0]&0x0f)*4:]
+			if binary.BigEndian.Uint16(msg[4:6]) != id {
+				continue
+			}
+		}
+		if parseICMPEchoReply(msg, seq) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no echo reply from %s within %s", host, timeout)
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package watchers
+
+import "time"
+
+// icmpEcho is unavailable off Linux; use tcp, dns or http probes instead.
+func icmpEcho(host string, timeout time.Duration) error {
+	return errPingUnsupported
+}
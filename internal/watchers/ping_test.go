@@ -0,0 +1,40 @@
+package watchers
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestBuildICMPEcho(t *testing.T) {
+	msg := buildICMPEcho(0x1234, 7, []byte("piguard"))
+	if msg[0] != icmpEchoRequest || msg[1] != 0 {
+		t.Errorf("type/code = %d/%d, want 8/0", msg[0], msg[1])
+	}
+	if id, seq := binary.BigEndian.Uint16(msg[4:]), binary.BigEndian.Uint16(msg[6:]); id != 0x1234 || seq != 7 {
+		t.Errorf("id/seq = %#x/%d", id, seq)
+	}
+	if string(msg[icmpHeaderLen:]) != "piguard" {
+		t.Errorf("payload = %q", msg[icmpHeaderLen:])
+	}
+	// A message including its own checksum sums to zero.
+	if sum := icmpChecksum(msg); sum != 0 {
+		t.Errorf("checksum over the message = %#x, want 0", sum)
+	}
+}
+
+func TestParseICMPEchoReply(t *testing.T) {
+	reply := buildICMPEcho(0, 42, nil)
+	reply[0] = icmpEchoReply
+	if !parseICMPEchoReply(reply, 42) {
+		t.Error("echo reply for seq 42 rejected")
+	}
+	if parseICMPEchoReply(reply, 43) {
+		t.Error("reply for another sequence accepted")
+	}
+	if parseICMPEchoReply(buildICMPEcho(0, 42, nil), 42) {
+		t.Error("echo request accepted as a reply")
+	}
+	if parseICMPEchoReply(reply[:4], 42) {
+		t.Error("truncated message accepted")
+	}
+}
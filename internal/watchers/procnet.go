@@ -0,0 +1,130 @@
+package watchers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Sockets in another network namespace (a bridge-networked container) are
+// invisible to a sock_diag dump or `ss` run from the host namespace, but the
+// kernel exposes each namespace's tables through /proc/<pid>/net of any
+// process inside it. Socket inodes are global, so the usual /proc/<pid>/fd
+// walk still attributes them to a PID.
+
+// foreignNetNamespaces returns one PID per network namespace other than the
+// host's (the namespace of PID 1), keyed by the namespace link, e.g.
+// "net:[4026532301]".
+func foreignNetNamespaces(procRoot string) map[string]int {
+	host, err := os.Readlink(filepath.Join(procRoot, "1", "ns", "net"))
+	if err != nil {
+		return nil
+	}
+	procs, err := os.ReadDir(procRoot)
+	if err != nil {
+		return nil
+	}
+	namespaces := make(map[string]int)
+	for _, p := range procs {
+		pid, err := strconv.Atoi(p.Name())
+		if err != nil {
+			continue
+		}
+		ns, err := os.Readlink(filepath.Join(procRoot, p.Name(), "ns", "net"))
+		if err != nil || ns == host {
+			continue
+		}
+		if _, seen := namespaces[ns]; !seen {
+			namespaces[ns] = pid
+		}
+	}
+	return namespaces
+}
+
+// parseProcNetTable decodes /proc/<pid>/net/{tcp,tcp6,udp,udp6}:
+//
+//	sl  local_address rem_address   st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+//	0: 0100007F:0CEA 00000000:0000 0A 00000000:00000000 00:00000000 00000000  108 0 20513 ...
+//
+// Addresses are printed as native-endian 32-bit words, ports as plain hex.
+func parseProcNetTable(data []byte, family uint8) []sockDiagEntry {
+	var entries []sockDiagEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Scan() // skip header line
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		srcIP, srcPort, ok := parseProcNetAddr(fields[1])
+		if !ok {
+			continue
+		}
+		dstIP, dstPort, ok := parseProcNetAddr(fields[2])
+		if !ok {
+			continue
+		}
+		state, err := strconv.ParseUint(fields[3], 16, 8)
+		if err != nil {
+			continue
+		}
+		uid, _ := strconv.ParseUint(fields[7], 10, 32)
+		inode, _ := strconv.ParseUint(fields[9], 10, 64)
+		entries = append(entries, sockDiagEntry{
+			Family:  family,
+			State:   uint8(state),
+			SrcIP:   srcIP,
+			SrcPort: srcPort,
+			DstIP:   dstIP,
+			DstPort: dstPort,
+			UID:     uint32(uid),
+			Inode:   inode,
+		})
+	}
+	return entries
+}
+
+// parseProcNetAddr decodes "0100007F:0CEA" (IPv4) or a 32-digit IPv6 form.
+func parseProcNetAddr(s string) (net.IP, uint16, bool) {
+	hexIP, hexPort, ok := strings.Cut(s, ":")
+	if !ok {
+		return nil, 0, false
+	}
+	raw, err := hex.DecodeString(hexIP)
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return nil, 0, false
+	}
+	port, err := strconv.ParseUint(hexPort, 16, 16)
+	if err != nil {
+		return nil, 0, false
+	}
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		binary.NativeEndian.PutUint32(ip[i:i+4], binary.BigEndian.Uint32(raw[i:i+4]))
+	}
+	return ip, uint16(port), true
+}
+
+// cgroupContainerRe matches the container ID in cgroup paths written by
+// Docker ("/docker/<id>", "docker-<id>.scope") and Podman ("libpod-<id>.scope").
+var cgroupContainerRe = regexp.MustCompile(`(?:docker[-/]|libpod-)([0-9a-f]{64})`)
+
+// containerIDFromCgroup returns the container a process runs in, or "" for
+// host processes.
+func containerIDFromCgroup(procRoot string, pid int) string {
+	data, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return ""
+	}
+	if m := cgroupContainerRe.FindSubmatch(data); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
@@ -0,0 +1,126 @@
+package watchers
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// procNetAddr encodes an address the way /proc/net/tcp prints it.
+func procNetAddr(ip net.IP, port uint16) string {
+	raw := ip.To4()
+	if raw == nil {
+		raw = ip.To16()
+	}
+	var sb strings.Builder
+	for i := 0; i < len(raw); i += 4 {
+		fmt.Fprintf(&sb, "%08X", binary.NativeEndian.Uint32(raw[i:i+4]))
+	}
+	return fmt.Sprintf("%s:%04X", sb.String(), port)
+}
+
+func TestParseProcNetTable(t *testing.T) {
+	table := "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n" +
+		fmt.Sprintf("   0: %s %s 0A 00000000:00000000 00:00000000 00000000     0        0 1111 1 0 100 0 0 10 0\n",
+			procNetAddr(net.IPv4zero, 80), procNetAddr(net.IPv4zero, 0)) +
+		fmt.Sprintf("   1: %s %s 01 00000000:00000000 02:000A7F6B 00000000  1000        0 2222 1 0 20 4 30 10 -1\n",
+			procNetAddr(net.ParseIP("172.17.0.2"), 40112), procNetAddr(net.ParseIP("203.0.113.7"), 3333)) +
+		"   2: garbage\n"
+
+	entries := parseProcNetTable([]byte(table), afInet)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].State != tcpListen || entries[0].SrcPort != 80 || entries[0].Inode != 1111 {
+		t.Errorf("entry 0 = %+v, want listener on :80 inode 1111", entries[0])
+	}
+	e := entries[1]
+	if e.State != tcpEstablished || e.UID != 1000 || e.Inode != 2222 {
+		t.Errorf("entry 1 = %+v, want established uid 1000 inode 2222", e)
+	}
+	if got := formatSockAddr(e.SrcIP, e.SrcPort); got != "172.17.0.2:40112" {
+		t.Errorf("src = %s", got)
+	}
+	if got := formatSockAddr(e.DstIP, e.DstPort); got != "203.0.113.7:3333" {
+		t.Errorf("dst = %s", got)
+	}
+}
+
+func TestParseProcNetTable_IPv6(t *testing.T) {
+	table := "  sl  local_address remote_address st tx_queue rx_queue tr tm->when retrnsmt uid timeout inode\n" +
+		fmt.Sprintf("   0: %s %s 01 00000000:00000000 00:00000000 00000000 0 0 3333 1\n",
+			procNetAddr(net.ParseIP("2001:db8::2"), 51000), procNetAddr(net.ParseIP("2001:db8::443"), 443))
+
+	entries := parseProcNetTable([]byte(table), afInet6)
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	if got := formatSockAddr(entries[0].DstIP, entries[0].DstPort); got != "2001:db8::443:443" {
+		t.Errorf("dst = %s", got)
+	}
+}
+
+func TestForeignNetNamespaces(t *testing.T) {
+	root := t.TempDir()
+	mkns := func(pid, target string) {
+		dir := filepath.Join(root, pid, "ns")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(target, filepath.Join(dir, "net")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mkns("1", "net:[100]")
+	mkns("50", "net:[100]")
+	mkns("200", "net:[200]")
+	mkns("201", "net:[200]")
+	mkns("300", "net:[300]")
+
+	got := foreignNetNamespaces(root)
+	if len(got) != 2 {
+		t.Fatalf("namespaces = %v, want 2 foreign", got)
+	}
+	if _, ok := got["net:[100]"]; ok {
+		t.Error("host namespace should be excluded")
+	}
+	if pid := got["net:[200]"]; pid != 200 && pid != 201 {
+		t.Errorf("net:[200] pid = %d", pid)
+	}
+	if got["net:[300]"] != 300 {
+		t.Errorf("net:[300] pid = %d, want 300", got["net:[300]"])
+	}
+}
+
+func TestContainerIDFromCgroup(t *testing.T) {
+	id := strings.Repeat("ab12", 16)
+	tests := []struct {
+		name   string
+		cgroup string
+		want   string
+	}{
+		{"cgroup v2 systemd", "0::/system.slice/docker-" + id + ".scope\n", id},
+		{"cgroup v1", "12:pids:/docker/" + id + "\n1:name=systemd:/docker/" + id + "\n", id},
+		{"podman", "0::/machine.slice/libpod-" + id + ".scope/container\n", id},
+		{"host process", "0::/system.slice/ssh.service\n", ""},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			pid := fmt.Sprint(100 + i)
+			if err := os.MkdirAll(filepath.Join(root, pid), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(root, pid, "cgroup"), []byte(tt.cgroup), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if got := containerIDFromCgroup(root, 100+i); got != tt.want {
+				t.Errorf("containerIDFromCgroup = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package watchers
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// rtnetlink wire constants (linux/rtnetlink.h, linux/if_link.h,
+// linux/if_addr.h). Like the inet_diag ones in sockdiag.go they are declared
+// here so encoding and parsing build and test on every platform; only the
+// socket I/O in rtnetlink_linux.go is Linux-only.
+const (
+	rtmNewLink = 16
+	rtmDelLink = 17
+	rtmGetLink = 18
+	rtmNewAddr = 20
+	rtmDelAddr = 21
+	rtmGetAddr = 22
+
+	rtmgrpLink       = 0x1
+	rtmgrpIPv4IfAddr = 0x10
+	rtmgrpIPv6IfAddr = 0x100
+
+	ifInfoMsgLen = 16 // struct ifinfomsg
+	ifAddrMsgLen = 8  // struct ifaddrmsg
+	rtaHdrLen    = 4
+
+	iflaIfName = 3
+	ifaAddress = 1
+	ifaLocal   = 2
+	ifaFlags   = 8
+
+	iffUp      = 0x1
+	iffLowerUp = 0x10000 // carrier present
+
+	ifaFTemporary = 0x1 // IPv6 privacy address, rotated by design
+)
+
+var errRtnetlinkUnsupported = errors.New("rtnetlink not supported on this platform")
+
+// rtnlMsg is one decoded link or address notification.
+type rtnlMsg struct {
+	Type      uint16 // rtmNewLink, rtmDelLink, rtmNewAddr or rtmDelAddr
+	Index     int
+	Name      string // links only
+	Up        bool   // links only: administratively up with carrier
+	Addr      string // addresses only: "ip/prefix"
+	LinkLocal bool   // addresses only
+	Temporary bool   // addresses only
+}
+
+// buildRtnlDump encodes a dump request for every link (rtmGetLink) or
+// address (rtmGetAddr).
+func buildRtnlDump(msgType uint16, seq uint32) []byte {
+	bodyLen := ifInfoMsgLen
+	if msgType == rtmGetAddr {
+		bodyLen = ifAddrMsgLen
+	}
+	buf := make([]byte, nlmsgHdrLen+bodyLen)
+	ne := binary.NativeEndian
+	ne.PutUint32(buf[0:4], uint32(len(buf)))
+	ne.PutUint16(buf[4:6], msgType)
+	ne.PutUint16(buf[6:8], nlmFRequest|nlmFDump)
+	ne.PutUint32(buf[8:12], seq)
+	// The body is left zero: family AF_UNSPEC, every interface.
+	return buf
+}
+
+// parseRtnlMsgs decodes one recv() worth of rtnetlink messages. done is
+// true when an NLMSG_DONE ends a dump.
+func parseRtnlMsgs(buf []byte) (msgs []rtnlMsg, done bool, err error) {
+	ne := binary.NativeEndian
+	for len(buf) >= nlmsgHdrLen {
+		msgLen := int(ne.Uint32(buf[0:4]))
+		msgType := ne.Uint16(buf[4:6])
+		if msgLen < nlmsgHdrLen || msgLen > len(buf) {
+			return msgs, false, fmt.Errorf("malformed netlink message (len %d, have %d)", msgLen, len(buf))
+		}
+		payload := buf[nlmsgHdrLen:msgLen]
+
+		switch msgType {
+		case nlmsgDone:
+			done = true
+		case nlmsgError:
+			if len(payload) >= 4 {
+				if errno := int32(ne.Uint32(payload[0:4])); errno != 0 {
+					return msgs, true, fmt.Errorf("netlink error: errno %d", -errno)
+				}
+			}
+		case rtmNewLink, rtmDelLink:
+			if m, ok := parseIfInfoMsg(msgType, payload); ok {
+				msgs = append(msgs, m)
+			}
+		case rtmNewAddr, rtmDelAddr:
+			if m, ok := parseIfAddrMsg(msgType, payload); ok {
+				msgs = append(msgs, m)
+			}
+		}
+
+		aligned := (msgLen + 3) &^ 3
+		if aligned > len(buf) {
+			break
+		}
+		buf = buf[aligned:]
+	}
+	return msgs, done, nil
+}
+
+func parseIfInfoMsg(msgType uint16, b []byte) (rtnlMsg, bool) {
+	if len(b) < ifInfoMsgLen {
+		return rtnlMsg{}, false
+	}
+	ne := binary.NativeEndian
+	flags := ne.Uint32(b[8:12])
+	m := rtnlMsg{
+		Type:  msgType,
+		Index: int(int32(ne.Uint32(b[4:8]))),
+		Up:    flags&iffUp != 0 && flags&iffLowerUp != 0,
+	}
+	m.Name = strings.TrimRight(string(rtAttrs(b[ifInfoMsgLen:])[iflaIfName]), "\x00")
+	return m, m.Name != ""
+}
+
+func parseIfAddrMsg(msgType uint16, b []byte) (rtnlMsg, bool) {
+	if len(b) < ifAddrMsgLen {
+		return rtnlMsg{}, false
+	}
+	family, prefix := b[0], int(b[1])
+	flags := uint32(b[2])
+	m := rtnlMsg{Type: msgType, Index: int(binary.NativeEndian.Uint32(b[4:8]))}
+
+	attrs := rtAttrs(b[ifAddrMsgLen:])
+	if val := attrs[ifaFlags]; len(val) >= 4 {
+		flags = binary.NativeEndian.Uint32(val) // the full 32-bit flags
+	}
+	// For point-to-point links IFA_ADDRESS is the peer; IFA_LOCAL is ours.
+	raw, ok := attrs[ifaLocal]
+	if !ok {
+		raw = attrs[ifaAddress]
+	}
+	if (family != afInet || len(raw) != net.IPv4len) && (family != afInet6 || len(raw) != net.IPv6len) {
+		return rtnlMsg{}, false
+	}
+	ip := net.IP(append([]byte(nil), raw...))
+	m.Addr = fmt.Sprintf("%s/%d", ip, prefix)
+	m.LinkLocal = ip.IsLinkLocalUnicast()
+	m.Temporary = flags&ifaFTemporary != 0
+	return m, true
+}
+
+// rtAttrs returns the route attributes in b by type.
+func rtAttrs(b []byte) map[uint16][]byte {
+	attrs := make(map[uint16][]byte)
+	ne := binary.NativeEndian
+	for len(b) >= rtaHdrLen {
+		l := int(ne.Uint16(b[0:2]))
+		if l < rtaHdrLen || l > len(b) {
+			break
+		}
+		attrs[ne.Uint16(b[2:4])] = b[rtaHdrLen:l]
+		aligned := (l + 3) &^ 3
+		if aligned > len(b) {
+			break
+		}
+		b = b[aligned:]
+	}
+	return attrs
+}
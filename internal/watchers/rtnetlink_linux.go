@@ -0,0 +1,90 @@
+//go:build linux
+
+package watchers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// rtnlListen subscribes to link and IPv4/IPv6 address notifications and
+// calls handle with every batch until ctx is done. It starts with a dump of
+// every link and address, passed with full set because it replaces all
+// earlier state, and dumps again if the kernel drops notifications.
+func rtnlListen(ctx context.Context, handle func(msgs []rtnlMsg, full bool)) error {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_ROUTE)
+	if err != nil {
+		return fmt.Errorf("netlink socket: %w", err)
+	}
+	defer unix.Close(fd)
+
+	groups := uint32(rtmgrpLink | rtmgrpIPv4IfAddr | rtmgrpIPv6IfAddr)
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: groups}); err != nil {
+		return fmt.Errorf("netlink bind: %w", err)
+	}
+	// Wake the reader regularly so it notices ctx being cancelled.
+	tv := unix.NsecToTimeval(time.Second.Nanoseconds())
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		return fmt.Errorf("netlink timeout: %w", err)
+	}
+
+	buf := make([]byte, 64*1024)
+	var seq uint32
+	dump := func() error {
+		var all []rtnlMsg
+		for _, msgType := range []uint16{rtmGetLink, rtmGetAddr} {
+			seq++
+			if err := unix.Sendto(fd, buildRtnlDump(msgType, seq), 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+				return fmt.Errorf("netlink send: %w", err)
+			}
+			for done := false; !done; {
+				n, _, err := unix.Recvfrom(fd, buf, 0)
+				if err != nil {
+					if err == unix.EINTR || err == unix.EAGAIN {
+						continue
+					}
+					return fmt.Errorf("netlink recv: %w", err)
+				}
+				var msgs []rtnlMsg
+				msgs, done, err = parseRtnlMsgs(buf[:n])
+				if err != nil {
+					return err
+				}
+				all = append(all, msgs...)
+			}
+		}
+		handle(all, true)
+		return nil
+	}
+	if err := dump(); err != nil {
+		return err
+	}
+
+	for ctx.Err() == nil {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		switch {
+		case errors.Is(err, unix.EAGAIN), errors.Is(err, unix.EINTR):
+			continue
+		case errors.Is(err, unix.ENOBUFS):
+			// The receive queue overflowed and notifications were lost.
+			if err := dump(); err != nil {
+				return err
+			}
+			continue
+		case err != nil:
+			return fmt.Errorf("netlink recv: %w", err)
+		}
+		msgs, _, err := parseRtnlMsgs(buf[:n])
+		if err != nil {
+			return err
+		}
+		if len(msgs) > 0 {
+			handle(msgs, false)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package watchers
+
+import "context"
+
+// rtnlListen is unavailable off Linux; only the public IP is watched.
+func rtnlListen(ctx context.Context, handle func(msgs []rtnlMsg, full bool)) error {
+	return errRtnetlinkUnsupported
+}
@@ -0,0 +1,108 @@
+package watchers
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+)
+
+// rtAttr encodes one route attribute, padded to 4 bytes.
+func rtAttr(typ uint16, val []byte) []byte {
+	b := make([]byte, rtaHdrLen, rtaHdrLen+len(val)+3)
+	binary.NativeEndian.PutUint16(b[0:2], uint16(rtaHdrLen+len(val)))
+	binary.NativeEndian.PutUint16(b[2:4], typ)
+	b = append(b, val...)
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func nlMsg(msgType uint16, body []byte) []byte {
+	b := make([]byte, nlmsgHdrLen, nlmsgHdrLen+len(body))
+	binary.NativeEndian.PutUint32(b[0:4], uint32(nlmsgHdrLen+len(body)))
+	binary.NativeEndian.PutUint16(b[4:6], msgType)
+	return append(b, body...)
+}
+
+func testLinkMsg(msgType uint16, index int, name string, flags uint32) []byte {
+	body := make([]byte, ifInfoMsgLen)
+	binary.NativeEndian.PutUint32(body[4:8], uint32(index))
+	binary.NativeEndian.PutUint32(body[8:12], flags)
+	body = append(body, rtAttr(iflaIfName, append([]byte(name), 0))...)
+	return nlMsg(msgType, body)
+}
+
+func testAddrMsg(msgType uint16, index int, cidr string, flags uint32) []byte {
+	ip, ipnet, _ := net.ParseCIDR(cidr)
+	prefix, _ := ipnet.Mask.Size()
+	family, raw := byte(afInet6), []byte(ip.To16())
+	if v4 := ip.To4(); v4 != nil {
+		family, raw = afInet, v4
+	}
+	body := []byte{family, byte(prefix), 0, 0, 0, 0, 0, 0}
+	binary.NativeEndian.PutUint32(body[4:8], uint32(index))
+	body = append(body, rtAttr(ifaAddress, raw)...)
+	if family == afInet {
+		body = append(body, rtAttr(ifaLocal, raw)...)
+	}
+	body = append(body, rtAttr(ifaFlags, binary.NativeEndian.AppendUint32(nil, flags))...)
+	return nlMsg(msgType, body)
+}
+
+func TestBuildRtnlDump(t *testing.T) {
+	req := buildRtnlDump(rtmGetAddr, 7)
+	ne := binary.NativeEndian
+	if got := ne.Uint32(req[0:4]); int(got) != len(req) || len(req) != nlmsgHdrLen+ifAddrMsgLen {
+		t.Errorf("length = %d (buffer %d)", got, len(req))
+	}
+	if ne.Uint16(req[4:6]) != rtmGetAddr || ne.Uint16(req[6:8]) != nlmFRequest|nlmFDump || ne.Uint32(req[8:12]) != 7 {
+		t.Errorf("header = % x", req[:nlmsgHdrLen])
+	}
+	if got := len(buildRtnlDump(rtmGetLink, 1)); got != nlmsgHdrLen+ifInfoMsgLen {
+		t.Errorf("link dump length = %d", got)
+	}
+}
+
+func TestParseRtnlMsgs(t *testing.T) {
+	var buf []byte
+	buf = append(buf, testLinkMsg(rtmNewLink, 2, "eth0", iffUp|iffLowerUp)...)
+	buf = append(buf, testLinkMsg(rtmNewLink, 3, "wlan0", iffUp)...) // no carrier
+	buf = append(buf, testAddrMsg(rtmNewAddr, 2, "192.168.1.20/24", 0)...)
+	buf = append(buf, testAddrMsg(rtmNewAddr, 2, "2001:db8::1234/64", ifaFTemporary)...)
+	buf = append(buf, testAddrMsg(rtmDelAddr, 2, "fe80::1/64", 0)...)
+	buf = append(buf, nlMsg(nlmsgDone, make([]byte, 4))...)
+
+	msgs, done, err := parseRtnlMsgs(buf)
+	if err != nil || !done {
+		t.Fatalf("parseRtnlMsgs: done=%v err=%v", done, err)
+	}
+	if len(msgs) != 5 {
+		t.Fatalf("got %d messages, want 5: %+v", len(msgs), msgs)
+	}
+	if m := msgs[0]; m.Type != rtmNewLink || m.Index != 2 || m.Name != "eth0" || !m.Up {
+		t.Errorf("eth0 link = %+v", m)
+	}
+	if msgs[1].Up {
+		t.Error("link without carrier reported up")
+	}
+	if m := msgs[2]; m.Addr != "192.168.1.20/24" || m.Index != 2 || m.Temporary || m.LinkLocal {
+		t.Errorf("IPv4 address = %+v", m)
+	}
+	if m := msgs[3]; m.Addr != "2001:db8::1234/64" || !m.Temporary {
+		t.Errorf("temporary IPv6 address = %+v", m)
+	}
+	if m := msgs[4]; m.Type != rtmDelAddr || !m.LinkLocal {
+		t.Errorf("link-local delete = %+v", m)
+	}
+}
+
+func TestParseRtnlMsgs_Errors(t *testing.T) {
+	if _, _, err := parseRtnlMsgs(testLinkMsg(rtmNewLink, 2, "eth0", 0)[:20]); err == nil {
+		t.Error("truncated message accepted")
+	}
+	errMsg := nlMsg(nlmsgError, binary.NativeEndian.AppendUint32(nil, uint32(0xffffffff))) // -EPERM
+	if _, done, err := parseRtnlMsgs(errMsg); err == nil || !done {
+		t.Errorf("netlink error: done=%v err=%v", done, err)
+	}
+}
@@ -0,0 +1,236 @@
+package watchers
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// inet_diag wire constants (linux/netlink.h, linux/inet_diag.h). They are
+// declared here rather than taken from x/sys/unix so the encoding and parsing
+// below build and test on every platform; only the socket I/O is Linux-only.
+const (
+	afInet  = 2
+	afInet6 = 10
+
+	ipprotoTCP = 6
+	ipprotoUDP = 17
+	ipprotoRaw = 255
+
+	// Socket states from the kernel's TCP state enum, shared by UDP and raw.
+	tcpEstablished = 1 // also connected UDP sockets
+	tcpSynSent     = 2
+	tcpClose       = 7 // unconnected UDP/raw sockets, i.e. "listening"
+	tcpListen      = 10
+
+	nlmsgHdrLen     = 16
+	nlmsgError      = 2
+	nlmsgDone       = 3
+	nlmFRequest     = 0x1
+	nlmFDump        = 0x300
+	sockDiagByFam   = 20
+	inetDiagReqLen  = 56 // struct inet_diag_req_v2
+	inetDiagMsgLen  = 72 // struct inet_diag_msg
+	inetDiagSockID  = 48 // struct inet_diag_sockid
+	inetDiagAddrLen = 16
+)
+
+var errSockDiagUnsupported = errors.New("netlink sock_diag not supported on this platform")
+
+// sockDiagEntry is one socket returned by an inet_diag dump.
+type sockDiagEntry struct {
+	Family  uint8
+	State   uint8
+	SrcIP   net.IP
+	SrcPort uint16
+	DstIP   net.IP
+	DstPort uint16
+	UID     uint32
+	Inode   uint64
+}
+
+// buildInetDiagReq encodes a SOCK_DIAG_BY_FAMILY dump request for every
+// socket of the given family/protocol whose state bit is set in states.
+func buildInetDiagReq(family, protocol uint8, states uint32, seq uint32) []byte {
+	buf := make([]byte, nlmsgHdrLen+inetDiagReqLen)
+	ne := binary.NativeEndian
+
+	ne.PutUint32(buf[0:4], uint32(len(buf)))
+	ne.PutUint16(buf[4:6], sockDiagByFam)
+	ne.PutUint16(buf[6:8], nlmFRequest|nlmFDump)
+	ne.PutUint32(buf[8:12], seq)
+	// buf[12:16] pid = 0 (kernel)
+
+	req := buf[nlmsgHdrLen:]
+	req[0] = family
+	req[1] = protocol
+	// req[2] idiag_ext; req[3] is sdiag_raw_protocol for raw_diag, where
+	// IPPROTO_RAW means "raw sockets of any protocol".
+	if protocol == ipprotoRaw {
+		req[3] = ipprotoRaw
+	}
+	ne.PutUint32(req[4:8], states)
+	// req[8:56] inet_diag_sockid left zero: no filtering on addresses
+	return buf
+}
+
+// parseInetDiagMsgs decodes one recv() worth of netlink messages. done is
+// true once NLMSG_DONE has been seen and the dump is complete.
+func parseInetDiagMsgs(buf []byte) (entries []sockDiagEntry, done bool, err error) {
+	ne := binary.NativeEndian
+	for len(buf) >= nlmsgHdrLen {
+		msgLen := int(ne.Uint32(buf[0:4]))
+		msgType := ne.Uint16(buf[4:6])
+		if msgLen < nlmsgHdrLen || msgLen > len(buf) {
+			return entries, false, fmt.Errorf("malformed netlink message (len %d, have %d)", msgLen, len(buf))
+		}
+		payload := buf[nlmsgHdrLen:msgLen]
+
+		switch msgType {
+		case nlmsgDone:
+			return entries, true, nil
+		case nlmsgError:
+			if len(payload) >= 4 {
+				if errno := int32(ne.Uint32(payload[0:4])); errno != 0 {
+					return entries, true, fmt.Errorf("netlink error: errno %d", -errno)
+				}
+			}
+			return entries, true, nil
+		case sockDiagByFam:
+			if e, ok := parseInetDiagMsg(payload); ok {
+				entries = append(entries, e)
+			}
+		}
+
+		// Messages are padded to 4-byte alignment.
+		aligned := (msgLen + 3) &^ 3
+		if aligned > len(buf) {
+			break
+		}
+		buf = buf[aligned:]
+	}
+	return entries, false, nil
+}
+
+func parseInetDiagMsg(b []byte) (sockDiagEntry, bool) {
+	if len(b) < inetDiagMsgLen {
+		return sockDiagEntry{}, false
+	}
+	ne := binary.NativeEndian
+	e := sockDiagEntry{
+		Family: b[0],
+		State:  b[1],
+	}
+	id := b[4 : 4+inetDiagSockID]
+	e.SrcPort = binary.BigEndian.Uint16(id[0:2])
+	e.DstPort = binary.BigEndian.Uint16(id[2:4])
+	e.SrcIP = diagAddr(e.Family, id[4:4+inetDiagAddrLen])
+	e.DstIP = diagAddr(e.Family, id[4+inetDiagAddrLen:4+2*inetDiagAddrLen])
+
+	rest := b[4+inetDiagSockID:]
+	// rest: expires, rqueue, wqueue, uid, inode (all u32)
+	e.UID = ne.Uint32(rest[12:16])
+	e.Inode = uint64(ne.Uint32(rest[16:20]))
+	return e, true
+}
+
+func diagAddr(family uint8, raw []byte) net.IP {
+	if family == afInet {
+		return net.IPv4(raw[0], raw[1], raw[2], raw[3]).To4()
+	}
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, raw[:net.IPv6len])
+	return ip
+}
+
+// formatSockAddr renders an address the way `ss` does (no IPv6 brackets),
+// so ignore patterns such as "::1:*" and dedup keys match on both paths.
+func formatSockAddr(ip net.IP, port uint16) string {
+	return ip.String() + ":" + strconv.Itoa(int(port))
+}
+
+// inodePIDResolver maps socket inodes to owning PIDs by scanning
+// /proc/<pid>/fd, the same walk `ss -p` does on every invocation. Results are
+// cached and /proc is only rescanned when an unknown inode appears.
+type inodePIDResolver struct {
+	procRoot string
+	cache    map[uint64]int
+}
+
+func newInodePIDResolver(procRoot string) *inodePIDResolver {
+	return &inodePIDResolver{procRoot: procRoot, cache: make(map[uint64]int)}
+}
+
+// resolve returns inode → PID for every wanted inode it can find. Cache
+// entries for inodes no longer wanted are dropped.
+func (r *inodePIDResolver) resolve(wanted map[uint64]bool) map[uint64]int {
+	missing := 0
+	for ino := range wanted {
+		if _, ok := r.cache[ino]; !ok {
+			missing++
+		}
+	}
+	if missing > 0 {
+		r.scan(wanted, missing)
+	}
+
+	result := make(map[uint64]int, len(wanted))
+	for ino, pid := range r.cache {
+		if wanted[ino] {
+			result[ino] = pid
+		} else {
+			delete(r.cache, ino)
+		}
+	}
+	return result
+}
+
+// scan walks /proc until every missing inode has been found.
+func (r *inodePIDResolver) scan(wanted map[uint64]bool, missing int) {
+	procs, err := os.ReadDir(r.procRoot)
+	if err != nil {
+		return
+	}
+	for _, p := range procs {
+		pid, err := strconv.Atoi(p.Name())
+		if err != nil {
+			continue
+		}
+		fdDir := filepath.Join(r.procRoot, p.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue // process exited or not ours to read
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil {
+				continue
+			}
+			ino, ok := parseSocketLink(link)
+			if !ok || !wanted[ino] {
+				continue
+			}
+			if _, known := r.cache[ino]; !known {
+				r.cache[ino] = pid
+				missing--
+				if missing == 0 {
+					return
+				}
+			}
+		}
+	}
+}
+
+// parseSocketLink extracts N from an fd symlink target of the form "socket:[N]".
+func parseSocketLink(link string) (uint64, bool) {
+	if !strings.HasPrefix(link, "socket:[") || !strings.HasSuffix(link, "]") {
+		return 0, false
+	}
+	ino, err := strconv.ParseUint(link[len("socket:["):len(link)-1], 10, 64)
+	return ino, err == nil
+}
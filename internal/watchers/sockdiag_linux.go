@@ -0,0 +1,59 @@
+//go:build linux
+
+package watchers
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// sockDiagTimeout bounds each read of a dump, so a kernel that never
+// answers makes the caller fall back to ss instead of hanging the poll.
+const sockDiagTimeout = 2 * time.Second
+
+// sockDiagDump runs one inet_diag dump over a NETLINK_SOCK_DIAG socket.
+func sockDiagDump(family, protocol uint8, states uint32) ([]sockDiagEntry, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, unix.NETLINK_SOCK_DIAG)
+	if err != nil {
+		return nil, fmt.Errorf("netlink socket: %w", err)
+	}
+	defer unix.Close(fd)
+
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return nil, fmt.Errorf("netlink bind: %w", err)
+	}
+	tv := unix.NsecToTimeval(sockDiagTimeout.Nanoseconds())
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		return nil, fmt.Errorf("netlink receive timeout: %w", err)
+	}
+
+	req := buildInetDiagReq(family, protocol, states, 1)
+	if err := unix.Sendto(fd, req, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return nil, fmt.Errorf("netlink send: %w", err)
+	}
+
+	var entries []sockDiagEntry
+	buf := make([]byte, 32*1024)
+	for {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if err != nil {
+			if err == unix.EINTR {
+				continue
+			}
+			if err == unix.EAGAIN {
+				return nil, fmt.Errorf("netlink recv: no reply within %s", sockDiagTimeout)
+			}
+			return nil, fmt.Errorf("netlink recv: %w", err)
+		}
+		batch, done, err := parseInetDiagMsgs(buf[:n])
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, batch...)
+		if done {
+			return entries, nil
+		}
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package watchers
+
+// sockDiagDump is unavailable off Linux; callers fall back to ss.
+func sockDiagDump(family, protocol uint8, states uint32) ([]sockDiagEntry, error) {
+	return nil, errSockDiagUnsupported
+}
@@ -0,0 +1,372 @@
+package watchers
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Fullex26/piguard/internal/analysers"
+	"github.com/Fullex26/piguard/internal/config"
+	"github.com/Fullex26/piguard/internal/eventbus"
+	"github.com/Fullex26/piguard/pkg/models"
+)
+
+// diagMsg builds one SOCK_DIAG_BY_FAMILY netlink message as the kernel would.
+func diagMsg(family uint8, src net.IP, sport uint16, inode uint32) []byte {
+	ne := binary.NativeEndian
+	buf := make([]byte, nlmsgHdrLen+inetDiagMsgLen)
+	ne.PutUint32(buf[0:4], uint32(len(buf)))
+	ne.PutUint16(buf[4:6], sockDiagByFam)
+
+	msg := buf[nlmsgHdrLen:]
+	msg[0] = family
+	msg[1] = tcpListen
+	id := msg[4:]
+	binary.BigEndian.PutUint16(id[0:2], sport)
+	if v4 := src.To4(); family == afInet && v4 != nil {
+		copy(id[4:8], v4)
+	} else {
+		copy(id[4:20], src.To16())
+	}
+	rest := msg[4+inetDiagSockID:]
+	ne.PutUint32(rest[12:16], 1000)
+	ne.PutUint32(rest[16:20], inode)
+	return buf
+}
+
+func nlControlMsg(msgType uint16, errno int32) []byte {
+	ne := binary.NativeEndian
+	buf := make([]byte, nlmsgHdrLen+4)
+	ne.PutUint32(buf[0:4], uint32(len(buf)))
+	ne.PutUint16(buf[4:6], msgType)
+	ne.PutUint32(buf[nlmsgHdrLen:], uint32(errno))
+	return buf
+}
+
+func TestBuildInetDiagReq(t *testing.T) {
+	req := buildInetDiagReq(afInet6, ipprotoTCP, 1<<tcpListen, 7)
+	ne := binary.NativeEndian
+
+	if len(req) != nlmsgHdrLen+inetDiagReqLen {
+		t.Fatalf("len = %d, want %d", len(req), nlmsgHdrLen+inetDiagReqLen)
+	}
+	if got := ne.Uint32(req[0:4]); int(got) != len(req) {
+		t.Errorf("nlmsg_len = %d, want %d", got, len(req))
+	}
+	if got := ne.Uint16(req[4:6]); got != sockDiagByFam {
+		t.Errorf("nlmsg_type = %d, want %d", got, sockDiagByFam)
+	}
+	if got := ne.Uint16(req[6:8]); got != nlmFRequest|nlmFDump {
+		t.Errorf("nlmsg_flags = %#x, want %#x", got, nlmFRequest|nlmFDump)
+	}
+	if got := ne.Uint32(req[8:12]); got != 7 {
+		t.Errorf("nlmsg_seq = %d, want 7", got)
+	}
+	if req[16] != afInet6 || req[17] != ipprotoTCP {
+		t.Errorf("family/protocol = %d/%d, want %d/%d", req[16], req[17], afInet6, ipprotoTCP)
+	}
+	if got := ne.Uint32(req[20:24]); got != 1<<tcpListen {
+		t.Errorf("idiag_states = %#x, want %#x", got, 1<<tcpListen)
+	}
+}
+
+func TestBuildInetDiagReq_Raw(t *testing.T) {
+	req := buildInetDiagReq(afInet, ipprotoRaw, 1<<tcpClose, 1)
+	if req[nlmsgHdrLen+3] != ipprotoRaw {
+		t.Errorf("sdiag_raw_protocol = %d, want %d", req[nlmsgHdrLen+3], ipprotoRaw)
+	}
+	if tcp := buildInetDiagReq(afInet, ipprotoTCP, 1<<tcpListen, 1); tcp[nlmsgHdrLen+3] != 0 {
+		t.Error("pad byte should stay zero for non-raw requests")
+	}
+}
+
+func TestParseInetDiagMsgs(t *testing.T) {
+	var buf []byte
+	buf = append(buf, diagMsg(afInet, net.ParseIP("0.0.0.0"), 22, 101)...)
+	buf = append(buf, diagMsg(afInet6, net.ParseIP("::1"), 5432, 202)...)
+	buf = append(buf, nlControlMsg(nlmsgDone, 0)...)
+
+	entries, done, err := parseInetDiagMsgs(buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !done {
+		t.Error("expected done after NLMSG_DONE")
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+
+	if got := formatSockAddr(entries[0].SrcIP, entries[0].SrcPort); got != "0.0.0.0:22" {
+		t.Errorf("entry 0 addr = %q, want %q", got, "0.0.0.0:22")
+	}
+	if entries[0].Inode != 101 || entries[0].UID != 1000 {
+		t.Errorf("entry 0 inode/uid = %d/%d, want 101/1000", entries[0].Inode, entries[0].UID)
+	}
+	if got := formatSockAddr(entries[1].SrcIP, entries[1].SrcPort); got != "::1:5432" {
+		t.Errorf("entry 1 addr = %q, want %q", got, "::1:5432")
+	}
+	if entries[1].Inode != 202 {
+		t.Errorf("entry 1 inode = %d, want 202", entries[1].Inode)
+	}
+}
+
+func TestParseInetDiagMsgs_PartialAndErrors(t *testing.T) {
+	t.Run("no done yet", func(t *testing.T) {
+		entries, done, err := parseInetDiagMsgs(diagMsg(afInet, net.ParseIP("127.0.0.1"), 80, 1))
+		if err != nil || done || len(entries) != 1 {
+			t.Errorf("got %d entries, done=%v, err=%v; want 1, false, nil", len(entries), done, err)
+		}
+	})
+
+	t.Run("netlink error", func(t *testing.T) {
+		_, done, err := parseInetDiagMsgs(nlControlMsg(nlmsgError, -13))
+		if err == nil || !done {
+			t.Errorf("done=%v, err=%v; want done with error", done, err)
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		msg := diagMsg(afInet, net.ParseIP("0.0.0.0"), 22, 1)
+		if _, _, err := parseInetDiagMsgs(msg[:40]); err == nil {
+			t.Error("expected error for truncated message")
+		}
+	})
+}
+
+func TestFormatSockAddr(t *testing.T) {
+	tests := []struct {
+		ip   string
+		port uint16
+		want string
+	}{
+		{"0.0.0.0", 22, "0.0.0.0:22"},
+		{"127.0.0.1", 8080, "127.0.0.1:8080"},
+		{"::", 443, ":::443"}, // matches ss output
+		{"::1", 5432, "::1:5432"},
+		{"fe80::1", 53, "fe80::1:53"},
+	}
+	for _, tt := range tests {
+		if got := formatSockAddr(net.ParseIP(tt.ip), tt.port); got != tt.want {
+			t.Errorf("formatSockAddr(%s, %d) = %q, want %q", tt.ip, tt.port, got, tt.want)
+		}
+	}
+}
+
+func TestParseSocketLink(t *testing.T) {
+	tests := []struct {
+		link string
+		want uint64
+		ok   bool
+	}{
+		{"socket:[12345]", 12345, true},
+		{"pipe:[12345]", 0, false},
+		{"/dev/null", 0, false},
+		{"socket:[abc]", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseSocketLink(tt.link)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseSocketLink(%q) = %d, %v; want %d, %v", tt.link, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestInodePIDResolver(t *testing.T) {
+	root := t.TempDir()
+	mkfd := func(pid, fd, target string) {
+		dir := filepath.Join(root, pid, "fd")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(target, filepath.Join(dir, fd)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mkfd("100", "3", "socket:[111]")
+	mkfd("100", "4", "/dev/null")
+	mkfd("200", "5", "socket:[222]")
+	if err := os.MkdirAll(filepath.Join(root, "self"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	r := newInodePIDResolver(root)
+	got := r.resolve(map[uint64]bool{111: true, 222: true, 333: true})
+	if got[111] != 100 || got[222] != 200 {
+		t.Errorf("resolve = %v, want 111→100, 222→200", got)
+	}
+	if _, ok := got[333]; ok {
+		t.Error("unknown inode 333 should not resolve")
+	}
+
+	// Inodes that are no longer listening are evicted from the cache.
+	r.resolve(map[uint64]bool{111: true})
+	if _, ok := r.cache[222]; ok {
+		t.Error("stale inode 222 should be evicted")
+	}
+}
+
+func TestNetlinkWatcher_ScanPorts_SockDiag(t *testing.T) {
+	labeller := analysers.NewPortLabeller()
+	labeller.ReadProcessNameFn(func(pid int) string {
+		if pid == 1234 {
+			return "sshd"
+		}
+		return ""
+	})
+	w := &NetlinkWatcher{
+		Base:     Base{Cfg: &config.Config{}, Bus: eventbus.New()},
+		labeller: labeller,
+		baseline: make(map[string]models.PortInfo),
+		runSS: func() ([]byte, error) {
+			t.Fatal("ss should not be called when netlink works")
+			return nil, nil
+		},
+		sockDiag: func(family, protocol uint8, states uint32) ([]sockDiagEntry, error) {
+			switch {
+			case protocol == ipprotoTCP && family == afInet:
+				return []sockDiagEntry{{Family: afInet, SrcIP: net.IPv4zero, SrcPort: 22, Inode: 1}}, nil
+			case protocol == ipprotoTCP && family == afInet6:
+				return []sockDiagEntry{{Family: afInet6, SrcIP: net.IPv6loopback, SrcPort: 5432, Inode: 2}}, nil
+			case protocol == ipprotoUDP && family == afInet:
+				if states != 1<<tcpClose {
+					t.Errorf("udp states = %#x, want TCP_CLOSE", states)
+				}
+				return []sockDiagEntry{{Family: afInet, SrcIP: net.IPv4zero, SrcPort: 5353, Inode: 3}}, nil
+			}
+			return nil, nil
+		},
+		inodes: &inodePIDResolver{procRoot: t.TempDir(), cache: map[uint64]int{1: 1234}},
+	}
+
+	ports, err := w.scanPorts()
+	if err != nil {
+		t.Fatalf("scanPorts: %v", err)
+	}
+	if len(ports) != 3 {
+		t.Fatalf("expected 3 ports, got %d", len(ports))
+	}
+	if ports[0].Key() != "tcp:0.0.0.0:22" || !ports[0].IsExposed || ports[0].ProcessName != "sshd" {
+		t.Errorf("port 0 = %+v, want exposed sshd on tcp:0.0.0.0:22", ports[0])
+	}
+	if ports[1].Key() != "tcp:::1:5432" || ports[1].IsExposed {
+		t.Errorf("port 1 = %+v, want local tcp:::1:5432", ports[1])
+	}
+	if ports[2].Key() != "udp:0.0.0.0:5353" || !ports[2].IsExposed {
+		t.Errorf("port 2 = %+v, want exposed udp:0.0.0.0:5353", ports[2])
+	}
+}
+
+func TestNetlinkWatcher_ScanPorts_FallsBackToSS(t *testing.T) {
+	calls := 0
+	w := &NetlinkWatcher{
+		Base:     Base{Cfg: &config.Config{}, Bus: eventbus.New()},
+		labeller: analysers.NewPortLabeller(),
+		baseline: make(map[string]models.PortInfo),
+		runSS: func() ([]byte, error) {
+			return []byte("State Recv-Q Send-Q Local Peer\n" +
+				"LISTEN 0 128 0.0.0.0:80 0.0.0.0:*\n"), nil
+		},
+		sockDiag: func(family, protocol uint8, states uint32) ([]sockDiagEntry, error) {
+			calls++
+			return nil, errors.New("permission denied")
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+		ports, err := w.scanPorts()
+		if err != nil {
+			t.Fatalf("scanPorts: %v", err)
+		}
+		if len(ports) != 1 || ports[0].Address != "0.0.0.0:80" {
+			t.Errorf("ports = %+v, want ss result", ports)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("netlink tried %d times, want once per protocol (fallback should stick)", calls)
+	}
+}
+
+func TestNetlinkWatcher_ScanPorts_FallsBackPerProtocol(t *testing.T) {
+	var ssArgs []string
+	w := &NetlinkWatcher{
+		Base:     Base{Cfg: &config.Config{}, Bus: eventbus.New()},
+		labeller: analysers.NewPortLabeller(),
+		baseline: make(map[string]models.PortInfo),
+		sockDiag: func(family, protocol uint8, states uint32) ([]sockDiagEntry, error) {
+			if protocol == ipprotoUDP {
+				return nil, errors.New("no such file or directory") // udp_diag not loaded
+			}
+			if family == afInet {
+				return []sockDiagEntry{
+					{Family: afInet, SrcIP: net.IPv4zero, SrcPort: 22},
+					{Family: afInet, SrcIP: net.IPv4(192, 168, 1, 2), SrcPort: 40000, DstIP: net.IPv4(192, 168, 1, 1), DstPort: 53},
+				}, nil
+			}
+			return nil, nil
+		},
+	}
+	w.runSS = func() ([]byte, error) {
+		ssArgs = w.ssArgs()
+		return []byte("State Recv-Q Send-Q Local Peer\n" +
+			"UNCONN 0 0 0.0.0.0:123 0.0.0.0:*\n" +
+			"ESTAB 0 0 192.168.1.2:41000 192.168.1.1:53\n"), nil
+	}
+
+	ports, err := w.scanPorts()
+	if err != nil {
+		t.Fatalf("scanPorts: %v", err)
+	}
+	var keys []string
+	for _, p := range ports {
+		keys = append(keys, p.Key())
+	}
+	if want := []string{"tcp:0.0.0.0:22", "udp:0.0.0.0:123"}; !slices.Equal(keys, want) {
+		t.Errorf("ports = %v, want %v (TCP by netlink, UDP by ss, connected sockets skipped)", keys, want)
+	}
+	if !slices.Equal(ssArgs, []string{"-lnp", "-u"}) {
+		t.Errorf("ss args = %v, want UDP only", ssArgs)
+	}
+}
+
+func TestNetlinkWatcher_Check_ShortLivedUDPNotReported(t *testing.T) {
+	bus := eventbus.New()
+	var captured []models.Event
+	var mu sync.Mutex
+	bus.Subscribe(func(e models.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		captured = append(captured, e)
+	})
+	query := "udp UNCONN 0 0 0.0.0.0:48213 0.0.0.0:*\n" // a resolver's one-off source port
+	w := &NetlinkWatcher{
+		Base:     Base{Cfg: &config.Config{}, Bus: bus},
+		labeller: analysers.NewPortLabeller(),
+		baseline: make(map[string]models.PortInfo),
+	}
+	w.runSS = func() ([]byte, error) {
+		return []byte("Netid State Recv-Q Send-Q Local Address:Port Peer Address:Port Process\n" + query), nil
+	}
+
+	w.check()
+	query = "udp UNCONN 0 0 0.0.0.0:51820 0.0.0.0:*\n" // the query is done; WireGuard starts
+	w.check()
+	w.check()
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(captured) != 1 || captured[0].Port.Key() != "udp:0.0.0.0:51820" {
+		var got []string
+		for _, e := range captured {
+			got = append(got, string(e.Type)+" "+e.Port.Key())
+		}
+		t.Errorf("events = %v, want only port.opened for udp:0.0.0.0:51820", got)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/Fullex26/piguard/internal/config"
+	"github.com/Fullex26/piguard/internal/dockerapi"
 	"github.com/Fullex26/piguard/internal/eventbus"
 	"github.com/Fullex26/piguard/pkg/models"
 )
@@ -21,16 +22,23 @@ type SystemWatcher struct {
 	readMemInfo func() ([]byte, error)
 	readCPUTemp func() ([]byte, error)
 	statfsFunc  func(string, *StatFS) error
+
+	topContainers func() []containerStats // largest memory users for memory alerts; nil = skip
 }
 
 func NewSystemWatcher(cfg *config.Config, bus *eventbus.Bus) *SystemWatcher {
-	return &SystemWatcher{
+	w := &SystemWatcher{
 		Base:        Base{Cfg: cfg, Bus: bus},
 		interval:    60 * time.Second,
 		readMemInfo: func() ([]byte, error) { return os.ReadFile("/proc/meminfo") },
 		readCPUTemp: func() ([]byte, error) { return os.ReadFile("/sys/class/thermal/thermal_zone0/temp") },
 		statfsFunc:  statfs,
 	}
+	if cfg.Docker.Enabled {
+		api := dockerapi.New(cfg.Docker.Socket)
+		w.topContainers = func() []containerStats { return topMemoryContainers(cgroupRoot, api, 3) }
+	}
+	return w
 }
 
 func (w *SystemWatcher) Name() string { return "system" }
@@ -81,6 +89,7 @@ func (w *SystemWatcher) check() {
 			Hostname:  hostname,
 			Timestamp: time.Now(),
 			Message:   fmt.Sprintf("Memory usage at %d%% (threshold: %d%%)", mem, w.Cfg.System.MemoryThreshold),
+			Details:   w.topContainersDetail(),
 			Suggested: "Check memory: free -h && docker stats --no-stream",
 			Source:    "system",
 		})
@@ -102,6 +111,23 @@ func (w *SystemWatcher) check() {
 	}
 }
 
+// topContainersDetail lists the containers using the most memory, e.g.
+// "Top containers: plex 1.2 GB, postgres 412 MB".
+func (w *SystemWatcher) topContainersDetail() string {
+	if w.topContainers == nil {
+		return ""
+	}
+	top := w.topContainers()
+	if len(top) == 0 {
+		return ""
+	}
+	parts := make([]string, len(top))
+	for i, c := range top {
+		parts[i] = c.Name + " " + formatKB(c.MemoryBytes/1024)
+	}
+	return "Top containers: " + strings.Join(parts, ", ")
+}
+
 func (w *SystemWatcher) getDiskUsage() int {
 	var stat StatFS
 	if err := w.statfsFunc("/", &stat); err != nil {
@@ -20,6 +20,7 @@ func newTestSystemWatcher(cfg *config.Config) (*SystemWatcher, *eventCapture) {
 		cap.events = append(cap.events, e)
 	})
 	w := NewSystemWatcher(cfg, bus)
+	w.topContainers = nil // don't read the host's cgroups
 	return w, cap
 }
 
@@ -215,6 +216,35 @@ func TestSystemWatcher_Check_PublishesMemoryEvent(t *testing.T) {
 	}
 }
 
+func TestSystemWatcher_Check_MemoryEventListsTopContainers(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.System.MemoryThreshold = 50
+	cfg.System.TempThreshold = 100
+	w, cap := newTestSystemWatcher(cfg)
+
+	w.readMemInfo = func() ([]byte, error) {
+		return []byte("MemTotal:        1000000 kB\nMemAvailable:    100000 kB\n"), nil
+	}
+	w.readCPUTemp = func() ([]byte, error) { return nil, fmt.Errorf("no zone") }
+	w.statfsFunc = func(path string, stat *StatFS) error { return fmt.Errorf("no fs") }
+	w.topContainers = func() []containerStats {
+		return []containerStats{{Name: "plex", MemoryBytes: 1200 << 20}, {Name: "postgres", MemoryBytes: 412 << 20}}
+	}
+
+	w.check()
+	time.Sleep(50 * time.Millisecond)
+
+	for _, e := range cap.Events() {
+		if e.Type == models.EventMemoryHigh {
+			if want := "Top containers: plex 1.2 GB, postgres 412 MB"; e.Details != want {
+				t.Errorf("Details = %q, want %q", e.Details, want)
+			}
+			return
+		}
+	}
+	t.Error("expected EventMemoryHigh to be published")
+}
+
 func TestSystemWatcher_Check_PublishesTempEvent(t *testing.T) {
 	cfg := config.DefaultConfig()
 	cfg.System.TempThreshold = 70
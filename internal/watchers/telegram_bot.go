@@ -1,17 +1,21 @@
 package watchers
 
 import (
-	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -19,11 +23,14 @@ import (
 
 	"github.com/Fullex26/piguard/internal/analysers"
 	"github.com/Fullex26/piguard/internal/config"
+	"github.com/Fullex26/piguard/internal/dockerapi"
 	"github.com/Fullex26/piguard/internal/doctor"
 	"github.com/Fullex26/piguard/internal/eventbus"
+	"github.com/Fullex26/piguard/internal/firewall"
 	"github.com/Fullex26/piguard/internal/logging"
 	"github.com/Fullex26/piguard/internal/notifiers"
 	"github.com/Fullex26/piguard/internal/store"
+	"github.com/Fullex26/piguard/pkg/models"
 )
 
 // TelegramBotWatcher polls for incoming Telegram messages and handles commands
@@ -34,26 +41,47 @@ type TelegramBotWatcher struct {
 	client         *http.Client
 	offset         int
 	labeller       *analysers.PortLabeller
+	knownPorts     *analysers.PortRegistry // nil = no labels in /ports
 	store          *store.Store
+	docker         *dockerapi.Client // nil = docker CLI only
+	cgroupRoot     string            // cgroup v2 mount point for /docker stats
+	composeExec    func(args []string) ([]byte, error) // nil = run `docker <args>` (or podman); injectable for tests
+	runSS          func(args ...string) ([]byte, error) // nil = run `ss <args>` for /ports; injectable for tests
 	BackupWatcher      *BackupWatcher      // nil when backup is disabled
 	AutoUpdateWatcher  *AutoUpdateWatcher  // always set; toggled via Telegram
+	ConnectivityWatcher *ConnectivityWatcher // nil when connectivity is disabled; probe results in /status
+	DockerWatcher       *DockerWatcher       // nil when docker is disabled; told about deliberate compose down
 	menuMu             sync.Mutex          // protects lastMenuMsgID
 	lastMenuMsgID      int                 // message_id of current navigation message (for edit-in-place)
 }
 
 func NewTelegramBotWatcher(cfg *config.Config, bus *eventbus.Bus, db *store.Store) *TelegramBotWatcher {
-	return &TelegramBotWatcher{
-		Base:     Base{Cfg: cfg, Bus: bus},
-		token:    cfg.Notifications.Telegram.BotToken,
-		chatID:   cfg.Notifications.Telegram.ChatID,
-		client:   &http.Client{Timeout: 35 * time.Second},
-		labeller: analysers.NewPortLabeller(),
-		store:    db,
-	}
+	w := &TelegramBotWatcher{
+		Base:       Base{Cfg: cfg, Bus: bus},
+		token:      cfg.Notifications.Telegram.BotToken,
+		chatID:     cfg.Notifications.Telegram.ChatID,
+		client:     &http.Client{Timeout: 35 * time.Second},
+		labeller:   analysers.NewPortLabeller(),
+		knownPorts: analysers.NewPortRegistry(knownPorts(cfg)),
+		store:      db,
+		docker:     dockerapi.New(cfg.Docker.Socket),
+		cgroupRoot: cgroupRoot,
+	}
+	w.labeller.SetDockerSocket(cfg.Docker.Socket)
+	w.labeller.SetRuntime(dockerapi.Runtime(cfg.Docker.Runtime))
+	return w
 }
 
 func (w *TelegramBotWatcher) Name() string { return "telegram-bot" }
 
+// runtime returns the configured container runtime, docker by default.
+func (w *TelegramBotWatcher) runtime() dockerapi.Runtime {
+	if w.Cfg == nil {
+		return dockerapi.RuntimeDocker
+	}
+	return dockerapi.Runtime(w.Cfg.Docker.Runtime)
+}
+
 func (w *TelegramBotWatcher) getMenuMsgID() int {
 	w.menuMu.Lock()
 	defer w.menuMu.Unlock()
@@ -218,6 +246,10 @@ func (w *TelegramBotWatcher) handleCommand(text string) {
 		response = w.cmdScan()
 	case "/ip":
 		response = w.cmdIP()
+	case "/devices":
+		response = w.cmdDevices()
+	case "/device":
+		response = w.cmdDeviceRouter(parts)
 	case "/services":
 		response = w.cmdServices()
 	case "/doctor":
@@ -230,6 +262,8 @@ func (w *TelegramBotWatcher) handleCommand(text string) {
 		response = w.cmdStorageRouter(parts)
 	case "/report":
 		response = w.cmdReport()
+	case "/outages":
+		response = w.cmdOutages()
 	case "/pilog":
 		response = w.cmdPilog()
 	case "/reboot":
@@ -260,7 +294,22 @@ func (w *TelegramBotWatcher) sendReply(text string) {
 		slog.Error("telegram reply failed", "error", err)
 		return
 	}
-	resp.Body.Close()
+	logReplyStatus(resp, "telegram reply failed")
+}
+
+// logReplyStatus logs a reply Telegram rejected, such as one over the
+// 4096-character limit, with the API's description, and closes the body.
+func logReplyStatus(resp *http.Response, msg string) {
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return
+	}
+	var result struct {
+		Description string `json:"description"`
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	json.Unmarshal(body, &result)
+	slog.Error(msg, "status", resp.StatusCode, "description", result.Description)
 }
 
 // InlineButton represents a Telegram inline keyboard button.
@@ -290,7 +339,7 @@ func (w *TelegramBotWatcher) sendReplyWithKeyboard(text string, buttons [][]Inli
 		slog.Error("telegram reply with keyboard failed", "error", err)
 		return
 	}
-	resp.Body.Close()
+	logReplyStatus(resp, "telegram reply with keyboard failed")
 }
 
 // sendReplyWithKeyboardReturnID sends a message with inline keyboard and returns the message_id.
@@ -434,6 +483,12 @@ func (w *TelegramBotWatcher) handleCallback(callbackID, data string) {
 	case strings.HasPrefix(data, "a:"):
 		w.handleAutoUpdateAction(data)
 		return
+	case strings.HasPrefix(data, "f:"):
+		w.handleFirewallAction(data)
+		return
+	case strings.HasPrefix(data, "i:"):
+		w.handleIntegrityAction(data)
+		return
 	}
 
 	// Legacy callbacks (backward compat with old inline keyboards in chat history)
@@ -494,7 +549,7 @@ func (w *TelegramBotWatcher) cmdStatus() string {
 		lastAlert = "unknown"
 	}
 
-	return fmt.Sprintf(`🛡️ <b>PiGuard — %s</b>
+	status := fmt.Sprintf(`🛡️ <b>PiGuard — %s</b>
 
 <b>System</b>
   💾 Disk: %s
@@ -508,16 +563,27 @@ func (w *TelegramBotWatcher) cmdStatus() string {
   🐳 Containers: %s
   ⚠️ Last alert: %s`,
 		hostname, disk, mem, temp, uptime, fw, ports, containers, lastAlert)
+	if w.ConnectivityWatcher != nil {
+		status += "\n\n<b>Connectivity</b> " + w.ConnectivityWatcher.GetStatus()
+	}
+	return status
 }
 
 func (w *TelegramBotWatcher) cmdPorts() string {
-	out, err := exec.Command("ss", "-tlnp").Output()
+	// Same protocols and parsing as the port watcher, so UDP sockets are
+	// listed (and labelled) as UDP.
+	scanner := &NetlinkWatcher{Base: w.Base}
+	runSS := w.runSS
+	if runSS == nil {
+		runSS = func(args ...string) ([]byte, error) { return exec.Command("ss", args...).Output() }
+	}
+	out, err := runSS(scanner.ssArgs()...)
 	if err != nil {
 		return "❌ Failed to read ports"
 	}
 
-	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
-	if len(lines) < 2 {
+	ports := scanner.parseSsOutput(out)
+	if len(ports) == 0 {
 		return "✅ No listening ports"
 	}
 
@@ -527,35 +593,34 @@ func (w *TelegramBotWatcher) cmdPorts() string {
 	exposed := 0
 	local := 0
 
-	for _, line := range lines[1:] {
-		fields := strings.Fields(line)
-		if len(fields) < 4 {
-			continue
+	for _, port := range ports {
+		procName := port.ProcessName
+		if procName == "" {
+			procName = "unknown"
 		}
 
-		addr := fields[3]
-		procName := "unknown"
-		if len(fields) >= 6 {
-			if idx := strings.Index(fields[5], "((\""); idx >= 0 {
-				nameStr := fields[5][idx+3:]
-				if end := strings.Index(nameStr, "\""); end > 0 {
-					procName = nameStr[:end]
-				}
+		if w.knownPorts != nil {
+			if k, ok := w.knownPorts.Lookup(port); ok {
+				port.Label, port.Risk = k.Label, k.Risk
 			}
 		}
 
-		host, _, _ := strings.Cut(addr, ":")
-		isExposed := host == "0.0.0.0" || host == "::" || host == "*"
-
 		icon := "✅"
-		if isExposed {
+		if port.IsExposed {
 			icon = "⚠️"
+			if port.RiskLevel() == models.SeverityCritical {
+				icon = "🔴"
+			}
 			exposed++
 		} else {
 			local++
 		}
 
-		b.WriteString(fmt.Sprintf("%s <code>%s</code> → %s\n", icon, addr, procName))
+		label := ""
+		if port.Label != "" {
+			label = " · <i>" + html.EscapeString(port.Label) + "</i>"
+		}
+		b.WriteString(fmt.Sprintf("%s <code>%s</code> → %s%s\n", icon, port.Key(), html.EscapeString(procName), label))
 	}
 
 	b.WriteString(fmt.Sprintf("\n📊 %d local, %d exposed", local, exposed))
@@ -596,45 +661,102 @@ func (w *TelegramBotWatcher) cmdFirewall() string {
 }
 
 func (w *TelegramBotWatcher) cmdDocker() string {
-	out, err := exec.Command("docker", "ps", "--format", "table {{.Names}}\t{{.Status}}\t{{.Ports}}").Output()
+	containers, err := w.containerStates()
 	if err != nil {
 		return "❌ Docker not available"
 	}
+	if len(containers) == 0 {
+		return "🐳 No containers found"
+	}
 
-	result := strings.TrimSpace(string(out))
-	if result == "" || strings.Count(result, "\n") == 0 {
-		return "🐳 No containers running"
+	byID := make(map[string]containerState, len(containers))
+	for _, c := range containers {
+		byID[c.ID] = c
 	}
+	projects := composeProjects(byID)
+	names := make([]string, 0, len(projects))
+	for name := range projects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
-	// Parse into nicer format
-	lines := strings.Split(result, "\n")
 	var b strings.Builder
-	b.WriteString("🐳 <b>Docker Containers</b>\n\n")
-
-	for i, line := range lines {
-		if i == 0 {
-			continue // skip header
+	b.WriteString("🐳 <b>Docker Containers</b>\n")
+
+	// Compose projects first, one block per project.
+	for _, name := range names {
+		p := projects[name]
+		up := len(p.Services) - len(p.down())
+		b.WriteString(fmt.Sprintf("\n📦 <b>%s</b> — %d/%d services up\n", html.EscapeString(name), up, len(p.Services)))
+
+		replicas := make(map[string]int)
+		for _, c := range containers {
+			if c.Project == name {
+				replicas[c.Service]++
+			}
 		}
-		fields := strings.Fields(line)
-		if len(fields) == 0 {
+		for _, c := range containers {
+			if c.Project != name {
+				continue
+			}
+			label := c.Service
+			if replicas[c.Service] > 1 {
+				label = c.Names
+			}
+			b.WriteString(fmt.Sprintf("   %s %s — %s\n", containerIcon(c), html.EscapeString(label), html.EscapeString(c.Status)))
+		}
+	}
+
+	// Then standalone containers.
+	for _, c := range containers {
+		if c.Project != "" {
 			continue
 		}
+		b.WriteString(fmt.Sprintf("\n%s <b>%s</b>\n   %s\n", containerIcon(c), html.EscapeString(c.Names), html.EscapeString(c.Status)))
+	}
 
-		name := fields[0]
-		// Determine health from status field
-		status := strings.Join(fields[1:], " ")
+	return strings.TrimRight(b.String(), "\n")
+}
 
-		icon := "✅"
-		if strings.Contains(status, "unhealthy") {
-			icon = "🔴"
-		} else if strings.Contains(status, "starting") {
-			icon = "🟡"
+// containerIcon summarises a container's state as an emoji.
+func containerIcon(c containerState) string {
+	switch {
+	case strings.Contains(c.Status, "unhealthy"):
+		return "🔴"
+	case strings.Contains(c.Status, "starting"), c.State == "restarting":
+		return "🟡"
+	case c.State == "running":
+		return "✅"
+	case serviceUp(c):
+		return "⚪" // completed or not started
+	}
+	return "🔴"
+}
+
+// containerStates lists all containers, sorted by name, through the Engine
+// API or the runtime's CLI when the socket can't be reached.
+func (w *TelegramBotWatcher) containerStates() ([]containerState, error) {
+	var containers []containerState
+	listed := false
+	if w.docker != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if list, err := w.docker.ListContainers(ctx, true); err == nil {
+			for _, c := range list {
+				containers = append(containers, stateFromList(c))
+			}
+			listed = true
 		}
-
-		b.WriteString(fmt.Sprintf("%s <b>%s</b>\n   %s\n", icon, name, status))
 	}
-
-	return b.String()
+	if !listed {
+		out, err := exec.Command(w.runtime().CLI(), w.runtime().PSArgs()...).Output()
+		if err != nil {
+			return nil, err
+		}
+		containers, _ = parseDockerOutput(string(out))
+	}
+	sort.Slice(containers, func(i, j int) bool { return containers[i].Names < containers[j].Names })
+	return containers, nil
 }
 
 // cmdDockerRouter dispatches /docker subcommands. With no subcommand it falls
@@ -657,9 +779,233 @@ func (w *TelegramBotWatcher) cmdDockerRouter(parts []string) string {
 		return w.cmdDockerLogs(args)
 	case "prune":
 		return w.cmdDockerPrune(args)
+	case "stats":
+		return w.cmdDockerStats()
+	case "project", "projects":
+		return w.cmdDockerProject(args)
+	default:
+		return w.cmdDocker() + "\n\n<i>Usage: /docker [stop|restart|remove|fix|logs|prune] &lt;name&gt;, /docker stats or /docker project &lt;name&gt; [restart|pull|up|down]</i>"
+	}
+}
+
+// composeActions are the project-level actions, in button order.
+var composeActions = []struct{ Action, Button, Verb string }{
+	{"restart", "🔄 Restart", "Restart every container of"},
+	{"pull", "⬇️ Pull", "Pull the latest images for"},
+	{"up", "🚀 Up", "Recreate changed containers of"},
+	{"down", "⏹ Down", "Stop and remove the containers and networks (volumes are kept) of"},
+}
+
+// cmdDockerProject lists Compose projects, or asks for confirmation of a
+// project-level action: /docker project <name> [restart|pull|up|down].
+func (w *TelegramBotWatcher) cmdDockerProject(args []string) string {
+	if len(args) == 0 {
+		text, _ := w.buildProjectList()
+		return text + "\n\n<i>Usage: /docker project &lt;name&gt; [restart|pull|up|down]</i>"
+	}
+	project := args[0]
+	if len(args) == 1 {
+		text, buttons := w.buildProjectView(project)
+		w.setMenuMsgID(w.sendReplyWithKeyboardReturnID(text, buttons))
+		return ""
+	}
+	action := strings.ToLower(args[1])
+	text, buttons, ok := w.buildProjectConfirm(project, action)
+	if !ok {
+		return text
+	}
+	w.setMenuMsgID(w.sendReplyWithKeyboardReturnID(text, buttons))
+	return ""
+}
+
+// buildProjectList lists the Compose projects with a button for each.
+func (w *TelegramBotWatcher) buildProjectList() (string, [][]InlineButton) {
+	containers, err := w.containerStates()
+	if err != nil {
+		return "❌ Docker not available", nil
+	}
+	byID := make(map[string]containerState, len(containers))
+	for _, c := range containers {
+		byID[c.ID] = c
+	}
+	projects := composeProjects(byID)
+	if len(projects) == 0 {
+		return "📦 No Compose projects found", nil
+	}
+	names := make([]string, 0, len(projects))
+	for name := range projects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("📦 <b>Compose Projects</b>\n")
+	var buttons [][]InlineButton
+	for _, name := range names {
+		p := projects[name]
+		down := p.down()
+		icon := "✅"
+		if len(down) > 0 {
+			icon = "🔴"
+		}
+		b.WriteString(fmt.Sprintf("\n%s <b>%s</b> — %d/%d services up", icon, html.EscapeString(name), len(p.Services)-len(down), len(p.Services)))
+		buttons = append(buttons, []InlineButton{{Text: "📦 " + name, Data: "d:p:" + projectRef(name)}})
+	}
+	return b.String(), buttons
+}
+
+// projectRef is a short stable reference to a Compose project for callback
+// data, which Telegram caps at 64 bytes; project names can be longer.
+func projectRef(project string) string {
+	sum := sha256.Sum256([]byte(project))
+	return hex.EncodeToString(sum[:6])
+}
+
+// resolveProjectRef finds the project a callback's projectRef points at.
+func (w *TelegramBotWatcher) resolveProjectRef(ref string) (string, bool) {
+	containers, err := w.containerStates()
+	if err != nil {
+		return "", false
+	}
+	for _, c := range containers {
+		if c.Project != "" && projectRef(c.Project) == ref {
+			return c.Project, true
+		}
+	}
+	return "", false
+}
+
+// buildProjectView shows one project's services and its action buttons.
+func (w *TelegramBotWatcher) buildProjectView(project string) (string, [][]InlineButton) {
+	containers, err := composeProjectContainers(w.docker, w.runtime(), project)
+	if err != nil {
+		return "❌ Docker not available", [][]InlineButton{{{Text: "◀️ Back", Data: "m:dock"}}}
+	}
+	if len(containers) == 0 {
+		return fmt.Sprintf("❌ No containers found for Compose project <b>%s</b>", html.EscapeString(project)),
+			[][]InlineButton{{{Text: "◀️ Back", Data: "m:dock"}}}
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("📦 <b>%s</b>\n", html.EscapeString(project)))
+	if dir := containers[0].Labels[composeWorkingDirLabel]; dir != "" {
+		b.WriteString(fmt.Sprintf("<code>%s</code>\n", html.EscapeString(dir)))
+	}
+	b.WriteString("\n")
+	for _, c := range containers {
+		state := stateFromList(c)
+		b.WriteString(fmt.Sprintf("%s %s — %s\n", containerIcon(state), html.EscapeString(state.Service), html.EscapeString(state.Status)))
+	}
+
+	var row []InlineButton
+	for _, a := range composeActions {
+		row = append(row, InlineButton{Text: a.Button, Data: "d:pc:" + a.Action + ":" + projectRef(project)})
+	}
+	buttons := [][]InlineButton{row, {{Text: "◀️ Back", Data: "m:dock"}}}
+	return strings.TrimRight(b.String(), "\n"), buttons
+}
+
+// buildProjectConfirm returns the confirmation dialog for a project action,
+// or an error message and false when the action or project is unknown.
+func (w *TelegramBotWatcher) buildProjectConfirm(project, action string) (string, [][]InlineButton, bool) {
+	for _, a := range composeActions {
+		if a.Action != action {
+			continue
+		}
+		ref := projectRef(project)
+		text, buttons := buildConfirmView(
+			fmt.Sprintf("Compose %s: %s", action, html.EscapeString(project)),
+			fmt.Sprintf("%s Compose project <b>%s</b>.", a.Verb, html.EscapeString(project)),
+			"d:p!:"+action+":"+ref, "d:p:"+ref)
+		return text, buttons, true
+	}
+	return "Usage: /docker project &lt;name&gt; [restart|pull|up|down]", nil, false
+}
+
+// composeProjectAction runs a project-level action. Restart goes through the
+// Engine API container by container; pull, up and down need the compose
+// files, so they run `docker compose` with the directory and files from the
+// labels. Before a down, the Docker watcher is told not to alert on the
+// project's removal.
+func (w *TelegramBotWatcher) composeProjectAction(project, action string) string {
+	safe := html.EscapeString(project)
+	containers, err := composeProjectContainers(w.docker, w.runtime(), project)
+	if err != nil {
+		return "❌ Docker not available"
+	}
+	if len(containers) == 0 {
+		return fmt.Sprintf("❌ No containers found for Compose project <b>%s</b>", safe)
+	}
+
+	if action == "restart" {
+		var failed []string
+		for _, c := range containers {
+			if err := w.dockerAction("restart", c.ID); err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %s", c.Name(), err))
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Sprintf("❌ Restarting <b>%s</b> failed for %d of %d containers:\n<code>%s</code>",
+				safe, len(failed), len(containers), html.EscapeString(strings.Join(failed, "\n")))
+		}
+		return fmt.Sprintf("🔄 Compose project <b>%s</b> restarted (%d containers).", safe, len(containers))
+	}
+
+	args := composeArgs(containers[0].Labels)
+	switch action {
+	case "pull":
+		args = append(args, "pull", "--quiet")
+	case "up":
+		args = append(args, "up", "--detach")
+	case "down":
+		args = append(args, "down")
+		if w.DockerWatcher != nil {
+			w.DockerWatcher.ExpectProjectDown(project)
+		}
 	default:
-		return w.cmdDocker() + "\n\n<i>Usage: /docker [stop|restart|remove|fix|logs|prune] &lt;name&gt;</i>"
+		return "Usage: /docker project &lt;name&gt; [restart|pull|up|down]"
+	}
+	out, err := w.runCompose(args)
+	if err != nil {
+		return fmt.Sprintf("❌ <code>docker compose %s</code> failed for <b>%s</b>:\n<code>%s</code>",
+			action, safe, html.EscapeString(truncate(strings.TrimSpace(string(out))+"\n"+err.Error(), 1500)))
+	}
+	switch action {
+	case "pull":
+		return fmt.Sprintf("⬇️ Images pulled for <b>%s</b>. Use Up to recreate containers on the new images.", safe)
+	case "down":
+		return fmt.Sprintf("⏹ Compose project <b>%s</b> is down.", safe)
 	}
+	return fmt.Sprintf("🚀 Compose project <b>%s</b> is up.", safe)
+}
+
+// runCompose runs `docker <args>` for compose actions, allowing ten minutes
+// for image pulls on a slow link.
+func (w *TelegramBotWatcher) runCompose(args []string) ([]byte, error) {
+	if w.composeExec != nil {
+		return w.composeExec(args)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	return exec.CommandContext(ctx, w.runtime().CLI(), args...).CombinedOutput()
+}
+
+// dockerStatsSample is how long /docker stats measures CPU and I/O rates over.
+var dockerStatsSample = time.Second
+
+// cmdDockerStats shows each container's CPU, memory, pids and I/O, read from
+// its cgroup v2 files rather than `docker stats`.
+func (w *TelegramBotWatcher) cmdDockerStats() string {
+	stats := sampleContainerStats(w.cgroupRoot, w.docker, dockerStatsSample)
+	if len(stats) == 0 {
+		return "📊 No running containers found in cgroup v2"
+	}
+	var b strings.Builder
+	b.WriteString("📊 <b>Container Resources</b>\n\n")
+	for _, s := range stats {
+		b.WriteString(fmt.Sprintf("<b>%s</b>\n   %s\n", html.EscapeString(s.Name), statsLine(s)))
+	}
+	return b.String()
 }
 
 func (w *TelegramBotWatcher) cmdDockerStop(args []string) string {
@@ -667,10 +1013,9 @@ func (w *TelegramBotWatcher) cmdDockerStop(args []string) string {
 		return "Usage: /docker stop &lt;name&gt;"
 	}
 	name := args[0]
-	out, err := exec.Command("docker", "stop", name).CombinedOutput()
-	if err != nil {
+	if err := w.dockerAction("stop", name); err != nil {
 		return fmt.Sprintf("❌ Failed to stop <b>%s</b>: %s",
-			html.EscapeString(name), html.EscapeString(strings.TrimSpace(string(out))))
+			html.EscapeString(name), html.EscapeString(err.Error()))
 	}
 	return fmt.Sprintf("⏹️ Container <b>%s</b> stopped.", html.EscapeString(name))
 }
@@ -680,10 +1025,9 @@ func (w *TelegramBotWatcher) cmdDockerRestart(args []string) string {
 		return "Usage: /docker restart &lt;name&gt;"
 	}
 	name := args[0]
-	out, err := exec.Command("docker", "restart", name).CombinedOutput()
-	if err != nil {
+	if err := w.dockerAction("restart", name); err != nil {
 		return fmt.Sprintf("❌ Failed to restart <b>%s</b>: %s",
-			html.EscapeString(name), html.EscapeString(strings.TrimSpace(string(out))))
+			html.EscapeString(name), html.EscapeString(err.Error()))
 	}
 	return fmt.Sprintf("🔄 Container <b>%s</b> restarted.", html.EscapeString(name))
 }
@@ -700,24 +1044,57 @@ func (w *TelegramBotWatcher) cmdDockerRemove(args []string) string {
 			[][]InlineButton{{{Text: "🗑️ Remove " + name, Data: "docker:rm:" + name}}})
 		return ""
 	}
-	out, err := exec.Command("docker", "rm", "-f", name).CombinedOutput()
-	if err != nil {
+	if err := w.dockerAction("rm", name); err != nil {
 		return fmt.Sprintf("❌ Failed to remove <b>%s</b>: %s",
-			safeName, html.EscapeString(strings.TrimSpace(string(out))))
+			safeName, html.EscapeString(err.Error()))
 	}
 	return fmt.Sprintf("🗑️ Container <b>%s</b> removed.", safeName)
 }
 
+// dockerAction stops, restarts or force-removes ("rm") a container through
+// the Engine API. The docker CLI is only used when the socket can't be
+// reached; an error from the daemon itself is returned as-is.
+func (w *TelegramBotWatcher) dockerAction(action, name string) error {
+	if w.docker != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		var err error
+		switch action {
+		case "stop":
+			err = w.docker.StopContainer(ctx, name)
+		case "restart":
+			err = w.docker.RestartContainer(ctx, name)
+		case "rm":
+			err = w.docker.RemoveContainer(ctx, name, true)
+		}
+		var apiErr *dockerapi.APIError
+		if err == nil || errors.As(err, &apiErr) || errors.Is(err, dockerapi.ErrNotFound) {
+			return err
+		}
+	}
+	args := []string{action, name}
+	if action == "rm" {
+		args = []string{"rm", "-f", name}
+	}
+	out, err := exec.Command(w.runtime().CLI(), args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return errors.New(msg)
+		}
+		return err
+	}
+	return nil
+}
+
 // cmdDockerFix is a UX alias for restart, targeted at unhealthy/exited containers.
 func (w *TelegramBotWatcher) cmdDockerFix(args []string) string {
 	if len(args) == 0 {
 		return "Usage: /docker fix &lt;name&gt;"
 	}
 	name := args[0]
-	out, err := exec.Command("docker", "restart", name).CombinedOutput()
-	if err != nil {
+	if err := w.dockerAction("restart", name); err != nil {
 		return fmt.Sprintf("❌ Failed to restart <b>%s</b>: %s",
-			html.EscapeString(name), html.EscapeString(strings.TrimSpace(string(out))))
+			html.EscapeString(name), html.EscapeString(err.Error()))
 	}
 	return fmt.Sprintf("🔧 Container <b>%s</b> restarted (fix applied).\nDockerWatcher will confirm recovery within 10s.", html.EscapeString(name))
 }
@@ -727,7 +1104,7 @@ func (w *TelegramBotWatcher) cmdDockerLogs(args []string) string {
 		return "Usage: /docker logs &lt;name&gt;"
 	}
 	name := args[0]
-	out, err := exec.Command("docker", "logs", "--tail", "20", name).CombinedOutput()
+	out, err := exec.Command(w.runtime().CLI(), "logs", "--tail", "20", name).CombinedOutput()
 	if err != nil {
 		return fmt.Sprintf("❌ Failed to get logs for <b>%s</b>: %s",
 			html.EscapeString(name), html.EscapeString(strings.TrimSpace(string(out))))
@@ -748,7 +1125,7 @@ func (w *TelegramBotWatcher) cmdDockerPrune(args []string) string {
 		return ""
 	}
 	w.sendReply("🧹 Running docker system prune...")
-	out, err := exec.Command("docker", "system", "prune", "-f").CombinedOutput()
+	out, err := exec.Command(w.runtime().CLI(), "system", "prune", "-f").CombinedOutput()
 	if err != nil {
 		return fmt.Sprintf("❌ Prune failed: %s", truncate(html.EscapeString(strings.TrimSpace(string(out))), 500))
 	}
@@ -797,7 +1174,7 @@ func (w *TelegramBotWatcher) cmdStorageReport() string {
 	}
 
 	// Docker space breakdown
-	out, err = exec.Command("docker", "system", "df").Output()
+	out, err = exec.Command(w.runtime().CLI(), "system", "df").Output()
 	if err != nil {
 		b.WriteString("🐳 Docker: not available\n")
 	} else {
@@ -818,7 +1195,7 @@ func (w *TelegramBotWatcher) cmdStorageImages(args []string) string {
 		return ""
 	}
 	w.sendReply("🧹 Pruning Docker images...")
-	out, err := exec.Command("docker", "image", "prune", "-af").CombinedOutput()
+	out, err := exec.Command(w.runtime().CLI(), "image", "prune", "-af").CombinedOutput()
 	if err != nil {
 		return fmt.Sprintf("❌ Image prune failed: %s", truncate(html.EscapeString(strings.TrimSpace(string(out))), 500))
 	}
@@ -834,7 +1211,7 @@ func (w *TelegramBotWatcher) cmdStorageVolumes(args []string) string {
 		return ""
 	}
 	w.sendReply("🧹 Pruning Docker volumes...")
-	out, err := exec.Command("docker", "volume", "prune", "-f").CombinedOutput()
+	out, err := exec.Command(w.runtime().CLI(), "volume", "prune", "-f").CombinedOutput()
 	if err != nil {
 		return fmt.Sprintf("❌ Volume prune failed: %s", truncate(html.EscapeString(strings.TrimSpace(string(out))), 500))
 	}
@@ -880,7 +1257,7 @@ func (w *TelegramBotWatcher) cmdStorageAll(args []string) string {
 	b.WriteString("🧹 <b>Full storage cleanup</b>\n\n")
 
 	// Images
-	imgOut, imgErr := exec.Command("docker", "image", "prune", "-af").CombinedOutput()
+	imgOut, imgErr := exec.Command(w.runtime().CLI(), "image", "prune", "-af").CombinedOutput()
 	if imgErr != nil {
 		b.WriteString(fmt.Sprintf("❌ Images: %s\n", truncate(html.EscapeString(strings.TrimSpace(string(imgOut))), 200)))
 	} else {
@@ -890,7 +1267,7 @@ func (w *TelegramBotWatcher) cmdStorageAll(args []string) string {
 	}
 
 	// Volumes
-	volOut, volErr := exec.Command("docker", "volume", "prune", "-f").CombinedOutput()
+	volOut, volErr := exec.Command(w.runtime().CLI(), "volume", "prune", "-f").CombinedOutput()
 	if volErr != nil {
 		b.WriteString(fmt.Sprintf("❌ Volumes: %s\n", truncate(html.EscapeString(strings.TrimSpace(string(volOut))), 200)))
 	} else {
@@ -1139,9 +1516,183 @@ func (w *TelegramBotWatcher) cmdIP() string {
 		b.WriteString(fmt.Sprintf("  %s: <code>%s</code>\n", label, ip))
 	}
 
+	if w.store == nil {
+		return b.String()
+	}
+	if public, err := w.store.GetState(PublicIPStateKey); err == nil && public != "" {
+		b.WriteString(fmt.Sprintf("  Public: <code>%s</code>\n", html.EscapeString(public)))
+	}
+	changes, err := w.store.ListAddressChanges(8)
+	if err == nil && len(changes) > 0 {
+		b.WriteString("\n<b>History</b>\n")
+		for _, c := range changes {
+			b.WriteString(fmt.Sprintf("• <code>%s</code> %s: %s → %s\n", c.ChangedAt.Format("02 Jan 15:04"),
+				html.EscapeString(c.Scope), html.EscapeString(orNone(c.Old)), html.EscapeString(orNone(c.New))))
+		}
+	}
+
+	return b.String()
+}
+
+// ── Network device commands ──
+
+func (w *TelegramBotWatcher) cmdDevices() string {
+	if w.store == nil {
+		return "❌ Event store not available"
+	}
+	devices, err := w.store.ListDevices()
+	if err != nil {
+		return "❌ Failed to read device inventory"
+	}
+	return formatDevices(devices, time.Now())
+}
+
+const (
+	// deviceListMax keeps the /devices reply well under Telegram's
+	// 4096-character message limit.
+	deviceListMax = 25
+	// deviceListStale hides devices not seen for this long from /devices;
+	// /device <mac> still shows them.
+	deviceListStale = 30 * 24 * time.Hour
+)
+
+// formatDevices renders the inventory for /devices: devices seen recently,
+// most recent first, capped at deviceListMax entries.
+func formatDevices(devices []store.Device, now time.Time) string {
+	if len(devices) == 0 {
+		return "📡 No devices recorded yet\nEnable <code>network.enabled</code> to build the inventory."
+	}
+
+	var recent []store.Device
+	for _, d := range devices {
+		if now.Sub(d.LastSeen) <= deviceListStale {
+			recent = append(recent, d)
+		}
+	}
+	stale := len(devices) - len(recent)
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("📡 <b>Network Devices</b> (%d)\n\n", len(recent)))
+	shown := recent
+	if len(shown) > deviceListMax {
+		shown = shown[:deviceListMax]
+	}
+	for _, d := range shown {
+		icon := "❓"
+		if d.Trusted {
+			icon = "✅"
+		}
+		name := "<i>unnamed</i>"
+		if d.Name != "" {
+			name = "<b>" + html.EscapeString(d.Name) + "</b>"
+		}
+		b.WriteString(fmt.Sprintf("%s %s — <code>%s</code>\n    <code>%s</code> · last seen %s\n",
+			icon, name, d.IP, d.MAC, d.LastSeen.Format("Jan 2 15:04")))
+	}
+	if more := len(recent) - len(shown); more > 0 {
+		b.WriteString(fmt.Sprintf("… %d more\n", more))
+	}
+	if stale > 0 {
+		b.WriteString(fmt.Sprintf("<i>%d not seen for 30 days hidden</i>\n", stale))
+	}
+	b.WriteString("\n<i>✅ trusted  ❓ untrusted — /device &lt;mac&gt; for details</i>")
+	return b.String()
+}
+
+func (w *TelegramBotWatcher) cmdDeviceRouter(parts []string) string {
+	const usage = "Usage: /device &lt;mac&gt; | /device name &lt;mac&gt; &lt;label&gt; | /device trust &lt;mac&gt; | /device untrust &lt;mac&gt;"
+	if w.store == nil {
+		return "❌ Event store not available"
+	}
+	if len(parts) < 2 {
+		return usage
+	}
+
+	switch strings.ToLower(parts[1]) {
+	case "name":
+		if len(parts) < 3 {
+			return usage
+		}
+		mac, ok := normaliseMAC(parts[2])
+		if !ok {
+			return "❌ Invalid MAC address: <code>" + html.EscapeString(parts[2]) + "</code>"
+		}
+		name := strings.Join(parts[3:], " ")
+		if err := w.store.SetDeviceName(mac, name); err != nil {
+			return deviceError(mac, err)
+		}
+		if name == "" {
+			return fmt.Sprintf("🏷 Name cleared for <code>%s</code>", mac)
+		}
+		return fmt.Sprintf("🏷 <code>%s</code> is now <b>%s</b>", mac, html.EscapeString(name))
+	case "trust", "untrust":
+		if len(parts) < 3 {
+			return usage
+		}
+		mac, ok := normaliseMAC(parts[2])
+		if !ok {
+			return "❌ Invalid MAC address: <code>" + html.EscapeString(parts[2]) + "</code>"
+		}
+		trusted := strings.ToLower(parts[1]) == "trust"
+		if err := w.store.SetDeviceTrusted(mac, trusted); err != nil {
+			return deviceError(mac, err)
+		}
+		if trusted {
+			return fmt.Sprintf("✅ <code>%s</code> marked as trusted", mac)
+		}
+		return fmt.Sprintf("❓ <code>%s</code> marked as untrusted", mac)
+	}
+
+	mac, ok := normaliseMAC(parts[1])
+	if !ok {
+		return usage
+	}
+	d, err := w.store.GetDevice(mac)
+	if err != nil {
+		return deviceError(mac, err)
+	}
+	var b strings.Builder
+	name := "unnamed"
+	if d.Name != "" {
+		name = html.EscapeString(d.Name)
+	}
+	trust := "❓ untrusted"
+	if d.Trusted {
+		trust = "✅ trusted"
+	}
+	b.WriteString(fmt.Sprintf("📡 <b>%s</b>\n\n", name))
+	b.WriteString(fmt.Sprintf("MAC: <code>%s</code>\n", d.MAC))
+	b.WriteString(fmt.Sprintf("IP: <code>%s</code>\n", d.IP))
+	b.WriteString(fmt.Sprintf("Status: %s\n", trust))
+	b.WriteString(fmt.Sprintf("First seen: %s\n", d.FirstSeen.Format("2006-01-02 15:04")))
+	b.WriteString(fmt.Sprintf("Last seen: %s\n", d.LastSeen.Format("2006-01-02 15:04")))
+	if len(d.IPs) > 1 {
+		b.WriteString("\n<b>Address history</b>\n")
+		for _, ip := range d.IPs {
+			b.WriteString(fmt.Sprintf("  <code>%s</code> %s → %s\n", ip.IP,
+				ip.FirstSeen.Format("Jan 2 15:04"), ip.LastSeen.Format("Jan 2 15:04")))
+		}
+	}
 	return b.String()
 }
 
+// normaliseMAC validates a MAC address and returns it in the lowercase,
+// colon-separated form the inventory is keyed by.
+func normaliseMAC(s string) (string, bool) {
+	hw, err := net.ParseMAC(s)
+	if err != nil || len(hw) != 6 {
+		return "", false
+	}
+	return hw.String(), true
+}
+
+func deviceError(mac string, err error) string {
+	if errors.Is(err, store.ErrNoDevice) {
+		return fmt.Sprintf("❌ Unknown device <code>%s</code> — see /devices", mac)
+	}
+	return "❌ Failed to update device: " + html.EscapeString(err.Error())
+}
+
 func (w *TelegramBotWatcher) cmdServices() string {
 	out, err := exec.Command("systemctl", "list-units", "--type=service", "--state=running", "--no-pager", "--no-legend").Output()
 	if err != nil {
@@ -1171,7 +1722,7 @@ func (w *TelegramBotWatcher) cmdServices() string {
 
 	b.WriteString(fmt.Sprintf("\n📊 %d services running", count))
 
-	if section := dockerContainerURLs(); section != "" {
+	if section := dockerContainerURLs(w.runtime()); section != "" {
 		b.WriteString("\n\n")
 		b.WriteString(section)
 	}
@@ -1182,11 +1733,12 @@ func (w *TelegramBotWatcher) cmdServices() string {
 // dockerContainerURLs returns a formatted Docker section listing running
 // containers with their host port bindings as local access URLs.
 // Returns "" when Docker is unavailable or no containers are running.
-func dockerContainerURLs() string {
-	out, err := exec.Command("docker", "ps", "--format", "{{json .}}").Output()
+func dockerContainerURLs(rt dockerapi.Runtime) string {
+	out, err := exec.Command(rt.CLI(), rt.PSArgs()...).Output()
 	if err != nil || len(strings.TrimSpace(string(out))) == 0 {
 		return ""
 	}
+	containers, _ := parseDockerOutput(string(out))
 
 	localIP := getLocalIP()
 
@@ -1196,14 +1748,8 @@ func dockerContainerURLs() string {
 	}
 	var entries []entry
 
-	scanner := bufio.NewScanner(strings.NewReader(string(out)))
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
-		}
-		var c containerState
-		if err := json.Unmarshal([]byte(line), &c); err != nil {
+	for _, c := range containers {
+		if c.State != "running" {
 			continue
 		}
 		ports := parseHostPorts(c.Ports)
@@ -1327,7 +1873,51 @@ func (w *TelegramBotWatcher) cmdReport() string {
 	}
 
 	uptimeStr := w.getUptimeStr()
-	return notifiers.FormatWeeklyReport(hostname, thisWeek, lastWeekOnly, totalThis, totalLast, uptimeStr)
+	var conn *models.Availability
+	if w.Cfg.Connectivity.Enabled {
+		now := time.Now()
+		if a, err := w.store.Availability(now.AddDate(0, 0, -7), now, now); err == nil {
+			conn = &a
+		}
+	}
+	return notifiers.FormatWeeklyReport(hostname, thisWeek, lastWeekOnly, totalThis, totalLast, uptimeStr, conn)
+}
+
+// cmdOutages lists recent connectivity outages with 7- and 30-day availability.
+func (w *TelegramBotWatcher) cmdOutages() string {
+	if w.store == nil {
+		return "❌ Event store not available"
+	}
+
+	outages, err := w.store.ListOutages(10)
+	if err != nil {
+		return "❌ Failed to read outages"
+	}
+
+	now := time.Now()
+	var b strings.Builder
+	b.WriteString("📶 <b>Connectivity Outages</b>\n\n")
+	for _, days := range []int{7, 30} {
+		if a, err := w.store.Availability(now.AddDate(0, 0, -days), now, now); err == nil {
+			b.WriteString(fmt.Sprintf("<b>Last %d days:</b> %s\n", days, notifiers.FormatAvailability(a)))
+		}
+	}
+
+	if len(outages) == 0 {
+		b.WriteString("\n✅ No outages recorded")
+		return b.String()
+	}
+
+	b.WriteString("\n<b>Recent:</b>\n")
+	for _, o := range outages {
+		length := notifiers.FormatDowntime(o.Duration(now))
+		if o.End.IsZero() {
+			length = "🔴 ongoing, " + length + " so far"
+		}
+		b.WriteString(fmt.Sprintf("• <code>%s</code> %s\n", o.Start.Format("02 Jan 15:04"), length))
+	}
+
+	return b.String()
 }
 
 func (w *TelegramBotWatcher) cmdReboot(parts []string) string {
@@ -1492,7 +2082,7 @@ func (w *TelegramBotWatcher) getUptimeStr() string {
 }
 
 func (w *TelegramBotWatcher) getContainerSummary() string {
-	out, err := exec.Command("docker", "ps", "-q").Output()
+	out, err := exec.Command(w.runtime().CLI(), "ps", "-q").Output()
 	if err != nil {
 		return "N/A"
 	}
@@ -1925,10 +2515,10 @@ func (w *TelegramBotWatcher) buildSecurityView() (string, [][]InlineButton) {
 // buildDockerView returns the Docker overview category.
 func (w *TelegramBotWatcher) buildDockerView() (string, [][]InlineButton) {
 	text := w.cmdDocker()
-	text += "\n\n<i>Use text commands for container actions:\n/docker stop|restart|fix|logs|remove &lt;name&gt;</i>"
+	text += "\n\n<i>Use text commands for container actions:\n/docker stop|restart|fix|logs|remove &lt;name&gt;\n/docker project &lt;name&gt; restart|pull|up</i>"
 
 	buttons := [][]InlineButton{
-		{{Text: "🧹 Prune", Data: "d:prune"}},
+		{{Text: "📦 Projects", Data: "d:projects"}, {Text: "📊 Stats", Data: "d:stats"}, {Text: "🧹 Prune", Data: "d:prune"}},
 		{{Text: "◀️ Back", Data: "m:home"}},
 	}
 
@@ -2001,7 +2591,7 @@ func (w *TelegramBotWatcher) buildReportsView() (string, [][]InlineButton) {
 	text := w.cmdReport()
 
 	buttons := [][]InlineButton{
-		{{Text: "📊 Refresh", Data: "r:refresh"}},
+		{{Text: "📊 Refresh", Data: "r:refresh"}, {Text: "📶 Outages", Data: "r:outages"}},
 		{{Text: "◀️ Back", Data: "m:home"}},
 	}
 
@@ -2138,8 +2728,54 @@ func (w *TelegramBotWatcher) handleSecurityDetail(data string) {
 	}
 }
 
+// handleProjectAction handles the Compose project buttons, whose callback
+// data ends in a projectRef: "d:p:<ref>", "d:pc:<action>:<ref>" and
+// "d:p!:<action>:<ref>".
+func (w *TelegramBotWatcher) handleProjectAction(data string) {
+	kind, rest, _ := strings.Cut(strings.TrimPrefix(data, "d:"), ":")
+	action, ref, _ := strings.Cut(rest, ":")
+	if kind == "p" {
+		action, ref = "", rest
+	}
+	project, ok := w.resolveProjectRef(ref)
+	if !ok {
+		w.editMessage(w.getMenuMsgID(), "❌ Compose project no longer exists", [][]InlineButton{{{Text: "◀️ Back", Data: "d:projects"}}})
+		return
+	}
+
+	switch kind {
+	case "p":
+		text, buttons := w.buildProjectView(project)
+		w.editMessage(w.getMenuMsgID(), text, buttons)
+	case "pc":
+		if text, buttons, ok := w.buildProjectConfirm(project, action); ok {
+			w.editMessage(w.getMenuMsgID(), text, buttons)
+		}
+	case "p!":
+		msgID := w.getMenuMsgID()
+		w.editMessage(msgID, fmt.Sprintf("⏳ Running %s for <b>%s</b>...", action, html.EscapeString(project)), nil)
+		go func() {
+			text, buttons := buildDetailView(w.composeProjectAction(project, action), "d:p:"+ref)
+			w.editMessage(msgID, text, buttons)
+		}()
+	}
+}
+
 func (w *TelegramBotWatcher) handleDockerAction(data string) {
+	switch {
+	case data == "d:projects":
+		text, buttons := w.buildProjectList()
+		w.editMessage(w.getMenuMsgID(), text, append(buttons, []InlineButton{{Text: "◀️ Back", Data: "m:dock"}}))
+		return
+	case strings.HasPrefix(data, "d:p:"), strings.HasPrefix(data, "d:pc:"), strings.HasPrefix(data, "d:p!:"):
+		w.handleProjectAction(data)
+		return
+	}
+
 	switch data {
+	case "d:stats":
+		text, buttons := buildDetailView(w.cmdDockerStats(), "m:dock")
+		w.editMessage(w.getMenuMsgID(), text, buttons)
 	case "d:prune":
 		text, buttons := buildConfirmView(
 			"Docker Prune",
@@ -2287,9 +2923,13 @@ func (w *TelegramBotWatcher) handleBackupAction(data string) {
 }
 
 func (w *TelegramBotWatcher) handleReportAction(data string) {
-	if data == "r:refresh" {
+	switch data {
+	case "r:refresh":
 		text, buttons := w.buildReportsView()
 		w.editMessage(w.getMenuMsgID(), text, buttons)
+	case "r:outages":
+		text, buttons := buildDetailView(w.cmdOutages(), "m:rep")
+		w.editMessage(w.getMenuMsgID(), text, buttons)
 	}
 }
 
@@ -2306,6 +2946,149 @@ func (w *TelegramBotWatcher) handleDiagAction(data string) {
 	}
 }
 
+// handleFirewallAction handles the restore and accept buttons attached to
+// firewall alerts. The confirm callback carries the snapshot ID so the ruleset
+// restored is the one the user saw, even if a newer snapshot lands in between.
+func (w *TelegramBotWatcher) handleFirewallAction(data string) {
+	switch {
+	case data == "f:accept":
+		if w.store == nil {
+			w.editMessage(w.getMenuMsgID(), "❌ Event store not available", nil)
+			return
+		}
+		text, buttons := buildDetailView(acceptFirewallRules(w.store, firewall.New().Capture), "m:sec")
+		w.editMessage(w.getMenuMsgID(), text, buttons)
+
+	case data == "f:restore":
+		if w.store == nil {
+			w.editMessage(w.getMenuMsgID(), "❌ Event store not available", nil)
+			return
+		}
+		snap, err := w.store.GetLastGoodFirewallSnapshot()
+		if err != nil {
+			text, buttons := buildDetailView(
+				"🔥 <b>No firewall snapshot saved</b>\n\nCreate one on the Pi with:\n<code>sudo piguard firewall snapshot --label good</code>",
+				"m:sec")
+			w.editMessage(w.getMenuMsgID(), text, buttons)
+			return
+		}
+		text, buttons := buildConfirmView(
+			"Restore Firewall",
+			fmt.Sprintf("Replace the live ruleset with snapshot <b>#%d</b> (%s, %s) from %s.\nThe current rules are saved first.",
+				snap.ID, html.EscapeString(snap.Label), snap.Backend, snap.CreatedAt.Format("2006-01-02 15:04")),
+			fmt.Sprintf("f:restore!:%d", snap.ID), "m:sec")
+		w.editMessage(w.getMenuMsgID(), text, buttons)
+
+	case strings.HasPrefix(data, "f:restore!:"):
+		if w.store == nil {
+			return
+		}
+		id, err := strconv.ParseInt(strings.TrimPrefix(data, "f:restore!:"), 10, 64)
+		if err != nil {
+			return
+		}
+		msgID := w.getMenuMsgID()
+		w.editMessage(msgID, "♻️ Restoring firewall...", nil)
+		go func() {
+			text, buttons := buildDetailView(w.restoreFirewallSnapshot(id), "m:sec")
+			w.editMessage(msgID, text, buttons)
+		}()
+	}
+}
+
+// restoreFirewallSnapshot saves the live ruleset, then restores snapshot id.
+func (w *TelegramBotWatcher) restoreFirewallSnapshot(id int64) string {
+	snap, err := w.store.GetFirewallSnapshot(id)
+	if err != nil {
+		return fmt.Sprintf("❌ Snapshot #%d not found", id)
+	}
+
+	fw := firewall.New()
+	preNote := ""
+	if backend, rules, err := fw.Capture(); err == nil {
+		if preID, err := w.store.SaveFirewallSnapshot(store.SnapshotPreRestore, fmt.Sprintf("pre-restore of #%d", id), backend, string(rules)); err == nil {
+			preNote = fmt.Sprintf("\nPrevious rules saved as snapshot #%d.", preID)
+		}
+	}
+
+	if err := fw.Restore(snap.Backend, []byte(snap.Rules)); err != nil {
+		slog.Error("firewall restore failed", "snapshot", id, "error", err)
+		return fmt.Sprintf("❌ <b>Restore failed</b>\n<code>%s</code>", truncate(html.EscapeString(err.Error()), 500))
+	}
+	slog.Info("firewall restored from snapshot", "snapshot", id, "label", snap.Label)
+	return fmt.Sprintf("✅ <b>Firewall restored</b> from snapshot #%d (%s).%s",
+		snap.ID, html.EscapeString(snap.Label), preNote)
+}
+
+// handleIntegrityAction answers the buttons under file integrity alerts.
+func (w *TelegramBotWatcher) handleIntegrityAction(data string) {
+	id, ok := strings.CutPrefix(data, "i:diff:")
+	if !ok {
+		return
+	}
+	if w.store == nil {
+		w.sendReply("❌ Event store not available")
+		return
+	}
+	event, err := w.store.GetEvent(id)
+	if err != nil || event.File == nil || event.File.Diff == "" {
+		w.sendReply("❌ Diff not found — the alert may have been pruned from history")
+		return
+	}
+	for _, msg := range fullDiffMessages(*event.File) {
+		w.sendReply(msg)
+	}
+}
+
+// Full diffs are sent in messages of at most fullDiffChunk bytes of escaped
+// text, and at most fullDiffMaxMessages of them.
+const (
+	fullDiffChunk       = 3500
+	fullDiffMaxMessages = 8
+)
+
+// fullDiffMessages splits a file's diff into messages that fit Telegram's
+// 4096 character limit, breaking at line boundaries.
+func fullDiffMessages(f models.FileChange) []string {
+	header := fmt.Sprintf("📄 <b>Full diff</b> — <code>%s</code>\n", html.EscapeString(f.Path))
+	lines := strings.Split(strings.TrimSuffix(f.Diff, "\n"), "\n")
+
+	var msgs []string
+	var chunk strings.Builder
+	flush := func() {
+		msgs = append(msgs, fmt.Sprintf("%s<pre>%s</pre>", header, strings.TrimSuffix(chunk.String(), "\n")))
+		header = ""
+		chunk.Reset()
+	}
+	for i, line := range lines {
+		// Escaping grows a line at most fivefold (& → &amp;).
+		line = html.EscapeString(strings.ToValidUTF8(truncate(line, fullDiffChunk/5), "")) + "\n"
+		if chunk.Len() > 0 && chunk.Len()+len(line) > fullDiffChunk {
+			flush()
+			if len(msgs) == fullDiffMaxMessages {
+				msgs[len(msgs)-1] += fmt.Sprintf("\n<i>… %d more lines not shown</i>", len(lines)-i)
+				return msgs
+			}
+		}
+		chunk.WriteString(line)
+	}
+	flush()
+	return msgs
+}
+
+// acceptFirewallRules saves the live ruleset as known good, so later restores
+// return to it.
+func acceptFirewallRules(db *store.Store, capture func() (string, []byte, error)) string {
+	id, saved, err := saveFirewallSnapshot(db, capture, store.SnapshotAccepted, "accepted from alert")
+	switch {
+	case err != nil:
+		return fmt.Sprintf("❌ <b>Could not save the rules</b>\n<code>%s</code>", truncate(html.EscapeString(err.Error()), 500))
+	case !saved:
+		return fmt.Sprintf("✅ Current rules already match known good snapshot #%d.", id)
+	}
+	return fmt.Sprintf("✅ <b>Current rules accepted</b> as known good snapshot #%d.", id)
+}
+
 func (w *TelegramBotWatcher) handleDangerAction(data string) {
 	switch data {
 	case "z:reboot":
@@ -5,10 +5,17 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/Fullex26/piguard/internal/config"
+	"github.com/Fullex26/piguard/internal/eventbus"
 	"github.com/Fullex26/piguard/internal/logging"
+	"github.com/Fullex26/piguard/internal/store"
+	"github.com/Fullex26/piguard/pkg/models"
 )
 
 func TestTelegramBotWatcher_Name(t *testing.T) {
@@ -116,6 +123,49 @@ func TestCmdDockerStop_NoName(t *testing.T) {
 	}
 }
 
+func TestCmdDockerStop_UsesEngineAPI(t *testing.T) {
+	var calls []string
+	w := &TelegramBotWatcher{docker: fakeDockerSocket(t, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		if strings.Contains(r.URL.Path, "ghost") {
+			rw.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(rw, `{"message":"No such container: ghost"}`)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	}))}
+
+	if result := w.cmdDockerStop([]string{"nginx"}); !containsString(result, "stopped") {
+		t.Errorf("expected stopped message, got: %q", result)
+	}
+	if result := w.cmdDockerStop([]string{"ghost"}); !containsString(result, "No such container") {
+		t.Errorf("expected daemon error, got: %q", result)
+	}
+	if len(calls) != 2 || calls[0] != "POST /containers/nginx/stop" {
+		t.Errorf("calls = %v", calls)
+	}
+}
+
+func TestCmdDockerStats(t *testing.T) {
+	root := t.TempDir()
+	setUsage(t, root, testContainerA, 0, 300<<20, 4)
+	w := &TelegramBotWatcher{cgroupRoot: root, docker: fakeDockerSocket(t, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(rw, `[{"Id":%q,"Names":["/db<1>"]}]`, testContainerA)
+	}))}
+	defer func(d time.Duration) { dockerStatsSample = d }(dockerStatsSample)
+	dockerStatsSample = 10 * time.Millisecond
+
+	result := w.cmdDockerStats()
+	if !containsString(result, "<b>db&lt;1&gt;</b>") || !containsString(result, "Memory 300 MB") || !containsString(result, "PIDs 4") {
+		t.Errorf("unexpected stats output: %q", result)
+	}
+
+	w.cgroupRoot = t.TempDir()
+	if result := w.cmdDockerStats(); !containsString(result, "No running containers") {
+		t.Errorf("expected empty message, got: %q", result)
+	}
+}
+
 // ── restart ───────────────────────────────────────────────────────────────────
 
 func TestCmdDockerRestart_NoName(t *testing.T) {
@@ -535,13 +585,16 @@ func TestCallbackDataLength_NewScheme(t *testing.T) {
 		"m:bak", "m:rep", "m:diag", "m:danger",
 		"s:disk", "s:mem", "s:temp", "s:up", "s:ip", "s:svc",
 		"x:ports", "x:fw", "x:events", "x:scan",
-		"d:prune", "d:prune!",
+		"d:stats", "d:prune", "d:prune!", "d:projects",
+		"d:p:" + projectRef("media"), "d:pc:restart:" + projectRef("media"), "d:p!:restart:" + projectRef("media"),
 		"t:img", "t:img!", "t:vol", "t:vol!", "t:apt", "t:apt!", "t:all", "t:all!",
 		"u:run", "u:run!",
 		"b:now", "b:now!",
-		"r:refresh",
+		"r:refresh", "r:outages",
 		"g:doctor", "g:pilog",
 		"z:reboot", "z:reboot!",
+		"f:restore", "f:restore!:9999999999", "f:accept",
+		"i:diff:fim-1760000000000000000-scan-modified",
 	}
 	for _, code := range codes {
 		if len(code) > 64 {
@@ -590,6 +643,40 @@ func TestHandleCallback_ConfirmationFlow(t *testing.T) {
 
 	// u:run should show confirmation — no panic
 	w.handleCallback("test-id", "u:run")
+
+	// f:restore without a store should report it rather than panic
+	w.handleCallback("test-id", "f:restore")
+	w.handleCallback("test-id", "f:restore!:1")
+	w.handleCallback("test-id", "f:accept")
+	w.handleCallback("test-id", "i:diff:fim-1-modified")
+}
+
+func TestFullDiffMessages(t *testing.T) {
+	msgs := fullDiffMessages(models.FileChange{Path: "/etc/hosts", Diff: "@@ -1 +1 @@\n-a <b>\n+a & b\n"})
+	want := "📄 <b>Full diff</b> — <code>/etc/hosts</code>\n<pre>@@ -1 +1 @@\n-a &lt;b&gt;\n+a &amp; b</pre>"
+	if len(msgs) != 1 || msgs[0] != want {
+		t.Errorf("fullDiffMessages = %q, want %q", msgs, want)
+	}
+
+	var long strings.Builder
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(&long, "+line %d & more\n", i)
+	}
+	msgs = fullDiffMessages(models.FileChange{Path: "/etc/big.conf", Diff: long.String()})
+	if len(msgs) < 2 || len(msgs) > fullDiffMaxMessages {
+		t.Fatalf("got %d messages", len(msgs))
+	}
+	for i, m := range msgs {
+		if len(m) > 4096 || !strings.HasSuffix(strings.SplitN(m, "</pre>", 2)[0], "more") {
+			t.Errorf("message %d is %d bytes or splits a line", i, len(m))
+		}
+		if (i == 0) != strings.HasPrefix(m, "📄") {
+			t.Errorf("message %d header placement wrong", i)
+		}
+	}
+	if last := msgs[len(msgs)-1]; len(msgs) == fullDiffMaxMessages && !strings.Contains(last, "more lines not shown") {
+		t.Errorf("truncated diff not noted: %q", last[len(last)-80:])
+	}
 }
 
 func TestBuildConfirmView_Layout(t *testing.T) {
@@ -639,3 +726,160 @@ func TestBuildDetailView_HasBackButton(t *testing.T) {
 		t.Error("back button text does not contain 'Back'")
 	}
 }
+
+// ── /devices and /device tests ───────────────────────────────────────────────
+
+func TestCmdDevices(t *testing.T) {
+	db, err := store.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	w := &TelegramBotWatcher{store: db}
+
+	if got := w.cmdDevices(); !strings.Contains(got, "No devices") {
+		t.Errorf("empty inventory: %q", got)
+	}
+
+	db.RecordDevice("aa:bb:cc:dd:ee:01", "192.168.1.20", time.Now())
+	if got := w.cmdDeviceRouter([]string{"/device", "name", "AA-BB-CC-DD-EE-01", "Living", "room", "<TV>"}); !strings.Contains(got, "Living room &lt;TV&gt;") {
+		t.Errorf("name reply: %q", got)
+	}
+	if got := w.cmdDeviceRouter([]string{"/device", "trust", "aa:bb:cc:dd:ee:01"}); !strings.Contains(got, "trusted") {
+		t.Errorf("trust reply: %q", got)
+	}
+	got := w.cmdDevices()
+	if !strings.Contains(got, "✅ <b>Living room &lt;TV&gt;</b>") || !strings.Contains(got, "192.168.1.20") {
+		t.Errorf("device list: %q", got)
+	}
+	if got := w.cmdDeviceRouter([]string{"/device", "aa:bb:cc:dd:ee:01"}); !strings.Contains(got, "First seen") {
+		t.Errorf("device detail: %q", got)
+	}
+
+	if got := w.cmdDeviceRouter([]string{"/device", "trust", "11:22:33:44:55:66"}); !strings.Contains(got, "Unknown device") {
+		t.Errorf("unknown device: %q", got)
+	}
+	if got := w.cmdDeviceRouter([]string{"/device", "name", "not-a-mac", "x"}); !strings.Contains(got, "Invalid MAC") {
+		t.Errorf("invalid MAC: %q", got)
+	}
+}
+
+func TestFormatDevices_CapsAndHidesStale(t *testing.T) {
+	now := time.Now()
+	var devices []store.Device
+	for i := 0; i < 200; i++ {
+		devices = append(devices, store.Device{
+			MAC:      fmt.Sprintf("aa:bb:cc:dd:%02x:%02x", i/256, i%256),
+			IP:       fmt.Sprintf("192.168.1.%d", i),
+			Name:     strings.Repeat("x", 60),
+			LastSeen: now.Add(-time.Duration(i) * time.Hour),
+		})
+	}
+	for i := 0; i < 10; i++ {
+		devices = append(devices, store.Device{
+			MAC:      fmt.Sprintf("de:ad:be:ef:00:%02x", i),
+			IP:       "10.0.0.1",
+			LastSeen: now.Add(-40 * 24 * time.Hour),
+		})
+	}
+
+	got := formatDevices(devices, now)
+	if n := len([]rune(got)); n > 4096 {
+		t.Errorf("reply is %d characters, over Telegram's limit", n)
+	}
+	if !strings.Contains(got, "(200)") || !strings.Contains(got, "… 175 more") {
+		t.Errorf("expected 200 recent devices with 175 not shown: %q", got)
+	}
+	if !strings.Contains(got, "10 not seen for 30 days hidden") || strings.Contains(got, "de:ad:be:ef") {
+		t.Errorf("stale devices not hidden: %q", got)
+	}
+}
+
+func TestCmdStatus_ConnectivityProbes(t *testing.T) {
+	w := &TelegramBotWatcher{Base: Base{Cfg: config.DefaultConfig()}}
+	if got := w.cmdStatus(); strings.Contains(got, "Connectivity") {
+		t.Errorf("status without a connectivity watcher shows connectivity: %q", got)
+	}
+
+	cfg := &config.Config{Connectivity: config.ConnectivityConfig{PollInterval: "30s", Hosts: []string{"8.8.8.8:53"}}}
+	conn := NewConnectivityWatcher(cfg, eventbus.New(), nil)
+	conn.dialFn = func(string, time.Duration) bool { return true }
+	conn.check()
+	w.ConnectivityWatcher = conn
+	got := w.cmdStatus()
+	if !strings.Contains(got, "<b>Connectivity</b> ✅ OK") || !strings.Contains(got, "tcp 8.8.8.8:53") {
+		t.Errorf("status missing probe results: %q", got)
+	}
+}
+
+func TestCmdOutages(t *testing.T) {
+	db := openNetworkTestStore(t)
+	w := &TelegramBotWatcher{store: db}
+	if got := w.cmdOutages(); !strings.Contains(got, "No outages recorded") || !strings.Contains(got, "Last 7 days:</b> 100% available") {
+		t.Errorf("empty history = %q", got)
+	}
+
+	now := time.Now()
+	id, _ := db.StartOutage(now.Add(-2 * time.Hour))
+	db.EndOutage(id, now.Add(-2*time.Hour+12*time.Minute))
+	id, _ = db.StartOutage(now.Add(-5 * time.Minute))
+	db.TouchOutage(id, now)
+
+	got := w.cmdOutages()
+	if !strings.Contains(got, "12m") || !strings.Contains(got, "ongoing, 5m so far") {
+		t.Errorf("outage list = %q", got)
+	}
+	if !strings.Contains(got, "down in 2 outages") {
+		t.Errorf("availability missing from %q", got)
+	}
+}
+
+func TestCmdIP_PublicAndHistory(t *testing.T) {
+	db := openNetworkTestStore(t)
+	w := &TelegramBotWatcher{store: db}
+	if got := w.cmdIP(); strings.Contains(got, "History") || strings.Contains(got, "Public") {
+		t.Errorf("empty store shows history: %q", got)
+	}
+
+	db.SetState(PublicIPStateKey, "198.51.100.23")
+	db.AddAddressChange(store.AddressChange{ChangedAt: time.Now(), Scope: "public", Old: "203.0.113.7", New: "198.51.100.23"})
+	db.AddAddressChange(store.AddressChange{ChangedAt: time.Now(), Scope: "wlan0", New: "192.168.1.57/24"})
+
+	got := w.cmdIP()
+	if !strings.Contains(got, "Public: <code>198.51.100.23</code>") {
+		t.Errorf("public IP missing from %q", got)
+	}
+	if !strings.Contains(got, "public: 203.0.113.7 → 198.51.100.23") || !strings.Contains(got, "wlan0: none → 192.168.1.57/24") {
+		t.Errorf("history missing from %q", got)
+	}
+}
+
+func TestCmdPorts_UDPLabelledAsUDP(t *testing.T) {
+	cfg := &config.Config{Ports: config.PortConfig{Known: []config.KnownPort{
+		{Addr: "tcp:*:123", Label: "Admin panel", Risk: "critical"},
+	}}}
+	w := NewTelegramBotWatcher(cfg, eventbus.New(), nil)
+	var gotArgs []string
+	w.runSS = func(args ...string) ([]byte, error) {
+		gotArgs = args
+		return []byte(`Netid State  Recv-Q Send-Q Local Address:Port Peer Address:Port Process
+tcp   LISTEN 0      128    0.0.0.0:22         0.0.0.0:*         users:(("sshd",pid=1,fd=3))
+udp   UNCONN 0      0      0.0.0.0:123        0.0.0.0:*         users:(("chronyd",pid=2,fd=5))
+udp   UNCONN 0      0      0.0.0.0:161        0.0.0.0:*         users:(("snmpd",pid=3,fd=6))
+`), nil
+	}
+
+	got := w.cmdPorts()
+	if !slices.Contains(gotArgs, "-u") {
+		t.Errorf("ss args = %v, want UDP included", gotArgs)
+	}
+	if !strings.Contains(got, "⚠️ <code>udp:0.0.0.0:123</code> → chronyd\n") {
+		t.Errorf("UDP 123 took the TCP label or risk: %q", got)
+	}
+	if !strings.Contains(got, "🔴 <code>udp:0.0.0.0:161</code> → snmpd · <i>SNMP</i>") {
+		t.Errorf("UDP-only known port not labelled: %q", got)
+	}
+	if !strings.Contains(got, "<code>tcp:0.0.0.0:22</code> → sshd · <i>SSH</i>") {
+		t.Errorf("TCP listener missing: %q", got)
+	}
+}
@@ -0,0 +1,227 @@
+package watchers
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	diffContext = 3 // unchanged lines around each change, as diff -u
+
+	// diffMaxEdits bounds the Myers search: past it the files are shown as
+	// wholly replaced rather than spending quadratic memory on a rewrite.
+	diffMaxEdits = 1000
+)
+
+// diffLine is one line of an edit script: ' ' kept, '-' removed, '+' added.
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// unifiedDiff returns the unified diff from old to new labelled with path,
+// or "" when their lines are equal.
+func unifiedDiff(path, old, new string) string {
+	lines := diffLines(splitLines(old), splitLines(new))
+
+	var b strings.Builder
+	for _, h := range diffHunks(lines) {
+		if b.Len() == 0 {
+			fmt.Fprintf(&b, "--- %s (last known)\n+++ %s\n", path, path)
+		}
+		b.WriteString(h)
+	}
+	return b.String()
+}
+
+// splitLines splits text into lines without their newlines.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines returns a shortest edit script from a to b (Myers' algorithm),
+// after setting aside the common prefix and suffix, which is where most of
+// a config file edit lies.
+func diffLines(a, b []string) []diffLine {
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	out := make([]diffLine, 0, len(a)+len(b))
+	for _, s := range a[:pre] {
+		out = append(out, diffLine{' ', s})
+	}
+	out = append(out, myers(a[pre:len(a)-suf], b[pre:len(b)-suf])...)
+	for _, s := range a[len(a)-suf:] {
+		out = append(out, diffLine{' ', s})
+	}
+	return out
+}
+
+// myers is the O((N+M)D) greedy shortest edit script. trace keeps, for each
+// edit count d, the furthest x reached on diagonals -d..d so the path can be
+// walked back.
+func myers(a, b []string) []diffLine {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return replaceAll(a, b)
+	}
+
+	var trace [][]int
+	var v []int
+	for d := 0; d <= n+m; d++ {
+		if d > diffMaxEdits {
+			return replaceAll(a, b)
+		}
+		next := make([]int, 2*d+1) // diagonals -d..d
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && at(v, d-1, k-1) < at(v, d-1, k+1)) {
+				x = at(v, d-1, k+1) // down: insert from b
+			} else {
+				x = at(v, d-1, k-1) + 1 // right: delete from a
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			next[k+d] = x
+			if x >= n && y >= m {
+				trace = append(trace, next)
+				return backtrack(a, b, trace)
+			}
+		}
+		trace = append(trace, next)
+		v = next
+	}
+	return replaceAll(a, b)
+}
+
+// at returns the furthest x on diagonal k after d edits. Before the first
+// step the only reachable diagonal is 1, at x = 0.
+func at(v []int, d, k int) int {
+	if d < 0 {
+		return 0
+	}
+	return v[k+d]
+}
+
+func backtrack(a, b []string, trace [][]int) []diffLine {
+	var rev []diffLine
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d-1]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(v, d-1, k-1) < at(v, d-1, k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(v, d-1, prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			rev = append(rev, diffLine{' ', a[x-1]})
+			x, y = x-1, y-1
+		}
+		if x == prevX {
+			rev = append(rev, diffLine{'+', b[y-1]})
+			y--
+		} else {
+			rev = append(rev, diffLine{'-', a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		rev = append(rev, diffLine{' ', a[x-1]})
+		x, y = x-1, y-1
+	}
+
+	out := make([]diffLine, len(rev))
+	for i, l := range rev {
+		out[len(rev)-1-i] = l
+	}
+	return out
+}
+
+func replaceAll(a, b []string) []diffLine {
+	out := make([]diffLine, 0, len(a)+len(b))
+	for _, s := range a {
+		out = append(out, diffLine{'-', s})
+	}
+	for _, s := range b {
+		out = append(out, diffLine{'+', s})
+	}
+	return out
+}
+
+// diffHunks groups an edit script into unified diff hunks, merging changes
+// fewer than 2*diffContext kept lines apart.
+func diffHunks(lines []diffLine) []string {
+	var hunks []string
+	for i := 0; i < len(lines); {
+		if lines[i].op == ' ' {
+			i++
+			continue
+		}
+		start := max(i-diffContext, 0)
+		end := i
+		for j := i; j < len(lines); j++ {
+			if lines[j].op != ' ' {
+				end = j + 1
+			} else if j-end >= 2*diffContext {
+				break
+			}
+		}
+		end = min(end+diffContext, len(lines))
+		hunks = append(hunks, formatHunk(lines, start, end))
+		i = end
+	}
+	return hunks
+}
+
+func formatHunk(lines []diffLine, start, end int) string {
+	oldStart, newStart := 1, 1
+	for _, l := range lines[:start] {
+		if l.op != '+' {
+			oldStart++
+		}
+		if l.op != '-' {
+			newStart++
+		}
+	}
+	var body strings.Builder
+	oldLen, newLen := 0, 0
+	for _, l := range lines[start:end] {
+		if l.op != '+' {
+			oldLen++
+		}
+		if l.op != '-' {
+			newLen++
+		}
+		body.WriteByte(l.op)
+		body.WriteString(l.text)
+		body.WriteByte('\n')
+	}
+	return fmt.Sprintf("@@ -%s +%s @@\n%s", hunkRange(oldStart, oldLen), hunkRange(newStart, newLen), body.String())
+}
+
+// hunkRange formats a hunk's line range as GNU diff does: the count is
+// omitted when it is 1, and an empty range names the line before it.
+func hunkRange(start, length int) string {
+	switch length {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, length)
+}
@@ -0,0 +1,92 @@
+package watchers
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	old := "# sshd_config\nPort 22\nPermitRootLogin no\nPasswordAuthentication no\nUsePAM yes\n"
+	new := "# sshd_config\nPort 22\nPermitRootLogin yes\nPasswordAuthentication no\nUsePAM yes\nAllowUsers pi\n"
+	want := `--- /etc/ssh/sshd_config (last known)
++++ /etc/ssh/sshd_config
+@@ -1,5 +1,6 @@
+ # sshd_config
+ Port 22
+-PermitRootLogin no
++PermitRootLogin yes
+ PasswordAuthentication no
+ UsePAM yes
++AllowUsers pi
+`
+	if got := unifiedDiff("/etc/ssh/sshd_config", old, new); got != want {
+		t.Errorf("unifiedDiff =\n%s\nwant\n%s", got, want)
+	}
+	if got := unifiedDiff("/etc/hosts", old, old); got != "" {
+		t.Errorf("equal files diff = %q", got)
+	}
+}
+
+func TestUnifiedDiff_Hunks(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 30; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	old := strings.Join(lines, "\n") + "\n"
+	lines[1] = "changed 2"
+	lines = append(lines[:20], lines[21:]...) // delete line 21
+	new := strings.Join(lines, "\n") + "\n"
+
+	got := unifiedDiff("f", old, new)
+	if !strings.Contains(got, "@@ -1,5 +1,5 @@\n line 1\n-line 2\n+changed 2\n line 3\n") {
+		t.Errorf("first hunk wrong:\n%s", got)
+	}
+	if !strings.Contains(got, "@@ -18,7 +18,6 @@\n line 18\n line 19\n line 20\n-line 21\n line 22\n") {
+		t.Errorf("second hunk wrong:\n%s", got)
+	}
+	if strings.Count(got, "@@ -") != 2 {
+		t.Errorf("want 2 hunks:\n%s", got)
+	}
+}
+
+func TestUnifiedDiff_EmptyAndRewritten(t *testing.T) {
+	if got := unifiedDiff("f", "", "a\nb\n"); !strings.Contains(got, "@@ -0,0 +1,2 @@\n+a\n+b\n") {
+		t.Errorf("diff from empty:\n%s", got)
+	}
+
+	// A rewrite past diffMaxEdits is shown as a whole replacement.
+	var a, b strings.Builder
+	for i := range diffMaxEdits + 10 {
+		fmt.Fprintf(&a, "old %d\n", i)
+		fmt.Fprintf(&b, "new %d\n", i)
+	}
+	got := unifiedDiff("f", a.String(), b.String())
+	if strings.Count(got, "\n-old") != diffMaxEdits+10 || strings.Count(got, "\n+new") != diffMaxEdits+10 {
+		t.Errorf("rewrite not shown as a replacement")
+	}
+}
+
+func TestDiffLines_Minimal(t *testing.T) {
+	a := strings.Split("a b c a b b a", " ")
+	b := strings.Split("c b a b a c", " ")
+	edits := 0
+	var gotA, gotB []string
+	for _, l := range diffLines(a, b) {
+		if l.op != ' ' {
+			edits++
+		}
+		if l.op != '+' {
+			gotA = append(gotA, l.text)
+		}
+		if l.op != '-' {
+			gotB = append(gotB, l.text)
+		}
+	}
+	if edits != 5 {
+		t.Errorf("edits = %d, want 5 (Myers' example)", edits)
+	}
+	if strings.Join(gotA, " ") != strings.Join(a, " ") || strings.Join(gotB, " ") != strings.Join(b, " ") {
+		t.Errorf("script does not rebuild the inputs: %v / %v", gotA, gotB)
+	}
+}
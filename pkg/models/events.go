@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // Severity levels for events
 type Severity int
@@ -23,6 +26,20 @@ func (s Severity) String() string {
 	return "unknown"
 }
 
+// ParseSeverity converts a severity name ("info", "warning", "critical")
+// back to a Severity. Matching is case-insensitive.
+func ParseSeverity(name string) (Severity, bool) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "info":
+		return SeverityInfo, true
+	case "warning":
+		return SeverityWarning, true
+	case "critical":
+		return SeverityCritical, true
+	}
+	return SeverityInfo, false
+}
+
 func (s Severity) Emoji() string {
 	switch s {
 	case SeverityInfo:
@@ -62,32 +79,133 @@ const (
 	EventNetworkDeviceLeft   EventType = "network.device_left"        // Known device disappeared from LAN
 	EventConnectivityLost     EventType = "connectivity.lost"      // All probe hosts unreachable
 	EventConnectivityRestored EventType = "connectivity.restored"   // Connectivity returned after outage
+	EventConnectivityDegraded EventType = "connectivity.degraded"   // A probe's latency or loss crossed the degraded thresholds
 	EventContainerUpdated     EventType = "docker.container_updated" // Container replaced with new image (Watchtower)
 	EventSystemUpdated        EventType = "system.updated"           // Successful apt upgrade
 	EventSystemUpdateFailed   EventType = "system.update_failed"     // apt upgrade error
 	EventBackupStarted        EventType = "backup.started"           // Backup job began
 	EventBackupCompleted      EventType = "backup.completed"         // Backup finished successfully
 	EventBackupFailed         EventType = "backup.failed"            // Backup encountered an error
+	EventOutboundNewDest      EventType = "outbound.new_destination" // Process connected somewhere it never has before
+	EventOutboundFirst        EventType = "outbound.first_connection" // Process with no outbound history started connecting out
+	EventOutboundBlocked      EventType = "outbound.blocked_port"    // Connection to a blocklisted remote port
+	EventContainerCrashLoop   EventType = "docker.container_crash_loop" // Container restarted N times within the crash-loop window
+	EventContainerOOM         EventType = "docker.container_oom"        // Container killed by the OOM killer
+	EventContainerInsecure    EventType = "docker.container_insecure"   // New container started with a risky configuration
+	EventContainerResource    EventType = "docker.container_resource_high" // Container CPU, memory, pids or I/O above threshold for the sustained period
+	EventProjectDegraded      EventType = "docker.project_degraded"   // A service of a Compose project is down
+	EventProjectRecovered     EventType = "docker.project_recovered"  // Every service of a degraded Compose project is back up
+	EventContainerExec        EventType = "docker.container_exec"     // Command run inside a container with docker exec
+	EventContainerCopy        EventType = "docker.container_copy"     // Files copied into or out of a container with docker cp
+	EventImagePull            EventType = "docker.image_pull"         // Image pulled from a registry
+	EventImageTag             EventType = "docker.image_tag"          // Image tagged
+	EventImageDelete          EventType = "docker.image_delete"       // Image deleted
+	EventVolumeCreate         EventType = "docker.volume_create"      // Named volume created
+	EventNetworkCreate        EventType = "docker.network_create"     // Network created
+	EventGatewayMACChanged    EventType = "network.gateway_mac_changed" // Default gateway's IP now answers from a different MAC
+	EventMACMultipleIPs       EventType = "network.mac_multiple_ips"    // One MAC holds more IPv4 addresses than network.mac_ip_limit
+	EventIPFlapping           EventType = "network.ip_flapping"         // An IP moved between MACs repeatedly within network.ip_flap_window
+	EventRogueDHCPServer      EventType = "network.rogue_dhcp_server"   // DHCP offer from a server not in network.dhcp.servers (or a second server)
+	EventDHCPOptionMismatch   EventType = "network.dhcp_option_mismatch" // DHCP offer with an unexpected router or DNS server
+	EventDNSResolverChanged   EventType = "dns.resolver_changed"        // Nameservers in resolv.conf or systemd-resolved's upstreams changed
+	EventDNSMismatch          EventType = "dns.mismatch"                // Canary name resolved to an unexpected address (hijacked or poisoned DNS)
+	EventDNSFailure           EventType = "dns.failure"                 // Canary name failed to resolve through the system resolver
+	EventDNSRestored          EventType = "dns.restored"                // Canary name resolves correctly again
+	EventAddressChanged       EventType = "network.address_changed"     // An interface gained, lost or changed an IP address
+	EventLinkDown             EventType = "network.link_down"           // An interface lost its link or was removed
+	EventLinkUp               EventType = "network.link_up"             // An interface's link came back
+	EventPublicIPChanged      EventType = "network.public_ip_changed"   // The public (WAN) IP changed
 )
 
 // PortInfo describes a listening port with full context
 type PortInfo struct {
-	Address       string `json:"address"`        // e.g. "0.0.0.0:8080"
-	Protocol      string `json:"protocol"`       // "tcp" or "udp"
-	PID           int    `json:"pid"`
-	ProcessName   string `json:"process_name"`   // e.g. "docker-proxy"
-	ContainerName string `json:"container_name"` // e.g. "nginx" (empty if not Docker)
-	ContainerID   string `json:"container_id"`
-	IsExposed     bool   `json:"is_exposed"`     // true if bound to 0.0.0.0 or ::
+	Address       string       `json:"address"`             // e.g. "0.0.0.0:8080"
+	Protocol      string       `json:"protocol"`            // "tcp", "udp" or "raw"
+	PID           int          `json:"pid"`
+	ProcessName   string       `json:"process_name"`        // e.g. "docker-proxy"
+	ContainerName string       `json:"container_name"`      // e.g. "nginx" (empty if not Docker)
+	ContainerID   string       `json:"container_id"`
+	IsExposed     bool         `json:"is_exposed"`          // true if bound to 0.0.0.0 or ::
+	Label         string       `json:"label,omitempty"`     // e.g. "Home Assistant" (from ports.known)
+	Risk          string       `json:"risk,omitempty"`      // severity when exposed: info, warning, critical
+	Reachable     Reachability `json:"reachable,omitempty"` // firewall verdict; empty if not analysed
+	Published     bool         `json:"published,omitempty"` // DNAT'd to a container by a rootful runtime, so it takes FORWARD
 }
 
+// Reachability is the effective exposure of a listener once the firewall
+// rules in front of it are taken into account.
+type Reachability string
+
+const (
+	ReachLocal    Reachability = "local"    // bound to loopback
+	ReachFiltered Reachability = "filtered" // firewall drops new inbound connections
+	ReachLAN      Reachability = "lan"      // reachable from private networks only
+	ReachWAN      Reachability = "wan"      // reachable from any address
+	ReachBypass   Reachability = "bypass"   // Docker-published, skips INPUT via FORWARD
+)
+
+// Key identifies the socket across protocols, e.g. "udp:0.0.0.0:5353".
+// A TCP and a UDP listener on the same address are different sockets.
+func (p PortInfo) Key() string {
+	proto := p.Protocol
+	if proto == "" {
+		proto = "tcp"
+	}
+	return proto + ":" + p.Address
+}
+
+// RiskLevel is the severity of this port being open. Localhost-only and
+// firewalled ports are info, Docker ports that bypass the firewall are
+// critical; other exposed ports use Risk when set, otherwise warning. A
+// critical-risk service behind the firewall stays a warning: one rule change
+// exposes it.
 func (p PortInfo) RiskLevel() Severity {
-	if !p.IsExposed {
+	switch {
+	case p.Reachable == ReachBypass:
+		return SeverityCritical
+	case p.IsExposed && p.Reachable == ReachFiltered:
+		if sev, ok := ParseSeverity(p.Risk); ok && sev == SeverityCritical {
+			return SeverityWarning
+		}
+		return SeverityInfo // dropped at INPUT, low concern
+	case !p.IsExposed || p.Reachable == ReachLocal:
 		return SeverityInfo // localhost only, low concern
 	}
+	if sev, ok := ParseSeverity(p.Risk); ok {
+		return sev
+	}
 	return SeverityWarning // exposed to network
 }
 
+// ConnectionInfo describes an established outbound flow and who owns it
+type ConnectionInfo struct {
+	Protocol      string `json:"protocol"`       // "tcp" or "udp"
+	LocalAddr     string `json:"local_addr"`     // e.g. "192.168.1.10:51234"
+	RemoteAddr    string `json:"remote_addr"`    // e.g. "203.0.113.7:3333"
+	PID           int    `json:"pid"`
+	ProcessName   string `json:"process_name"`
+	ContainerName string `json:"container_name"` // empty if not in a container
+	ContainerID   string `json:"container_id"`
+}
+
+// Owner names what the flow is attributed to: the container if there is
+// one, otherwise the process.
+func (c ConnectionInfo) Owner() string {
+	if c.ContainerName != "" {
+		return "container:" + c.ContainerName
+	}
+	return c.ProcessName
+}
+
+// Destination identifies the remote end across protocols, e.g. "tcp:203.0.113.7:443".
+func (c ConnectionInfo) Destination() string {
+	proto := c.Protocol
+	if proto == "" {
+		proto = "tcp"
+	}
+	return proto + ":" + c.RemoteAddr
+}
+
 // FirewallState captures iptables chain state
 type FirewallState struct {
 	Chain        string `json:"chain"`
@@ -97,6 +215,12 @@ type FirewallState struct {
 	HasDropRule  bool   `json:"has_drop_rule"`
 }
 
+// FileChange describes a change to a file watched for integrity
+type FileChange struct {
+	Path string `json:"path"`
+	Diff string `json:"diff,omitempty"` // unified diff from the last known content; "" = not kept
+}
+
 // SystemHealth holds system metrics
 type SystemHealth struct {
 	DiskUsagePercent   int     `json:"disk_usage_percent"`
@@ -108,6 +232,24 @@ type SystemHealth struct {
 	ListeningPorts     int     `json:"listening_ports"`
 }
 
+// Availability is how much of a period the internet was reachable, from the
+// connectivity outages recorded in it.
+type Availability struct {
+	From     time.Time     `json:"from"`
+	To       time.Time     `json:"to"`
+	Downtime time.Duration `json:"downtime"`
+	Outages  int           `json:"outages"` // outages overlapping the period
+}
+
+// Percent returns the share of the period without an outage.
+func (a Availability) Percent() float64 {
+	period := a.To.Sub(a.From)
+	if period <= 0 {
+		return 100
+	}
+	return 100 * (1 - float64(a.Downtime)/float64(period))
+}
+
 // Event is the core event structure that flows through the system
 type Event struct {
 	ID        string    `json:"id"`
@@ -121,7 +263,9 @@ type Event struct {
 	Source    string    `json:"source"`     // Which watcher generated this
 
 	// Optional typed payloads
-	Port     *PortInfo      `json:"port,omitempty"`
-	Firewall *FirewallState `json:"firewall,omitempty"`
-	Health   *SystemHealth  `json:"health,omitempty"`
+	Port       *PortInfo       `json:"port,omitempty"`
+	Connection *ConnectionInfo `json:"connection,omitempty"`
+	Firewall   *FirewallState  `json:"firewall,omitempty"`
+	Health     *SystemHealth   `json:"health,omitempty"`
+	File       *FileChange     `json:"file,omitempty"`
 }
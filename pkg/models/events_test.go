@@ -49,6 +49,15 @@ func TestPortInfo_RiskLevel(t *testing.T) {
 		{"not exposed", PortInfo{IsExposed: false}, SeverityInfo},
 		{"exposed", PortInfo{IsExposed: true}, SeverityWarning},
 		{"zero value", PortInfo{}, SeverityInfo},
+		{"exposed critical", PortInfo{IsExposed: true, Risk: "critical"}, SeverityCritical},
+		{"exposed info", PortInfo{IsExposed: true, Risk: "info"}, SeverityInfo},
+		{"local critical", PortInfo{IsExposed: false, Risk: "critical"}, SeverityInfo},
+		{"bad risk", PortInfo{IsExposed: true, Risk: "nope"}, SeverityWarning},
+		{"filtered", PortInfo{IsExposed: true, Reachable: ReachFiltered}, SeverityInfo},
+		{"filtered critical", PortInfo{IsExposed: true, Risk: "critical", Reachable: ReachFiltered}, SeverityWarning},
+		{"filtered warning", PortInfo{IsExposed: true, Risk: "warning", Reachable: ReachFiltered}, SeverityInfo},
+		{"lan", PortInfo{IsExposed: true, Reachable: ReachLAN}, SeverityWarning},
+		{"docker bypass", PortInfo{IsExposed: true, Reachable: ReachBypass}, SeverityCritical},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -59,6 +68,25 @@ func TestPortInfo_RiskLevel(t *testing.T) {
 	}
 }
 
+func TestConnectionInfo_OwnerAndDestination(t *testing.T) {
+	c := ConnectionInfo{RemoteAddr: "203.0.113.7:443", ProcessName: "curl"}
+	if got := c.Owner(); got != "curl" {
+		t.Errorf("Owner() = %q, want %q", got, "curl")
+	}
+	if got := c.Destination(); got != "tcp:203.0.113.7:443" {
+		t.Errorf("Destination() = %q, want tcp default", got)
+	}
+
+	c.Protocol = "udp"
+	c.ContainerName = "miner"
+	if got := c.Owner(); got != "container:miner" {
+		t.Errorf("Owner() = %q, want container owner", got)
+	}
+	if got := c.Destination(); got != "udp:203.0.113.7:443" {
+		t.Errorf("Destination() = %q", got)
+	}
+}
+
 func TestEventType_Uniqueness(t *testing.T) {
 	types := []EventType{
 		EventPortOpened, EventPortClosed,
@@ -69,8 +97,17 @@ func TestEventType_Uniqueness(t *testing.T) {
 		EventFileChanged, EventDailySummary, EventWeeklySummary,
 		EventMalwareFound, EventRootkitWarning,
 		EventNetworkNewDevice, EventNetworkDeviceLeft,
-		EventConnectivityLost, EventConnectivityRestored,
+		EventConnectivityLost, EventConnectivityRestored, EventConnectivityDegraded,
 		EventContainerUpdated, EventSystemUpdated, EventSystemUpdateFailed,
+		EventOutboundNewDest, EventOutboundFirst, EventOutboundBlocked,
+		EventContainerCrashLoop, EventContainerOOM, EventContainerInsecure,
+		EventContainerResource, EventProjectDegraded, EventProjectRecovered,
+		EventContainerExec, EventContainerCopy, EventImagePull, EventImageTag, EventImageDelete,
+		EventVolumeCreate, EventNetworkCreate,
+		EventGatewayMACChanged, EventMACMultipleIPs, EventIPFlapping,
+		EventRogueDHCPServer, EventDHCPOptionMismatch,
+		EventDNSResolverChanged, EventDNSMismatch, EventDNSFailure, EventDNSRestored,
+		EventAddressChanged, EventLinkDown, EventLinkUp, EventPublicIPChanged,
 	}
 
 	seen := make(map[EventType]bool)
@@ -209,6 +246,9 @@ func TestEvent_JSONOmitsNilPayloads(t *testing.T) {
 	if contains(s, "port") {
 		t.Errorf("JSON should omit nil port, got: %s", s)
 	}
+	if contains(s, "connection") {
+		t.Errorf("JSON should omit nil connection, got: %s", s)
+	}
 	if contains(s, "firewall") {
 		t.Errorf("JSON should omit nil firewall, got: %s", s)
 	}
@@ -234,3 +274,14 @@ func contains(s, sub string) bool {
 	}
 	return false
 }
+
+func TestAvailability_Percent(t *testing.T) {
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	a := Availability{From: from, To: from.Add(100 * time.Hour), Downtime: time.Hour}
+	if got := a.Percent(); got != 99 {
+		t.Errorf("Percent() = %v, want 99", got)
+	}
+	if got := (Availability{From: from, To: from}).Percent(); got != 100 {
+		t.Errorf("empty period Percent() = %v, want 100", got)
+	}
+}
@@ -0,0 +1,33 @@
+#!/bin/bash
+# Regenerates internal/netdevice/oui.txt.gz, the MAC vendor registry embedded
+# in the binary, from the IEEE MA-L (OUI) registry. Run from the repo root,
+# or with `make oui`, and commit the result.
+set -euo pipefail
+
+URL="${OUI_URL:-https://standards-oui.ieee.org/oui/oui.txt}"
+OUT="internal/netdevice/oui.txt.gz"
+
+tmp=$(mktemp)
+gz=$(mktemp)
+trap 'rm -f "$tmp" "$gz"' EXIT
+
+curl -fsSL --retry 3 -A "piguard-update-oui" -o "$tmp" "$URL"
+
+{
+    echo "# IEEE MA-L registry, generated by scripts/update-oui.sh from $URL on $(date -u +%Y-%m-%d)."
+    echo "# Format: 6 hex digits, space, vendor name."
+    # "28-CD-C1   (hex)		Raspberry Pi Trading Ltd" -> "28CDC1 Raspberry Pi Trading Ltd"
+    tr -d '\r' <"$tmp" |
+        sed -nE 's/^([0-9A-Fa-f]{2})-([0-9A-Fa-f]{2})-([0-9A-Fa-f]{2})[[:space:]]+\(hex\)[[:space:]]+(.*[^[:space:]])[[:space:]]*$/\1\2\3 \4/p' |
+        LC_ALL=C sort -u
+} | gzip -9n >"$gz"
+
+# A truncated download or a format change must not replace a good registry.
+count=$(gzip -dc "$gz" | grep -vc '^#' || true)
+if [ "$count" -lt 10000 ]; then
+    echo "Only $count vendors parsed from $URL; $OUT left unchanged" >&2
+    exit 1
+fi
+chmod 644 "$gz"
+mv "$gz" "$OUT"
+echo "Wrote $count vendors to $OUT"